@@ -9,18 +9,25 @@ import (
 	"syscall"
 	"time"
 
+	"driftive.cloud/api/pkg/blobstore"
 	"driftive.cloud/api/pkg/config"
 	"driftive.cloud/api/pkg/db"
+	"driftive.cloud/api/pkg/middleware/bodylimit"
 	"driftive.cloud/api/pkg/middleware/perms"
 	"driftive.cloud/api/pkg/model"
 	"driftive.cloud/api/pkg/observability"
 	"driftive.cloud/api/pkg/repository"
 	"driftive.cloud/api/pkg/usecase/auth"
 	"driftive.cloud/api/pkg/usecase/auth/github"
+	"driftive.cloud/api/pkg/usecase/checks"
 	"driftive.cloud/api/pkg/usecase/cleanup"
+	"driftive.cloud/api/pkg/usecase/dispatch"
 	"driftive.cloud/api/pkg/usecase/drift_stream"
+	"driftive.cloud/api/pkg/usecase/issues"
 	"driftive.cloud/api/pkg/usecase/orgs"
+	"driftive.cloud/api/pkg/usecase/outputs"
 	"driftive.cloud/api/pkg/usecase/repos"
+	"driftive.cloud/api/pkg/usecase/schedule"
 	github3 "driftive.cloud/api/pkg/usecase/sync/org/github"
 	github2 "driftive.cloud/api/pkg/usecase/sync/user_resources/github"
 	"driftive.cloud/api/pkg/utils"
@@ -85,7 +92,11 @@ func main() {
 
 	repo := repository.NewRepository(db_, cfg)
 
-	app := fiber.New()
+	// Streaming request bodies hands uploads over the default 4 MiB body limit to the handler as
+	// a stream instead of rejecting them; the drift ingest enforces its own decoded-size limits.
+	// Every other route keeps the default limit through bodylimit, registered after the ingest
+	// routes.
+	app := fiber.New(fiber.Config{StreamRequestBody: true})
 	app.Use(requestid.New())
 	app.Use(logger.New(logger.Config{
 		TimeFormat: time.RFC3339,
@@ -100,6 +111,7 @@ func main() {
 
 	api := app.Group("/api")
 	v1 := api.Group("/v1")
+	v2 := api.Group("/v2")
 
 	// Repos
 	userRepo := repo.UserRepository()
@@ -128,14 +140,31 @@ func main() {
 			staleRunMinutes = int32(parsed)
 		}
 	}
-	cleanupService := cleanup.NewCleanupService(driftRepo, maxRunsPerRepo, staleRunMinutes)
+	blobs, err := blobstore.New(cfg.BlobStore)
+	if err != nil {
+		log.Panic("error configuring blob store. ", err)
+	}
+	cleanupService := cleanup.NewCleanupService(driftRepo, maxRunsPerRepo, staleRunMinutes, blobs)
+	outputService := outputs.NewOutputService(driftRepo, blobs)
+	issueService := issues.NewIssueService(driftRepo, outputService, issues.NewGitHubTracker)
+	checkService := checks.NewCheckService(driftRepo, orgRepo, repoRepo, checks.NewGitHubPublisher)
+	overdueMonitor := schedule.NewOverdueMonitor(repoRepo)
 
 	// handlers
 	ghOAuthHandler := github.NewOAuthHandler(*cfg, db_, userRepo, syncStatusUserRepo)
 	organizationHandler := orgs.NewGitOrganizationHandler(*cfg, db_, orgRepo)
 	repositoryHandler := repos.NewGitRepositoryHandler(orgRepo, repoRepo, userRepo, driftRepo)
-	driftStateHandler := drift_stream.NewDriftStateHandler(cfg, orgRepo, repoRepo, driftRepo, cleanupService)
+	driftStateHandler := drift_stream.NewDriftStateHandler(cfg, orgRepo, repoRepo, driftRepo, cleanupService, outputService, issueService, checkService)
 	profileHandler := auth.NewProfileHandler(userRepo)
+	dispatchHandler := dispatch.NewDispatchHandler(orgRepo, repoRepo, userRepo, driftRepo, dispatch.NewGitHubDispatcher)
+
+	// Drift ingest routes, which read their request body as a stream
+	v1.Post("/drift_analysis", func(c fiber.Ctx) error { return driftStateHandler.HandleUpdate(c) })
+	v1.Post("/drift_analysis/progress", func(c fiber.Ctx) error { return driftStateHandler.HandleProgress(c) })
+	v1.Post("/drift_analysis/stream", func(c fiber.Ctx) error { return driftStateHandler.HandleStream(c) })
+	v2.Post("/drift_analysis", func(c fiber.Ctx) error { return driftStateHandler.HandleUpdateV2(c) })
+
+	app.Use(bodylimit.New(fiber.DefaultBodyLimit))
 
 	// Public routes
 	app.Get("/", func(c fiber.Ctx) error {
@@ -147,8 +176,7 @@ func main() {
 	v1.Get("/auth/github/callback", func(c fiber.Ctx) error {
 		return ghOAuthHandler.Callback(c)
 	})
-	v1.Post("/drift_analysis", func(c fiber.Ctx) error { return driftStateHandler.HandleUpdate(c) })
-	v1.Post("/drift_analysis/progress", func(c fiber.Ctx) error { return driftStateHandler.HandleProgress(c) })
+	v2.Get("/drift_analysis/schema", func(c fiber.Ctx) error { return driftStateHandler.GetSchema(c) })
 	v1.Get("/orgs/gh_installed", func(c fiber.Ctx) error { return organizationHandler.HandleGHOrganizationInstalled(c) })
 
 	app.Use(jwtware.New(jwtware.Config{
@@ -161,13 +189,37 @@ func main() {
 	v1.Get("/auth/me", func(c fiber.Ctx) error { return profileHandler.GetLoggedUser(c) })
 	v1.Get("/org/:org_id/repos", func(c fiber.Ctx) error { return repositoryHandler.ListOrganizationRepos(c) })
 	v1.Get("/org/:org_id/repo", func(c fiber.Ctx) error { return repositoryHandler.GetRepoByOrgIdAndName(c) })
+	v1.Get("/org/:org_id/redaction_rules", func(c fiber.Ctx) error { return organizationHandler.ListRedactionRules(c) })
+	v1.Post("/org/:org_id/redaction_rules", func(c fiber.Ctx) error { return organizationHandler.CreateRedactionRule(c) })
+	v1.Delete("/org/:org_id/redaction_rules/:rule_id", func(c fiber.Ctx) error { return organizationHandler.DeleteRedactionRule(c) })
+	v1.Get("/org/:org_id/trends/resources", func(c fiber.Ctx) error { return driftStateHandler.GetOrganizationResourceTrends(c) })
+	v1.Get("/org/:org_id/inventory/versions", func(c fiber.Ctx) error { return driftStateHandler.GetOrganizationVersionInventory(c) })
+	v1.Get("/org/:org_id/inventory/outdated", func(c fiber.Ctx) error { return driftStateHandler.GetOrganizationOutdatedVersions(c) })
 	v1.Get("/repo/:repo_id/token", func(c fiber.Ctx) error { return repositoryHandler.GetRepoTokenById(c) })
 	v1.Post("/repo/:repo_id/token", func(c fiber.Ctx) error { return repositoryHandler.RegenerateToken(c) })
+	v1.Put("/repo/:repo_id/drift_issues", func(c fiber.Ctx) error { return repositoryHandler.UpdateDriftIssues(c) })
+	v1.Put("/repo/:repo_id/dispatch_settings", func(c fiber.Ctx) error { return repositoryHandler.UpdateDispatchSettings(c) })
+	v1.Put("/repo/:repo_id/scan_schedule", func(c fiber.Ctx) error { return repositoryHandler.UpdateScanSchedule(c) })
+	v1.Get("/repo/:repo_id/scan_alerts", func(c fiber.Ctx) error { return repositoryHandler.ListScanAlerts(c) })
+	v1.Get("/repo/:repo_id/dispatches", func(c fiber.Ctx) error { return dispatchHandler.ListDispatches(c) })
+	v1.Post("/repo/:repo_id/dispatches/scan", func(c fiber.Ctx) error { return dispatchHandler.DispatchScan(c) })
+	v1.Post("/repo/:repo_id/dispatches/remediation", func(c fiber.Ctx) error { return dispatchHandler.DispatchRemediation(c) })
 	v1.Delete("/repo/:repo_id", func(c fiber.Ctx) error { return repositoryHandler.EraseRepositoryData(c) })
 	v1.Get("/repo/:repo_id/runs", func(c fiber.Ctx) error { return driftStateHandler.ListRunsByRepoId(c) })
+	v1.Get("/repo/:repo_id/projects", func(c fiber.Ctx) error { return driftStateHandler.ListRepositoryProjects(c) })
+	v1.Get("/repo/:repo_id/projects/history", func(c fiber.Ctx) error { return driftStateHandler.GetProjectHistory(c) })
+	v1.Get("/repo/:repo_id/projects/tree", func(c fiber.Ctx) error { return driftStateHandler.GetProjectTree(c) })
+	v1.Get("/repo/:repo_id/projects/tree/trends", func(c fiber.Ctx) error { return driftStateHandler.GetSubtreeTrends(c) })
+	v1.Get("/repo/:repo_id/owners", func(c fiber.Ctx) error { return driftStateHandler.ListRepositoryOwners(c) })
 	v1.Get("/repo/:repo_id/stats", func(c fiber.Ctx) error { return driftStateHandler.GetRepositoryStats(c) })
 	v1.Get("/repo/:repo_id/trends", func(c fiber.Ctx) error { return driftStateHandler.GetRepositoryTrends(c) })
+	v1.Get("/repo/:repo_id/trends/resources", func(c fiber.Ctx) error { return driftStateHandler.GetRepositoryResourceTrends(c) })
+	v1.Get("/repo/:repo_id/trends/durations", func(c fiber.Ctx) error { return driftStateHandler.GetRepositoryDurationTrends(c) })
+	v1.Get("/repo/:repo_id/trends/warnings", func(c fiber.Ctx) error { return driftStateHandler.GetRepositoryWarningTrends(c) })
+	v1.Get("/repo/:repo_id/trends/owner", func(c fiber.Ctx) error { return driftStateHandler.GetOwnerTrends(c) })
 	v1.Get("/analysis/run/:run_id", func(c fiber.Ctx) error { return driftStateHandler.GetRunById(c) })
+	v1.Get("/analysis/run/:run_id/output", func(c fiber.Ctx) error { return driftStateHandler.GetRunProjectOutput(c) })
+	v1.Get("/analysis/run/:run_id/diff", func(c fiber.Ctx) error { return driftStateHandler.GetRunProjectOutputDiff(c) })
 	v1.Post("/sync_user", func(c fiber.Ctx) error { return userSync.HandleUserSyncRequest(c) })
 
 	ghG := v1.Group("/gh")
@@ -185,6 +237,16 @@ func main() {
 	go observability.SuperviseLoop(ctx, "user_sync", userSync.StartSyncLoop)
 	go observability.SuperviseLoop(ctx, "org_sync", orgSync.StartSyncLoop)
 	go observability.SuperviseLoop(ctx, "stale_run_sweeper", cleanupService.StartStaleRunSweeper)
+	go observability.SuperviseLoop(ctx, "check_run_timeout_reporter", checkService.StartTimeoutReporter)
+	go observability.SuperviseLoop(ctx, "shard_finalizer", driftStateHandler.StartShardFinalizer)
+	go observability.SuperviseLoop(ctx, "run_publisher", driftStateHandler.StartPublisher)
+	go observability.SuperviseLoop(ctx, "command_output_collector", outputService.StartGarbageCollector)
+	go observability.SuperviseLoop(ctx, "legacy_output_migration", outputService.StartLegacyMigration)
+	go observability.SuperviseLoop(ctx, "plan_summary_backfill", driftStateHandler.StartSummaryBackfill)
+	go observability.SuperviseLoop(ctx, "scan_schedule_monitor", overdueMonitor.StartOverdueMonitor)
+	if blobs != nil {
+		go observability.SuperviseLoop(ctx, "output_blob_reaper", cleanupService.StartOutputBlobReaper)
+	}
 
 	// Handle shutdown signals
 	go func() {
@@ -0,0 +1,12 @@
+ALTER TABLE drift_analysis_run
+    ADD COLUMN expected_shards INT CHECK (expected_shards > 0);
+
+CREATE TABLE drift_analysis_run_shard
+(
+    drift_analysis_run_id    UUID         NOT NULL REFERENCES drift_analysis_run (uuid) ON DELETE CASCADE,
+    shard_id                 VARCHAR(255) NOT NULL,
+    total_projects           INT          NOT NULL,
+    analysis_duration_millis BIGINT       NOT NULL,
+    reported_at              TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (drift_analysis_run_id, shard_id)
+);
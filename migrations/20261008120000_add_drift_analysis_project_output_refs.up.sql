@@ -0,0 +1,37 @@
+ALTER TABLE drift_analysis_project
+    ADD COLUMN init_output_ref  VARCHAR(512),
+    ADD COLUMN init_output_size BIGINT,
+    ADD COLUMN plan_output_ref  VARCHAR(512),
+    ADD COLUMN plan_output_size BIGINT;
+
+-- Blobs referenced by deleted project rows, waiting for the cleanup service to remove them from the
+-- blob store. Filled by a trigger so every path that deletes projects is covered, including the
+-- ON DELETE CASCADE from runs, repositories and organizations, and a failed blob delete is retried.
+CREATE TABLE output_blob_deletion
+(
+    ref         VARCHAR(512) PRIMARY KEY,
+    enqueued_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE FUNCTION enqueue_output_blob_deletion() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF TG_OP = 'DELETE' OR OLD.init_output_ref IS DISTINCT FROM NEW.init_output_ref THEN
+        IF OLD.init_output_ref IS NOT NULL THEN
+            INSERT INTO output_blob_deletion (ref) VALUES (OLD.init_output_ref) ON CONFLICT DO NOTHING;
+        END IF;
+    END IF;
+    IF TG_OP = 'DELETE' OR OLD.plan_output_ref IS DISTINCT FROM NEW.plan_output_ref THEN
+        IF OLD.plan_output_ref IS NOT NULL THEN
+            INSERT INTO output_blob_deletion (ref) VALUES (OLD.plan_output_ref) ON CONFLICT DO NOTHING;
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER drift_analysis_project_output_blob_deletion
+    AFTER DELETE OR UPDATE OF init_output_ref, plan_output_ref
+    ON drift_analysis_project
+    FOR EACH ROW
+EXECUTE FUNCTION enqueue_output_blob_deletion();
@@ -0,0 +1,103 @@
+-- Init and plan outputs stored once per repository and content hash, zstd-compressed. data holds
+-- the compressed bytes, or blob_ref points at them in the blob store when one is configured.
+-- Scoped to the repository so the reference counts of popular outputs (the same init output in
+-- every run) are never contended across tenants.
+CREATE TABLE command_output
+(
+    id              BIGSERIAL PRIMARY KEY,
+    repository_id   BIGINT      NOT NULL REFERENCES git_repository (id) ON DELETE CASCADE,
+    hash            BYTEA       NOT NULL,
+    size            BIGINT      NOT NULL,
+    compressed_size BIGINT      NOT NULL,
+    data            BYTEA,
+    blob_ref        VARCHAR(512),
+    ref_count       INT         NOT NULL DEFAULT 0,
+    unreferenced_at TIMESTAMPTZ DEFAULT NOW(),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (repository_id, hash),
+    CHECK ((data IS NULL) <> (blob_ref IS NULL))
+);
+
+CREATE INDEX command_output_unreferenced_idx
+    ON command_output (unreferenced_at)
+    WHERE ref_count = 0;
+
+ALTER TABLE drift_analysis_project
+    ADD COLUMN init_output_id BIGINT REFERENCES command_output (id),
+    ADD COLUMN plan_output_id BIGINT REFERENCES command_output (id);
+
+-- Backs the FK check when garbage collection deletes an output.
+CREATE INDEX drift_analysis_project_init_output_id_idx
+    ON drift_analysis_project (init_output_id)
+    WHERE init_output_id IS NOT NULL;
+CREATE INDEX drift_analysis_project_plan_output_id_idx
+    ON drift_analysis_project (plan_output_id)
+    WHERE plan_output_id IS NOT NULL;
+
+-- Keeps command_output.ref_count equal to the number of project columns pointing at each row.
+-- unreferenced_at records when the count last dropped to zero, for the collector's grace period.
+CREATE FUNCTION adjust_command_output_ref_count(output_id BIGINT, delta INT) RETURNS VOID AS
+$$
+BEGIN
+    IF output_id IS NULL THEN
+        RETURN;
+    END IF;
+    UPDATE command_output
+    SET ref_count       = ref_count + delta,
+        unreferenced_at = CASE WHEN ref_count + delta = 0 THEN NOW() END
+    WHERE id = output_id;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION track_command_output_refs() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        PERFORM adjust_command_output_ref_count(NEW.init_output_id, 1);
+        PERFORM adjust_command_output_ref_count(NEW.plan_output_id, 1);
+    ELSIF TG_OP = 'DELETE' THEN
+        PERFORM adjust_command_output_ref_count(OLD.init_output_id, -1);
+        PERFORM adjust_command_output_ref_count(OLD.plan_output_id, -1);
+    ELSE
+        IF OLD.init_output_id IS DISTINCT FROM NEW.init_output_id THEN
+            PERFORM adjust_command_output_ref_count(OLD.init_output_id, -1);
+            PERFORM adjust_command_output_ref_count(NEW.init_output_id, 1);
+        END IF;
+        IF OLD.plan_output_id IS DISTINCT FROM NEW.plan_output_id THEN
+            PERFORM adjust_command_output_ref_count(OLD.plan_output_id, -1);
+            PERFORM adjust_command_output_ref_count(NEW.plan_output_id, 1);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER drift_analysis_project_command_output_refs
+    AFTER INSERT OR DELETE OR UPDATE OF init_output_id, plan_output_id
+    ON drift_analysis_project
+    FOR EACH ROW
+EXECUTE FUNCTION track_command_output_refs();
+
+-- A deleted output's blob goes through the same deletion queue as per-project blobs.
+CREATE FUNCTION enqueue_command_output_blob_deletion() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF OLD.blob_ref IS NOT NULL THEN
+        INSERT INTO output_blob_deletion (ref) VALUES (OLD.blob_ref) ON CONFLICT DO NOTHING;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER command_output_blob_deletion
+    AFTER DELETE
+    ON command_output
+    FOR EACH ROW
+EXECUTE FUNCTION enqueue_command_output_blob_deletion();
+
+-- Rows written before command_output still hold their outputs inline or behind a per-project blob
+-- reference until the background migration moves them. The index lets the migration find them
+-- without scanning the table, and empties itself as it goes.
+CREATE INDEX drift_analysis_project_legacy_output_idx
+    ON drift_analysis_project (id)
+    WHERE init_output <> '' OR plan_output <> '' OR init_output_ref IS NOT NULL OR plan_output_ref IS NOT NULL;
@@ -0,0 +1,15 @@
+-- Custom secret patterns an organization redacts from init and plan outputs on top of the built-in
+-- detectors.
+CREATE TABLE organization_redaction_rule
+(
+    id              BIGSERIAL PRIMARY KEY,
+    organization_id BIGINT        NOT NULL REFERENCES git_organization (id) ON DELETE CASCADE,
+    name            VARCHAR(64)   NOT NULL,
+    pattern         VARCHAR(1000) NOT NULL,
+    created_at      TIMESTAMPTZ   NOT NULL DEFAULT NOW(),
+    UNIQUE (organization_id, name)
+);
+
+-- Number of secrets masked per detector, e.g. {"github_token": 2}. NULL when nothing was redacted.
+ALTER TABLE drift_analysis_project
+    ADD COLUMN redactions JSONB;
@@ -0,0 +1,8 @@
+-- Widen the project type check to Pulumi and CloudFormation drift results. The new constraint is
+-- added NOT VALID so the table isn't scanned under an exclusive lock; the next migration validates
+-- it in a transaction of its own, since golang-migrate runs each file in one.
+ALTER TABLE drift_analysis_project DROP CONSTRAINT drift_analysis_project_type_check;
+
+ALTER TABLE drift_analysis_project
+    ADD CONSTRAINT drift_analysis_project_type_check
+        CHECK ( type IN ('TERRAFORM', 'TOFU', 'TERRAGRUNT', 'PULUMI', 'CLOUDFORMATION') ) NOT VALID;
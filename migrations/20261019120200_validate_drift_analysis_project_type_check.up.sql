@@ -0,0 +1,3 @@
+-- Validates the constraint added NOT VALID by the previous migration. Only takes a SHARE UPDATE
+-- EXCLUSIVE lock, so project writes carry on while the table is scanned.
+ALTER TABLE drift_analysis_project VALIDATE CONSTRAINT drift_analysis_project_type_check;
@@ -0,0 +1,13 @@
+-- Counts for the plan summary categories beyond add/change/destroy. summary_parser_version records
+-- which parser filled the counts; rows below the current version are re-parsed in the background
+-- from their stored plan output, since most of it now sits compressed in command_output where SQL
+-- can't read it. Existing rows were parsed by the original parser, version 1.
+ALTER TABLE drift_analysis_project
+    ADD COLUMN resources_imported     INT,
+    ADD COLUMN resources_forgotten    INT,
+    ADD COLUMN resources_moved        INT,
+    ADD COLUMN outputs_changed        INT,
+    ADD COLUMN summary_parser_version SMALLINT NOT NULL DEFAULT 1;
+
+CREATE INDEX drift_analysis_project_summary_parser_version_idx
+    ON drift_analysis_project (summary_parser_version);
@@ -0,0 +1,3 @@
+-- Resource addresses and actions parsed from the plan headers of each project, as a JSON array of
+-- {"address", "action"} objects. Older rows are filled in by the plan summary backfill.
+ALTER TABLE drift_analysis_project ADD COLUMN resource_changes JSONB;
@@ -0,0 +1,4 @@
+-- Hex SHA-256 of the normalized changes in a drifted project's plan, compared across runs to tell
+-- unchanged drift from new drift. NULL when the project has no drift. Older rows are filled in by
+-- the plan summary backfill.
+ALTER TABLE drift_analysis_project ADD COLUMN drift_fingerprint VARCHAR(64);
@@ -0,0 +1,8 @@
+-- Per-project timing reported by the CLI: how long init and plan took, when the analysis started
+-- and finished (including retries) and how many times it was retried. NULL when not reported.
+ALTER TABLE drift_analysis_project
+    ADD COLUMN init_duration_millis BIGINT,
+    ADD COLUMN plan_duration_millis BIGINT,
+    ADD COLUMN started_at           TIMESTAMPTZ,
+    ADD COLUMN finished_at          TIMESTAMPTZ,
+    ADD COLUMN retries              INT;
@@ -0,0 +1,4 @@
+-- Why a failed project errored, e.g. CREDENTIALS or STATE_LOCK, classified from its init and plan
+-- output. NULL for projects that succeeded.
+ALTER TABLE drift_analysis_project
+    ADD COLUMN error_class VARCHAR(32);
@@ -0,0 +1,7 @@
+-- Core and provider versions parsed from the init output, for the organization version inventory.
+-- core_tool is TERRAFORM or OPENTOFU; provider_versions is a JSON array of {"source", "version"}.
+-- NULL when the output doesn't say.
+ALTER TABLE drift_analysis_project
+    ADD COLUMN core_tool         VARCHAR(16),
+    ADD COLUMN core_version      VARCHAR(64),
+    ADD COLUMN provider_versions JSONB;
@@ -0,0 +1,4 @@
+-- Warning diagnostics parsed from the init and plan output, as a JSON array of
+-- {"category", "summary", "detail", "address", "occurrences"}. NULL when there are none.
+ALTER TABLE drift_analysis_project
+    ADD COLUMN warnings JSONB;
@@ -0,0 +1,54 @@
+-- The status of a project in a run, shared by the catalog and the API so they cannot disagree: a
+-- skipped project is SKIPPED even if it failed, a failed one ERRORED even if it reported drift.
+CREATE FUNCTION drift_project_status(skipped_due_to_pr BOOLEAN, succeeded BOOLEAN, drifted BOOLEAN) RETURNS VARCHAR
+    LANGUAGE sql
+    IMMUTABLE AS
+$$
+SELECT CASE
+           WHEN skipped_due_to_pr THEN 'SKIPPED'
+           WHEN NOT succeeded THEN 'ERRORED'
+           WHEN drifted THEN 'DRIFTED'
+           ELSE 'IN_SYNC'
+           END
+$$;
+
+-- Every project a repository has reported, across its completed runs. Maintained by the
+-- transactions that complete runs, so listing a repository's projects doesn't scan its run history.
+-- status is the outcome of the latest run the project was seen in; once retention deletes that run
+-- last_run_id is cleared and the project is pruned.
+CREATE TABLE drift_project
+(
+    id              BIGSERIAL PRIMARY KEY,
+    repository_id   BIGINT        NOT NULL REFERENCES git_repository (id) ON DELETE CASCADE,
+    dir             VARCHAR(1500) NOT NULL,
+    type            VARCHAR       NOT NULL CHECK ( type IN ('TERRAFORM', 'TOFU', 'TERRAGRUNT', 'PULUMI', 'CLOUDFORMATION') ),
+    status          VARCHAR(16)   NOT NULL CHECK ( status IN ('IN_SYNC', 'DRIFTED', 'ERRORED', 'SKIPPED') ),
+    first_seen_at   TIMESTAMPTZ   NOT NULL,
+    last_seen_at    TIMESTAMPTZ   NOT NULL,
+    last_drifted_at TIMESTAMPTZ,
+    last_errored_at TIMESTAMPTZ,
+    last_run_id     UUID REFERENCES drift_analysis_run (uuid) ON DELETE SET NULL,
+    UNIQUE (repository_id, dir, type)
+);
+
+-- Backs the FK check when retention deletes a run.
+CREATE INDEX drift_project_last_run_id_idx
+    ON drift_project (last_run_id)
+    WHERE last_run_id IS NOT NULL;
+
+-- Seed the catalog from the runs already stored.
+INSERT INTO drift_project (repository_id, dir, type, status, first_seen_at, last_seen_at, last_drifted_at, last_errored_at, last_run_id)
+SELECT DISTINCT ON (r.repository_id, p.dir, p.type) r.repository_id,
+                                                    p.dir,
+                                                    p.type,
+                                                    drift_project_status(p.skipped_due_to_pr, p.succeeded, p.drifted),
+                                                    MIN(r.created_at) OVER w,
+                                                    r.created_at,
+                                                    MAX(r.created_at) FILTER (WHERE p.drifted AND p.succeeded AND NOT p.skipped_due_to_pr) OVER w,
+                                                    MAX(r.created_at) FILTER (WHERE NOT p.succeeded AND NOT p.skipped_due_to_pr) OVER w,
+                                                    r.uuid
+FROM drift_analysis_project p
+         JOIN drift_analysis_run r ON r.uuid = p.drift_analysis_run_id
+WHERE r.status = 'COMPLETED'
+WINDOW w AS (PARTITION BY r.repository_id, p.dir, p.type)
+ORDER BY r.repository_id, p.dir, p.type, r.created_at DESC;
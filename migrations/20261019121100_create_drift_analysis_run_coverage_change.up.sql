@@ -0,0 +1,11 @@
+-- Dirs a completed run scanned that the previous completed run of its repository didn't (ADDED), and
+-- dirs the previous run scanned that it didn't (REMOVED). A REMOVED project usually means a CLI
+-- config change dropped it from drift detection.
+CREATE TABLE drift_analysis_run_coverage_change
+(
+    drift_analysis_run_id UUID          NOT NULL REFERENCES drift_analysis_run (uuid) ON DELETE CASCADE,
+    dir                   VARCHAR(1500) NOT NULL,
+    type                  VARCHAR       NOT NULL,
+    change                VARCHAR(16)   NOT NULL CHECK ( change IN ('ADDED', 'REMOVED') ),
+    PRIMARY KEY (drift_analysis_run_id, dir)
+);
@@ -0,0 +1,9 @@
+-- The CODEOWNERS file of a repository as of the last organization sync, matched against project dirs
+-- when they are read. No row when the repository has none.
+CREATE TABLE git_repository_codeowners
+(
+    repository_id BIGINT PRIMARY KEY REFERENCES git_repository (id) ON DELETE CASCADE,
+    path          VARCHAR(64) NOT NULL,
+    content       TEXT        NOT NULL,
+    synced_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
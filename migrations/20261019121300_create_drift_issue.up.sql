@@ -0,0 +1,21 @@
+-- Opt-in to a GitHub issue per drifted project, see drift_issue.
+ALTER TABLE git_repository
+    ADD COLUMN drift_issues_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The GitHub issue opened when a project of a repository started drifting, commented on while the
+-- drift persists and closed once a run finds the dir clean. At most one is open per dir:
+-- issue_number is NULL while the issue is being created, so a concurrent run cannot open another.
+CREATE TABLE drift_issue
+(
+    id            BIGSERIAL PRIMARY KEY,
+    repository_id BIGINT        NOT NULL REFERENCES git_repository (id) ON DELETE CASCADE,
+    dir           VARCHAR(1500) NOT NULL,
+    issue_number  INTEGER,
+    -- The last run the issue was updated from, so the shards of a run finishing together update it
+    -- once. Not a foreign key: retention deletes runs while their issues stay open.
+    last_run_id   UUID          NOT NULL,
+    opened_at     TIMESTAMPTZ   NOT NULL DEFAULT NOW(),
+    closed_at     TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX drift_issue_open_dir_idx ON drift_issue (repository_id, dir) WHERE closed_at IS NULL;
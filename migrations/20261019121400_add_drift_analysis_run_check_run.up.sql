@@ -0,0 +1,19 @@
+-- The commit a run scanned, as sent by the CLI, and the GitHub check run reporting the run on it.
+ALTER TABLE drift_analysis_run
+    ADD COLUMN commit_sha   VARCHAR(64),
+    ADD COLUMN check_run_id BIGINT;
+
+-- Check runs of the runs the stale run sweeper deleted, waiting to be concluded as timed out. The
+-- sweeper queues them in the statement deleting their runs, so a check run is retried until
+-- GitHub accepts its conclusion rather than left in progress by a failed call.
+CREATE TABLE check_run_timeout
+(
+    check_run_id  BIGINT PRIMARY KEY,
+    run_id        UUID        NOT NULL,
+    repository_id BIGINT      NOT NULL REFERENCES git_repository (id) ON DELETE CASCADE,
+    attempts      INT         NOT NULL DEFAULT 0,
+    retry_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    enqueued_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX check_run_timeout_retry_at_idx ON check_run_timeout (retry_at);
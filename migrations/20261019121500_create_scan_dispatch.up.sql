@@ -0,0 +1,27 @@
+-- How scans are triggered from the dashboard: a workflow_dispatch of this workflow file on the
+-- default branch, or a repository_dispatch event when NULL. Remediation applies are opt-in.
+ALTER TABLE git_repository
+    ADD COLUMN dispatch_workflow   VARCHAR(255),
+    ADD COLUMN remediation_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- A scan or remediation apply requested from the dashboard. The workflow it dispatched sends id
+-- back as the Idempotency-Key of its upload, which ties the resulting run to the dispatch.
+CREATE TABLE scan_dispatch
+(
+    id            UUID PRIMARY KEY,
+    repository_id BIGINT      NOT NULL REFERENCES git_repository (id) ON DELETE CASCADE,
+    -- SCAN or REMEDIATE.
+    kind          VARCHAR(16) NOT NULL,
+    -- The dirs to scan, all of them when empty, or the one dir to remediate.
+    dirs          TEXT[]      NOT NULL DEFAULT '{}',
+    project_type  VARCHAR(32),
+    requested_by  BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX scan_dispatch_repository_created_at_idx ON scan_dispatch (repository_id, created_at DESC);
+
+-- The dirs a run scanned when its dispatch scoped the scan to some of them, NULL for a scan of the
+-- whole repository. Copied from the dispatch the run's Idempotency-Key names when it is created.
+ALTER TABLE drift_analysis_run
+    ADD COLUMN scoped_dirs TEXT[];
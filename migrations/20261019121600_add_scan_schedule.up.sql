@@ -0,0 +1,25 @@
+-- The cadence a repository expects its scans at: a cron expression of when they are scheduled, or
+-- the longest time allowed between completed runs. scan_schedule_set_at is when it was declared,
+-- which the cadence counts from until a run completes after it.
+ALTER TABLE git_repository
+    ADD COLUMN scan_schedule             VARCHAR(100),
+    ADD COLUMN scan_max_interval_minutes INTEGER,
+    ADD COLUMN scan_schedule_set_at      TIMESTAMPTZ,
+    ADD CONSTRAINT git_repository_scan_schedule_check
+        CHECK (scan_schedule IS NULL OR scan_max_interval_minutes IS NULL);
+
+-- A repository found overdue for a completed run, resolved once one completes or the expected
+-- cadence is changed.
+CREATE TABLE scan_overdue_alert
+(
+    id                BIGSERIAL PRIMARY KEY,
+    repository_id     BIGINT      NOT NULL REFERENCES git_repository (id) ON DELETE CASCADE,
+    -- When the run that didn't complete was expected by.
+    due_at            TIMESTAMPTZ NOT NULL,
+    last_completed_at TIMESTAMPTZ,
+    raised_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at       TIMESTAMPTZ
+);
+
+-- At most one open alert per repository, so monitors running on several instances raise it once.
+CREATE UNIQUE INDEX scan_overdue_alert_open_idx ON scan_overdue_alert (repository_id) WHERE resolved_at IS NULL;
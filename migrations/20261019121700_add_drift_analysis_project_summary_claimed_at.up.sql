@@ -0,0 +1,4 @@
+-- Lease the plan summary backfill takes on the projects it re-parses. It parses outside any
+-- transaction, so the lease is what keeps the backfill on other API instances off the same rows.
+ALTER TABLE drift_analysis_project
+    ADD COLUMN summary_claimed_at TIMESTAMPTZ;
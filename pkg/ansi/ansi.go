@@ -0,0 +1,140 @@
+// Package ansi renders command output printed to a terminal. Terraform, OpenTofu and Pulumi color
+// their output unless told otherwise, so stored outputs often carry escape sequences.
+package ansi
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// escapeRegex matches CSI sequences, such as colors and cursor movement, and OSC sequences, such as
+// hyperlinks and window titles. The first submatch holds the parameters of an SGR (color) sequence.
+var escapeRegex = regexp.MustCompile(`\x1b\[([0-9;]*)m|\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+var colorNames = [8]string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// Strip removes the escape sequences from s.
+func Strip(s string) string {
+	return escapeRegex.ReplaceAllString(s, "")
+}
+
+// style is the SGR state in effect at some point of the output.
+type style struct {
+	bold, dim, italic, underline bool
+	fg, bg                       string
+}
+
+// classes lists the CSS classes of s, e.g. "ansi-bold ansi-red", or "" for the default style.
+func (s style) classes() string {
+	var classes []string
+	for _, c := range []struct {
+		on   bool
+		name string
+	}{{s.bold, "bold"}, {s.dim, "dim"}, {s.italic, "italic"}, {s.underline, "underline"}} {
+		if c.on {
+			classes = append(classes, "ansi-"+c.name)
+		}
+	}
+	if s.fg != "" {
+		classes = append(classes, "ansi-"+s.fg)
+	}
+	if s.bg != "" {
+		classes = append(classes, "ansi-bg-"+s.bg)
+	}
+	return strings.Join(classes, " ")
+}
+
+// apply updates s with the parameters of an SGR sequence. 256-color and true color parameters are
+// consumed but not rendered.
+func (s *style) apply(params string) {
+	if params == "" {
+		*s = style{}
+		return
+	}
+	codes := strings.Split(params, ";")
+	for i := 0; i < len(codes); i++ {
+		code, err := strconv.Atoi(codes[i])
+		if err != nil {
+			continue
+		}
+		switch {
+		case code == 0:
+			*s = style{}
+		case code == 1:
+			s.bold = true
+		case code == 2:
+			s.dim = true
+		case code == 3:
+			s.italic = true
+		case code == 4:
+			s.underline = true
+		case code == 22:
+			s.bold, s.dim = false, false
+		case code == 23:
+			s.italic = false
+		case code == 24:
+			s.underline = false
+		case code >= 30 && code <= 37:
+			s.fg = colorNames[code-30]
+		case code == 39:
+			s.fg = ""
+		case code >= 40 && code <= 47:
+			s.bg = colorNames[code-40]
+		case code == 49:
+			s.bg = ""
+		case code >= 90 && code <= 97:
+			s.fg = "bright-" + colorNames[code-90]
+		case code >= 100 && code <= 107:
+			s.bg = "bright-" + colorNames[code-100]
+		case code == 38 || code == 48:
+			// Skip the color: 5;<index> or 2;<r>;<g>;<b>.
+			if i+1 < len(codes) && codes[i+1] == "5" {
+				i += 2
+			} else if i+1 < len(codes) && codes[i+1] == "2" {
+				i += 4
+			}
+		}
+	}
+}
+
+// ToHTML escapes s for HTML and converts its color sequences to spans with "ansi-" classes:
+// ansi-bold, ansi-dim, ansi-italic, ansi-underline, ansi-<color>, ansi-bright-<color>,
+// ansi-bg-<color> and ansi-bg-bright-<color>. Other escape sequences are removed.
+func ToHTML(s string) string {
+	var b strings.Builder
+	var current style
+	open := false
+	write := func(text string) {
+		if text == "" {
+			return
+		}
+		if classes := current.classes(); classes != "" && !open {
+			b.WriteString(`<span class="` + classes + `">`)
+			open = true
+		}
+		b.WriteString(html.EscapeString(text))
+	}
+
+	last := 0
+	for _, m := range escapeRegex.FindAllStringSubmatchIndex(s, -1) {
+		write(s[last:m[0]])
+		last = m[1]
+		if m[2] < 0 {
+			continue
+		}
+		next := current
+		next.apply(s[m[2]:m[3]])
+		if next != current && open {
+			b.WriteString("</span>")
+			open = false
+		}
+		current = next
+	}
+	write(s[last:])
+	if open {
+		b.WriteString("</span>")
+	}
+	return b.String()
+}
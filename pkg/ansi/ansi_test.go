@@ -0,0 +1,56 @@
+package ansi
+
+import "testing"
+
+func TestStrip(t *testing.T) {
+	in := "\x1b[1m\x1b[33m~\x1b[0m\x1b[0m update in-place\x1b[2K\x1b]8;;https://example.com\x07link\x1b]8;;\x07"
+	if got, want := Strip(in), "~ update in-placelink"; got != want {
+		t.Errorf("Strip() = %q, want %q", got, want)
+	}
+}
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "plain text is escaped",
+			in:   `name = "<b>" & more`,
+			want: "name = &#34;&lt;b&gt;&#34; &amp; more",
+		},
+		{
+			name: "terraform change marker",
+			in:   "  \x1b[33m~\x1b[0m\x1b[0m resource \"aws_s3_bucket\" \"logs\" {",
+			want: "  <span class=\"ansi-yellow\">~</span> resource &#34;aws_s3_bucket&#34; &#34;logs&#34; {",
+		},
+		{
+			name: "combined and partially reset attributes",
+			in:   "\x1b[1;31mError:\x1b[22m detail\x1b[39m done",
+			want: "<span class=\"ansi-bold ansi-red\">Error:</span><span class=\"ansi-red\"> detail</span> done",
+		},
+		{
+			name: "bright and background colors, extended colors ignored",
+			in:   "\x1b[92;41mok\x1b[38;5;208m still\x1b[m",
+			want: "<span class=\"ansi-bright-green ansi-bg-red\">ok still</span>",
+		},
+		{
+			name: "unclosed style",
+			in:   "\x1b[4mtitle",
+			want: "<span class=\"ansi-underline\">title</span>",
+		},
+		{
+			name: "cursor sequences removed",
+			in:   "\x1b[2K\x1b[1Gdone",
+			want: "done",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToHTML(tt.in); got != tt.want {
+				t.Errorf("ToHTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
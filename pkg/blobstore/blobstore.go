@@ -0,0 +1,45 @@
+// Package blobstore keeps large, write-once payloads such as terraform init and plan outputs out of
+// Postgres. Callers store a key in the database and fetch the blob only when it is displayed.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"driftive.cloud/api/pkg/config"
+)
+
+// ErrNotFound is returned by Get when no blob exists under the key.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore is a flat key/value store for immutable blobs. Keys are slash-separated paths made of
+// URL-safe characters. Put overwrites, and Delete of a missing key is not an error, so both are
+// safe to retry.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// New builds the store selected by cfg.Driver. An empty driver returns a nil store, meaning outputs
+// stay inline in Postgres.
+func New(cfg config.BlobStoreConfig) (BlobStore, error) {
+	switch cfg.Driver {
+	case "":
+		return nil, nil
+	case "local":
+		return NewLocalStore(cfg.LocalPath)
+	case "s3":
+		return NewS3Store(S3Options{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			UsePathStyle:    cfg.S3UsePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob store driver %q", cfg.Driver)
+	}
+}
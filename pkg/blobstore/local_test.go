@@ -0,0 +1,55 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStore_RoundTrip(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalStore(root)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+
+	const key = "runs/0b9c/abc/init.log"
+	if err := store.Put(ctx, key, []byte("first")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := store.Put(ctx, key, []byte("second")); err != nil {
+		t.Fatalf("Put overwrite: %v", err)
+	}
+	got, err := store.Get(ctx, key)
+	if err != nil || string(got) != "second" {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: expected ErrNotFound, got %v", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing key should succeed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "runs")); !os.IsNotExist(err) {
+		t.Errorf("expected empty directories to be pruned, stat err = %v", err)
+	}
+}
+
+func TestLocalStore_RejectsEscapingKeys(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	for _, key := range []string{"", "/etc/passwd", "../outside", "runs/../../outside"} {
+		if err := store.Put(context.Background(), key, []byte("x")); err == nil {
+			t.Errorf("Put(%q) should fail", key)
+		}
+	}
+}
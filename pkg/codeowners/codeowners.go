@@ -0,0 +1,185 @@
+// Package codeowners parses GitHub CODEOWNERS files and resolves the owners of project dirs.
+package codeowners
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Paths are where GitHub looks for a CODEOWNERS file, in the order it does.
+var Paths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// anyFile stands for the unknown part of a file name directly in a dir. Only wildcards match it.
+const anyFile = "\x00"
+
+// projectFiles stand for the files a project dir is made of: the Terraform and OpenTofu sources
+// under any name, and the config files Terragrunt and Pulumi look for. A pattern naming them, e.g.
+// *.tf or terragrunt.hcl, owns the dir, while one naming other files, e.g. *.md, does not.
+var projectFiles = []string{
+	anyFile,
+	anyFile + ".tf",
+	anyFile + ".tf.json",
+	anyFile + ".tofu",
+	anyFile + ".tofu.json",
+	anyFile + ".hcl",
+	"terragrunt.hcl",
+	"Pulumi.yaml",
+	"Pulumi.yml",
+}
+
+// Rule is one line of a CODEOWNERS file. A rule without owners leaves the paths it matches unowned.
+type Rule struct {
+	Pattern string
+	Owners  []string
+
+	re *regexp.Regexp
+	// filesOnly is set for patterns ending in /*, which own the files of a dir but not its subdirs.
+	filesOnly bool
+}
+
+// Ruleset is a parsed CODEOWNERS file.
+type Ruleset struct {
+	Rules []Rule
+}
+
+// Parse parses the content of a CODEOWNERS file. Like GitHub, it skips the lines it cannot
+// interpret: negated patterns and character ranges are not supported.
+func Parse(content string) *Ruleset {
+	rs := &Ruleset{}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		pattern := strings.TrimPrefix(fields[0], "\\")
+		if strings.HasPrefix(fields[0], "!") || strings.ContainsAny(pattern, "[]") {
+			continue
+		}
+		owners := []string{}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			owners = append(owners, owner)
+		}
+		rule, ok := compile(pattern)
+		if !ok {
+			continue
+		}
+		rule.Owners = owners
+		rs.Rules = append(rs.Rules, rule)
+	}
+	return rs
+}
+
+// compile translates a gitignore-style pattern into a regexp over slash-separated paths without a
+// leading slash. A pattern with a slash other than a trailing one is relative to the root,
+// otherwise it matches at any depth.
+func compile(pattern string) (Rule, bool) {
+	rule := Rule{Pattern: pattern}
+	p := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return rule, false
+	}
+	rule.filesOnly = anchored && (p == "*" || strings.HasSuffix(p, "/*"))
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case p[i] == '*':
+			sb.WriteString("[^/]*")
+		case p[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	sb.WriteString("$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return rule, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// Owners returns the owners of the files of dir, a path relative to the repository root, as set by
+// the last matching rule. A rule matches when it matches dir, one of its parents or one of the
+// projectFiles in dir. Returns nil when no rule matches.
+func (rs *Ruleset) Owners(dir string) []string {
+	dir = NormalizeDir(dir)
+	for i := len(rs.Rules) - 1; i >= 0; i-- {
+		rule := rs.Rules[i]
+		if matchesProjectFile(rule.re, dir) || (!rule.filesOnly && matchesDirOrParent(rule.re, dir)) {
+			return rule.Owners
+		}
+	}
+	return nil
+}
+
+func matchesProjectFile(re *regexp.Regexp, dir string) bool {
+	for _, name := range projectFiles {
+		file := name
+		if dir != "" {
+			file = dir + "/" + name
+		}
+		if re.MatchString(file) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesDirOrParent(re *regexp.Regexp, dir string) bool {
+	for dir != "" {
+		if re.MatchString(dir) {
+			return true
+		}
+		i := strings.LastIndex(dir, "/")
+		if i < 0 {
+			break
+		}
+		dir = dir[:i]
+	}
+	return false
+}
+
+// HasOwner reports whether owner, compared case-insensitively like GitHub handles, is in owners.
+func HasOwner(owners []string, owner string) bool {
+	for _, o := range owners {
+		if strings.EqualFold(o, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeDir drops the leading ./ and / and the trailing / of a project dir, so ./envs/prod,
+// /envs/prod and envs/prod/ are the same dir.
+func NormalizeDir(dir string) string {
+	for {
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(dir, "./"), "/")
+		if trimmed == dir {
+			break
+		}
+		dir = trimmed
+	}
+	dir = strings.Trim(dir, "/")
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
@@ -0,0 +1,106 @@
+package codeowners
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sample = `# Default owners
+*       @acme/platform
+
+/envs/        @acme/infra
+/envs/prod/** @acme/sre # production needs sign-off
+envs/dev/*    @alice
+**/modules    @acme/modules
+*.md          @acme/docs
+/envs/prod/sandbox
+!/envs/legacy @nobody
+/envs/[ab]    @nobody
+`
+
+func TestOwners(t *testing.T) {
+	rs := Parse(sample)
+	tests := []struct {
+		dir  string
+		want []string
+	}{
+		{"", []string{"@acme/platform"}},
+		{"services/api", []string{"@acme/platform"}},
+		{"envs", []string{"@acme/infra"}},
+		{"./envs/staging/vpc", []string{"@acme/infra"}},
+		{"envs/prod", []string{"@acme/sre"}},
+		{"/envs/prod/vpc/", []string{"@acme/sre"}},
+		// The rule without owners unowns the sandbox.
+		{"envs/prod/sandbox", []string{}},
+		{"envs/prod/sandbox/tmp", []string{}},
+		// envs/dev/* owns the files of envs/dev, not those of its subdirs.
+		{"envs/dev", []string{"@alice"}},
+		{"envs/dev/vpc", []string{"@acme/infra"}},
+		{"stacks/modules", []string{"@acme/modules"}},
+		{"stacks/modules/network", []string{"@acme/modules"}},
+	}
+	for _, tt := range tests {
+		if got := rs.Owners(tt.dir); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Owners(%q) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestParse_SkipsUnsupportedLines(t *testing.T) {
+	rs := Parse(sample)
+	if len(rs.Rules) != 7 {
+		t.Fatalf("got %d rules, want 7", len(rs.Rules))
+	}
+	if got := rs.Rules[2].Owners; !reflect.DeepEqual(got, []string{"@acme/sre"}) {
+		t.Errorf("trailing comment: owners = %v, want [@acme/sre]", got)
+	}
+}
+
+func TestOwners_NoMatch(t *testing.T) {
+	rs := Parse("/envs/ @acme/infra\n")
+	if got := rs.Owners("services/api"); got != nil {
+		t.Errorf("Owners = %v, want nil", got)
+	}
+	if !HasOwner([]string{"@Acme/Infra"}, "@acme/infra") {
+		t.Error("HasOwner should compare case-insensitively")
+	}
+}
+
+func TestOwners_FilePatterns(t *testing.T) {
+	rs := Parse("*.tf @acme/terraform\n/live/**/terragrunt.hcl @acme/live\n*.md @acme/docs\n")
+	tests := []struct {
+		dir  string
+		want []string
+	}{
+		{"envs/prod", []string{"@acme/terraform"}},
+		{"", []string{"@acme/terraform"}},
+		{"live/prod/vpc", []string{"@acme/live"}},
+	}
+	for _, tt := range tests {
+		if got := rs.Owners(tt.dir); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Owners(%q) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+	// Patterns naming files a project is not made of own no dir.
+	if got := Parse("*.md @acme/docs\n").Owners("envs/prod"); got != nil {
+		t.Errorf("Owners = %v, want nil", got)
+	}
+}
+
+func TestNormalizeDir(t *testing.T) {
+	tests := map[string]string{
+		"envs/prod":     "envs/prod",
+		"./envs/prod":   "envs/prod",
+		"/envs/prod/":   "envs/prod",
+		".//./envs/dev": "envs/dev",
+		".hidden/stack": ".hidden/stack",
+		"./":            "",
+		".":             "",
+		"":              "",
+	}
+	for dir, want := range tests {
+		if got := NormalizeDir(dir); got != want {
+			t.Errorf("NormalizeDir(%q) = %q, want %q", dir, got, want)
+		}
+	}
+}
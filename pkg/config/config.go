@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"driftive.cloud/api/pkg/errclass"
 	"driftive.cloud/api/pkg/utils"
 )
 
@@ -14,6 +15,10 @@ type Config struct {
 	GithubAppConfig GitHubAppConfig
 	Auth            AuthConfig
 	Frontend        FrontendConfig
+	Ingest          IngestConfig
+	BlobStore       BlobStoreConfig
+	Redaction       RedactionConfig
+	ErrorClass      ErrorClassConfig
 }
 
 type Database struct {
@@ -48,6 +53,52 @@ type FrontendConfig struct {
 	FrontendURL string
 }
 
+type IngestConfig struct {
+	// MaxRequestBytes caps a drift analysis upload after Content-Encoding is decoded, so a small
+	// compressed body cannot expand without bound. Default is 64 MiB. Zero disables the check.
+	MaxRequestBytes int64
+	// MaxProjectBytes caps the combined init and plan output of a single project. Default is 8 MiB.
+	// Zero disables the check.
+	MaxProjectBytes int64
+	// MaxProjects caps the number of project results in a single v2 upload. Default is 10000. Zero
+	// disables the check.
+	MaxProjects int
+	// ShardTimeoutMinutes is how long a sharded run waits for its missing shards, counted from the
+	// last report, before it is completed with the shards that did report. Default is 60.
+	ShardTimeoutMinutes int32
+}
+
+type BlobStoreConfig struct {
+	// Driver selects where init and plan outputs are stored: "local", "s3", or empty to keep them
+	// inline in Postgres.
+	Driver string
+	// LocalPath is the root directory of the local driver. Default is data/blobs.
+	LocalPath string
+	// S3Endpoint is the base URL of the S3-compatible service, e.g. https://s3.eu-west-1.amazonaws.com
+	// or http://localhost:9000 for MinIO.
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	// S3UsePathStyle addresses the bucket as a path segment rather than a subdomain, which most
+	// self-hosted S3-compatible services require.
+	S3UsePathStyle bool
+}
+
+type RedactionConfig struct {
+	// Enabled turns secret redaction of init and plan outputs on. Default is true.
+	Enabled bool
+	// DisabledDetectors lists built-in detectors to skip, e.g. ["jwt"]. Organization rules still apply.
+	DisabledDetectors []string
+}
+
+type ErrorClassConfig struct {
+	// Rules classify failed projects before the built-in rules, in order, so they can add classes or
+	// take precedence. Read from ERROR_CLASS_RULES, e.g. [{"class":"QUOTA","pattern":"(?i)quota exceeded"}].
+	Rules []errclass.Rule
+}
+
 func LoadConfig() (*Config, error) {
 	port, err := strconv.Atoi(utils.GetEnvOrDefault("DB_PORT", "5432"))
 	if err != nil {
@@ -101,11 +152,80 @@ func LoadConfig() (*Config, error) {
 		FrontendURL: utils.GetEnvOrDefault("DRIFTIVE_UI_BASE_URL", "http://localhost:3001"),
 	}
 
+	maxRequestBytes, err := strconv.ParseInt(utils.GetEnvOrDefault("INGEST_MAX_REQUEST_BYTES", "67108864"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	maxProjectBytes, err := strconv.ParseInt(utils.GetEnvOrDefault("INGEST_MAX_PROJECT_BYTES", "8388608"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	maxProjects, err := strconv.Atoi(utils.GetEnvOrDefault("INGEST_MAX_PROJECTS", "10000"))
+	if err != nil {
+		return nil, err
+	}
+
+	shardTimeoutMinutes := int32(60)
+	if parsed, err := strconv.Atoi(utils.GetEnvOrDefault("SHARD_TIMEOUT_MINUTES", "60")); err == nil && parsed > 0 {
+		shardTimeoutMinutes = int32(parsed)
+	}
+
+	ingest := IngestConfig{
+		MaxRequestBytes:     maxRequestBytes,
+		MaxProjectBytes:     maxProjectBytes,
+		MaxProjects:         maxProjects,
+		ShardTimeoutMinutes: shardTimeoutMinutes,
+	}
+
+	s3UsePathStyle, err := strconv.ParseBool(utils.GetEnvOrDefault("BLOB_STORE_S3_PATH_STYLE", "false"))
+	if err != nil {
+		return nil, err
+	}
+
+	blobStore := BlobStoreConfig{
+		Driver:            os.Getenv("BLOB_STORE_DRIVER"),
+		LocalPath:         utils.GetEnvOrDefault("BLOB_STORE_LOCAL_PATH", "data/blobs"),
+		S3Endpoint:        os.Getenv("BLOB_STORE_S3_ENDPOINT"),
+		S3Region:          utils.GetEnvOrDefault("BLOB_STORE_S3_REGION", "us-east-1"),
+		S3Bucket:          os.Getenv("BLOB_STORE_S3_BUCKET"),
+		S3AccessKeyID:     os.Getenv("BLOB_STORE_S3_ACCESS_KEY_ID"),
+		S3SecretAccessKey: os.Getenv("BLOB_STORE_S3_SECRET_ACCESS_KEY"),
+		S3UsePathStyle:    s3UsePathStyle,
+	}
+
+	redactionEnabled, err := strconv.ParseBool(utils.GetEnvOrDefault("REDACTION_ENABLED", "true"))
+	if err != nil {
+		return nil, err
+	}
+
+	var disabledDetectors []string
+	for _, name := range strings.Split(os.Getenv("REDACTION_DISABLED_DETECTORS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			disabledDetectors = append(disabledDetectors, name)
+		}
+	}
+
+	redaction := RedactionConfig{
+		Enabled:           redactionEnabled,
+		DisabledDetectors: disabledDetectors,
+	}
+
+	errorClassRules, err := errclass.ParseRules(os.Getenv("ERROR_CLASS_RULES"))
+	if err != nil {
+		return nil, fmt.Errorf("ERROR_CLASS_RULES: %w", err)
+	}
+
 	config := Config{
 		Database:        database,
 		GithubAppConfig: ghAppConfig,
 		Auth:            auth,
 		Frontend:        frontend,
+		Ingest:          ingest,
+		BlobStore:       blobStore,
+		Redaction:       redaction,
+		ErrorClass:      ErrorClassConfig{Rules: errorClassRules},
 	}
 
 	return &config, nil
@@ -0,0 +1,129 @@
+// Package errclass classifies why a project's analysis failed from its init and plan output, so a
+// held state lock can be told apart from expired credentials without reading the logs.
+package errclass
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Class is the kind of failure a project hit.
+type Class string
+
+const (
+	Credentials      Class = "CREDENTIALS"
+	StateLock        Class = "STATE_LOCK"
+	ProviderDownload Class = "PROVIDER_DOWNLOAD"
+	BackendConfig    Class = "BACKEND_CONFIG"
+	RateLimit        Class = "RATE_LIMIT"
+	Validation       Class = "VALIDATION"
+	Timeout          Class = "TIMEOUT"
+	// Unknown is the class of a failure no rule recognized.
+	Unknown Class = "UNKNOWN"
+)
+
+// Rule assigns a class to outputs matching a pattern.
+type Rule struct {
+	Class   Class
+	pattern *regexp.Regexp
+}
+
+// NewRule compiles a rule. Patterns are matched against each output as a whole, so use (?m) to
+// anchor on lines.
+func NewRule(class Class, pattern string) (Rule, error) {
+	if class == "" {
+		return Rule{}, fmt.Errorf("class must not be empty")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid pattern for %s: %w", class, err)
+	}
+	if re.MatchString("") {
+		return Rule{}, fmt.Errorf("pattern for %s must not match the empty string", class)
+	}
+	return Rule{Class: class, pattern: re}, nil
+}
+
+// ParseRules compiles the rules of a JSON array such as [{"class": "QUOTA", "pattern": "(?i)quota
+// exceeded"}], in order. Classes are upper-cased to match the built-in ones. An empty string holds
+// no rules.
+func ParseRules(s string) ([]Rule, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var raw []struct {
+		Class   string `json:"class"`
+		Pattern string `json:"pattern"`
+	}
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+	rules := make([]Rule, len(raw))
+	for i, r := range raw {
+		rule, err := NewRule(Class(strings.ToUpper(strings.TrimSpace(r.Class))), r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		rules[i] = rule
+	}
+	return rules, nil
+}
+
+func mustRule(class Class, pattern string) Rule {
+	r, err := NewRule(class, pattern)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// builtins are ordered from the most to the least specific cause. A failed plan usually prints
+// several symptoms, e.g. a lock error that also names the backend, or a credentials error pointing
+// at a line of configuration, and the first matching rule wins.
+var builtins = []Rule{
+	mustRule(StateLock, `(?i)error acquiring the state lock|error locking state|state (?:is )?locked|ConditionalCheckFailedException|(?m)^\s*(?:│\s*)?Lock Info:`),
+	mustRule(Credentials, `(?i)NoCredentialProviders|no valid credential sources|unable to locate credentials|failed to refresh cached credentials|`+
+		`ExpiredToken|InvalidClientTokenId|UnrecognizedClientException|SignatureDoesNotMatch|security token included in the request is (?:expired|invalid)|`+
+		`could not find default credentials|oauth2: cannot fetch token|invalid_grant|AADSTS\d+|AuthorizationFailed|`+
+		`\b401 Unauthorized\b|AccessDenied(?:Exception)?\b|error: getting credentials`),
+	mustRule(RateLimit, `(?i)\bThrottl(?:ing|ed)(?:Exception)?\b|rate exceeded|rate limit|TooManyRequests|Too Many Requests|status(?: code)?:? 429|RequestLimitExceeded|SlowDown`),
+	mustRule(ProviderDownload, `(?i)failed to install provider|failed to query available provider packages|could not (?:download|retrieve) (?:the )?(?:provider|plugin)|`+
+		`failed to download module|error while installing|could not connect to registry\.|failed to install plugin|no available releases match`),
+	mustRule(BackendConfig, `(?i)backend initialization required|error configuring the backend|backend configuration changed|`+
+		`failed to get existing workspaces|error (?:loading|refreshing) state|failed to load state|NoSuchBucket|error: failed to (?:login|select stack)`),
+	mustRule(Timeout, `(?i)context deadline exceeded|i/o timeout|timeout while waiting|timed out|TLS handshake timeout|deadline exceeded`),
+	mustRule(Validation, `(?i)Error: (?:Unsupported (?:argument|attribute|block type)|Missing required argument|Invalid (?:reference|expression|value for (?:input )?variable|function argument|template|block definition)|`+
+		`Reference to undeclared \w+|Argument or block definition required|Unclosed configuration block|Duplicate \w+|Call to unknown function|Module not installed|Missing newline after argument)|`+
+		`syntax error|error parsing|failed to parse|Template format error|ValidationError`),
+}
+
+// Builtins returns the default rule set.
+func Builtins() []Rule {
+	return append([]Rule(nil), builtins...)
+}
+
+// Classifier applies an ordered rule set. A nil Classifier classifies everything as Unknown.
+type Classifier struct {
+	rules []Rule
+}
+
+func New(rules ...Rule) *Classifier {
+	return &Classifier{rules: rules}
+}
+
+// Classify returns the class of the first rule matching any of outputs, in rule order, or Unknown.
+func (c *Classifier) Classify(outputs ...string) Class {
+	if c == nil {
+		return Unknown
+	}
+	for _, r := range c.rules {
+		for _, output := range outputs {
+			if output != "" && r.pattern.MatchString(output) {
+				return r.Class
+			}
+		}
+	}
+	return Unknown
+}
@@ -0,0 +1,135 @@
+package errclass
+
+import "testing"
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs []string
+		want    Class
+	}{
+		{
+			name:    "state lock",
+			outputs: []string{"", "│ Error: Error acquiring the state lock\n│\n│ Error message: ConditionalCheckFailedException: The conditional request failed\n│ Lock Info:\n│   ID:        7c1f2d5e\n│   Path:      tf-state/prod/terraform.tfstate"},
+			want:    StateLock,
+		},
+		{
+			name:    "expired aws credentials",
+			outputs: []string{"", "│ Error: reading EC2 Instance: operation error EC2: DescribeInstances, https response error StatusCode: 400, api error ExpiredToken: The security token included in the request is expired\n│\n│   with aws_instance.web,\n│   on main.tf line 12"},
+			want:    Credentials,
+		},
+		{
+			name:    "missing gcp credentials",
+			outputs: []string{"", "Error: Attempted to load application default credentials since neither `credentials` nor `access_token` was set in the provider block. No credentials loaded. To use your gcloud credentials, run 'gcloud auth application-default login'. Original error: google: could not find default credentials."},
+			want:    Credentials,
+		},
+		{
+			name:    "throttled",
+			outputs: []string{"", "Error: reading IAM Role: operation error IAM: GetRole, exceeded maximum number of attempts, 25, https response error StatusCode: 400, api error Throttling: Rate exceeded"},
+			want:    RateLimit,
+		},
+		{
+			name:    "provider download in init",
+			outputs: []string{"Initializing provider plugins...\n│ Error: Failed to query available provider packages\n│\n│ Could not retrieve the list of available versions for provider hashicorp/aws: could not connect to registry.terraform.io", ""},
+			want:    ProviderDownload,
+		},
+		{
+			name:    "backend changed",
+			outputs: []string{"│ Error: Backend configuration changed\n│\n│ A change in the backend configuration has been detected, which may require migrating existing state.", ""},
+			want:    BackendConfig,
+		},
+		{
+			name:    "timeout",
+			outputs: []string{"", "Error: Get \"https://kubernetes.internal/api/v1/namespaces/app\": dial tcp 10.0.0.4:443: i/o timeout"},
+			want:    Timeout,
+		},
+		{
+			name:    "invalid configuration",
+			outputs: []string{"", "│ Error: Unsupported argument\n│\n│   on main.tf line 4, in resource \"aws_s3_bucket\" \"logs\":\n│    4:   acl_policy = \"private\"\n│\n│ An argument named \"acl_policy\" is not expected here."},
+			want:    Validation,
+		},
+		{
+			name:    "unrecognized",
+			outputs: []string{"Terraform has been successfully initialized!", "Error: unexpected EOF"},
+			want:    Unknown,
+		},
+		{
+			name:    "no output",
+			outputs: []string{"", ""},
+			want:    Unknown,
+		},
+	}
+	c := New(Builtins()...)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Classify(tt.outputs...); got != tt.want {
+				t.Errorf("Classify() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassify_RuleOrderWins(t *testing.T) {
+	// A held lock names the backend's bucket too, and must not be reported as a backend problem.
+	out := "Error: Error acquiring the state lock\n\nError message: operation error S3: PutObject, NoSuchBucket"
+	if got := New(Builtins()...).Classify(out); got != StateLock {
+		t.Errorf("Classify() = %s, want %s", got, StateLock)
+	}
+}
+
+func TestNewRule_Custom(t *testing.T) {
+	r, err := NewRule(Credentials, `vault: permission denied`)
+	if err != nil {
+		t.Fatalf("NewRule: %v", err)
+	}
+	c := New(append([]Rule{r}, Builtins()...)...)
+	if got := c.Classify("", "Error: vault: permission denied"); got != Credentials {
+		t.Errorf("Classify() = %s, want %s", got, Credentials)
+	}
+}
+
+func TestNewRule_Rejects(t *testing.T) {
+	tests := []struct {
+		class   Class
+		pattern string
+	}{
+		{"", `x`},
+		{Timeout, `(`},
+		{Timeout, `a*`},
+	}
+	for _, tt := range tests {
+		if _, err := NewRule(tt.class, tt.pattern); err == nil {
+			t.Errorf("NewRule(%q, %q) should fail", tt.class, tt.pattern)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(`[{"class": "quota", "pattern": "(?i)quota exceeded"}, {"class": "TIMEOUT", "pattern": "stuck"}]`)
+	if err != nil {
+		t.Fatalf("ParseRules: %v", err)
+	}
+	c := New(append(rules, Builtins()...)...)
+	if got := c.Classify("", "Error: Quota exceeded for resource"); got != "QUOTA" {
+		t.Errorf("Classify() = %s, want QUOTA", got)
+	}
+	if got := c.Classify("", "Error: stuck"); got != Timeout {
+		t.Errorf("Classify() = %s, want %s", got, Timeout)
+	}
+
+	if rules, err := ParseRules(" "); err != nil || rules != nil {
+		t.Errorf("ParseRules(blank) = %v, %v, want no rules", rules, err)
+	}
+	for _, s := range []string{`{"class": "X"}`, `[{"class": "", "pattern": "x"}]`, `[{"class": "X", "pattern": "("}]`} {
+		if _, err := ParseRules(s); err == nil {
+			t.Errorf("ParseRules(%s) should fail", s)
+		}
+	}
+}
+
+func TestClassify_NilClassifier(t *testing.T) {
+	var c *Classifier
+	if got := c.Classify("Error acquiring the state lock"); got != Unknown {
+		t.Errorf("nil Classifier = %s, want %s", got, Unknown)
+	}
+}
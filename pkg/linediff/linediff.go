@@ -0,0 +1,194 @@
+// Package linediff compares two texts line by line and formats the result as a unified diff.
+package linediff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxEdits bounds the search for the shortest edit script, whose memory grows with the square of
+// the number of edits. Texts further apart are diffed as one removal followed by one insertion.
+const maxEdits = 1000
+
+// Op is the operation of an Edit.
+type Op byte
+
+const (
+	Equal  Op = ' '
+	Delete Op = '-'
+	Insert Op = '+'
+)
+
+// Edit is one line of the edit script turning a into b.
+type Edit struct {
+	Op   Op
+	Text string
+}
+
+// Lines splits s into lines, ignoring the newline ending the last one.
+func Lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Diff returns a shortest edit script turning a into b, deletions before insertions where both
+// apply.
+func Diff(a, b []string) []Edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		edits = append(edits, Edit{Equal, line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Equal, line})
+	}
+	return edits
+}
+
+// myers implements Myers' O(ND) algorithm. trace[d] holds, for each diagonal k in [-d, d] at
+// index k+d, the furthest x reached with d edits.
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	var trace [][]int
+	for d := 0; ; d++ {
+		if d > maxEdits {
+			return replace(a, b)
+		}
+		v := make([]int, 2*d+1)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			switch {
+			case d == 0:
+				x = 0
+			case k == -d || (k != d && trace[d-1][k-1+d-1] < trace[d-1][k+1+d-1]):
+				x = trace[d-1][k+1+d-1]
+			default:
+				x = trace[d-1][k-1+d-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[k+d] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, append(trace, v))
+			}
+		}
+		trace = append(trace, v)
+	}
+}
+
+func backtrack(a, b []string, trace [][]int) []Edit {
+	var edits []Edit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			edits = append(edits, Edit{Equal, a[x]})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, Edit{Insert, b[y]})
+		} else {
+			x--
+			edits = append(edits, Edit{Delete, a[x]})
+		}
+	}
+	for x > 0 {
+		x--
+		edits = append(edits, Edit{Equal, a[x]})
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+func replace(a, b []string) []Edit {
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, line := range a {
+		edits = append(edits, Edit{Delete, line})
+	}
+	for _, line := range b {
+		edits = append(edits, Edit{Insert, line})
+	}
+	return edits
+}
+
+// Unified formats edits as a unified diff from fromName to toName, with context unchanged lines
+// around each change. Returns "" when edits change nothing.
+func Unified(fromName, toName string, edits []Edit, context int) string {
+	// aPos[i] and bPos[i] are the number of lines of a and b before edits[i].
+	aPos, bPos := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	for i, e := range edits {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if e.Op != Insert {
+			aPos[i+1]++
+		}
+		if e.Op != Delete {
+			bPos[i+1]++
+		}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(edits); {
+		for i < len(edits) && edits[i].Op == Equal {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+		// Changes closer than twice the context share a hunk.
+		start, last := max(i-context, 0), i
+		for j := i + 1; j < len(edits) && j-last <= 2*context; j++ {
+			if edits[j].Op != Equal {
+				last = j
+			}
+		}
+		end := min(last+context+1, len(edits))
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aPos[start], aPos[end]), hunkRange(bPos[start], bPos[end]))
+		for _, e := range edits[start:end] {
+			sb.WriteByte(byte(e.Op))
+			sb.WriteString(e.Text)
+			sb.WriteByte('\n')
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+// hunkRange formats the lines from start to end, 0-based and exclusive, the way diff -u does: 1-based,
+// with the count omitted when it is 1 and the line before the hunk when it is 0.
+func hunkRange(start, end int) string {
+	switch count := end - start; count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
@@ -0,0 +1,85 @@
+package linediff
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	got := Diff([]string{"a", "b", "c", "d"}, []string{"a", "c", "x", "d"})
+	want := []Edit{{Equal, "a"}, {Delete, "b"}, {Equal, "c"}, {Insert, "x"}, {Equal, "d"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+	// Whatever the script, it must rebuild both texts.
+	from, to := Lines("a\nb\nc\na\nb\nb\na\n"), Lines("c\nb\na\nb\na\nc\n")
+	var rebuiltFrom, rebuiltTo []string
+	for _, e := range Diff(from, to) {
+		if e.Op != Insert {
+			rebuiltFrom = append(rebuiltFrom, e.Text)
+		}
+		if e.Op != Delete {
+			rebuiltTo = append(rebuiltTo, e.Text)
+		}
+	}
+	if !reflect.DeepEqual(rebuiltFrom, from) || !reflect.DeepEqual(rebuiltTo, to) {
+		t.Errorf("Diff() rebuilds %q and %q, want %q and %q", rebuiltFrom, rebuiltTo, from, to)
+	}
+	if got := Diff(nil, []string{"a"}); !reflect.DeepEqual(got, []Edit{{Insert, "a"}}) {
+		t.Errorf("Diff(nil, a) = %v", got)
+	}
+	if got := Diff(nil, nil); len(got) != 0 {
+		t.Errorf("Diff(nil, nil) = %v, want no edits", got)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	var a, b []string
+	for i := 1; i <= 20; i++ {
+		a = append(a, strconv.Itoa(i))
+		b = append(b, strconv.Itoa(i))
+	}
+	b[1] = "two"
+	b = append(b[:15], b[16:]...)
+	b = append(b, "21")
+
+	want := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -13,8 +13,8 @@
+ 13
+ 14
+ 15
+-16
+ 17
+ 18
+ 19
+ 20
++21
+`
+	if got := Unified("old", "new", Diff(a, b), 3); got != want {
+		t.Errorf("Unified() =\n%s\nwant\n%s", got, want)
+	}
+	if got := Unified("old", "new", Diff(a, a), 3); got != "" {
+		t.Errorf("Unified() of equal texts = %q, want empty", got)
+	}
+	if got, want := Unified("old", "new", Diff(nil, []string{"x"}), 3), "--- old\n+++ new\n@@ -0,0 +1 @@\n+x\n"; got != want {
+		t.Errorf("Unified() of an insertion into an empty text = %q, want %q", got, want)
+	}
+}
+
+func TestLines(t *testing.T) {
+	if got := Lines("a\nb\n"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Lines() = %q", got)
+	}
+	if got := Lines(""); got != nil {
+		t.Errorf("Lines(\"\") = %q, want nil", got)
+	}
+}
@@ -0,0 +1,42 @@
+// Package bodylimit caps request bodies on an app that streams them. Fiber only enforces its
+// BodyLimit on buffered bodies: with StreamRequestBody, a body over the limit reaches the handler
+// as a stream, and c.Body() reads all of it into memory.
+//
+// Routes that read the stream under their own limits are registered before the middleware, so the
+// router matches them, trailing slashes and letter case included, and they never reach it.
+package bodylimit
+
+import (
+	"io"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// New returns a middleware that buffers the request body and answers 413 once it is over limit
+// bytes, like fiber does without streaming.
+func New(limit int) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if c.Request().Header.ContentLength() > limit {
+			return tooLarge(c)
+		}
+		stream := c.Request().BodyStream()
+		if stream == nil {
+			return c.Next()
+		}
+		body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+		if err != nil {
+			return c.SendStatus(fiber.StatusBadRequest)
+		}
+		if len(body) > limit {
+			return tooLarge(c)
+		}
+		c.Request().SetBody(body)
+		return c.Next()
+	}
+}
+
+// tooLarge rejects the request and closes the connection, whose unread body can't be skipped.
+func tooLarge(c fiber.Ctx) error {
+	c.RequestCtx().SetConnectionClose()
+	return c.SendStatus(fiber.StatusRequestEntityTooLarge)
+}
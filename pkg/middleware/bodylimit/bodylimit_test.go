@@ -0,0 +1,65 @@
+package bodylimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const testLimit = 1024
+
+// newTestApp streams bodies over half the middleware's limit, so both buffered and streamed bodies
+// go through it. /stream is registered ahead of it, the way main registers the ingest routes.
+func newTestApp() *fiber.App {
+	app := fiber.New(fiber.Config{StreamRequestBody: true, BodyLimit: testLimit / 2})
+	echoSize := func(c fiber.Ctx) error { return c.SendString(strconv.Itoa(len(c.Body()))) }
+	app.Post("/stream", echoSize)
+	app.Use(New(testLimit))
+	app.Post("/json", echoSize)
+	return app
+}
+
+func post(t *testing.T, app *fiber.App, path string, size int) (int, string) {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, path, bytes.NewReader(bytes.Repeat([]byte{'a'}, size)))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(respBody)
+}
+
+func TestNew(t *testing.T) {
+	app := newTestApp()
+	cases := []struct {
+		name       string
+		path       string
+		size       int
+		wantStatus int
+	}{
+		{"buffered", "/json", testLimit / 4, http.StatusOK},
+		{"streamed under the limit", "/json", testLimit, http.StatusOK},
+		{"over the limit", "/json", testLimit + 1, http.StatusRequestEntityTooLarge},
+		{"route ahead of the limit", "/stream", 4 * testLimit, http.StatusOK},
+		{"route ahead of the limit, other spelling", "/Stream/", 4 * testLimit, http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := post(t, app, tc.path, tc.size)
+			if status != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tc.wantStatus)
+			}
+			if status == http.StatusOK && body != strconv.Itoa(tc.size) {
+				t.Errorf("handler read %s bytes, want %d", body, tc.size)
+			}
+		})
+	}
+}
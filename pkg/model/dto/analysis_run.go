@@ -11,6 +11,7 @@ type DriftAnalysisRunDTO struct {
 	TotalProjectsSkipped int32     `json:"total_projects_skipped"`
 	DurationMillis       int64     `json:"duration_millis"`
 	Status               string    `json:"status"`
+	CommitSha            *string   `json:"commit_sha"`
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at"`
 }
@@ -28,12 +29,68 @@ type DriftAnalysisProjectDTO struct {
 	ResourcesAdded     *int32  `json:"resources_added"`
 	ResourcesChanged   *int32  `json:"resources_changed"`
 	ResourcesDestroyed *int32  `json:"resources_destroyed"`
+	InitOutputSize     *int64  `json:"init_output_size"`
+	PlanOutputSize     *int64  `json:"plan_output_size"`
+	ResourcesImported  *int32  `json:"resources_imported"`
+	ResourcesForgotten *int32  `json:"resources_forgotten"`
+	ResourcesMoved     *int32  `json:"resources_moved"`
+	OutputsChanged     *int32  `json:"outputs_changed"`
+	// Redactions counts the secrets masked in the outputs, by detector name.
+	Redactions map[string]int32 `json:"redactions"`
+	// ResourceChanges lists the resources named in the plan and the action taken on each.
+	ResourceChanges []ResourceChangeDTO `json:"resource_changes"`
+	// DriftFingerprint identifies the drift in the plan: equal fingerprints mean the same drift.
+	DriftFingerprint *string `json:"drift_fingerprint"`
+	// DriftUnchanged is true when the repository's previous completed run had the same drift here.
+	DriftUnchanged bool `json:"drift_unchanged"`
+	// Timing of the analysis as reported by the CLI, nil when it wasn't.
+	InitDurationMillis *int64     `json:"init_duration_millis"`
+	PlanDurationMillis *int64     `json:"plan_duration_millis"`
+	StartedAt          *time.Time `json:"started_at"`
+	FinishedAt         *time.Time `json:"finished_at"`
+	Retries            *int32     `json:"retries"`
+	// ErrorClass is why a failed project errored, e.g. CREDENTIALS or STATE_LOCK.
+	ErrorClass *string `json:"error_class"`
+	// Versions parsed from the init output, nil or empty when it doesn't name them.
+	CoreTool         *string              `json:"core_tool"`
+	CoreVersion      *string              `json:"core_version"`
+	ProviderVersions []ProviderVersionDTO `json:"provider_versions"`
+	// Warnings lists the warning diagnostics printed by init and plan.
+	Warnings []WarningDTO `json:"warnings"`
+	// Owners of the dir per the CODEOWNERS of the latest organization sync, not the one at the time
+	// of the run.
+	Owners []string `json:"owners"`
+}
+
+type WarningDTO struct {
+	Category    string `json:"category"`
+	Summary     string `json:"summary"`
+	Detail      string `json:"detail,omitempty"`
+	Address     string `json:"address,omitempty"`
+	Occurrences int32  `json:"occurrences"`
+}
+
+type ResourceChangeDTO struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
 }
 
 type DriftAnalysisRunWithProjectsDTO struct {
 	DriftAnalysisRunDTO
 	RunningProjects []string                  `json:"running_projects"`
 	Projects        []DriftAnalysisProjectDTO `json:"projects"`
+	// WarningCount totals the warnings of every project, WarningCounts splits it by category.
+	WarningCount  int64            `json:"warning_count"`
+	WarningCounts map[string]int64 `json:"warning_counts"`
+	// CoverageChanges lists the dirs the run added to or dropped from the previous completed run.
+	CoverageChanges []CoverageChangeDTO `json:"coverage_changes"`
+}
+
+type CoverageChangeDTO struct {
+	Dir  string `json:"dir"`
+	Type string `json:"type"`
+	// Change is ADDED or REMOVED.
+	Change string `json:"change"`
 }
 
 type RepositoryRunStatsDTO struct {
@@ -41,4 +98,44 @@ type RepositoryRunStatsDTO struct {
 	RunsWithDrift int64                `json:"runs_with_drift"`
 	LastRunAt     *time.Time           `json:"last_run_at"`
 	LatestRun     *DriftAnalysisRunDTO `json:"latest_run"`
+	// LatestRunErrorClasses breaks the latest run's failed projects down by error class.
+	LatestRunErrorClasses []ErrorClassCountDTO `json:"latest_run_error_classes"`
+	// UnscannedProjects lists the projects seen in earlier runs that the latest run didn't scan.
+	UnscannedProjects []UnscannedProjectDTO `json:"unscanned_projects"`
+	// ScanDueAt is when the next completed run is expected by, nil without a scan schedule.
+	ScanDueAt *time.Time `json:"scan_due_at"`
+	// ScanOverdue is set once ScanDueAt passed without a completed run, which tells a broken scan
+	// pipeline apart from a repository without drift.
+	ScanOverdue bool `json:"scan_overdue"`
+}
+
+type UnscannedProjectDTO struct {
+	Dir        string    `json:"dir"`
+	Type       string    `json:"type"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// LastRunID is nil once retention deleted the last run that scanned the project.
+	LastRunID *string `json:"last_run_id"`
+}
+
+type ErrorClassCountDTO struct {
+	ErrorClass   string `json:"error_class"`
+	ProjectCount int64  `json:"project_count"`
+}
+
+// ProjectOutputDTO is a project's plan output, as plain text or as HTML with colors converted to spans.
+type ProjectOutputDTO struct {
+	RunID  string `json:"run_id"`
+	Dir    string `json:"dir"`
+	Format string `json:"format"`
+	Output string `json:"output"`
+}
+
+// ProjectOutputDiffDTO is the unified diff of a project's plan output between two runs.
+type ProjectOutputDiffDTO struct {
+	Dir       string `json:"dir"`
+	BaseRunID string `json:"base_run_id"`
+	RunID     string `json:"run_id"`
+	Diff      string `json:"diff"`
+	Added     int    `json:"added"`
+	Removed   int    `json:"removed"`
 }
@@ -1,10 +1,75 @@
 package dto
 
+import "time"
+
 type GitRepositoryDTO struct {
-	ID               int64  `json:"id"`
-	OrganizationID   int64  `json:"organization_id"`
-	ProviderID       string `json:"provider_id"`
-	Name             string `json:"name"`
-	IsPrivate        bool   `json:"is_private"`
-	HasAnalysisToken bool   `json:"has_analysis_token"`
+	ID                     int64   `json:"id"`
+	OrganizationID         int64   `json:"organization_id"`
+	ProviderID             string  `json:"provider_id"`
+	Name                   string  `json:"name"`
+	IsPrivate              bool    `json:"is_private"`
+	HasAnalysisToken       bool    `json:"has_analysis_token"`
+	DriftIssuesEnabled     bool    `json:"drift_issues_enabled"`
+	DispatchWorkflow       *string `json:"dispatch_workflow"`
+	RemediationEnabled     bool    `json:"remediation_enabled"`
+	ScanSchedule           *string `json:"scan_schedule"`
+	ScanMaxIntervalMinutes *int32  `json:"scan_max_interval_minutes"`
+}
+
+// UpdateDriftIssuesRequest turns the GitHub issues opened for drifted projects on or off.
+type UpdateDriftIssuesRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// UpdateDispatchSettingsRequest sets how the dashboard triggers scans of a repository and whether it
+// may trigger remediation applies. A null or empty workflow dispatches repository_dispatch events.
+type UpdateDispatchSettingsRequest struct {
+	Workflow           *string `json:"workflow"`
+	RemediationEnabled *bool   `json:"remediation_enabled"`
+}
+
+// UpdateScanScheduleRequest sets the cadence a repository expects its scans at: a cron expression
+// of when they are scheduled, in UTC unless prefixed with CRON_TZ=, or the max minutes between
+// completed runs. At most one may be set; neither clears the schedule.
+type UpdateScanScheduleRequest struct {
+	Cron               *string `json:"cron"`
+	MaxIntervalMinutes *int32  `json:"max_interval_minutes"`
+}
+
+// ScanOverdueAlertDTO is an alert raised when a repository had no completed run by DueAt.
+// ResolvedAt is nil while the alert is open.
+type ScanOverdueAlertDTO struct {
+	ID              int64      `json:"id"`
+	DueAt           time.Time  `json:"due_at"`
+	LastCompletedAt *time.Time `json:"last_completed_at"`
+	RaisedAt        time.Time  `json:"raised_at"`
+	ResolvedAt      *time.Time `json:"resolved_at"`
+}
+
+// ScanDispatchRequest triggers a scan of the given dirs, or of the whole repository when empty.
+type ScanDispatchRequest struct {
+	Dirs []string `json:"dirs"`
+}
+
+// RemediationDispatchRequest triggers a remediation apply of one catalog project.
+type RemediationDispatchRequest struct {
+	Dir  string `json:"dir"`
+	Type string `json:"type"`
+}
+
+type DispatchResponse struct {
+	// DispatchID is the Idempotency-Key the dispatched workflow uploads its run with.
+	DispatchID string `json:"dispatch_id"`
+}
+
+type ScanDispatchDTO struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"`
+	Dirs        []string  `json:"dirs"`
+	ProjectType *string   `json:"project_type"`
+	RequestedBy string    `json:"requested_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	// RunID and RunStatus are nil until the dispatched scan starts uploading, and for remediations.
+	RunID     *string `json:"run_id"`
+	RunStatus *string `json:"run_status"`
 }
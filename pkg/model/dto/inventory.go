@@ -0,0 +1,54 @@
+package dto
+
+import "time"
+
+type ProviderVersionDTO struct {
+	Source  string `json:"source"`
+	Version string `json:"version"`
+}
+
+// ProjectVersionsDTO represents the versions a project reported in its latest run
+type ProjectVersionsDTO struct {
+	RepositoryID     int64                `json:"repository_id"`
+	RepositoryName   string               `json:"repository_name"`
+	Dir              string               `json:"dir"`
+	Type             string               `json:"type"`
+	CoreTool         *string              `json:"core_tool"`
+	CoreVersion      *string              `json:"core_version"`
+	ProviderVersions []ProviderVersionDTO `json:"provider_versions"`
+	LastRunAt        time.Time            `json:"last_run_at"`
+}
+
+// VersionUsageDTO represents how many projects run a version of a core tool or provider
+type VersionUsageDTO struct {
+	// Component is TERRAFORM or OPENTOFU for core versions, or the provider source.
+	Component    string `json:"component"`
+	Version      string `json:"version"`
+	ProjectCount int64  `json:"project_count"`
+}
+
+// VersionInventoryDTO is the response for the organization version inventory endpoint
+type VersionInventoryDTO struct {
+	CoreVersions     []VersionUsageDTO    `json:"core_versions"`
+	ProviderVersions []VersionUsageDTO    `json:"provider_versions"`
+	Projects         []ProjectVersionsDTO `json:"projects"`
+	DaysBack         int                  `json:"days_back"`
+}
+
+// OutdatedProjectDTO represents a project running a version below the requested minimum
+type OutdatedProjectDTO struct {
+	RepositoryID   int64     `json:"repository_id"`
+	RepositoryName string    `json:"repository_name"`
+	Dir            string    `json:"dir"`
+	Type           string    `json:"type"`
+	Version        string    `json:"version"`
+	LastRunAt      time.Time `json:"last_run_at"`
+}
+
+// OutdatedProjectsDTO is the response for the outdated versions endpoint
+type OutdatedProjectsDTO struct {
+	Component  string               `json:"component"`
+	MinVersion string               `json:"min_version"`
+	Projects   []OutdatedProjectDTO `json:"projects"`
+	DaysBack   int                  `json:"days_back"`
+}
@@ -1,8 +1,28 @@
 package dto
 
+import "time"
+
 type OrganizationDTO struct {
 	ID        int64  `json:"id"`
 	Name      string `json:"name"`
 	Installed bool   `json:"installed"`
 	AvatarURL string `json:"avatar_url"`
 }
+
+type RedactionRuleDTO struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Pattern   string    `json:"pattern"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RedactionRulesDTO struct {
+	// Builtins are the detectors applied to every organization on top of Rules.
+	Builtins []string           `json:"builtins"`
+	Rules    []RedactionRuleDTO `json:"rules"`
+}
+
+type CreateRedactionRuleRequest struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
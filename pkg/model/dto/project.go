@@ -0,0 +1,91 @@
+package dto
+
+import "time"
+
+// ProjectDTO represents a project of the repository catalog
+type ProjectDTO struct {
+	ID   int64  `json:"id"`
+	Dir  string `json:"dir"`
+	Type string `json:"type"`
+	// Status is the outcome of the latest run the project was seen in: IN_SYNC, DRIFTED, ERRORED
+	// or SKIPPED.
+	Status        string     `json:"status"`
+	FirstSeenAt   time.Time  `json:"first_seen_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	LastDriftedAt *time.Time `json:"last_drifted_at"`
+	LastErroredAt *time.Time `json:"last_errored_at"`
+	// LastRunID is nil once retention deleted the latest run the project was seen in.
+	LastRunID *string `json:"last_run_id"`
+	// Owners are the users and teams the repository's CODEOWNERS assigns the project's dir to.
+	Owners []string `json:"owners"`
+}
+
+// ProjectRunDTO represents the outcome of a project in one run
+type ProjectRunDTO struct {
+	RunID string `json:"run_id"`
+	// Status is IN_SYNC, DRIFTED, ERRORED or SKIPPED.
+	Status             string    `json:"status"`
+	Type               string    `json:"type"`
+	ResourcesAdded     *int32    `json:"resources_added"`
+	ResourcesChanged   *int32    `json:"resources_changed"`
+	ResourcesDestroyed *int32    `json:"resources_destroyed"`
+	ErrorClass         *string   `json:"error_class"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// DriftPeriodDTO represents a stretch of runs a project stayed drifted
+type DriftPeriodDTO struct {
+	Type      string    `json:"type"`
+	StartedAt time.Time `json:"started_at"`
+	// EndedAt is the first run the drift was gone, nil while it is ongoing.
+	EndedAt *time.Time `json:"ended_at"`
+}
+
+// ProjectHistoryDTO is the response for the project history endpoint
+type ProjectHistoryDTO struct {
+	Dir          string           `json:"dir"`
+	Runs         []ProjectRunDTO  `json:"runs"`
+	DriftPeriods []DriftPeriodDTO `json:"drift_periods"`
+}
+
+// ProjectStatusCountsDTO counts the projects of a tree node by status
+type ProjectStatusCountsDTO struct {
+	Total   int `json:"total"`
+	InSync  int `json:"in_sync"`
+	Drifted int `json:"drifted"`
+	Errored int `json:"errored"`
+	Skipped int `json:"skipped"`
+}
+
+// ProjectTreeLeafDTO represents the project whose dir is a tree node
+type ProjectTreeLeafDTO struct {
+	Type string `json:"type"`
+	// Status is IN_SYNC, DRIFTED, ERRORED or SKIPPED.
+	Status string `json:"status"`
+}
+
+// ProjectTreeNodeDTO represents a directory of the project tree, with the counts of every project
+// under it
+type ProjectTreeNodeDTO struct {
+	// Path is the dir of the node without leading ./ or /, "" for the root.
+	Path   string                 `json:"path"`
+	Name   string                 `json:"name"`
+	Counts ProjectStatusCountsDTO `json:"counts"`
+	// Project is set when the node is itself a project's dir.
+	Project  *ProjectTreeLeafDTO  `json:"project"`
+	Children []ProjectTreeNodeDTO `json:"children"`
+}
+
+// ProjectTreeDTO is the response for the project tree endpoint
+type ProjectTreeDTO struct {
+	RunID     string             `json:"run_id"`
+	CreatedAt time.Time          `json:"created_at"`
+	Root      ProjectTreeNodeDTO `json:"root"`
+}
+
+// OwnerSummaryDTO counts the catalog projects of an owner by status
+type OwnerSummaryDTO struct {
+	// Owner is nil for the projects no CODEOWNERS rule assigns.
+	Owner  *string                `json:"owner"`
+	Counts ProjectStatusCountsDTO `json:"counts"`
+}
@@ -19,6 +19,99 @@ type FrequentlyDriftedProject struct {
 	DriftPercentage  float64 `json:"drift_percentage"`
 }
 
+// FrequentlyDriftedResource represents a resource address that drifts frequently
+type FrequentlyDriftedResource struct {
+	Dir        string `json:"dir"`
+	Address    string `json:"address"`
+	DriftCount int64  `json:"drift_count"`
+}
+
+// ResourceTypeDriftStat represents a resource type that drifts frequently
+type ResourceTypeDriftStat struct {
+	ResourceType  string `json:"resource_type"`
+	Provider      string `json:"provider"`
+	DriftCount    int64  `json:"drift_count"`
+	ResourceCount int64  `json:"resource_count"`
+	// RepositoryCount is how many repositories it drifted in, 1 within a single repository.
+	RepositoryCount int64 `json:"repository_count"`
+}
+
+// ProviderDriftStat represents a provider whose resources drift frequently
+type ProviderDriftStat struct {
+	Provider        string `json:"provider"`
+	DriftCount      int64  `json:"drift_count"`
+	ResourceCount   int64  `json:"resource_count"`
+	RepositoryCount int64  `json:"repository_count"`
+}
+
+// ResourceTrendsDTO is the response for the resource type and provider trends endpoints
+type ResourceTrendsDTO struct {
+	ResourceTypes []ResourceTypeDriftStat `json:"resource_types"`
+	Providers     []ProviderDriftStat     `json:"providers"`
+	DaysBack      int                     `json:"days_back"`
+}
+
+// SlowProjectDTO represents a project that takes long to analyze
+type SlowProjectDTO struct {
+	Dir                   string `json:"dir"`
+	Type                  string `json:"type"`
+	RunCount              int64  `json:"run_count"`
+	AvgDurationMillis     int64  `json:"avg_duration_millis"`
+	MaxDurationMillis     int64  `json:"max_duration_millis"`
+	AvgInitDurationMillis int64  `json:"avg_init_duration_millis"`
+	AvgPlanDurationMillis int64  `json:"avg_plan_duration_millis"`
+	TotalRetries          int64  `json:"total_retries"`
+}
+
+// DurationRegressionDTO represents a project whose analysis got slower within the window
+type DurationRegressionDTO struct {
+	Dir                       string  `json:"dir"`
+	BaselineAvgDurationMillis int64   `json:"baseline_avg_duration_millis"`
+	RecentAvgDurationMillis   int64   `json:"recent_avg_duration_millis"`
+	IncreasePercent           float64 `json:"increase_percent"`
+}
+
+// DurationTrendsDTO is the response for the duration trends endpoint
+type DurationTrendsDTO struct {
+	SlowestProjects []SlowProjectDTO        `json:"slowest_projects"`
+	Regressions     []DurationRegressionDTO `json:"regressions"`
+	DaysBack        int                     `json:"days_back"`
+}
+
+// ErrorClassStat represents how often failed projects hit an error class
+type ErrorClassStat struct {
+	ErrorClass   string    `json:"error_class"`
+	ProjectCount int64     `json:"project_count"`
+	RunCount     int64     `json:"run_count"`
+	LastSeenAt   time.Time `json:"last_seen_at"`
+}
+
+// RunWarningCountDTO represents the number of warnings printed in one run
+type RunWarningCountDTO struct {
+	RunID        string    `json:"run_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	WarningCount int64     `json:"warning_count"`
+}
+
+// RepeatedWarningDTO represents a warning a project printed in several runs
+type RepeatedWarningDTO struct {
+	Dir         string    `json:"dir"`
+	Category    string    `json:"category"`
+	Summary     string    `json:"summary"`
+	Address     string    `json:"address,omitempty"`
+	Detail      string    `json:"detail,omitempty"`
+	RunCount    int64     `json:"run_count"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// WarningTrendsDTO is the response for the warning trends endpoint
+type WarningTrendsDTO struct {
+	WarningsPerRun   []RunWarningCountDTO `json:"warnings_per_run"`
+	RepeatedWarnings []RepeatedWarningDTO `json:"repeated_warnings"`
+	DaysBack         int                  `json:"days_back"`
+}
+
 // DriftFreeStreakDTO represents the current drift-free streak
 type DriftFreeStreakDTO struct {
 	StreakCount int64      `json:"streak_count"`
@@ -42,10 +135,38 @@ type TrendsSummaryDTO struct {
 
 // RepositoryTrendsDTO is the main response for the trends endpoint
 type RepositoryTrendsDTO struct {
-	Summary                   TrendsSummaryDTO           `json:"summary"`
-	DriftRateOverTime         []DriftRateDataPoint       `json:"drift_rate_over_time"`
-	FrequentlyDriftedProjects []FrequentlyDriftedProject `json:"frequently_drifted_projects"`
-	DriftFreeStreak           DriftFreeStreakDTO         `json:"drift_free_streak"`
-	ResolutionTimes           []ResolutionTimeDataPoint  `json:"resolution_times"`
-	DaysBack                  int                        `json:"days_back"`
+	Summary                    TrendsSummaryDTO            `json:"summary"`
+	DriftRateOverTime          []DriftRateDataPoint        `json:"drift_rate_over_time"`
+	FrequentlyDriftedProjects  []FrequentlyDriftedProject  `json:"frequently_drifted_projects"`
+	FrequentlyDriftedResources []FrequentlyDriftedResource `json:"frequently_drifted_resources"`
+	DriftFreeStreak            DriftFreeStreakDTO          `json:"drift_free_streak"`
+	ResolutionTimes            []ResolutionTimeDataPoint   `json:"resolution_times"`
+	ErrorClasses               []ErrorClassStat            `json:"error_classes"`
+	DaysBack                   int                         `json:"days_back"`
+}
+
+// ScopedDriftDataPoint represents a single day's drift data of part of the projects of a repository,
+// such as those under a dir or those of an owner
+type ScopedDriftDataPoint struct {
+	Date             string  `json:"date"`
+	TotalRuns        int64   `json:"total_runs"`
+	RunsWithDrift    int64   `json:"runs_with_drift"`
+	DriftRatePercent float64 `json:"drift_rate_percent"`
+	ProjectsScanned  int64   `json:"projects_scanned"`
+	ProjectsDrifted  int64   `json:"projects_drifted"`
+	ProjectsErrored  int64   `json:"projects_errored"`
+}
+
+// SubtreeTrendsDTO is the response for the subtree trends endpoint
+type SubtreeTrendsDTO struct {
+	Prefix            string                 `json:"prefix"`
+	DriftRateOverTime []ScopedDriftDataPoint `json:"drift_rate_over_time"`
+	DaysBack          int                    `json:"days_back"`
+}
+
+// OwnerTrendsDTO is the response for the owner trends endpoint
+type OwnerTrendsDTO struct {
+	Owner             string                 `json:"owner"`
+	DriftRateOverTime []ScopedDriftDataPoint `json:"drift_rate_over_time"`
+	DaysBack          int                    `json:"days_back"`
 }
@@ -28,6 +28,9 @@ type Metrics struct {
 
 	// Background job supervisor metrics
 	BgJobPanicsTotal metric.Int64Counter
+
+	// Scan schedule metrics
+	ScansOverdueTotal metric.Int64Counter
 }
 
 // metricsInstance is the singleton instance
@@ -134,6 +137,14 @@ func createMetrics(meter metric.Meter) (*Metrics, error) {
 		return nil, err
 	}
 
+	scansOverdueTotal, err := meter.Int64Counter(
+		"scans_overdue_total",
+		metric.WithDescription("Number of times a repository was found overdue for a completed run on its expected schedule"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Metrics{
 		meter:                 meter,
 		TokenRefreshTotal:     tokenRefreshTotal,
@@ -142,5 +153,6 @@ func createMetrics(meter metric.Meter) (*Metrics, error) {
 		TokenRefreshDisabled:  tokenRefreshDisabled,
 		TokenRefreshRateLimit: tokenRefreshRateLimit,
 		BgJobPanicsTotal:      bgJobPanicsTotal,
+		ScansOverdueTotal:     scansOverdueTotal,
 	}, nil
 }
@@ -0,0 +1,162 @@
+// Package redact masks secrets in command output before it is stored. Terraform hides values
+// marked sensitive, but providers, init logs and failing plans still print tokens, keys and
+// connection strings verbatim.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MaxPatternLength bounds a custom pattern. Go's regexp runs in linear time, so the limit is
+	// about keeping rules readable rather than guarding against catastrophic backtracking.
+	MaxPatternLength = 1000
+
+	// secretGroup names the part of a match to mask. Patterns without it mask the whole match.
+	secretGroup = "secret"
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Detector finds one kind of secret.
+type Detector struct {
+	Name    string
+	pattern *regexp.Regexp
+	// secret is the submatch index of the secret group, or 0 to mask the whole match.
+	secret int
+}
+
+// NewDetector compiles a detector. If pattern has a group named "secret" only that group is
+// masked, which keeps the surrounding context, e.g. the user in a URL, readable.
+func NewDetector(name, pattern string) (Detector, error) {
+	if !namePattern.MatchString(name) {
+		return Detector{}, fmt.Errorf("name %q must be 1-64 lowercase letters, digits, '_' or '-'", name)
+	}
+	if len(pattern) > MaxPatternLength {
+		return Detector{}, fmt.Errorf("pattern exceeds %d characters", MaxPatternLength)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Detector{}, fmt.Errorf("invalid pattern: %w", err)
+	}
+	if re.MatchString("") {
+		return Detector{}, fmt.Errorf("pattern must not match the empty string")
+	}
+	return Detector{Name: name, pattern: re, secret: max(re.SubexpIndex(secretGroup), 0)}, nil
+}
+
+func mustDetector(name, pattern string) Detector {
+	d, err := NewDetector(name, pattern)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+var builtins = []Detector{
+	mustDetector("aws_access_key_id", `\b(?:AKIA|ASIA|AROA|AIDA)[0-9A-Z]{16}\b`),
+	mustDetector("aws_secret_access_key", `(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})`),
+	mustDetector("github_token", `\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})`),
+	// A block cut off by output truncation is masked to the end rather than left half visible.
+	mustDetector("pem_private_key", `-----BEGIN[A-Z ]* PRIVATE KEY-----(?:[\s\S]*?-----END[A-Z ]* PRIVATE KEY-----|[\s\S]*)`),
+	mustDetector("jwt", `\beyJ[A-Za-z0-9_-]{4,}\.eyJ[A-Za-z0-9_-]{4,}\.[A-Za-z0-9_-]*`),
+	mustDetector("url_credentials", `\b[A-Za-z][A-Za-z0-9+.-]*://[^\s/:@]+:(?P<secret>[^\s/@]+)@`),
+}
+
+// Builtins returns the detectors applied to every organization, minus the names in disabled.
+func Builtins(disabled ...string) []Detector {
+	detectors := make([]Detector, 0, len(builtins))
+	for _, d := range builtins {
+		if !containsName(disabled, d.Name) {
+			detectors = append(detectors, d)
+		}
+	}
+	return detectors
+}
+
+// IsBuiltin reports whether name belongs to a built-in detector.
+func IsBuiltin(name string) bool {
+	for _, d := range builtins {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.TrimSpace(n) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Counts holds the number of redactions per detector name.
+type Counts map[string]int32
+
+// Add merges other into c, allocating c if needed, and returns it.
+func (c Counts) Add(other Counts) Counts {
+	if len(other) == 0 {
+		return c
+	}
+	if c == nil {
+		c = make(Counts, len(other))
+	}
+	for name, n := range other {
+		c[name] += n
+	}
+	return c
+}
+
+// Redactor applies a set of detectors in order. A nil Redactor redacts nothing.
+type Redactor struct {
+	detectors []Detector
+}
+
+func New(detectors ...Detector) *Redactor {
+	return &Redactor{detectors: detectors}
+}
+
+// Redact returns s with every detected secret replaced by a [REDACTED:<detector>] marker, and the
+// number of secrets each detector masked. Counts is nil when nothing was found.
+func (r *Redactor) Redact(s string) (string, Counts) {
+	if r == nil || s == "" {
+		return s, nil
+	}
+	var counts Counts
+	for _, d := range r.detectors {
+		var n int32
+		s, n = d.redact(s)
+		if n > 0 {
+			counts = counts.Add(Counts{d.Name: n})
+		}
+	}
+	return s, counts
+}
+
+func (d Detector) redact(s string) (string, int32) {
+	matches := d.pattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, 0
+	}
+	marker := "[REDACTED:" + d.Name + "]"
+	var b strings.Builder
+	b.Grow(len(s))
+	last, n := 0, int32(0)
+	for _, m := range matches {
+		start, end := m[2*d.secret], m[2*d.secret+1]
+		if start < 0 || start == end {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(marker)
+		last = end
+		n++
+	}
+	b.WriteString(s[last:])
+	return b.String(), n
+}
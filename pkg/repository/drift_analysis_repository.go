@@ -14,18 +14,45 @@ type DriftAnalysisRepository interface {
 	CreateDriftAnalysisProject(ctx context.Context, params queries.CreateDriftAnalysisProjectParams) (queries.DriftAnalysisProject, error)
 	UpsertDriftAnalysisProjects(ctx context.Context, rows []queries.UpsertDriftAnalysisProjectParams) error
 	UpdateDriftAnalysisRunProgress(ctx context.Context, params queries.UpdateDriftAnalysisRunProgressParams) error
+	TouchRunningDriftAnalysisRun(ctx context.Context, runId uuid.UUID) (bool, error)
+	DeleteRunningDriftAnalysisRun(ctx context.Context, runId uuid.UUID) error
 	MarkDriftAnalysisRunCompleted(ctx context.Context, params queries.MarkDriftAnalysisRunCompletedParams) error
+	CompleteDriftAnalysisRunFromProjects(ctx context.Context, params queries.CompleteDriftAnalysisRunFromProjectsParams) error
+	SetDriftAnalysisRunExpectedShards(ctx context.Context, runId uuid.UUID, expectedShards int32) (bool, error)
+	SetDriftAnalysisRunCommitSha(ctx context.Context, runId uuid.UUID, commitSha string) (bool, error)
+	SetDriftAnalysisRunCheckRun(ctx context.Context, runId uuid.UUID, checkRunId int64) (bool, error)
+	RecordDriftAnalysisRunShard(ctx context.Context, params queries.RecordDriftAnalysisRunShardParams) error
+	RefreshShardedDriftAnalysisRun(ctx context.Context, runId uuid.UUID) (string, error)
 	FindDriftAnalysisRunsByRepositoryID(ctx context.Context, repoId int64, page int) ([]queries.DriftAnalysisRun, error)
+	FindDriftAnalysisRunsByRepositoryIDAndDirs(ctx context.Context, repoId int64, dirs []string, page int) ([]queries.DriftAnalysisRun, error)
 	FindDriftAnalysisRunByUUID(ctx context.Context, uuid uuid.UUID) (queries.DriftAnalysisRun, error)
 	FindRunByRepoAndIdempotencyKey(ctx context.Context, repoId int64, idempotencyKey string) (queries.DriftAnalysisRun, error)
 	FindDriftAnalysisProjectsByRunId(ctx context.Context, runId uuid.UUID) ([]queries.DriftAnalysisProject, error)
 	CountDriftAnalysisProjectsByRunId(ctx context.Context, runId uuid.UUID) (int64, error)
+	CountDriftAnalysisRunShards(ctx context.Context, runId uuid.UUID) (int64, error)
+	GetPreviousRunDriftFingerprints(ctx context.Context, runId uuid.UUID) ([]queries.GetPreviousRunDriftFingerprintsRow, error)
 	GetRepositoryRunStats(ctx context.Context, repoId int64) (queries.GetRepositoryRunStatsRow, error)
 	GetLatestRunForRepository(ctx context.Context, repoId int64) (queries.DriftAnalysisRun, error)
+	GetLatestFullRunForRepository(ctx context.Context, repoId int64) (queries.DriftAnalysisRun, error)
+	GetRunErrorClassBreakdown(ctx context.Context, runId uuid.UUID) ([]queries.GetRunErrorClassBreakdownRow, error)
+	GetRunProjectStatuses(ctx context.Context, runId uuid.UUID) ([]queries.GetRunProjectStatusesRow, error)
+	RecordDriftAnalysisRunCoverageChanges(ctx context.Context, runId uuid.UUID) error
+	GetRunCoverageChanges(ctx context.Context, runId uuid.UUID) ([]queries.GetRunCoverageChangesRow, error)
+	GetUnscannedProjects(ctx context.Context, repoId int64, runId uuid.UUID) ([]queries.GetUnscannedProjectsRow, error)
 
 	// Trend analytics methods
 	GetDriftRateOverTime(ctx context.Context, repoId int64, daysBack int32) ([]queries.GetDriftRateOverTimeRow, error)
+	GetDirsDriftOverTime(ctx context.Context, repoId int64, daysBack int32, dirs []string) ([]queries.GetDirsDriftOverTimeRow, error)
 	GetMostFrequentlyDriftedProjects(ctx context.Context, repoId int64, daysBack int32, maxResults int32) ([]queries.GetMostFrequentlyDriftedProjectsRow, error)
+	GetMostFrequentlyDriftedResources(ctx context.Context, repoId int64, daysBack int32, maxResults int32) ([]queries.GetMostFrequentlyDriftedResourcesRow, error)
+	GetMostFrequentlyDriftedResourceTypes(ctx context.Context, params queries.GetMostFrequentlyDriftedResourceTypesParams) ([]queries.GetMostFrequentlyDriftedResourceTypesRow, error)
+	GetMostFrequentlyDriftedProviders(ctx context.Context, params queries.GetMostFrequentlyDriftedProvidersParams) ([]queries.GetMostFrequentlyDriftedProvidersRow, error)
+	GetSlowestProjects(ctx context.Context, repoId int64, daysBack int32, maxResults int32) ([]queries.GetSlowestProjectsRow, error)
+	GetProjectDurationRegressions(ctx context.Context, repoId int64, daysBack int32, minIncreasePercent int32, maxResults int32) ([]queries.GetProjectDurationRegressionsRow, error)
+	GetErrorClassBreakdown(ctx context.Context, repoId int64, daysBack int32) ([]queries.GetErrorClassBreakdownRow, error)
+	GetWarningCountsPerRun(ctx context.Context, repoId int64, daysBack int32) ([]queries.GetWarningCountsPerRunRow, error)
+	GetRepeatedWarnings(ctx context.Context, repoId int64, daysBack int32, maxResults int32) ([]queries.GetRepeatedWarningsRow, error)
+	GetOrganizationVersionInventory(ctx context.Context, orgId int64, daysBack int32) ([]queries.GetOrganizationVersionInventoryRow, error)
 	GetDriftFreeStreak(ctx context.Context, repoId int64) (queries.GetDriftFreeStreakRow, error)
 	GetMeanTimeToResolution(ctx context.Context, repoId int64, daysBack int32) ([]queries.GetMeanTimeToResolutionRow, error)
 
@@ -33,6 +60,40 @@ type DriftAnalysisRepository interface {
 	DeleteOldestRunsExceedingLimit(ctx context.Context, repoId int64, maxRunsToKeep int32) error
 	DeleteDriftAnalysisRunsByRepositoryId(ctx context.Context, repoId int64) error
 	DeleteStaleRunningRuns(ctx context.Context, staleMinutes int32, maxRows int32) (int64, error)
+	ClaimCheckRunTimeouts(ctx context.Context, maxRows int32) ([]queries.CheckRunTimeout, error)
+	DeleteCheckRunTimeout(ctx context.Context, checkRunId int64) error
+	RetryCheckRunTimeout(ctx context.Context, checkRunId int64, retryMinutes int32) error
+	ClaimOutputBlobDeletions(ctx context.Context, maxRows int32) ([]string, error)
+	DeleteOutputBlobDeletions(ctx context.Context, refs []string) error
+	EnqueueUnreferencedOutputBlobDeletions(ctx context.Context, repoId int64, refs []string, hashes [][]byte) error
+	CompleteTimedOutShardedRuns(ctx context.Context, timeoutMinutes int32, maxRows int32) ([]queries.CompleteTimedOutShardedRunsRow, error)
+
+	// Command output methods
+	FindCommandOutputHashes(ctx context.Context, repoId int64, hashes [][]byte) ([][]byte, error)
+	FindCommandOutputId(ctx context.Context, repoId int64, hash []byte) (int64, error)
+	InsertCommandOutput(ctx context.Context, params queries.InsertCommandOutputParams) (int64, error)
+	FindCommandOutputsByIds(ctx context.Context, ids []int64) ([]queries.FindCommandOutputsByIdsRow, error)
+	DeleteUnreferencedCommandOutputs(ctx context.Context, graceMinutes int32, maxRows int32) (int64, error)
+	ClaimLegacyOutputProjects(ctx context.Context, withRefs bool, maxRows int32) ([]queries.ClaimLegacyOutputProjectsRow, error)
+	SetDriftAnalysisProjectOutputs(ctx context.Context, params queries.SetDriftAnalysisProjectOutputsParams) error
+
+	// Plan summary backfill methods
+	ClaimOutdatedSummaryProjects(ctx context.Context, parserVersion int16, leaseMinutes int32, maxRows int32) ([]queries.DriftAnalysisProject, error)
+	SetDriftAnalysisProjectSummary(ctx context.Context, params queries.SetDriftAnalysisProjectSummaryParams) error
+
+	// Project catalog methods
+	SyncDriftProjectsFromRun(ctx context.Context, runId uuid.UUID) error
+	ListDriftProjectsByRepositoryId(ctx context.Context, params queries.ListDriftProjectsByRepositoryIdParams) ([]queries.DriftProject, error)
+	GetProjectHistory(ctx context.Context, repoId int64, dir string, projectType *string, maxResults int32) ([]queries.GetProjectHistoryRow, error)
+	GetProjectDriftPeriods(ctx context.Context, repoId int64, dir string, projectType *string) ([]queries.GetProjectDriftPeriodsRow, error)
+
+	// Drift issue methods
+	ListOpenDriftIssues(ctx context.Context, repoId int64) ([]queries.DriftIssue, error)
+	ClaimDriftIssue(ctx context.Context, repoId int64, dir string, runId uuid.UUID) (queries.DriftIssue, error)
+	SetDriftIssueNumber(ctx context.Context, id int64, number int32) error
+	AdvanceDriftIssue(ctx context.Context, id int64, runId uuid.UUID) (bool, error)
+	CloseDriftIssue(ctx context.Context, id int64) error
+	DeleteDriftIssue(ctx context.Context, id int64) error
 
 	WithTx(ctx context.Context, txFunc func(context.Context) error) error
 }
@@ -73,10 +134,66 @@ func (r *DriftAnalysisRepo) UpdateDriftAnalysisRunProgress(ctx context.Context,
 	return r.db.Queries(ctx).UpdateDriftAnalysisRunProgress(ctx, params)
 }
 
+// TouchRunningDriftAnalysisRun reports false when the run is no longer RUNNING.
+func (r *DriftAnalysisRepo) TouchRunningDriftAnalysisRun(ctx context.Context, runId uuid.UUID) (bool, error) {
+	n, err := r.db.Queries(ctx).TouchRunningDriftAnalysisRun(ctx, runId)
+	return n > 0, err
+}
+
+func (r *DriftAnalysisRepo) DeleteRunningDriftAnalysisRun(ctx context.Context, runId uuid.UUID) error {
+	return r.db.Queries(ctx).DeleteRunningDriftAnalysisRun(ctx, runId)
+}
+
 func (r *DriftAnalysisRepo) MarkDriftAnalysisRunCompleted(ctx context.Context, params queries.MarkDriftAnalysisRunCompletedParams) error {
 	return r.db.Queries(ctx).MarkDriftAnalysisRunCompleted(ctx, params)
 }
 
+func (r *DriftAnalysisRepo) CompleteDriftAnalysisRunFromProjects(ctx context.Context, params queries.CompleteDriftAnalysisRunFromProjectsParams) error {
+	return r.db.Queries(ctx).CompleteDriftAnalysisRunFromProjects(ctx, params)
+}
+
+// SetDriftAnalysisRunExpectedShards is the first write of a shard's transaction on purpose: it
+// takes the run's row lock, so shards finishing at the same time serialize and the last one to
+// commit sees every other shard's row when it decides whether to complete the run. It reports
+// false when the run is no longer RUNNING.
+func (r *DriftAnalysisRepo) SetDriftAnalysisRunExpectedShards(ctx context.Context, runId uuid.UUID, expectedShards int32) (bool, error) {
+	n, err := r.db.Queries(ctx).SetDriftAnalysisRunExpectedShards(ctx, queries.SetDriftAnalysisRunExpectedShardsParams{
+		ExpectedShards: &expectedShards,
+		Uuid:           runId,
+	})
+	return n > 0, err
+}
+
+// SetDriftAnalysisRunCommitSha reports whether the commit was recorded, false when the run already
+// had one.
+func (r *DriftAnalysisRepo) SetDriftAnalysisRunCommitSha(ctx context.Context, runId uuid.UUID, commitSha string) (bool, error) {
+	n, err := r.db.Queries(ctx).SetDriftAnalysisRunCommitSha(ctx, queries.SetDriftAnalysisRunCommitShaParams{
+		CommitSha: &commitSha,
+		Uuid:      runId,
+	})
+	return n > 0, err
+}
+
+// SetDriftAnalysisRunCheckRun reports whether the check run was recorded, false when the run
+// already had one.
+func (r *DriftAnalysisRepo) SetDriftAnalysisRunCheckRun(ctx context.Context, runId uuid.UUID, checkRunId int64) (bool, error) {
+	n, err := r.db.Queries(ctx).SetDriftAnalysisRunCheckRun(ctx, queries.SetDriftAnalysisRunCheckRunParams{
+		CheckRunID: &checkRunId,
+		Uuid:       runId,
+	})
+	return n > 0, err
+}
+
+func (r *DriftAnalysisRepo) RecordDriftAnalysisRunShard(ctx context.Context, params queries.RecordDriftAnalysisRunShardParams) error {
+	return r.db.Queries(ctx).RecordDriftAnalysisRunShard(ctx, params)
+}
+
+// RefreshShardedDriftAnalysisRun returns the run's status after the refresh, or pgx.ErrNoRows when
+// the run was already COMPLETED.
+func (r *DriftAnalysisRepo) RefreshShardedDriftAnalysisRun(ctx context.Context, runId uuid.UUID) (string, error) {
+	return r.db.Queries(ctx).RefreshShardedDriftAnalysisRun(ctx, runId)
+}
+
 func (r *DriftAnalysisRepo) FindDriftAnalysisRunsByRepositoryID(ctx context.Context, repoId int64, page int) ([]queries.DriftAnalysisRun, error) {
 	params := queries.FindDriftAnalysisRunsByRepositoryIdParams{
 		RepositoryID: repoId,
@@ -86,6 +203,16 @@ func (r *DriftAnalysisRepo) FindDriftAnalysisRunsByRepositoryID(ctx context.Cont
 	return r.db.Queries(ctx).FindDriftAnalysisRunsByRepositoryId(ctx, params)
 }
 
+func (r *DriftAnalysisRepo) FindDriftAnalysisRunsByRepositoryIDAndDirs(ctx context.Context, repoId int64, dirs []string, page int) ([]queries.DriftAnalysisRun, error) {
+	params := queries.FindDriftAnalysisRunsByRepositoryIdAndDirsParams{
+		RepositoryID: repoId,
+		Dirs:         dirs,
+		Queryoffset:  int32(page * 25),
+		Maxresults:   25,
+	}
+	return r.db.Queries(ctx).FindDriftAnalysisRunsByRepositoryIdAndDirs(ctx, params)
+}
+
 func (r *DriftAnalysisRepo) FindDriftAnalysisRunByUUID(ctx context.Context, uuid uuid.UUID) (queries.DriftAnalysisRun, error) {
 	return r.db.Queries(ctx).FindDriftAnalysisRunByUUID(ctx, uuid)
 }
@@ -107,10 +234,26 @@ func (r *DriftAnalysisRepo) CountDriftAnalysisProjectsByRunId(ctx context.Contex
 	return r.db.Queries(ctx).CountDriftAnalysisProjectsByRunId(ctx, runId)
 }
 
+func (r *DriftAnalysisRepo) CountDriftAnalysisRunShards(ctx context.Context, runId uuid.UUID) (int64, error) {
+	return r.db.Queries(ctx).CountDriftAnalysisRunShards(ctx, runId)
+}
+
+func (r *DriftAnalysisRepo) GetPreviousRunDriftFingerprints(ctx context.Context, runId uuid.UUID) ([]queries.GetPreviousRunDriftFingerprintsRow, error) {
+	return r.db.Queries(ctx).GetPreviousRunDriftFingerprints(ctx, runId)
+}
+
 // DeleteDriftAnalysisRunsByRepositoryId removes every run for a repository. Project rows go
-// with them via the ON DELETE CASCADE on drift_analysis_project.
+// with them via the ON DELETE CASCADE on drift_analysis_project, the catalog projects are pruned,
+// and the outputs they leave unreferenced are deleted right away, queuing their blobs.
 func (r *DriftAnalysisRepo) DeleteDriftAnalysisRunsByRepositoryId(ctx context.Context, repoId int64) error {
-	return r.db.Queries(ctx).DeleteDriftAnalysisRunsByRepositoryId(ctx, repoId)
+	q := r.db.Queries(ctx)
+	if err := q.DeleteDriftAnalysisRunsByRepositoryId(ctx, repoId); err != nil {
+		return err
+	}
+	if err := q.DeleteDriftProjectsWithoutRun(ctx, repoId); err != nil {
+		return err
+	}
+	return q.DeleteUnreferencedCommandOutputsByRepositoryId(ctx, repoId)
 }
 
 func (r *DriftAnalysisRepo) GetRepositoryRunStats(ctx context.Context, repoId int64) (queries.GetRepositoryRunStatsRow, error) {
@@ -121,6 +264,33 @@ func (r *DriftAnalysisRepo) GetLatestRunForRepository(ctx context.Context, repoI
 	return r.db.Queries(ctx).GetLatestRunForRepository(ctx, repoId)
 }
 
+func (r *DriftAnalysisRepo) GetLatestFullRunForRepository(ctx context.Context, repoId int64) (queries.DriftAnalysisRun, error) {
+	return r.db.Queries(ctx).GetLatestFullRunForRepository(ctx, repoId)
+}
+
+func (r *DriftAnalysisRepo) GetRunErrorClassBreakdown(ctx context.Context, runId uuid.UUID) ([]queries.GetRunErrorClassBreakdownRow, error) {
+	return r.db.Queries(ctx).GetRunErrorClassBreakdown(ctx, runId)
+}
+
+func (r *DriftAnalysisRepo) GetRunProjectStatuses(ctx context.Context, runId uuid.UUID) ([]queries.GetRunProjectStatusesRow, error) {
+	return r.db.Queries(ctx).GetRunProjectStatuses(ctx, runId)
+}
+
+func (r *DriftAnalysisRepo) RecordDriftAnalysisRunCoverageChanges(ctx context.Context, runId uuid.UUID) error {
+	return r.db.Queries(ctx).RecordDriftAnalysisRunCoverageChanges(ctx, runId)
+}
+
+func (r *DriftAnalysisRepo) GetRunCoverageChanges(ctx context.Context, runId uuid.UUID) ([]queries.GetRunCoverageChangesRow, error) {
+	return r.db.Queries(ctx).GetRunCoverageChanges(ctx, runId)
+}
+
+func (r *DriftAnalysisRepo) GetUnscannedProjects(ctx context.Context, repoId int64, runId uuid.UUID) ([]queries.GetUnscannedProjectsRow, error) {
+	return r.db.Queries(ctx).GetUnscannedProjects(ctx, queries.GetUnscannedProjectsParams{
+		RepositoryID: repoId,
+		RunID:        runId,
+	})
+}
+
 func (r *DriftAnalysisRepo) WithTx(ctx context.Context, txFunc func(context.Context) error) error {
 	return r.db.WithTx(ctx, txFunc)
 }
@@ -132,6 +302,14 @@ func (r *DriftAnalysisRepo) GetDriftRateOverTime(ctx context.Context, repoId int
 	})
 }
 
+func (r *DriftAnalysisRepo) GetDirsDriftOverTime(ctx context.Context, repoId int64, daysBack int32, dirs []string) ([]queries.GetDirsDriftOverTimeRow, error) {
+	return r.db.Queries(ctx).GetDirsDriftOverTime(ctx, queries.GetDirsDriftOverTimeParams{
+		RepositoryID: repoId,
+		DaysBack:     daysBack,
+		Dirs:         dirs,
+	})
+}
+
 func (r *DriftAnalysisRepo) GetMostFrequentlyDriftedProjects(ctx context.Context, repoId int64, daysBack int32, maxResults int32) ([]queries.GetMostFrequentlyDriftedProjectsRow, error) {
 	return r.db.Queries(ctx).GetMostFrequentlyDriftedProjects(ctx, queries.GetMostFrequentlyDriftedProjectsParams{
 		RepositoryID: repoId,
@@ -140,6 +318,68 @@ func (r *DriftAnalysisRepo) GetMostFrequentlyDriftedProjects(ctx context.Context
 	})
 }
 
+func (r *DriftAnalysisRepo) GetMostFrequentlyDriftedResources(ctx context.Context, repoId int64, daysBack int32, maxResults int32) ([]queries.GetMostFrequentlyDriftedResourcesRow, error) {
+	return r.db.Queries(ctx).GetMostFrequentlyDriftedResources(ctx, queries.GetMostFrequentlyDriftedResourcesParams{
+		RepositoryID: repoId,
+		DaysBack:     daysBack,
+		MaxResults:   maxResults,
+	})
+}
+
+func (r *DriftAnalysisRepo) GetMostFrequentlyDriftedResourceTypes(ctx context.Context, params queries.GetMostFrequentlyDriftedResourceTypesParams) ([]queries.GetMostFrequentlyDriftedResourceTypesRow, error) {
+	return r.db.Queries(ctx).GetMostFrequentlyDriftedResourceTypes(ctx, params)
+}
+
+func (r *DriftAnalysisRepo) GetMostFrequentlyDriftedProviders(ctx context.Context, params queries.GetMostFrequentlyDriftedProvidersParams) ([]queries.GetMostFrequentlyDriftedProvidersRow, error) {
+	return r.db.Queries(ctx).GetMostFrequentlyDriftedProviders(ctx, params)
+}
+
+func (r *DriftAnalysisRepo) GetSlowestProjects(ctx context.Context, repoId int64, daysBack int32, maxResults int32) ([]queries.GetSlowestProjectsRow, error) {
+	return r.db.Queries(ctx).GetSlowestProjects(ctx, queries.GetSlowestProjectsParams{
+		RepositoryID: repoId,
+		DaysBack:     daysBack,
+		MaxResults:   maxResults,
+	})
+}
+
+func (r *DriftAnalysisRepo) GetProjectDurationRegressions(ctx context.Context, repoId int64, daysBack int32, minIncreasePercent int32, maxResults int32) ([]queries.GetProjectDurationRegressionsRow, error) {
+	return r.db.Queries(ctx).GetProjectDurationRegressions(ctx, queries.GetProjectDurationRegressionsParams{
+		DaysBack:           daysBack,
+		RepositoryID:       repoId,
+		MinIncreasePercent: minIncreasePercent,
+		MaxResults:         maxResults,
+	})
+}
+
+func (r *DriftAnalysisRepo) GetErrorClassBreakdown(ctx context.Context, repoId int64, daysBack int32) ([]queries.GetErrorClassBreakdownRow, error) {
+	return r.db.Queries(ctx).GetErrorClassBreakdown(ctx, queries.GetErrorClassBreakdownParams{
+		RepositoryID: repoId,
+		DaysBack:     daysBack,
+	})
+}
+
+func (r *DriftAnalysisRepo) GetWarningCountsPerRun(ctx context.Context, repoId int64, daysBack int32) ([]queries.GetWarningCountsPerRunRow, error) {
+	return r.db.Queries(ctx).GetWarningCountsPerRun(ctx, queries.GetWarningCountsPerRunParams{
+		RepositoryID: repoId,
+		DaysBack:     daysBack,
+	})
+}
+
+func (r *DriftAnalysisRepo) GetRepeatedWarnings(ctx context.Context, repoId int64, daysBack int32, maxResults int32) ([]queries.GetRepeatedWarningsRow, error) {
+	return r.db.Queries(ctx).GetRepeatedWarnings(ctx, queries.GetRepeatedWarningsParams{
+		RepositoryID: repoId,
+		DaysBack:     daysBack,
+		MaxResults:   maxResults,
+	})
+}
+
+func (r *DriftAnalysisRepo) GetOrganizationVersionInventory(ctx context.Context, orgId int64, daysBack int32) ([]queries.GetOrganizationVersionInventoryRow, error) {
+	return r.db.Queries(ctx).GetOrganizationVersionInventory(ctx, queries.GetOrganizationVersionInventoryParams{
+		OrganizationID: orgId,
+		DaysBack:       daysBack,
+	})
+}
+
 func (r *DriftAnalysisRepo) GetDriftFreeStreak(ctx context.Context, repoId int64) (queries.GetDriftFreeStreakRow, error) {
 	return r.db.Queries(ctx).GetDriftFreeStreak(ctx, repoId)
 }
@@ -151,18 +391,197 @@ func (r *DriftAnalysisRepo) GetMeanTimeToResolution(ctx context.Context, repoId
 	})
 }
 
+// DeleteOldestRunsExceedingLimit applies retention to a repository's runs, then prunes the catalog
+// projects only the deleted runs included.
 func (r *DriftAnalysisRepo) DeleteOldestRunsExceedingLimit(ctx context.Context, repoId int64, maxRunsToKeep int32) error {
-	return r.db.Queries(ctx).DeleteOldestRunsExceedingLimit(ctx, queries.DeleteOldestRunsExceedingLimitParams{
-		RepositoryID:  repoId,
-		MaxRunsToKeep: maxRunsToKeep,
+	return r.WithTx(ctx, func(ctx context.Context) error {
+		q := r.db.Queries(ctx)
+		if err := q.DeleteOldestRunsExceedingLimit(ctx, queries.DeleteOldestRunsExceedingLimitParams{
+			RepositoryID:  repoId,
+			MaxRunsToKeep: maxRunsToKeep,
+		}); err != nil {
+			return err
+		}
+		return q.DeleteDriftProjectsWithoutRun(ctx, repoId)
 	})
 }
 
-// DeleteStaleRunningRuns removes runs left RUNNING by a crashed CLI and returns how many it
-// deleted. Safe to call concurrently from multiple API instances.
+// DeleteStaleRunningRuns removes runs left RUNNING by a crashed CLI, queueing their check runs to
+// be timed out, and returns how many it deleted. Safe to call concurrently from multiple API
+// instances.
 func (r *DriftAnalysisRepo) DeleteStaleRunningRuns(ctx context.Context, staleMinutes int32, maxRows int32) (int64, error) {
 	return r.db.Queries(ctx).DeleteStaleRunningRuns(ctx, queries.DeleteStaleRunningRunsParams{
 		StaleMinutes: staleMinutes,
 		MaxRows:      maxRows,
 	})
 }
+
+// ClaimCheckRunTimeouts returns up to maxRows queued check runs due for a timeout attempt, locking
+// them until the surrounding transaction ends. Must run inside WithTx.
+func (r *DriftAnalysisRepo) ClaimCheckRunTimeouts(ctx context.Context, maxRows int32) ([]queries.CheckRunTimeout, error) {
+	return r.db.Queries(ctx).ClaimCheckRunTimeouts(ctx, maxRows)
+}
+
+func (r *DriftAnalysisRepo) DeleteCheckRunTimeout(ctx context.Context, checkRunId int64) error {
+	return r.db.Queries(ctx).DeleteCheckRunTimeout(ctx, checkRunId)
+}
+
+// RetryCheckRunTimeout counts a failed attempt of a queued check run and schedules the next one.
+func (r *DriftAnalysisRepo) RetryCheckRunTimeout(ctx context.Context, checkRunId int64, retryMinutes int32) error {
+	return r.db.Queries(ctx).RetryCheckRunTimeout(ctx, queries.RetryCheckRunTimeoutParams{
+		RetryMinutes: retryMinutes,
+		CheckRunID:   checkRunId,
+	})
+}
+
+// CompleteTimedOutShardedRuns finalizes sharded runs that stopped hearing from their shards and
+// returns the runs it completed. Safe to call concurrently from multiple API instances.
+func (r *DriftAnalysisRepo) CompleteTimedOutShardedRuns(ctx context.Context, timeoutMinutes int32, maxRows int32) ([]queries.CompleteTimedOutShardedRunsRow, error) {
+	return r.db.Queries(ctx).CompleteTimedOutShardedRuns(ctx, queries.CompleteTimedOutShardedRunsParams{
+		TimeoutMinutes: timeoutMinutes,
+		MaxRows:        maxRows,
+	})
+}
+
+// ClaimOutputBlobDeletions returns blob references whose project rows were deleted, locking them
+// until the surrounding transaction ends. Must run inside WithTx.
+func (r *DriftAnalysisRepo) ClaimOutputBlobDeletions(ctx context.Context, maxRows int32) ([]string, error) {
+	return r.db.Queries(ctx).ClaimOutputBlobDeletions(ctx, maxRows)
+}
+
+func (r *DriftAnalysisRepo) DeleteOutputBlobDeletions(ctx context.Context, refs []string) error {
+	return r.db.Queries(ctx).DeleteOutputBlobDeletions(ctx, refs)
+}
+
+// EnqueueUnreferencedOutputBlobDeletions queues the blobs at refs, written for the outputs with the
+// matching hashes, unless the repository holds one of those outputs by now.
+func (r *DriftAnalysisRepo) EnqueueUnreferencedOutputBlobDeletions(ctx context.Context, repoId int64, refs []string, hashes [][]byte) error {
+	return r.db.Queries(ctx).EnqueueUnreferencedOutputBlobDeletions(ctx, queries.EnqueueUnreferencedOutputBlobDeletionsParams{
+		Refs:         refs,
+		Hashes:       hashes,
+		RepositoryID: repoId,
+	})
+}
+
+func (r *DriftAnalysisRepo) FindCommandOutputHashes(ctx context.Context, repoId int64, hashes [][]byte) ([][]byte, error) {
+	return r.db.Queries(ctx).FindCommandOutputHashes(ctx, queries.FindCommandOutputHashesParams{
+		RepositoryID: repoId,
+		Hashes:       hashes,
+	})
+}
+
+// FindCommandOutputId returns the id of a repository's output with the given content hash, locking
+// it until the surrounding transaction ends. Must run inside WithTx.
+func (r *DriftAnalysisRepo) FindCommandOutputId(ctx context.Context, repoId int64, hash []byte) (int64, error) {
+	return r.db.Queries(ctx).FindCommandOutputId(ctx, queries.FindCommandOutputIdParams{
+		RepositoryID: repoId,
+		Hash:         hash,
+	})
+}
+
+func (r *DriftAnalysisRepo) InsertCommandOutput(ctx context.Context, params queries.InsertCommandOutputParams) (int64, error) {
+	return r.db.Queries(ctx).InsertCommandOutput(ctx, params)
+}
+
+func (r *DriftAnalysisRepo) FindCommandOutputsByIds(ctx context.Context, ids []int64) ([]queries.FindCommandOutputsByIdsRow, error) {
+	return r.db.Queries(ctx).FindCommandOutputsByIds(ctx, ids)
+}
+
+// DeleteUnreferencedCommandOutputs deletes outputs that have had no references for graceMinutes.
+// Safe to call concurrently from multiple API instances.
+func (r *DriftAnalysisRepo) DeleteUnreferencedCommandOutputs(ctx context.Context, graceMinutes int32, maxRows int32) (int64, error) {
+	return r.db.Queries(ctx).DeleteUnreferencedCommandOutputs(ctx, queries.DeleteUnreferencedCommandOutputsParams{
+		GraceMinutes: graceMinutes,
+		MaxRows:      maxRows,
+	})
+}
+
+// ClaimLegacyOutputProjects returns project rows whose outputs predate command_output. Inside WithTx
+// they stay locked until the transaction ends.
+func (r *DriftAnalysisRepo) ClaimLegacyOutputProjects(ctx context.Context, withRefs bool, maxRows int32) ([]queries.ClaimLegacyOutputProjectsRow, error) {
+	return r.db.Queries(ctx).ClaimLegacyOutputProjects(ctx, queries.ClaimLegacyOutputProjectsParams{
+		WithRefs: withRefs,
+		MaxRows:  maxRows,
+	})
+}
+
+func (r *DriftAnalysisRepo) SetDriftAnalysisProjectOutputs(ctx context.Context, params queries.SetDriftAnalysisProjectOutputsParams) error {
+	return r.db.Queries(ctx).SetDriftAnalysisProjectOutputs(ctx, params)
+}
+
+// ClaimOutdatedSummaryProjects returns project rows whose counts were parsed by a parser older than
+// parserVersion, leasing them for leaseMinutes or until SetDriftAnalysisProjectSummary writes them.
+func (r *DriftAnalysisRepo) ClaimOutdatedSummaryProjects(ctx context.Context, parserVersion int16, leaseMinutes int32, maxRows int32) ([]queries.DriftAnalysisProject, error) {
+	return r.db.Queries(ctx).ClaimOutdatedSummaryProjects(ctx, queries.ClaimOutdatedSummaryProjectsParams{
+		ParserVersion: parserVersion,
+		LeaseMinutes:  leaseMinutes,
+		MaxRows:       maxRows,
+	})
+}
+
+func (r *DriftAnalysisRepo) SetDriftAnalysisProjectSummary(ctx context.Context, params queries.SetDriftAnalysisProjectSummaryParams) error {
+	return r.db.Queries(ctx).SetDriftAnalysisProjectSummary(ctx, params)
+}
+
+func (r *DriftAnalysisRepo) SyncDriftProjectsFromRun(ctx context.Context, runId uuid.UUID) error {
+	return r.db.Queries(ctx).SyncDriftProjectsFromRun(ctx, runId)
+}
+
+func (r *DriftAnalysisRepo) ListDriftProjectsByRepositoryId(ctx context.Context, params queries.ListDriftProjectsByRepositoryIdParams) ([]queries.DriftProject, error) {
+	return r.db.Queries(ctx).ListDriftProjectsByRepositoryId(ctx, params)
+}
+
+func (r *DriftAnalysisRepo) GetProjectHistory(ctx context.Context, repoId int64, dir string, projectType *string, maxResults int32) ([]queries.GetProjectHistoryRow, error) {
+	return r.db.Queries(ctx).GetProjectHistory(ctx, queries.GetProjectHistoryParams{
+		RepositoryID: repoId,
+		Dir:          dir,
+		Type:         projectType,
+		MaxResults:   maxResults,
+	})
+}
+
+func (r *DriftAnalysisRepo) GetProjectDriftPeriods(ctx context.Context, repoId int64, dir string, projectType *string) ([]queries.GetProjectDriftPeriodsRow, error) {
+	return r.db.Queries(ctx).GetProjectDriftPeriods(ctx, queries.GetProjectDriftPeriodsParams{
+		RepositoryID: repoId,
+		Dir:          dir,
+		Type:         projectType,
+	})
+}
+
+func (r *DriftAnalysisRepo) ListOpenDriftIssues(ctx context.Context, repoId int64) ([]queries.DriftIssue, error) {
+	return r.db.Queries(ctx).ListOpenDriftIssues(ctx, repoId)
+}
+
+// ClaimDriftIssue returns pgx.ErrNoRows when the dir already has an open issue.
+func (r *DriftAnalysisRepo) ClaimDriftIssue(ctx context.Context, repoId int64, dir string, runId uuid.UUID) (queries.DriftIssue, error) {
+	return r.db.Queries(ctx).ClaimDriftIssue(ctx, queries.ClaimDriftIssueParams{
+		RepositoryID: repoId,
+		Dir:          dir,
+		RunID:        runId,
+	})
+}
+
+func (r *DriftAnalysisRepo) SetDriftIssueNumber(ctx context.Context, id int64, number int32) error {
+	return r.db.Queries(ctx).SetDriftIssueNumber(ctx, queries.SetDriftIssueNumberParams{
+		IssueNumber: &number,
+		ID:          id,
+	})
+}
+
+// AdvanceDriftIssue reports whether the issue moved on to the run, false when it was already
+// updated from it.
+func (r *DriftAnalysisRepo) AdvanceDriftIssue(ctx context.Context, id int64, runId uuid.UUID) (bool, error) {
+	n, err := r.db.Queries(ctx).AdvanceDriftIssue(ctx, queries.AdvanceDriftIssueParams{
+		RunID: runId,
+		ID:    id,
+	})
+	return n > 0, err
+}
+
+func (r *DriftAnalysisRepo) CloseDriftIssue(ctx context.Context, id int64) error {
+	return r.db.Queries(ctx).CloseDriftIssue(ctx, id)
+}
+
+func (r *DriftAnalysisRepo) DeleteDriftIssue(ctx context.Context, id int64) error {
+	return r.db.Queries(ctx).DeleteDriftIssue(ctx, id)
+}
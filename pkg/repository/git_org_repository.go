@@ -16,7 +16,12 @@ type GitOrgRepository interface {
 	IsUserMemberOfOrg(ctx context.Context, orgId, userId int64) (bool, error)
 	FindGitOrganizationByRepoId(ctx context.Context, repoId int64) (queries.GitOrganization, error)
 	IsUserMemberOfOrganizationByRepoId(ctx context.Context, repoId, userId int64) (bool, error)
+	IsUserAdminOfOrganizationByRepoId(ctx context.Context, repoId, userId int64) (bool, error)
+	IsUserAdminOfOrg(ctx context.Context, orgId, userId int64) (bool, error)
 	FindAllUserOrganizationIds(ctx context.Context, userId int64) ([]int64, error)
+	FindOrganizationRedactionRules(ctx context.Context, orgId int64) ([]queries.OrganizationRedactionRule, error)
+	CreateOrganizationRedactionRule(ctx context.Context, arg queries.CreateOrganizationRedactionRuleParams) (queries.OrganizationRedactionRule, error)
+	DeleteOrganizationRedactionRule(ctx context.Context, orgId, ruleId int64) (bool, error)
 }
 
 type GitOrgRepo struct {
@@ -71,6 +76,50 @@ func (g GitOrgRepo) IsUserMemberOfOrganizationByRepoId(ctx context.Context, repo
 	return g.db.Queries(ctx).IsUserMemberOfOrganizationByRepoId(ctx, params)
 }
 
+func (g GitOrgRepo) IsUserAdminOfOrganizationByRepoId(ctx context.Context, repoId, userId int64) (bool, error) {
+	params := queries.IsUserAdminOfOrganizationByRepoIdParams{RepoID: repoId, UserID: userId}
+	return g.db.Queries(ctx).IsUserAdminOfOrganizationByRepoId(ctx, params)
+}
+
+func (g GitOrgRepo) IsUserAdminOfOrg(ctx context.Context, orgId, userId int64) (bool, error) {
+	params := queries.IsUserAdminOfOrganizationParams{GitOrganizationID: orgId, UserID: userId}
+	return g.db.Queries(ctx).IsUserAdminOfOrganization(ctx, params)
+}
+
 func (g GitOrgRepo) FindAllUserOrganizationIds(ctx context.Context, userId int64) ([]int64, error) {
 	return g.db.Queries(ctx).FindAllUserOrganizationIds(ctx, userId)
 }
+
+func (g GitOrgRepo) FindOrganizationRedactionRules(ctx context.Context, orgId int64) ([]queries.OrganizationRedactionRule, error) {
+	rules, err := g.db.Queries(ctx).FindOrganizationRedactionRules(ctx, orgId)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		return []queries.OrganizationRedactionRule{}, nil
+	}
+	return rules, nil
+}
+
+// CreateOrganizationRedactionRule returns pgx.ErrNoRows when the organization already has
+// arg.MaxRules rules. The organization row is locked while the rules are counted, so concurrent
+// requests cannot both take the last slot.
+func (g GitOrgRepo) CreateOrganizationRedactionRule(ctx context.Context, arg queries.CreateOrganizationRedactionRuleParams) (queries.OrganizationRedactionRule, error) {
+	var rule queries.OrganizationRedactionRule
+	err := g.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := g.db.Queries(ctx).LockGitOrganization(ctx, arg.OrganizationID); err != nil {
+			return err
+		}
+		var err error
+		rule, err = g.db.Queries(ctx).CreateOrganizationRedactionRule(ctx, arg)
+		return err
+	})
+	return rule, err
+}
+
+// DeleteOrganizationRedactionRule reports whether the organization had a rule with that id.
+func (g GitOrgRepo) DeleteOrganizationRedactionRule(ctx context.Context, orgId, ruleId int64) (bool, error) {
+	params := queries.DeleteOrganizationRedactionRuleParams{ID: ruleId, OrganizationID: orgId}
+	deleted, err := g.db.Queries(ctx).DeleteOrganizationRedactionRule(ctx, params)
+	return deleted > 0, err
+}
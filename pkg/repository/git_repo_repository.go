@@ -2,8 +2,11 @@ package repository
 
 import (
 	"context"
+	"time"
+
 	"driftive.cloud/api/pkg/db"
 	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/google/uuid"
 )
 
 type GitRepositoryRepository interface {
@@ -13,7 +16,21 @@ type GitRepositoryRepository interface {
 	FindGitRepositoryByOrgIdAndName(ctx context.Context, orgId int64, repoName string) (queries.GitRepository, error)
 	UpdateRepositoryToken(ctx context.Context, params queries.UpdateRepositoryTokenParams) (*string, error)
 	ClearRepositoryAnalysisToken(ctx context.Context, id int64) error
+	UpdateRepositoryDriftIssues(ctx context.Context, id int64, enabled bool) error
+	UpdateRepositoryDispatchSettings(ctx context.Context, params queries.UpdateRepositoryDispatchSettingsParams) error
+	UpdateRepositoryScanSchedule(ctx context.Context, params queries.UpdateRepositoryScanScheduleParams) error
 	FindGitRepositoryByToken(ctx context.Context, token string) (queries.GitRepository, error)
+	FindRepositoryCodeowners(ctx context.Context, repoId int64) (queries.GitRepositoryCodeowner, error)
+	UpsertRepositoryCodeowners(ctx context.Context, repoId int64, path string, content string) error
+	DeleteRepositoryCodeowners(ctx context.Context, repoId int64) error
+	CreateScanDispatch(ctx context.Context, params queries.CreateScanDispatchParams) (queries.ScanDispatch, error)
+	DeleteScanDispatch(ctx context.Context, id uuid.UUID) error
+	ListScanDispatches(ctx context.Context, repoId int64, limit int32) ([]queries.ListScanDispatchesRow, error)
+	ListScheduledRepositories(ctx context.Context) ([]queries.ListScheduledRepositoriesRow, error)
+	GetLastCompletedScanAt(ctx context.Context, repoId int64) (time.Time, error)
+	ListScanOverdueAlerts(ctx context.Context, repoId int64, limit int32) ([]queries.ScanOverdueAlert, error)
+	RaiseScanOverdueAlert(ctx context.Context, params queries.RaiseScanOverdueAlertParams) (bool, error)
+	ResolveScanOverdueAlert(ctx context.Context, repoId int64) error
 }
 
 type GitRepoRepo struct {
@@ -48,6 +65,78 @@ func (r *GitRepoRepo) ClearRepositoryAnalysisToken(ctx context.Context, id int64
 	return r.db.Queries(ctx).ClearRepositoryAnalysisToken(ctx, id)
 }
 
+func (r *GitRepoRepo) UpdateRepositoryDriftIssues(ctx context.Context, id int64, enabled bool) error {
+	return r.db.Queries(ctx).UpdateRepositoryDriftIssues(ctx, queries.UpdateRepositoryDriftIssuesParams{
+		Enabled: enabled,
+		ID:      id,
+	})
+}
+
+func (r *GitRepoRepo) UpdateRepositoryDispatchSettings(ctx context.Context, params queries.UpdateRepositoryDispatchSettingsParams) error {
+	return r.db.Queries(ctx).UpdateRepositoryDispatchSettings(ctx, params)
+}
+
+func (r *GitRepoRepo) UpdateRepositoryScanSchedule(ctx context.Context, params queries.UpdateRepositoryScanScheduleParams) error {
+	return r.db.Queries(ctx).UpdateRepositoryScanSchedule(ctx, params)
+}
+
 func (r *GitRepoRepo) FindGitRepositoryByToken(ctx context.Context, token string) (queries.GitRepository, error) {
 	return r.db.Queries(ctx).FindGitRepositoryByToken(ctx, &token)
 }
+
+func (r *GitRepoRepo) FindRepositoryCodeowners(ctx context.Context, repoId int64) (queries.GitRepositoryCodeowner, error) {
+	return r.db.Queries(ctx).FindRepositoryCodeowners(ctx, repoId)
+}
+
+func (r *GitRepoRepo) UpsertRepositoryCodeowners(ctx context.Context, repoId int64, path string, content string) error {
+	return r.db.Queries(ctx).UpsertRepositoryCodeowners(ctx, queries.UpsertRepositoryCodeownersParams{
+		RepositoryID: repoId,
+		Path:         path,
+		Content:      content,
+	})
+}
+
+func (r *GitRepoRepo) DeleteRepositoryCodeowners(ctx context.Context, repoId int64) error {
+	return r.db.Queries(ctx).DeleteRepositoryCodeowners(ctx, repoId)
+}
+
+func (r *GitRepoRepo) CreateScanDispatch(ctx context.Context, params queries.CreateScanDispatchParams) (queries.ScanDispatch, error) {
+	return r.db.Queries(ctx).CreateScanDispatch(ctx, params)
+}
+
+func (r *GitRepoRepo) DeleteScanDispatch(ctx context.Context, id uuid.UUID) error {
+	return r.db.Queries(ctx).DeleteScanDispatch(ctx, id)
+}
+
+func (r *GitRepoRepo) ListScanDispatches(ctx context.Context, repoId int64, limit int32) ([]queries.ListScanDispatchesRow, error) {
+	return r.db.Queries(ctx).ListScanDispatches(ctx, queries.ListScanDispatchesParams{
+		RepositoryID: repoId,
+		MaxRows:      limit,
+	})
+}
+
+func (r *GitRepoRepo) ListScheduledRepositories(ctx context.Context) ([]queries.ListScheduledRepositoriesRow, error) {
+	return r.db.Queries(ctx).ListScheduledRepositories(ctx)
+}
+
+func (r *GitRepoRepo) GetLastCompletedScanAt(ctx context.Context, repoId int64) (time.Time, error) {
+	return r.db.Queries(ctx).GetLastCompletedScanAt(ctx, repoId)
+}
+
+func (r *GitRepoRepo) ListScanOverdueAlerts(ctx context.Context, repoId int64, limit int32) ([]queries.ScanOverdueAlert, error) {
+	return r.db.Queries(ctx).ListScanOverdueAlerts(ctx, queries.ListScanOverdueAlertsParams{
+		RepositoryID: repoId,
+		MaxRows:      limit,
+	})
+}
+
+// RaiseScanOverdueAlert returns whether an alert was raised, false when the repository already had
+// an open one.
+func (r *GitRepoRepo) RaiseScanOverdueAlert(ctx context.Context, params queries.RaiseScanOverdueAlertParams) (bool, error) {
+	rows, err := r.db.Queries(ctx).RaiseScanOverdueAlert(ctx, params)
+	return rows > 0, err
+}
+
+func (r *GitRepoRepo) ResolveScanOverdueAlert(ctx context.Context, repoId int64) error {
+	return r.db.Queries(ctx).ResolveScanOverdueAlert(ctx, repoId)
+}
@@ -8,6 +8,7 @@ package queries
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -18,18 +19,42 @@ var (
 )
 
 const upsertDriftAnalysisProject = `-- name: UpsertDriftAnalysisProject :batchexec
-INSERT INTO drift_analysis_project (drift_analysis_run_id, dir, type, drifted, succeeded, init_output, plan_output, skipped_due_to_pr, resources_added, resources_changed, resources_destroyed)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+INSERT INTO drift_analysis_project (drift_analysis_run_id, dir, type, drifted, succeeded, init_output, plan_output, skipped_due_to_pr, resources_added, resources_changed, resources_destroyed, init_output_size, plan_output_size, init_output_id, plan_output_id, redactions, resources_imported, resources_forgotten, resources_moved, outputs_changed, summary_parser_version, resource_changes, drift_fingerprint, init_duration_millis, plan_duration_millis, started_at, finished_at, retries, error_class, core_tool, core_version, provider_versions, warnings)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33)
 ON CONFLICT (drift_analysis_run_id, dir) DO UPDATE
-SET type                = EXCLUDED.type,
-    drifted             = EXCLUDED.drifted,
-    succeeded           = EXCLUDED.succeeded,
-    init_output         = EXCLUDED.init_output,
-    plan_output         = EXCLUDED.plan_output,
-    skipped_due_to_pr   = EXCLUDED.skipped_due_to_pr,
-    resources_added     = EXCLUDED.resources_added,
-    resources_changed   = EXCLUDED.resources_changed,
-    resources_destroyed = EXCLUDED.resources_destroyed
+SET type                   = EXCLUDED.type,
+    drifted                = EXCLUDED.drifted,
+    succeeded              = EXCLUDED.succeeded,
+    init_output            = EXCLUDED.init_output,
+    plan_output            = EXCLUDED.plan_output,
+    skipped_due_to_pr      = EXCLUDED.skipped_due_to_pr,
+    resources_added        = EXCLUDED.resources_added,
+    resources_changed      = EXCLUDED.resources_changed,
+    resources_destroyed    = EXCLUDED.resources_destroyed,
+    init_output_size       = EXCLUDED.init_output_size,
+    plan_output_size       = EXCLUDED.plan_output_size,
+    init_output_id         = EXCLUDED.init_output_id,
+    plan_output_id         = EXCLUDED.plan_output_id,
+    redactions             = EXCLUDED.redactions,
+    resources_imported     = EXCLUDED.resources_imported,
+    resources_forgotten    = EXCLUDED.resources_forgotten,
+    resources_moved        = EXCLUDED.resources_moved,
+    outputs_changed        = EXCLUDED.outputs_changed,
+    summary_parser_version = EXCLUDED.summary_parser_version,
+    resource_changes       = EXCLUDED.resource_changes,
+    drift_fingerprint      = EXCLUDED.drift_fingerprint,
+    init_duration_millis   = EXCLUDED.init_duration_millis,
+    plan_duration_millis   = EXCLUDED.plan_duration_millis,
+    started_at             = EXCLUDED.started_at,
+    finished_at            = EXCLUDED.finished_at,
+    retries                = EXCLUDED.retries,
+    error_class            = EXCLUDED.error_class,
+    core_tool              = EXCLUDED.core_tool,
+    core_version           = EXCLUDED.core_version,
+    provider_versions      = EXCLUDED.provider_versions,
+    warnings               = EXCLUDED.warnings,
+    init_output_ref        = NULL,
+    plan_output_ref        = NULL
 `
 
 type UpsertDriftAnalysisProjectBatchResults struct {
@@ -39,21 +64,45 @@ type UpsertDriftAnalysisProjectBatchResults struct {
 }
 
 type UpsertDriftAnalysisProjectParams struct {
-	DriftAnalysisRunID uuid.UUID
-	Dir                string
-	Type               string
-	Drifted            bool
-	Succeeded          bool
-	InitOutput         *string
-	PlanOutput         *string
-	SkippedDueToPr     bool
-	ResourcesAdded     *int32
-	ResourcesChanged   *int32
-	ResourcesDestroyed *int32
+	DriftAnalysisRunID   uuid.UUID
+	Dir                  string
+	Type                 string
+	Drifted              bool
+	Succeeded            bool
+	InitOutput           *string
+	PlanOutput           *string
+	SkippedDueToPr       bool
+	ResourcesAdded       *int32
+	ResourcesChanged     *int32
+	ResourcesDestroyed   *int32
+	InitOutputSize       *int64
+	PlanOutputSize       *int64
+	InitOutputID         *int64
+	PlanOutputID         *int64
+	Redactions           []byte
+	ResourcesImported    *int32
+	ResourcesForgotten   *int32
+	ResourcesMoved       *int32
+	OutputsChanged       *int32
+	SummaryParserVersion int16
+	ResourceChanges      []byte
+	DriftFingerprint     *string
+	InitDurationMillis   *int64
+	PlanDurationMillis   *int64
+	StartedAt            *time.Time
+	FinishedAt           *time.Time
+	Retries              *int32
+	ErrorClass           *string
+	CoreTool             *string
+	CoreVersion          *string
+	ProviderVersions     []byte
+	Warnings             []byte
 }
 
 // Shared write path for the progress ticks and the terminal ingest. Keyed on the unique index
 // (drift_analysis_run_id, dir), so re-sending a project updates it in place instead of duplicating.
+// Outputs are written to command_output first; a per-project blob reference left by an older row is
+// cleared, which queues its blob for deletion.
 func (q *Queries) UpsertDriftAnalysisProject(ctx context.Context, arg []UpsertDriftAnalysisProjectParams) *UpsertDriftAnalysisProjectBatchResults {
 	batch := &pgx.Batch{}
 	for _, a := range arg {
@@ -69,6 +118,28 @@ func (q *Queries) UpsertDriftAnalysisProject(ctx context.Context, arg []UpsertDr
 			a.ResourcesAdded,
 			a.ResourcesChanged,
 			a.ResourcesDestroyed,
+			a.InitOutputSize,
+			a.PlanOutputSize,
+			a.InitOutputID,
+			a.PlanOutputID,
+			a.Redactions,
+			a.ResourcesImported,
+			a.ResourcesForgotten,
+			a.ResourcesMoved,
+			a.OutputsChanged,
+			a.SummaryParserVersion,
+			a.ResourceChanges,
+			a.DriftFingerprint,
+			a.InitDurationMillis,
+			a.PlanDurationMillis,
+			a.StartedAt,
+			a.FinishedAt,
+			a.Retries,
+			a.ErrorClass,
+			a.CoreTool,
+			a.CoreVersion,
+			a.ProviderVersions,
+			a.Warnings,
 		}
 		batch.Queue(upsertDriftAnalysisProject, vals...)
 	}
@@ -0,0 +1,20 @@
+-- name: ClaimCheckRunTimeouts :many
+-- Rows stay locked until the caller's transaction ends, so concurrent reporters on other API
+-- instances skip them. Rows waiting for a retry are left alone until retry_at.
+SELECT *
+FROM check_run_timeout
+WHERE retry_at <= NOW()
+ORDER BY enqueued_at
+LIMIT @max_rows
+FOR UPDATE SKIP LOCKED;
+
+-- name: DeleteCheckRunTimeout :exec
+DELETE FROM check_run_timeout
+WHERE check_run_id = @check_run_id;
+
+-- name: RetryCheckRunTimeout :exec
+-- Backs off linearly: each failed attempt waits retry_minutes more than the previous one.
+UPDATE check_run_timeout
+SET attempts = attempts + 1,
+    retry_at = NOW() + ((attempts + 1) * sqlc.arg(retry_minutes)::INTEGER || ' minutes')::INTERVAL
+WHERE check_run_id = @check_run_id;
@@ -0,0 +1,70 @@
+-- name: ClaimLegacyOutputProjects :many
+-- Project rows still holding their outputs inline or behind a per-project blob reference, locked
+-- until the caller's transaction ends. Rows whose only legacy outputs are blob references are left
+-- out unless with_refs is set, since they cannot be read without a blob store.
+SELECT p.id, r.repository_id, p.init_output, p.init_output_ref, p.plan_output, p.plan_output_ref
+FROM drift_analysis_project p
+         JOIN drift_analysis_run r ON r.uuid = p.drift_analysis_run_id
+WHERE (p.init_output <> '' OR p.plan_output <> '' OR p.init_output_ref IS NOT NULL OR p.plan_output_ref IS NOT NULL)
+  AND (sqlc.arg(with_refs)::BOOLEAN OR p.init_output <> '' OR p.plan_output <> '')
+ORDER BY p.id
+LIMIT @max_rows
+FOR UPDATE OF p SKIP LOCKED;
+
+-- name: DeleteUnreferencedCommandOutputsByRepositoryId :exec
+-- Skips the grace period of DeleteUnreferencedCommandOutputs, for a repository whose runs were
+-- erased and will not be re-sent.
+DELETE FROM command_output
+WHERE repository_id = @repository_id
+  AND ref_count = 0;
+
+-- name: DeleteUnreferencedCommandOutputs :execrows
+-- Deletes outputs no project has referenced for grace_minutes. An output a writer has locked
+-- through FindCommandOutputId is skipped, and one it referenced meanwhile fails the ref_count
+-- re-check, so the collector never deletes an output that is about to be used again.
+DELETE FROM command_output
+WHERE id IN (SELECT id
+             FROM command_output
+             WHERE ref_count = 0
+               AND unreferenced_at < NOW() - (sqlc.arg(grace_minutes)::INTEGER || ' minutes')::INTERVAL
+             ORDER BY unreferenced_at
+             LIMIT @max_rows
+             FOR UPDATE SKIP LOCKED);
+
+-- name: FindCommandOutputHashes :many
+-- Returns which of the given hashes the repository holds an output for, without locking them.
+SELECT hash
+FROM command_output
+WHERE repository_id = @repository_id
+  AND hash = ANY (@hashes::BYTEA[]);
+
+-- name: FindCommandOutputId :one
+-- Locks the row until the caller's transaction ends, so the garbage collector cannot delete it
+-- before the caller's project rows reference it.
+SELECT id
+FROM command_output
+WHERE repository_id = @repository_id
+  AND hash = @hash
+FOR NO KEY UPDATE;
+
+-- name: FindCommandOutputsByIds :many
+SELECT id, data, blob_ref
+FROM command_output
+WHERE id = ANY (@ids::BIGINT[]);
+
+-- name: InsertCommandOutput :one
+-- Returns no row when a concurrent writer inserted the same content first.
+INSERT INTO command_output (repository_id, hash, size, compressed_size, data, blob_ref)
+VALUES (@repository_id, @hash, @size, @compressed_size, @data, @blob_ref)
+ON CONFLICT (repository_id, hash) DO NOTHING
+RETURNING id;
+
+-- name: SetDriftAnalysisProjectOutputs :exec
+UPDATE drift_analysis_project
+SET init_output     = @init_output,
+    init_output_ref = @init_output_ref,
+    init_output_id  = @init_output_id,
+    plan_output     = @plan_output,
+    plan_output_ref = @plan_output_ref,
+    plan_output_id  = @plan_output_id
+WHERE id = @id;
@@ -1,11 +1,26 @@
 -- name: CreateDriftAnalysisRun :one
-INSERT INTO drift_analysis_run (uuid, repository_id, total_projects, total_projects_drifted, total_projects_errored, total_projects_skipped, analysis_duration_millis, idempotency_key, status)
-VALUES (@uuid, @repository_id, @total_projects, @total_projects_drifted, @total_projects_errored, @total_projects_skipped, @analysis_duration_millis, @idempotency_key, 'COMPLETED')
+-- A run uploaded with the id of a scan dispatch scoped to some dirs as its Idempotency-Key takes
+-- over those dirs as its scoped_dirs.
+INSERT INTO drift_analysis_run (uuid, repository_id, total_projects, total_projects_drifted, total_projects_errored, total_projects_skipped, analysis_duration_millis, idempotency_key, status, scoped_dirs)
+VALUES (@uuid, @repository_id, @total_projects, @total_projects_drifted, @total_projects_errored, @total_projects_skipped, @analysis_duration_millis, @idempotency_key, 'COMPLETED',
+        (SELECT d.dirs
+         FROM scan_dispatch d
+         WHERE d.repository_id = @repository_id
+           AND d.id::text = @idempotency_key
+           AND d.kind = 'SCAN'
+           AND cardinality(d.dirs) > 0))
 RETURNING *;
 
 -- name: CreateRunningDriftAnalysisRun :one
-INSERT INTO drift_analysis_run (uuid, repository_id, total_projects, total_projects_drifted, total_projects_errored, total_projects_skipped, analysis_duration_millis, idempotency_key, status, running_projects)
-VALUES (@uuid, @repository_id, @total_projects, 0, 0, 0, 0, @idempotency_key, 'RUNNING', @running_projects)
+-- Takes over the scoped dirs of the run's scan dispatch like CreateDriftAnalysisRun.
+INSERT INTO drift_analysis_run (uuid, repository_id, total_projects, total_projects_drifted, total_projects_errored, total_projects_skipped, analysis_duration_millis, idempotency_key, status, running_projects, scoped_dirs)
+VALUES (@uuid, @repository_id, @total_projects, 0, 0, 0, 0, @idempotency_key, 'RUNNING', @running_projects,
+        (SELECT d.dirs
+         FROM scan_dispatch d
+         WHERE d.repository_id = @repository_id
+           AND d.id::text = @idempotency_key
+           AND d.kind = 'SCAN'
+           AND cardinality(d.dirs) > 0))
 RETURNING *;
 
 -- name: UpdateDriftAnalysisRunProgress :exec
@@ -37,17 +52,205 @@ SET total_projects           = @total_projects,
     updated_at               = NOW()
 WHERE uuid = @uuid;
 
--- name: DeleteStaleRunningRuns :execrows
--- Collects runs abandoned by a crashed CLI. FOR UPDATE SKIP LOCKED keeps concurrent sweepers on
--- other API instances from blocking or double-deleting, and skips a run whose progress update is
--- in flight. Project rows go via ON DELETE CASCADE.
+-- name: CompleteDriftAnalysisRunFromProjects :exec
+-- Completes a run whose project rows were written incrementally, as the streaming ingest does. The
+-- counters are recomputed from the rows like UpdateDriftAnalysisRunProgress; the client only
+-- supplies what the rows cannot tell, the planned project count and the duration.
+UPDATE drift_analysis_run r
+SET total_projects           = GREATEST(sqlc.arg(total_projects)::INT, c.total)::INT,
+    total_projects_drifted   = c.drifted::INT,
+    total_projects_errored   = c.errored::INT,
+    total_projects_skipped   = c.skipped::INT,
+    analysis_duration_millis = @analysis_duration_millis,
+    status                   = 'COMPLETED',
+    running_projects         = '{}',
+    updated_at               = NOW()
+FROM (SELECT COUNT(*)                                                            AS total,
+             COUNT(*) FILTER (WHERE drifted AND succeeded AND NOT skipped_due_to_pr) AS drifted,
+             COUNT(*) FILTER (WHERE NOT succeeded)                                   AS errored,
+             COUNT(*) FILTER (WHERE skipped_due_to_pr)                               AS skipped
+      FROM drift_analysis_project
+      WHERE drift_analysis_run_id = @uuid) c
+WHERE r.uuid = @uuid;
+
+-- name: TouchRunningDriftAnalysisRun :execrows
+-- Marks a RUNNING run as still reporting, which keeps the stale run sweeper off a long upload, and
+-- takes its row lock for the rest of the transaction. Matches no row once the run was swept or
+-- completed.
+UPDATE drift_analysis_run
+SET updated_at = NOW()
+WHERE uuid = @uuid AND status = 'RUNNING';
+
+-- name: DeleteRunningDriftAnalysisRun :exec
+-- Drops a run whose upload failed and cannot be resumed. Project rows go via ON DELETE CASCADE.
 DELETE FROM drift_analysis_run
-WHERE uuid IN (SELECT r.uuid
-               FROM drift_analysis_run r
-               WHERE r.status = 'RUNNING'
-                 AND r.updated_at < NOW() - (sqlc.arg(stale_minutes)::INTEGER || ' minutes')::INTERVAL
-               FOR UPDATE SKIP LOCKED
-               LIMIT sqlc.arg(max_rows));
+WHERE uuid = @uuid AND status = 'RUNNING';
+
+-- name: DeleteStaleRunningRuns :one
+-- Collects runs abandoned by a crashed CLI and returns how many it deleted. FOR UPDATE SKIP LOCKED
+-- keeps concurrent sweepers on other API instances from blocking or double-deleting, and skips a
+-- run whose progress update is in flight. Project rows go via ON DELETE CASCADE. Sharded runs are
+-- left to CompleteTimedOutShardedRuns, which keeps the results the reporting shards already sent.
+-- The check runs of the deleted runs are queued in check_run_timeout by the same statement.
+WITH deleted AS (
+    DELETE FROM drift_analysis_run
+    WHERE uuid IN (SELECT r.uuid
+                   FROM drift_analysis_run r
+                   WHERE r.status = 'RUNNING'
+                     AND r.expected_shards IS NULL
+                     AND r.updated_at < NOW() - (sqlc.arg(stale_minutes)::INTEGER || ' minutes')::INTERVAL
+                   FOR UPDATE SKIP LOCKED
+                   LIMIT sqlc.arg(max_rows))
+    RETURNING uuid, repository_id, check_run_id
+), queued AS (
+    INSERT INTO check_run_timeout (check_run_id, run_id, repository_id)
+    SELECT check_run_id, uuid, repository_id
+    FROM deleted
+    WHERE check_run_id IS NOT NULL
+    ON CONFLICT DO NOTHING
+)
+SELECT count(*)
+FROM deleted;
+
+-- name: RecordDriftAnalysisRunShard :exec
+-- A retried shard overwrites its own row, so the reported count never exceeds the distinct shard ids.
+INSERT INTO drift_analysis_run_shard (drift_analysis_run_id, shard_id, total_projects, analysis_duration_millis)
+VALUES (@drift_analysis_run_id, @shard_id, @total_projects, @analysis_duration_millis)
+ON CONFLICT (drift_analysis_run_id, shard_id) DO UPDATE
+SET total_projects           = EXCLUDED.total_projects,
+    analysis_duration_millis = EXCLUDED.analysis_duration_millis,
+    reported_at              = NOW();
+
+-- name: RecordDriftAnalysisRunCoverageChanges :exec
+-- Records the dirs a run added to or dropped from the previous completed run of its repository.
+-- Must run in the transaction that completes the run. The first run of a repository has nothing to
+-- compare with and records nothing. Only runs that cover the whole repository are compared: a
+-- sharded run missing shards or a run scoped to some dirs records nothing and is never the
+-- previous run of another.
+WITH full_runs AS (
+    SELECT r.uuid, r.repository_id, r.status, r.created_at
+    FROM drift_analysis_run r
+    WHERE r.scoped_dirs IS NULL
+      AND (r.expected_shards IS NULL
+        OR (SELECT COUNT(*) FROM drift_analysis_run_shard s WHERE s.drift_analysis_run_id = r.uuid) >= r.expected_shards)
+),
+current_run AS (
+    SELECT uuid, repository_id, created_at
+    FROM full_runs
+    WHERE uuid = @run_id
+),
+previous_run AS (
+    SELECT prev.uuid
+    FROM full_runs prev
+    JOIN current_run cur ON prev.repository_id = cur.repository_id
+    WHERE prev.status = 'COMPLETED'
+      AND prev.uuid <> cur.uuid
+      AND prev.created_at < cur.created_at
+    ORDER BY prev.created_at DESC
+    LIMIT 1
+),
+current_projects AS (
+    SELECT dap.dir, dap.type
+    FROM drift_analysis_project dap
+    JOIN current_run cur ON dap.drift_analysis_run_id = cur.uuid
+),
+previous_projects AS (
+    SELECT dap.dir, dap.type
+    FROM drift_analysis_project dap
+    JOIN previous_run prev ON dap.drift_analysis_run_id = prev.uuid
+)
+INSERT INTO drift_analysis_run_coverage_change (drift_analysis_run_id, dir, type, change)
+SELECT cur.uuid, c.dir, c.type, 'ADDED'
+FROM current_projects c, current_run cur
+WHERE EXISTS (SELECT 1 FROM previous_run)
+  AND NOT EXISTS (SELECT 1 FROM previous_projects p WHERE p.dir = c.dir)
+UNION ALL
+SELECT cur.uuid, p.dir, p.type, 'REMOVED'
+FROM previous_projects p, current_run cur
+WHERE NOT EXISTS (SELECT 1 FROM current_projects c WHERE c.dir = p.dir)
+ON CONFLICT DO NOTHING;
+
+-- name: SetDriftAnalysisRunCommitSha :execrows
+-- Records the commit a run scanned. The first one sent sticks, so a later tick or shard of the run
+-- cannot move it to another commit.
+UPDATE drift_analysis_run
+SET commit_sha = @commit_sha
+WHERE uuid = @uuid AND commit_sha IS NULL;
+
+-- name: SetDriftAnalysisRunCheckRun :execrows
+-- Records the GitHub check run reporting a run, unless one was recorded already.
+UPDATE drift_analysis_run
+SET check_run_id = @check_run_id
+WHERE uuid = @uuid AND check_run_id IS NULL;
+
+-- name: SetDriftAnalysisRunExpectedShards :execrows
+-- Matches no row once the run completed, so a shard arriving late is turned away before it writes.
+UPDATE drift_analysis_run
+SET expected_shards = @expected_shards,
+    updated_at      = NOW()
+WHERE uuid = @uuid AND status = 'RUNNING';
+
+-- name: RefreshShardedDriftAnalysisRun :one
+-- Recomputes the counters from the project rows like UpdateDriftAnalysisRunProgress, and completes
+-- the run once every expected shard has reported. Shards scan disjoint slices of the repository, so
+-- total_projects is the sum of what each shard planned to scan and the duration is the slowest
+-- shard's. Returns no row when the run is already COMPLETED.
+UPDATE drift_analysis_run r
+SET total_projects           = GREATEST(s.total_projects, c.total)::INT,
+    total_projects_drifted   = c.drifted::INT,
+    total_projects_errored   = c.errored::INT,
+    total_projects_skipped   = c.skipped::INT,
+    analysis_duration_millis = s.duration_millis::BIGINT,
+    status                   = CASE WHEN s.reported >= r.expected_shards THEN 'COMPLETED' ELSE r.status END,
+    running_projects         = CASE WHEN s.reported >= r.expected_shards THEN '{}' ELSE r.running_projects END,
+    updated_at               = NOW()
+FROM (SELECT COUNT(*)                                                            AS total,
+             COUNT(*) FILTER (WHERE drifted AND succeeded AND NOT skipped_due_to_pr) AS drifted,
+             COUNT(*) FILTER (WHERE NOT succeeded)                                   AS errored,
+             COUNT(*) FILTER (WHERE skipped_due_to_pr)                               AS skipped
+      FROM drift_analysis_project
+      WHERE drift_analysis_run_id = @uuid) c,
+     (SELECT COUNT(*)                                   AS reported,
+             COALESCE(SUM(total_projects), 0)           AS total_projects,
+             COALESCE(MAX(analysis_duration_millis), 0) AS duration_millis
+      FROM drift_analysis_run_shard
+      WHERE drift_analysis_run_id = @uuid) s
+WHERE r.uuid = @uuid AND r.status = 'RUNNING'
+RETURNING r.status;
+
+-- name: CompleteTimedOutShardedRuns :many
+-- Finalizes sharded runs whose missing shards never reported, with whatever the others sent, those
+-- that went longest without a report first. The timeout counts from the last shard or progress
+-- report. FOR UPDATE SKIP LOCKED makes it safe to run on every API instance at once.
+UPDATE drift_analysis_run r
+SET total_projects           = GREATEST(
+        COALESCE((SELECT SUM(s.total_projects) FROM drift_analysis_run_shard s WHERE s.drift_analysis_run_id = r.uuid), 0),
+        (SELECT COUNT(*) FROM drift_analysis_project p WHERE p.drift_analysis_run_id = r.uuid))::INT,
+    total_projects_drifted   = (SELECT COUNT(*)
+                                FROM drift_analysis_project p
+                                WHERE p.drift_analysis_run_id = r.uuid
+                                  AND p.drifted AND p.succeeded AND NOT p.skipped_due_to_pr)::INT,
+    total_projects_errored   = (SELECT COUNT(*)
+                                FROM drift_analysis_project p
+                                WHERE p.drift_analysis_run_id = r.uuid AND NOT p.succeeded)::INT,
+    total_projects_skipped   = (SELECT COUNT(*)
+                                FROM drift_analysis_project p
+                                WHERE p.drift_analysis_run_id = r.uuid AND p.skipped_due_to_pr)::INT,
+    analysis_duration_millis = COALESCE((SELECT MAX(s.analysis_duration_millis)
+                                         FROM drift_analysis_run_shard s
+                                         WHERE s.drift_analysis_run_id = r.uuid), 0)::BIGINT,
+    status                   = 'COMPLETED',
+    running_projects         = '{}',
+    updated_at               = NOW()
+WHERE r.uuid IN (SELECT t.uuid
+                 FROM drift_analysis_run t
+                 WHERE t.status = 'RUNNING'
+                   AND t.expected_shards IS NOT NULL
+                   AND t.updated_at < NOW() - (sqlc.arg(timeout_minutes)::INTEGER || ' minutes')::INTERVAL
+                 ORDER BY t.updated_at
+                 FOR UPDATE SKIP LOCKED
+                 LIMIT sqlc.arg(max_rows))
+RETURNING r.uuid, r.repository_id;
 
 -- name: FindDriftAnalysisRunByRepoAndIdempotencyKey :one
 SELECT *
@@ -62,18 +265,44 @@ RETURNING *;
 -- name: UpsertDriftAnalysisProject :batchexec
 -- Shared write path for the progress ticks and the terminal ingest. Keyed on the unique index
 -- (drift_analysis_run_id, dir), so re-sending a project updates it in place instead of duplicating.
-INSERT INTO drift_analysis_project (drift_analysis_run_id, dir, type, drifted, succeeded, init_output, plan_output, skipped_due_to_pr, resources_added, resources_changed, resources_destroyed)
-VALUES (@drift_analysis_run_id, @dir, @type, @drifted, @succeeded, @init_output, @plan_output, @skipped_due_to_pr, @resources_added, @resources_changed, @resources_destroyed)
+-- Outputs are written to command_output first; a per-project blob reference left by an older row is
+-- cleared, which queues its blob for deletion.
+INSERT INTO drift_analysis_project (drift_analysis_run_id, dir, type, drifted, succeeded, init_output, plan_output, skipped_due_to_pr, resources_added, resources_changed, resources_destroyed, init_output_size, plan_output_size, init_output_id, plan_output_id, redactions, resources_imported, resources_forgotten, resources_moved, outputs_changed, summary_parser_version, resource_changes, drift_fingerprint, init_duration_millis, plan_duration_millis, started_at, finished_at, retries, error_class, core_tool, core_version, provider_versions, warnings)
+VALUES (@drift_analysis_run_id, @dir, @type, @drifted, @succeeded, @init_output, @plan_output, @skipped_due_to_pr, @resources_added, @resources_changed, @resources_destroyed, @init_output_size, @plan_output_size, @init_output_id, @plan_output_id, @redactions, @resources_imported, @resources_forgotten, @resources_moved, @outputs_changed, @summary_parser_version, @resource_changes, @drift_fingerprint, @init_duration_millis, @plan_duration_millis, @started_at, @finished_at, @retries, @error_class, @core_tool, @core_version, @provider_versions, @warnings)
 ON CONFLICT (drift_analysis_run_id, dir) DO UPDATE
-SET type                = EXCLUDED.type,
-    drifted             = EXCLUDED.drifted,
-    succeeded           = EXCLUDED.succeeded,
-    init_output         = EXCLUDED.init_output,
-    plan_output         = EXCLUDED.plan_output,
-    skipped_due_to_pr   = EXCLUDED.skipped_due_to_pr,
-    resources_added     = EXCLUDED.resources_added,
-    resources_changed   = EXCLUDED.resources_changed,
-    resources_destroyed = EXCLUDED.resources_destroyed;
+SET type                   = EXCLUDED.type,
+    drifted                = EXCLUDED.drifted,
+    succeeded              = EXCLUDED.succeeded,
+    init_output            = EXCLUDED.init_output,
+    plan_output            = EXCLUDED.plan_output,
+    skipped_due_to_pr      = EXCLUDED.skipped_due_to_pr,
+    resources_added        = EXCLUDED.resources_added,
+    resources_changed      = EXCLUDED.resources_changed,
+    resources_destroyed    = EXCLUDED.resources_destroyed,
+    init_output_size       = EXCLUDED.init_output_size,
+    plan_output_size       = EXCLUDED.plan_output_size,
+    init_output_id         = EXCLUDED.init_output_id,
+    plan_output_id         = EXCLUDED.plan_output_id,
+    redactions             = EXCLUDED.redactions,
+    resources_imported     = EXCLUDED.resources_imported,
+    resources_forgotten    = EXCLUDED.resources_forgotten,
+    resources_moved        = EXCLUDED.resources_moved,
+    outputs_changed        = EXCLUDED.outputs_changed,
+    summary_parser_version = EXCLUDED.summary_parser_version,
+    resource_changes       = EXCLUDED.resource_changes,
+    drift_fingerprint      = EXCLUDED.drift_fingerprint,
+    init_duration_millis   = EXCLUDED.init_duration_millis,
+    plan_duration_millis   = EXCLUDED.plan_duration_millis,
+    started_at             = EXCLUDED.started_at,
+    finished_at            = EXCLUDED.finished_at,
+    retries                = EXCLUDED.retries,
+    error_class            = EXCLUDED.error_class,
+    core_tool              = EXCLUDED.core_tool,
+    core_version           = EXCLUDED.core_version,
+    provider_versions      = EXCLUDED.provider_versions,
+    warnings               = EXCLUDED.warnings,
+    init_output_ref        = NULL,
+    plan_output_ref        = NULL;
 
 -- name: FindDriftAnalysisRunsByRepositoryId :many
 SELECT *
@@ -82,6 +311,19 @@ WHERE repository_id = @repository_id
 ORDER BY created_at DESC
 OFFSET @queryOffset LIMIT @maxResults;
 
+-- name: FindDriftAnalysisRunsByRepositoryIdAndDirs :many
+-- Runs of a repository in which one of the projects in dirs drifted, newest first
+SELECT *
+FROM drift_analysis_run
+WHERE repository_id = @repository_id
+  AND EXISTS (SELECT 1
+              FROM drift_analysis_project dap
+              WHERE dap.drift_analysis_run_id = drift_analysis_run.uuid
+                AND dap.dir = ANY (sqlc.arg(dirs)::TEXT[])
+                AND dap.drifted AND dap.succeeded AND NOT dap.skipped_due_to_pr)
+ORDER BY created_at DESC
+OFFSET @queryOffset LIMIT @maxResults;
+
 -- name: FindDriftAnalysisRunByUUID :one
 SELECT *
 FROM drift_analysis_run
@@ -100,6 +342,23 @@ ORDER BY
     END,
     dir ASC;
 
+-- name: GetPreviousRunDriftFingerprints :many
+-- Returns the drifted projects of the repository's last completed run before the given one, with
+-- their fingerprints. Empty when there is no earlier run.
+SELECT dap.dir, dap.drift_fingerprint
+FROM drift_analysis_project dap
+WHERE dap.drifted = true
+  AND dap.drift_analysis_run_id = (
+    SELECT prev.uuid
+    FROM drift_analysis_run prev
+    JOIN drift_analysis_run cur ON cur.repository_id = prev.repository_id
+    WHERE cur.uuid = @uuid
+      AND prev.status = 'COMPLETED'
+      AND prev.created_at < cur.created_at
+    ORDER BY prev.created_at DESC
+    LIMIT 1
+  );
+
 -- name: GetRepositoryRunStats :one
 SELECT
     COUNT(*) AS total_runs,
@@ -116,6 +375,24 @@ WHERE repository_id = @repository_id
 ORDER BY created_at DESC
 LIMIT 1;
 
+-- name: GetLatestFullRunForRepository :one
+-- The latest completed run that scanned the whole repository, not just the dirs a dispatch scoped
+-- it to.
+SELECT *
+FROM drift_analysis_run
+WHERE repository_id = @repository_id
+  AND status = 'COMPLETED'
+  AND scoped_dirs IS NULL
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetRunCoverageChanges :many
+-- Returns the dirs a run added to or dropped from the previous completed run, removals first
+SELECT dir, type, change
+FROM drift_analysis_run_coverage_change
+WHERE drift_analysis_run_id = @drift_analysis_run_id
+ORDER BY change DESC, dir;
+
 -- name: GetDriftRateOverTime :many
 -- Returns daily drift rate data for the specified time range
 SELECT
@@ -129,6 +406,24 @@ WHERE repository_id = @repository_id
 GROUP BY DATE(created_at)
 ORDER BY DATE(created_at) ASC;
 
+-- name: GetDirsDriftOverTime :many
+-- Returns daily drift data of the projects in dirs. Only runs that scanned one of them are counted.
+SELECT
+    DATE(dar.created_at) AS date,
+    COUNT(DISTINCT dar.uuid)::BIGINT AS total_runs,
+    COUNT(DISTINCT dar.uuid) FILTER (WHERE dap.drifted AND dap.succeeded AND NOT dap.skipped_due_to_pr)::BIGINT AS runs_with_drift,
+    COUNT(*)::BIGINT AS projects_scanned,
+    COUNT(*) FILTER (WHERE dap.drifted AND dap.succeeded AND NOT dap.skipped_due_to_pr)::BIGINT AS projects_drifted,
+    COUNT(*) FILTER (WHERE NOT dap.succeeded AND NOT dap.skipped_due_to_pr)::BIGINT AS projects_errored
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+WHERE dar.repository_id = @repository_id
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - (sqlc.arg(days_back)::INTEGER || ' days')::INTERVAL
+  AND dap.dir = ANY (sqlc.arg(dirs)::TEXT[])
+GROUP BY DATE(dar.created_at)
+ORDER BY DATE(dar.created_at) ASC;
+
 -- name: GetMostFrequentlyDriftedProjects :many
 -- Returns projects ranked by how often they drift (top N)
 SELECT
@@ -146,6 +441,256 @@ HAVING COUNT(*) FILTER (WHERE dap.drifted = true) > 0
 ORDER BY drift_count DESC
 LIMIT sqlc.arg(max_results);
 
+-- name: GetMostFrequentlyDriftedResources :many
+-- Returns resource addresses ranked by how many drifted runs named them (top N). Data sources read
+-- during apply are not drift and are left out, and only projects that planned and were not skipped
+-- count.
+SELECT
+    dap.dir,
+    rc.address::TEXT AS address,
+    COUNT(DISTINCT dar.uuid)::BIGINT AS drift_count
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+CROSS JOIN LATERAL jsonb_to_recordset(dap.resource_changes) AS rc(address TEXT, action TEXT)
+WHERE dar.repository_id = @repository_id
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - (sqlc.arg(days_back)::INTEGER || ' days')::INTERVAL
+  AND dap.drifted = true
+  AND dap.succeeded = true
+  AND dap.skipped_due_to_pr = false
+  AND rc.action <> 'READ'
+GROUP BY dap.dir, rc.address
+ORDER BY drift_count DESC, dap.dir, address
+LIMIT sqlc.arg(max_results);
+
+-- name: GetMostFrequentlyDriftedResourceTypes :many
+-- Returns resource types ranked by how many drifted runs changed a resource of that type (top N),
+-- in one repository or across every repository of an organization. The type is the address segment
+-- after any module path, e.g. aws_security_group. Only projects that planned and were not skipped
+-- count, as in every other drift metric.
+SELECT
+    rt.resource_type::TEXT AS resource_type,
+    COUNT(DISTINCT dar.uuid)::BIGINT AS drift_count,
+    COUNT(DISTINCT (dar.repository_id, dap.dir, rc.address))::BIGINT AS resource_count,
+    COUNT(DISTINCT dar.repository_id)::BIGINT AS repository_count
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+JOIN git_repository gr ON dar.repository_id = gr.id
+CROSS JOIN LATERAL jsonb_to_recordset(dap.resource_changes) AS rc(address TEXT, action TEXT)
+CROSS JOIN LATERAL substring(rc.address FROM '^(?:module\.[^.\[]+(?:\[[^\]]*\])?\.)*(?:data\.)?([^.\[]+)') AS rt(resource_type)
+WHERE (sqlc.narg(repository_id)::BIGINT IS NULL OR dar.repository_id = sqlc.narg(repository_id))
+  AND (sqlc.narg(organization_id)::BIGINT IS NULL OR gr.organization_id = sqlc.narg(organization_id))
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - (sqlc.arg(days_back)::INTEGER || ' days')::INTERVAL
+  AND dap.drifted = true
+  AND dap.succeeded = true
+  AND dap.skipped_due_to_pr = false
+  AND rc.action <> 'READ'
+GROUP BY rt.resource_type
+ORDER BY drift_count DESC, resource_count DESC, resource_type
+LIMIT sqlc.arg(max_results);
+
+-- name: GetMostFrequentlyDriftedProviders :many
+-- Returns providers ranked by how many drifted runs changed one of their resources (top N), scoped
+-- and counted like GetMostFrequentlyDriftedResourceTypes. The provider is the prefix of the
+-- resource type, the same way Terraform infers it.
+SELECT
+    split_part(rt.resource_type, '_', 1)::TEXT AS provider,
+    COUNT(DISTINCT dar.uuid)::BIGINT AS drift_count,
+    COUNT(DISTINCT (dar.repository_id, dap.dir, rc.address))::BIGINT AS resource_count,
+    COUNT(DISTINCT dar.repository_id)::BIGINT AS repository_count
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+JOIN git_repository gr ON dar.repository_id = gr.id
+CROSS JOIN LATERAL jsonb_to_recordset(dap.resource_changes) AS rc(address TEXT, action TEXT)
+CROSS JOIN LATERAL substring(rc.address FROM '^(?:module\.[^.\[]+(?:\[[^\]]*\])?\.)*(?:data\.)?([^.\[]+)') AS rt(resource_type)
+WHERE (sqlc.narg(repository_id)::BIGINT IS NULL OR dar.repository_id = sqlc.narg(repository_id))
+  AND (sqlc.narg(organization_id)::BIGINT IS NULL OR gr.organization_id = sqlc.narg(organization_id))
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - (sqlc.arg(days_back)::INTEGER || ' days')::INTERVAL
+  AND dap.drifted = true
+  AND dap.succeeded = true
+  AND dap.skipped_due_to_pr = false
+  AND rc.action <> 'READ'
+GROUP BY provider
+ORDER BY drift_count DESC, resource_count DESC, provider
+LIMIT sqlc.arg(max_results);
+
+-- name: GetSlowestProjects :many
+-- Returns the projects with the longest average analysis time (top N). A project's time is the
+-- span from started_at to finished_at when reported, which includes retries, else init plus plan.
+WITH project_durations AS (
+    SELECT
+        dap.dir,
+        dap.type,
+        dap.init_duration_millis,
+        dap.plan_duration_millis,
+        dap.retries,
+        COALESCE(
+            (EXTRACT(EPOCH FROM (dap.finished_at - dap.started_at)) * 1000)::BIGINT,
+            dap.init_duration_millis + dap.plan_duration_millis,
+            dap.plan_duration_millis,
+            dap.init_duration_millis
+        ) AS duration_millis
+    FROM drift_analysis_project dap
+    JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+    WHERE dar.repository_id = @repository_id
+      AND dar.status = 'COMPLETED'
+      AND dar.created_at >= NOW() - (sqlc.arg(days_back)::INTEGER || ' days')::INTERVAL
+)
+SELECT
+    dir,
+    type,
+    COUNT(*)::BIGINT AS run_count,
+    AVG(duration_millis)::BIGINT AS avg_duration_millis,
+    MAX(duration_millis)::BIGINT AS max_duration_millis,
+    COALESCE(AVG(init_duration_millis), 0)::BIGINT AS avg_init_duration_millis,
+    COALESCE(AVG(plan_duration_millis), 0)::BIGINT AS avg_plan_duration_millis,
+    COALESCE(SUM(retries), 0)::BIGINT AS total_retries
+FROM project_durations
+WHERE duration_millis IS NOT NULL
+GROUP BY dir, type
+ORDER BY avg_duration_millis DESC, dir
+LIMIT sqlc.arg(max_results);
+
+-- name: GetProjectDurationRegressions :many
+-- Returns the projects whose average analysis time in the second half of the window is at least
+-- min_increase_percent above the first half, ranked by the increase (top N). Projects measured in
+-- only one half are left out.
+WITH project_durations AS (
+    SELECT
+        dap.dir,
+        dar.created_at >= NOW() - (sqlc.arg(days_back)::INTEGER * 12 || ' hours')::INTERVAL AS recent,
+        COALESCE(
+            (EXTRACT(EPOCH FROM (dap.finished_at - dap.started_at)) * 1000)::BIGINT,
+            dap.init_duration_millis + dap.plan_duration_millis,
+            dap.plan_duration_millis,
+            dap.init_duration_millis
+        ) AS duration_millis
+    FROM drift_analysis_project dap
+    JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+    WHERE dar.repository_id = @repository_id
+      AND dar.status = 'COMPLETED'
+      AND dar.created_at >= NOW() - (sqlc.arg(days_back)::INTEGER || ' days')::INTERVAL
+),
+halves AS (
+    SELECT
+        dir,
+        AVG(duration_millis) FILTER (WHERE NOT recent) AS baseline,
+        AVG(duration_millis) FILTER (WHERE recent) AS latest
+    FROM project_durations
+    WHERE duration_millis IS NOT NULL
+    GROUP BY dir
+)
+SELECT
+    dir,
+    baseline::BIGINT AS baseline_avg_duration_millis,
+    latest::BIGINT AS recent_avg_duration_millis
+FROM halves
+WHERE baseline > 0
+  AND latest >= baseline * (1 + sqlc.arg(min_increase_percent)::INTEGER / 100.0)
+ORDER BY latest - baseline DESC, dir
+LIMIT sqlc.arg(max_results);
+
+-- name: GetRepeatedWarnings :many
+-- Returns the warnings a project printed in at least two runs of the window, the most repeated
+-- first (top N).
+SELECT
+    dap.dir,
+    w.category::TEXT AS category,
+    w.summary::TEXT AS summary,
+    COALESCE(w.address, '')::TEXT AS address,
+    COALESCE(MAX(w.detail), '')::TEXT AS detail,
+    COUNT(DISTINCT dar.uuid)::BIGINT AS run_count,
+    MIN(dar.created_at)::TIMESTAMPTZ AS first_seen_at,
+    MAX(dar.created_at)::TIMESTAMPTZ AS last_seen_at
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+CROSS JOIN LATERAL jsonb_to_recordset(dap.warnings) AS w(category TEXT, summary TEXT, detail TEXT, address TEXT)
+WHERE dar.repository_id = @repository_id
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - (sqlc.arg(days_back)::INTEGER || ' days')::INTERVAL
+GROUP BY dap.dir, w.category, w.summary, COALESCE(w.address, '')
+HAVING COUNT(DISTINCT dar.uuid) >= 2
+ORDER BY run_count DESC, last_seen_at DESC, dap.dir, summary
+LIMIT sqlc.arg(max_results);
+
+-- name: GetWarningCountsPerRun :many
+-- Returns the number of warnings of each completed run in the window, oldest first.
+SELECT
+    dar.uuid AS run_id,
+    dar.created_at,
+    COALESCE(SUM(w.occurrences), 0)::BIGINT AS warning_count
+FROM drift_analysis_run dar
+LEFT JOIN drift_analysis_project dap ON dap.drift_analysis_run_id = dar.uuid
+LEFT JOIN LATERAL jsonb_to_recordset(dap.warnings) AS w(occurrences INT) ON true
+WHERE dar.repository_id = @repository_id
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - (sqlc.arg(days_back)::INTEGER || ' days')::INTERVAL
+GROUP BY dar.uuid, dar.created_at
+ORDER BY dar.created_at;
+
+-- name: GetOrganizationVersionInventory :many
+-- Returns the latest core and provider versions reported by each project of an organization's
+-- repositories within the window. Projects whose init output named no version are left out.
+SELECT DISTINCT ON (gr.id, dap.dir)
+    gr.id AS repository_id,
+    gr.name AS repository_name,
+    dap.dir,
+    dap.type,
+    dap.core_tool,
+    dap.core_version,
+    dap.provider_versions,
+    dar.created_at AS last_run_at
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+JOIN git_repository gr ON dar.repository_id = gr.id
+WHERE gr.organization_id = @organization_id
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - (sqlc.arg(days_back)::INTEGER || ' days')::INTERVAL
+  AND (dap.core_version IS NOT NULL OR dap.provider_versions IS NOT NULL)
+ORDER BY gr.id, dap.dir, dar.created_at DESC;
+
+-- name: GetErrorClassBreakdown :many
+-- Returns how often failed projects hit each error class. Rows stored before the classifier and not
+-- yet backfilled count as UNKNOWN.
+SELECT
+    COALESCE(dap.error_class, 'UNKNOWN')::TEXT AS error_class,
+    COUNT(*)::BIGINT AS project_count,
+    COUNT(DISTINCT dar.uuid)::BIGINT AS run_count,
+    MAX(dar.created_at)::TIMESTAMPTZ AS last_seen_at
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+WHERE dar.repository_id = @repository_id
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - (sqlc.arg(days_back)::INTEGER || ' days')::INTERVAL
+  AND dap.succeeded = false
+  AND dap.skipped_due_to_pr = false
+GROUP BY COALESCE(dap.error_class, 'UNKNOWN')
+ORDER BY project_count DESC, error_class;
+
+-- name: GetRunErrorClassBreakdown :many
+-- Returns the number of failed projects of a run per error class.
+SELECT
+    COALESCE(error_class, 'UNKNOWN')::TEXT AS error_class,
+    COUNT(*)::BIGINT AS project_count
+FROM drift_analysis_project
+WHERE drift_analysis_run_id = @drift_analysis_run_id
+  AND succeeded = false
+  AND skipped_due_to_pr = false
+GROUP BY COALESCE(error_class, 'UNKNOWN')
+ORDER BY project_count DESC, error_class;
+
+-- name: GetRunProjectStatuses :many
+-- Returns the dir, type and status of each project of a run.
+SELECT
+    dir,
+    type,
+    drift_project_status(skipped_due_to_pr, succeeded, drifted)::VARCHAR AS status
+FROM drift_analysis_project
+WHERE drift_analysis_run_id = @drift_analysis_run_id
+ORDER BY dir, type;
+
 -- name: GetDriftFreeStreak :one
 -- Returns the current consecutive run count without drift
 WITH ranked_runs AS (
@@ -224,6 +769,71 @@ FROM matched_resolutions
 GROUP BY DATE(resolved_at)
 ORDER BY DATE(resolved_at) ASC;
 
+-- name: GetProjectDriftPeriods :many
+-- Returns the periods the project in dir stayed drifted, newest first, using the same transitions
+-- as GetMeanTimeToResolution. Runs where the project failed or was skipped are left out, since they
+-- say nothing about its drift. A dir whose project changed type is tracked per type, so the runs of
+-- the old type cannot end or start a drift of the new one; a drift the old type's last run left open
+-- ends with the first later run of another type. ended_at is NULL while the drift is ongoing.
+WITH project_states AS (
+    SELECT
+        dap.type,
+        dar.created_at,
+        dap.drifted,
+        LAG(dap.drifted) OVER (PARTITION BY dap.type ORDER BY dar.created_at) AS prev_drifted,
+        LEAD(dar.created_at) OVER (PARTITION BY dap.type ORDER BY dar.created_at) AS next_created_at
+    FROM drift_analysis_project dap
+    JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+    WHERE dar.repository_id = @repository_id
+      AND dap.dir = @dir
+      AND dar.status = 'COMPLETED'
+      AND dap.succeeded = true
+      AND dap.skipped_due_to_pr = false
+),
+drift_starts AS (
+    SELECT type, created_at AS started_at, ROW_NUMBER() OVER (PARTITION BY type ORDER BY created_at) AS start_num
+    FROM project_states
+    WHERE drifted = true AND (prev_drifted IS NULL OR prev_drifted = false)
+),
+drift_ends AS (
+    SELECT type, ended_at, ROW_NUMBER() OVER (PARTITION BY type ORDER BY ended_at) AS end_num
+    FROM (SELECT type, created_at AS ended_at
+          FROM project_states
+          WHERE drifted = false AND prev_drifted = true
+          UNION ALL
+          SELECT last.type, MIN(other.created_at)
+          FROM project_states last
+          JOIN project_states other ON other.type <> last.type AND other.created_at > last.created_at
+          WHERE last.drifted = true AND last.next_created_at IS NULL
+          GROUP BY last.type) ends
+)
+SELECT s.type, s.started_at, e.ended_at
+FROM drift_starts s
+LEFT JOIN drift_ends e ON e.type = s.type AND e.end_num = s.start_num
+WHERE (sqlc.narg(type)::VARCHAR IS NULL OR s.type = sqlc.narg(type))
+ORDER BY s.started_at DESC, s.type;
+
+-- name: GetProjectHistory :many
+-- Returns the outcome of the project in dir in the latest completed runs of a repository, newest
+-- first, optionally narrowed to one type
+SELECT
+    dar.uuid AS run_id,
+    dar.created_at,
+    dap.type,
+    drift_project_status(dap.skipped_due_to_pr, dap.succeeded, dap.drifted)::VARCHAR AS status,
+    dap.resources_added,
+    dap.resources_changed,
+    dap.resources_destroyed,
+    dap.error_class
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+WHERE dar.repository_id = @repository_id
+  AND dap.dir = @dir
+  AND (sqlc.narg(type)::VARCHAR IS NULL OR dap.type = sqlc.narg(type))
+  AND dar.status = 'COMPLETED'
+ORDER BY dar.created_at DESC, dap.type
+LIMIT @max_results;
+
 -- name: DeleteOldestRunsExceedingLimit :exec
 -- Deletes the oldest runs for a repository, keeping only the most recent N runs
 DELETE FROM drift_analysis_run dar
@@ -239,7 +849,48 @@ SELECT count(*)
 FROM drift_analysis_project
 WHERE drift_analysis_run_id = @drift_analysis_run_id;
 
+-- name: CountDriftAnalysisRunShards :one
+SELECT count(*)
+FROM drift_analysis_run_shard
+WHERE drift_analysis_run_id = @drift_analysis_run_id;
+
 -- name: DeleteDriftAnalysisRunsByRepositoryId :exec
 -- Projects are removed by the ON DELETE CASCADE on drift_analysis_project
 DELETE FROM drift_analysis_run
 WHERE repository_id = @repository_id;
+
+-- name: ClaimOutdatedSummaryProjects :many
+-- Leases a batch of projects whose counts were parsed by an older plan summary parser. The lease
+-- outlives the statement, so the batch is parsed outside any transaction while the backfill on
+-- other API instances skips it; the lease of an instance that died expires after lease_minutes.
+UPDATE drift_analysis_project
+SET summary_claimed_at = NOW()
+WHERE id IN (SELECT id
+             FROM drift_analysis_project
+             WHERE summary_parser_version < @parser_version
+               AND (summary_claimed_at IS NULL
+                 OR summary_claimed_at < NOW() - (sqlc.arg(lease_minutes)::INTEGER || ' minutes')::INTERVAL)
+             ORDER BY id
+             LIMIT @max_rows
+             FOR NO KEY UPDATE SKIP LOCKED)
+RETURNING *;
+
+-- name: SetDriftAnalysisProjectSummary :exec
+UPDATE drift_analysis_project
+SET resources_added        = @resources_added,
+    resources_changed      = @resources_changed,
+    resources_destroyed    = @resources_destroyed,
+    resources_imported     = @resources_imported,
+    resources_forgotten    = @resources_forgotten,
+    resources_moved        = @resources_moved,
+    outputs_changed        = @outputs_changed,
+    summary_parser_version = @summary_parser_version,
+    resource_changes       = @resource_changes,
+    drift_fingerprint      = @drift_fingerprint,
+    error_class            = @error_class,
+    core_tool              = @core_tool,
+    core_version           = @core_version,
+    provider_versions      = @provider_versions,
+    warnings               = @warnings,
+    summary_claimed_at     = NULL
+WHERE id = @id;
@@ -13,6 +13,185 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const claimOutdatedSummaryProjects = `-- name: ClaimOutdatedSummaryProjects :many
+UPDATE drift_analysis_project
+SET summary_claimed_at = NOW()
+WHERE id IN (SELECT id
+             FROM drift_analysis_project
+             WHERE summary_parser_version < $1
+               AND (summary_claimed_at IS NULL
+                 OR summary_claimed_at < NOW() - ($2::INTEGER || ' minutes')::INTERVAL)
+             ORDER BY id
+             LIMIT $3
+             FOR NO KEY UPDATE SKIP LOCKED)
+RETURNING id, drift_analysis_run_id, dir, type, drifted, succeeded, init_output, plan_output, skipped_due_to_pr, resources_added, resources_changed, resources_destroyed, init_output_ref, init_output_size, plan_output_ref, plan_output_size, init_output_id, plan_output_id, redactions, resources_imported, resources_forgotten, resources_moved, outputs_changed, summary_parser_version, resource_changes, drift_fingerprint, init_duration_millis, plan_duration_millis, started_at, finished_at, retries, error_class, core_tool, core_version, provider_versions, warnings, summary_claimed_at
+`
+
+type ClaimOutdatedSummaryProjectsParams struct {
+	ParserVersion int16
+	LeaseMinutes  int32
+	MaxRows       int32
+}
+
+// Leases a batch of projects whose counts were parsed by an older plan summary parser. The lease
+// outlives the statement, so the batch is parsed outside any transaction while the backfill on
+// other API instances skips it; the lease of an instance that died expires after lease_minutes.
+func (q *Queries) ClaimOutdatedSummaryProjects(ctx context.Context, arg ClaimOutdatedSummaryProjectsParams) ([]DriftAnalysisProject, error) {
+	rows, err := q.db.Query(ctx, claimOutdatedSummaryProjects, arg.ParserVersion, arg.LeaseMinutes, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DriftAnalysisProject
+	for rows.Next() {
+		var i DriftAnalysisProject
+		if err := rows.Scan(
+			&i.ID,
+			&i.DriftAnalysisRunID,
+			&i.Dir,
+			&i.Type,
+			&i.Drifted,
+			&i.Succeeded,
+			&i.InitOutput,
+			&i.PlanOutput,
+			&i.SkippedDueToPr,
+			&i.ResourcesAdded,
+			&i.ResourcesChanged,
+			&i.ResourcesDestroyed,
+			&i.InitOutputRef,
+			&i.InitOutputSize,
+			&i.PlanOutputRef,
+			&i.PlanOutputSize,
+			&i.InitOutputID,
+			&i.PlanOutputID,
+			&i.Redactions,
+			&i.ResourcesImported,
+			&i.ResourcesForgotten,
+			&i.ResourcesMoved,
+			&i.OutputsChanged,
+			&i.SummaryParserVersion,
+			&i.ResourceChanges,
+			&i.DriftFingerprint,
+			&i.InitDurationMillis,
+			&i.PlanDurationMillis,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.Retries,
+			&i.ErrorClass,
+			&i.CoreTool,
+			&i.CoreVersion,
+			&i.ProviderVersions,
+			&i.Warnings,
+			&i.SummaryClaimedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeDriftAnalysisRunFromProjects = `-- name: CompleteDriftAnalysisRunFromProjects :exec
+UPDATE drift_analysis_run r
+SET total_projects           = GREATEST($1::INT, c.total)::INT,
+    total_projects_drifted   = c.drifted::INT,
+    total_projects_errored   = c.errored::INT,
+    total_projects_skipped   = c.skipped::INT,
+    analysis_duration_millis = $2,
+    status                   = 'COMPLETED',
+    running_projects         = '{}',
+    updated_at               = NOW()
+FROM (SELECT COUNT(*)                                                            AS total,
+             COUNT(*) FILTER (WHERE drifted AND succeeded AND NOT skipped_due_to_pr) AS drifted,
+             COUNT(*) FILTER (WHERE NOT succeeded)                                   AS errored,
+             COUNT(*) FILTER (WHERE skipped_due_to_pr)                               AS skipped
+      FROM drift_analysis_project
+      WHERE drift_analysis_run_id = $3) c
+WHERE r.uuid = $3
+`
+
+type CompleteDriftAnalysisRunFromProjectsParams struct {
+	TotalProjects          int32
+	AnalysisDurationMillis int64
+	Uuid                   uuid.UUID
+}
+
+// Completes a run whose project rows were written incrementally, as the streaming ingest does. The
+// counters are recomputed from the rows like UpdateDriftAnalysisRunProgress; the client only
+// supplies what the rows cannot tell, the planned project count and the duration.
+func (q *Queries) CompleteDriftAnalysisRunFromProjects(ctx context.Context, arg CompleteDriftAnalysisRunFromProjectsParams) error {
+	_, err := q.db.Exec(ctx, completeDriftAnalysisRunFromProjects, arg.TotalProjects, arg.AnalysisDurationMillis, arg.Uuid)
+	return err
+}
+
+const completeTimedOutShardedRuns = `-- name: CompleteTimedOutShardedRuns :many
+UPDATE drift_analysis_run r
+SET total_projects           = GREATEST(
+        COALESCE((SELECT SUM(s.total_projects) FROM drift_analysis_run_shard s WHERE s.drift_analysis_run_id = r.uuid), 0),
+        (SELECT COUNT(*) FROM drift_analysis_project p WHERE p.drift_analysis_run_id = r.uuid))::INT,
+    total_projects_drifted   = (SELECT COUNT(*)
+                                FROM drift_analysis_project p
+                                WHERE p.drift_analysis_run_id = r.uuid
+                                  AND p.drifted AND p.succeeded AND NOT p.skipped_due_to_pr)::INT,
+    total_projects_errored   = (SELECT COUNT(*)
+                                FROM drift_analysis_project p
+                                WHERE p.drift_analysis_run_id = r.uuid AND NOT p.succeeded)::INT,
+    total_projects_skipped   = (SELECT COUNT(*)
+                                FROM drift_analysis_project p
+                                WHERE p.drift_analysis_run_id = r.uuid AND p.skipped_due_to_pr)::INT,
+    analysis_duration_millis = COALESCE((SELECT MAX(s.analysis_duration_millis)
+                                         FROM drift_analysis_run_shard s
+                                         WHERE s.drift_analysis_run_id = r.uuid), 0)::BIGINT,
+    status                   = 'COMPLETED',
+    running_projects         = '{}',
+    updated_at               = NOW()
+WHERE r.uuid IN (SELECT t.uuid
+                 FROM drift_analysis_run t
+                 WHERE t.status = 'RUNNING'
+                   AND t.expected_shards IS NOT NULL
+                   AND t.updated_at < NOW() - ($1::INTEGER || ' minutes')::INTERVAL
+                 ORDER BY t.updated_at
+                 FOR UPDATE SKIP LOCKED
+                 LIMIT $2)
+RETURNING r.uuid, r.repository_id
+`
+
+type CompleteTimedOutShardedRunsParams struct {
+	TimeoutMinutes int32
+	MaxRows        int32
+}
+
+type CompleteTimedOutShardedRunsRow struct {
+	Uuid         uuid.UUID
+	RepositoryID int64
+}
+
+// Finalizes sharded runs whose missing shards never reported, with whatever the others sent, those
+// that went longest without a report first. The timeout counts from the last shard or progress
+// report. FOR UPDATE SKIP LOCKED makes it safe to run on every API instance at once.
+func (q *Queries) CompleteTimedOutShardedRuns(ctx context.Context, arg CompleteTimedOutShardedRunsParams) ([]CompleteTimedOutShardedRunsRow, error) {
+	rows, err := q.db.Query(ctx, completeTimedOutShardedRuns, arg.TimeoutMinutes, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CompleteTimedOutShardedRunsRow
+	for rows.Next() {
+		var i CompleteTimedOutShardedRunsRow
+		if err := rows.Scan(&i.Uuid, &i.RepositoryID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const countDriftAnalysisProjectsByRunId = `-- name: CountDriftAnalysisProjectsByRunId :one
 SELECT count(*)
 FROM drift_analysis_project
@@ -26,10 +205,23 @@ func (q *Queries) CountDriftAnalysisProjectsByRunId(ctx context.Context, driftAn
 	return count, err
 }
 
+const countDriftAnalysisRunShards = `-- name: CountDriftAnalysisRunShards :one
+SELECT count(*)
+FROM drift_analysis_run_shard
+WHERE drift_analysis_run_id = $1
+`
+
+func (q *Queries) CountDriftAnalysisRunShards(ctx context.Context, driftAnalysisRunID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countDriftAnalysisRunShards, driftAnalysisRunID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createDriftAnalysisProject = `-- name: CreateDriftAnalysisProject :one
 INSERT INTO drift_analysis_project (drift_analysis_run_id, dir, type, drifted, succeeded, init_output, plan_output, skipped_due_to_pr, resources_added, resources_changed, resources_destroyed)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-RETURNING id, drift_analysis_run_id, dir, type, drifted, succeeded, init_output, plan_output, skipped_due_to_pr, resources_added, resources_changed, resources_destroyed
+RETURNING id, drift_analysis_run_id, dir, type, drifted, succeeded, init_output, plan_output, skipped_due_to_pr, resources_added, resources_changed, resources_destroyed, init_output_ref, init_output_size, plan_output_ref, plan_output_size, init_output_id, plan_output_id, redactions, resources_imported, resources_forgotten, resources_moved, outputs_changed, summary_parser_version, resource_changes, drift_fingerprint, init_duration_millis, plan_duration_millis, started_at, finished_at, retries, error_class, core_tool, core_version, provider_versions, warnings, summary_claimed_at
 `
 
 type CreateDriftAnalysisProjectParams struct {
@@ -74,14 +266,45 @@ func (q *Queries) CreateDriftAnalysisProject(ctx context.Context, arg CreateDrif
 		&i.ResourcesAdded,
 		&i.ResourcesChanged,
 		&i.ResourcesDestroyed,
+		&i.InitOutputRef,
+		&i.InitOutputSize,
+		&i.PlanOutputRef,
+		&i.PlanOutputSize,
+		&i.InitOutputID,
+		&i.PlanOutputID,
+		&i.Redactions,
+		&i.ResourcesImported,
+		&i.ResourcesForgotten,
+		&i.ResourcesMoved,
+		&i.OutputsChanged,
+		&i.SummaryParserVersion,
+		&i.ResourceChanges,
+		&i.DriftFingerprint,
+		&i.InitDurationMillis,
+		&i.PlanDurationMillis,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.Retries,
+		&i.ErrorClass,
+		&i.CoreTool,
+		&i.CoreVersion,
+		&i.ProviderVersions,
+		&i.Warnings,
+		&i.SummaryClaimedAt,
 	)
 	return i, err
 }
 
 const createDriftAnalysisRun = `-- name: CreateDriftAnalysisRun :one
-INSERT INTO drift_analysis_run (uuid, repository_id, total_projects, total_projects_drifted, total_projects_errored, total_projects_skipped, analysis_duration_millis, idempotency_key, status)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'COMPLETED')
-RETURNING uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects
+INSERT INTO drift_analysis_run (uuid, repository_id, total_projects, total_projects_drifted, total_projects_errored, total_projects_skipped, analysis_duration_millis, idempotency_key, status, scoped_dirs)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'COMPLETED',
+        (SELECT d.dirs
+         FROM scan_dispatch d
+         WHERE d.repository_id = $2
+           AND d.id::text = $8
+           AND d.kind = 'SCAN'
+           AND cardinality(d.dirs) > 0))
+RETURNING uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects, expected_shards, commit_sha, check_run_id, scoped_dirs
 `
 
 type CreateDriftAnalysisRunParams struct {
@@ -95,6 +318,8 @@ type CreateDriftAnalysisRunParams struct {
 	IdempotencyKey         *string
 }
 
+// A run uploaded with the id of a scan dispatch scoped to some dirs as its Idempotency-Key takes
+// over those dirs as its scoped_dirs.
 func (q *Queries) CreateDriftAnalysisRun(ctx context.Context, arg CreateDriftAnalysisRunParams) (DriftAnalysisRun, error) {
 	row := q.db.QueryRow(ctx, createDriftAnalysisRun,
 		arg.Uuid,
@@ -120,14 +345,24 @@ func (q *Queries) CreateDriftAnalysisRun(ctx context.Context, arg CreateDriftAna
 		&i.IdempotencyKey,
 		&i.Status,
 		&i.RunningProjects,
+		&i.ExpectedShards,
+		&i.CommitSha,
+		&i.CheckRunID,
+		&i.ScopedDirs,
 	)
 	return i, err
 }
 
 const createRunningDriftAnalysisRun = `-- name: CreateRunningDriftAnalysisRun :one
-INSERT INTO drift_analysis_run (uuid, repository_id, total_projects, total_projects_drifted, total_projects_errored, total_projects_skipped, analysis_duration_millis, idempotency_key, status, running_projects)
-VALUES ($1, $2, $3, 0, 0, 0, 0, $4, 'RUNNING', $5)
-RETURNING uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects
+INSERT INTO drift_analysis_run (uuid, repository_id, total_projects, total_projects_drifted, total_projects_errored, total_projects_skipped, analysis_duration_millis, idempotency_key, status, running_projects, scoped_dirs)
+VALUES ($1, $2, $3, 0, 0, 0, 0, $4, 'RUNNING', $5,
+        (SELECT d.dirs
+         FROM scan_dispatch d
+         WHERE d.repository_id = $2
+           AND d.id::text = $4
+           AND d.kind = 'SCAN'
+           AND cardinality(d.dirs) > 0))
+RETURNING uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects, expected_shards, commit_sha, check_run_id, scoped_dirs
 `
 
 type CreateRunningDriftAnalysisRunParams struct {
@@ -138,6 +373,7 @@ type CreateRunningDriftAnalysisRunParams struct {
 	RunningProjects []string
 }
 
+// Takes over the scoped dirs of the run's scan dispatch like CreateDriftAnalysisRun.
 func (q *Queries) CreateRunningDriftAnalysisRun(ctx context.Context, arg CreateRunningDriftAnalysisRunParams) (DriftAnalysisRun, error) {
 	row := q.db.QueryRow(ctx, createRunningDriftAnalysisRun,
 		arg.Uuid,
@@ -160,6 +396,10 @@ func (q *Queries) CreateRunningDriftAnalysisRun(ctx context.Context, arg CreateR
 		&i.IdempotencyKey,
 		&i.Status,
 		&i.RunningProjects,
+		&i.ExpectedShards,
+		&i.CommitSha,
+		&i.CheckRunID,
+		&i.ScopedDirs,
 	)
 	return i, err
 }
@@ -196,14 +436,37 @@ func (q *Queries) DeleteOldestRunsExceedingLimit(ctx context.Context, arg Delete
 	return err
 }
 
-const deleteStaleRunningRuns = `-- name: DeleteStaleRunningRuns :execrows
+const deleteRunningDriftAnalysisRun = `-- name: DeleteRunningDriftAnalysisRun :exec
 DELETE FROM drift_analysis_run
-WHERE uuid IN (SELECT r.uuid
-               FROM drift_analysis_run r
-               WHERE r.status = 'RUNNING'
-                 AND r.updated_at < NOW() - ($1::INTEGER || ' minutes')::INTERVAL
-               FOR UPDATE SKIP LOCKED
-               LIMIT $2)
+WHERE uuid = $1 AND status = 'RUNNING'
+`
+
+// Drops a run whose upload failed and cannot be resumed. Project rows go via ON DELETE CASCADE.
+func (q *Queries) DeleteRunningDriftAnalysisRun(ctx context.Context, argUuid uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteRunningDriftAnalysisRun, argUuid)
+	return err
+}
+
+const deleteStaleRunningRuns = `-- name: DeleteStaleRunningRuns :one
+WITH deleted AS (
+    DELETE FROM drift_analysis_run
+    WHERE uuid IN (SELECT r.uuid
+                   FROM drift_analysis_run r
+                   WHERE r.status = 'RUNNING'
+                     AND r.expected_shards IS NULL
+                     AND r.updated_at < NOW() - ($1::INTEGER || ' minutes')::INTERVAL
+                   FOR UPDATE SKIP LOCKED
+                   LIMIT $2)
+    RETURNING uuid, repository_id, check_run_id
+), queued AS (
+    INSERT INTO check_run_timeout (check_run_id, run_id, repository_id)
+    SELECT check_run_id, uuid, repository_id
+    FROM deleted
+    WHERE check_run_id IS NOT NULL
+    ON CONFLICT DO NOTHING
+)
+SELECT count(*)
+FROM deleted
 `
 
 type DeleteStaleRunningRunsParams struct {
@@ -211,19 +474,20 @@ type DeleteStaleRunningRunsParams struct {
 	MaxRows      int32
 }
 
-// Collects runs abandoned by a crashed CLI. FOR UPDATE SKIP LOCKED keeps concurrent sweepers on
-// other API instances from blocking or double-deleting, and skips a run whose progress update is
-// in flight. Project rows go via ON DELETE CASCADE.
+// Collects runs abandoned by a crashed CLI and returns how many it deleted. FOR UPDATE SKIP LOCKED
+// keeps concurrent sweepers on other API instances from blocking or double-deleting, and skips a
+// run whose progress update is in flight. Project rows go via ON DELETE CASCADE. Sharded runs are
+// left to CompleteTimedOutShardedRuns, which keeps the results the reporting shards already sent.
+// The check runs of the deleted runs are queued in check_run_timeout by the same statement.
 func (q *Queries) DeleteStaleRunningRuns(ctx context.Context, arg DeleteStaleRunningRunsParams) (int64, error) {
-	result, err := q.db.Exec(ctx, deleteStaleRunningRuns, arg.StaleMinutes, arg.MaxRows)
-	if err != nil {
-		return 0, err
-	}
-	return result.RowsAffected(), nil
+	row := q.db.QueryRow(ctx, deleteStaleRunningRuns, arg.StaleMinutes, arg.MaxRows)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
 }
 
 const findDriftAnalysisProjectsByRunId = `-- name: FindDriftAnalysisProjectsByRunId :many
-SELECT id, drift_analysis_run_id, dir, type, drifted, succeeded, init_output, plan_output, skipped_due_to_pr, resources_added, resources_changed, resources_destroyed
+SELECT id, drift_analysis_run_id, dir, type, drifted, succeeded, init_output, plan_output, skipped_due_to_pr, resources_added, resources_changed, resources_destroyed, init_output_ref, init_output_size, plan_output_ref, plan_output_size, init_output_id, plan_output_id, redactions, resources_imported, resources_forgotten, resources_moved, outputs_changed, summary_parser_version, resource_changes, drift_fingerprint, init_duration_millis, plan_duration_millis, started_at, finished_at, retries, error_class, core_tool, core_version, provider_versions, warnings, summary_claimed_at
 FROM drift_analysis_project
 WHERE drift_analysis_run_id = $1
 ORDER BY
@@ -258,6 +522,31 @@ func (q *Queries) FindDriftAnalysisProjectsByRunId(ctx context.Context, driftAna
 			&i.ResourcesAdded,
 			&i.ResourcesChanged,
 			&i.ResourcesDestroyed,
+			&i.InitOutputRef,
+			&i.InitOutputSize,
+			&i.PlanOutputRef,
+			&i.PlanOutputSize,
+			&i.InitOutputID,
+			&i.PlanOutputID,
+			&i.Redactions,
+			&i.ResourcesImported,
+			&i.ResourcesForgotten,
+			&i.ResourcesMoved,
+			&i.OutputsChanged,
+			&i.SummaryParserVersion,
+			&i.ResourceChanges,
+			&i.DriftFingerprint,
+			&i.InitDurationMillis,
+			&i.PlanDurationMillis,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.Retries,
+			&i.ErrorClass,
+			&i.CoreTool,
+			&i.CoreVersion,
+			&i.ProviderVersions,
+			&i.Warnings,
+			&i.SummaryClaimedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -270,7 +559,7 @@ func (q *Queries) FindDriftAnalysisProjectsByRunId(ctx context.Context, driftAna
 }
 
 const findDriftAnalysisRunByRepoAndIdempotencyKey = `-- name: FindDriftAnalysisRunByRepoAndIdempotencyKey :one
-SELECT uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects
+SELECT uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects, expected_shards, commit_sha, check_run_id, scoped_dirs
 FROM drift_analysis_run
 WHERE repository_id = $1 AND idempotency_key = $2
 `
@@ -296,12 +585,16 @@ func (q *Queries) FindDriftAnalysisRunByRepoAndIdempotencyKey(ctx context.Contex
 		&i.IdempotencyKey,
 		&i.Status,
 		&i.RunningProjects,
+		&i.ExpectedShards,
+		&i.CommitSha,
+		&i.CheckRunID,
+		&i.ScopedDirs,
 	)
 	return i, err
 }
 
 const findDriftAnalysisRunByUUID = `-- name: FindDriftAnalysisRunByUUID :one
-SELECT uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects
+SELECT uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects, expected_shards, commit_sha, check_run_id, scoped_dirs
 FROM drift_analysis_run
 WHERE uuid = $1
 `
@@ -322,12 +615,16 @@ func (q *Queries) FindDriftAnalysisRunByUUID(ctx context.Context, argUuid uuid.U
 		&i.IdempotencyKey,
 		&i.Status,
 		&i.RunningProjects,
+		&i.ExpectedShards,
+		&i.CommitSha,
+		&i.CheckRunID,
+		&i.ScopedDirs,
 	)
 	return i, err
 }
 
 const findDriftAnalysisRunsByRepositoryId = `-- name: FindDriftAnalysisRunsByRepositoryId :many
-SELECT uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects
+SELECT uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects, expected_shards, commit_sha, check_run_id, scoped_dirs
 FROM drift_analysis_run
 WHERE repository_id = $1
 ORDER BY created_at DESC
@@ -362,6 +659,134 @@ func (q *Queries) FindDriftAnalysisRunsByRepositoryId(ctx context.Context, arg F
 			&i.IdempotencyKey,
 			&i.Status,
 			&i.RunningProjects,
+			&i.ExpectedShards,
+			&i.CommitSha,
+			&i.CheckRunID,
+			&i.ScopedDirs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findDriftAnalysisRunsByRepositoryIdAndDirs = `-- name: FindDriftAnalysisRunsByRepositoryIdAndDirs :many
+SELECT uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects, expected_shards, commit_sha, check_run_id, scoped_dirs
+FROM drift_analysis_run
+WHERE repository_id = $1
+  AND EXISTS (SELECT 1
+              FROM drift_analysis_project dap
+              WHERE dap.drift_analysis_run_id = drift_analysis_run.uuid
+                AND dap.dir = ANY ($2::TEXT[])
+                AND dap.drifted AND dap.succeeded AND NOT dap.skipped_due_to_pr)
+ORDER BY created_at DESC
+OFFSET $3 LIMIT $4
+`
+
+type FindDriftAnalysisRunsByRepositoryIdAndDirsParams struct {
+	RepositoryID int64
+	Dirs         []string
+	Queryoffset  int32
+	Maxresults   int32
+}
+
+// Runs of a repository in which one of the projects in dirs drifted, newest first
+func (q *Queries) FindDriftAnalysisRunsByRepositoryIdAndDirs(ctx context.Context, arg FindDriftAnalysisRunsByRepositoryIdAndDirsParams) ([]DriftAnalysisRun, error) {
+	rows, err := q.db.Query(ctx, findDriftAnalysisRunsByRepositoryIdAndDirs,
+		arg.RepositoryID,
+		arg.Dirs,
+		arg.Queryoffset,
+		arg.Maxresults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DriftAnalysisRun
+	for rows.Next() {
+		var i DriftAnalysisRun
+		if err := rows.Scan(
+			&i.Uuid,
+			&i.RepositoryID,
+			&i.TotalProjects,
+			&i.TotalProjectsDrifted,
+			&i.AnalysisDurationMillis,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TotalProjectsErrored,
+			&i.TotalProjectsSkipped,
+			&i.IdempotencyKey,
+			&i.Status,
+			&i.RunningProjects,
+			&i.ExpectedShards,
+			&i.CommitSha,
+			&i.CheckRunID,
+			&i.ScopedDirs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDirsDriftOverTime = `-- name: GetDirsDriftOverTime :many
+SELECT
+    DATE(dar.created_at) AS date,
+    COUNT(DISTINCT dar.uuid)::BIGINT AS total_runs,
+    COUNT(DISTINCT dar.uuid) FILTER (WHERE dap.drifted AND dap.succeeded AND NOT dap.skipped_due_to_pr)::BIGINT AS runs_with_drift,
+    COUNT(*)::BIGINT AS projects_scanned,
+    COUNT(*) FILTER (WHERE dap.drifted AND dap.succeeded AND NOT dap.skipped_due_to_pr)::BIGINT AS projects_drifted,
+    COUNT(*) FILTER (WHERE NOT dap.succeeded AND NOT dap.skipped_due_to_pr)::BIGINT AS projects_errored
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+WHERE dar.repository_id = $1
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - ($2::INTEGER || ' days')::INTERVAL
+  AND dap.dir = ANY ($3::TEXT[])
+GROUP BY DATE(dar.created_at)
+ORDER BY DATE(dar.created_at) ASC
+`
+
+type GetDirsDriftOverTimeParams struct {
+	RepositoryID int64
+	DaysBack     int32
+	Dirs         []string
+}
+
+type GetDirsDriftOverTimeRow struct {
+	Date            pgtype.Date
+	TotalRuns       int64
+	RunsWithDrift   int64
+	ProjectsScanned int64
+	ProjectsDrifted int64
+	ProjectsErrored int64
+}
+
+// Returns daily drift data of the projects in dirs. Only runs that scanned one of them are counted.
+func (q *Queries) GetDirsDriftOverTime(ctx context.Context, arg GetDirsDriftOverTimeParams) ([]GetDirsDriftOverTimeRow, error) {
+	rows, err := q.db.Query(ctx, getDirsDriftOverTime, arg.RepositoryID, arg.DaysBack, arg.Dirs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDirsDriftOverTimeRow
+	for rows.Next() {
+		var i GetDirsDriftOverTimeRow
+		if err := rows.Scan(
+			&i.Date,
+			&i.TotalRuns,
+			&i.RunsWithDrift,
+			&i.ProjectsScanned,
+			&i.ProjectsDrifted,
+			&i.ProjectsErrored,
 		); err != nil {
 			return nil, err
 		}
@@ -455,8 +880,100 @@ func (q *Queries) GetDriftRateOverTime(ctx context.Context, arg GetDriftRateOver
 	return items, nil
 }
 
+const getErrorClassBreakdown = `-- name: GetErrorClassBreakdown :many
+SELECT
+    COALESCE(dap.error_class, 'UNKNOWN')::TEXT AS error_class,
+    COUNT(*)::BIGINT AS project_count,
+    COUNT(DISTINCT dar.uuid)::BIGINT AS run_count,
+    MAX(dar.created_at)::TIMESTAMPTZ AS last_seen_at
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+WHERE dar.repository_id = $1
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - ($2::INTEGER || ' days')::INTERVAL
+  AND dap.succeeded = false
+  AND dap.skipped_due_to_pr = false
+GROUP BY COALESCE(dap.error_class, 'UNKNOWN')
+ORDER BY project_count DESC, error_class
+`
+
+type GetErrorClassBreakdownParams struct {
+	RepositoryID int64
+	DaysBack     int32
+}
+
+type GetErrorClassBreakdownRow struct {
+	ErrorClass   string
+	ProjectCount int64
+	RunCount     int64
+	LastSeenAt   time.Time
+}
+
+// Returns how often failed projects hit each error class. Rows stored before the classifier and not
+// yet backfilled count as UNKNOWN.
+func (q *Queries) GetErrorClassBreakdown(ctx context.Context, arg GetErrorClassBreakdownParams) ([]GetErrorClassBreakdownRow, error) {
+	rows, err := q.db.Query(ctx, getErrorClassBreakdown, arg.RepositoryID, arg.DaysBack)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetErrorClassBreakdownRow
+	for rows.Next() {
+		var i GetErrorClassBreakdownRow
+		if err := rows.Scan(
+			&i.ErrorClass,
+			&i.ProjectCount,
+			&i.RunCount,
+			&i.LastSeenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLatestFullRunForRepository = `-- name: GetLatestFullRunForRepository :one
+SELECT uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects, expected_shards, commit_sha, check_run_id, scoped_dirs
+FROM drift_analysis_run
+WHERE repository_id = $1
+  AND status = 'COMPLETED'
+  AND scoped_dirs IS NULL
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+// The latest completed run that scanned the whole repository, not just the dirs a dispatch scoped
+// it to.
+func (q *Queries) GetLatestFullRunForRepository(ctx context.Context, repositoryID int64) (DriftAnalysisRun, error) {
+	row := q.db.QueryRow(ctx, getLatestFullRunForRepository, repositoryID)
+	var i DriftAnalysisRun
+	err := row.Scan(
+		&i.Uuid,
+		&i.RepositoryID,
+		&i.TotalProjects,
+		&i.TotalProjectsDrifted,
+		&i.AnalysisDurationMillis,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TotalProjectsErrored,
+		&i.TotalProjectsSkipped,
+		&i.IdempotencyKey,
+		&i.Status,
+		&i.RunningProjects,
+		&i.ExpectedShards,
+		&i.CommitSha,
+		&i.CheckRunID,
+		&i.ScopedDirs,
+	)
+	return i, err
+}
+
 const getLatestRunForRepository = `-- name: GetLatestRunForRepository :one
-SELECT uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects
+SELECT uuid, repository_id, total_projects, total_projects_drifted, analysis_duration_millis, created_at, updated_at, total_projects_errored, total_projects_skipped, idempotency_key, status, running_projects, expected_shards, commit_sha, check_run_id, scoped_dirs
 FROM drift_analysis_run
 WHERE repository_id = $1
   AND status = 'COMPLETED'
@@ -480,6 +997,10 @@ func (q *Queries) GetLatestRunForRepository(ctx context.Context, repositoryID in
 		&i.IdempotencyKey,
 		&i.Status,
 		&i.RunningProjects,
+		&i.ExpectedShards,
+		&i.CommitSha,
+		&i.CheckRunID,
+		&i.ScopedDirs,
 	)
 	return i, err
 }
@@ -626,34 +1147,881 @@ func (q *Queries) GetMostFrequentlyDriftedProjects(ctx context.Context, arg GetM
 	return items, nil
 }
 
-const getRepositoryRunStats = `-- name: GetRepositoryRunStats :one
+const getMostFrequentlyDriftedProviders = `-- name: GetMostFrequentlyDriftedProviders :many
 SELECT
-    COUNT(*) AS total_runs,
-    COUNT(*) FILTER (WHERE total_projects_drifted > 0) AS runs_with_drift,
-    MAX(created_at) AS last_run_at
-FROM drift_analysis_run
-WHERE repository_id = $1
+    split_part(rt.resource_type, '_', 1)::TEXT AS provider,
+    COUNT(DISTINCT dar.uuid)::BIGINT AS drift_count,
+    COUNT(DISTINCT (dar.repository_id, dap.dir, rc.address))::BIGINT AS resource_count,
+    COUNT(DISTINCT dar.repository_id)::BIGINT AS repository_count
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+JOIN git_repository gr ON dar.repository_id = gr.id
+CROSS JOIN LATERAL jsonb_to_recordset(dap.resource_changes) AS rc(address TEXT, action TEXT)
+CROSS JOIN LATERAL substring(rc.address FROM '^(?:module\.[^.\[]+(?:\[[^\]]*\])?\.)*(?:data\.)?([^.\[]+)') AS rt(resource_type)
+WHERE ($1::BIGINT IS NULL OR dar.repository_id = $1)
+  AND ($2::BIGINT IS NULL OR gr.organization_id = $2)
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - ($3::INTEGER || ' days')::INTERVAL
+  AND dap.drifted = true
+  AND dap.succeeded = true
+  AND dap.skipped_due_to_pr = false
+  AND rc.action <> 'READ'
+GROUP BY provider
+ORDER BY drift_count DESC, resource_count DESC, provider
+LIMIT $4
 `
 
-type GetRepositoryRunStatsRow struct {
-	TotalRuns     int64
-	RunsWithDrift int64
-	LastRunAt     interface{}
+type GetMostFrequentlyDriftedProvidersParams struct {
+	RepositoryID   *int64
+	OrganizationID *int64
+	DaysBack       int32
+	MaxResults     int32
 }
 
-func (q *Queries) GetRepositoryRunStats(ctx context.Context, repositoryID int64) (GetRepositoryRunStatsRow, error) {
-	row := q.db.QueryRow(ctx, getRepositoryRunStats, repositoryID)
-	var i GetRepositoryRunStatsRow
-	err := row.Scan(&i.TotalRuns, &i.RunsWithDrift, &i.LastRunAt)
-	return i, err
+type GetMostFrequentlyDriftedProvidersRow struct {
+	Provider        string
+	DriftCount      int64
+	ResourceCount   int64
+	RepositoryCount int64
 }
 
-const markDriftAnalysisRunCompleted = `-- name: MarkDriftAnalysisRunCompleted :exec
-UPDATE drift_analysis_run
-SET total_projects           = $1,
-    total_projects_drifted   = $2,
-    total_projects_errored   = $3,
-    total_projects_skipped   = $4,
+// Returns providers ranked by how many drifted runs changed one of their resources (top N), scoped
+// and counted like GetMostFrequentlyDriftedResourceTypes. The provider is the prefix of the
+// resource type, the same way Terraform infers it.
+func (q *Queries) GetMostFrequentlyDriftedProviders(ctx context.Context, arg GetMostFrequentlyDriftedProvidersParams) ([]GetMostFrequentlyDriftedProvidersRow, error) {
+	rows, err := q.db.Query(ctx, getMostFrequentlyDriftedProviders,
+		arg.RepositoryID,
+		arg.OrganizationID,
+		arg.DaysBack,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMostFrequentlyDriftedProvidersRow
+	for rows.Next() {
+		var i GetMostFrequentlyDriftedProvidersRow
+		if err := rows.Scan(
+			&i.Provider,
+			&i.DriftCount,
+			&i.ResourceCount,
+			&i.RepositoryCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMostFrequentlyDriftedResourceTypes = `-- name: GetMostFrequentlyDriftedResourceTypes :many
+SELECT
+    rt.resource_type::TEXT AS resource_type,
+    COUNT(DISTINCT dar.uuid)::BIGINT AS drift_count,
+    COUNT(DISTINCT (dar.repository_id, dap.dir, rc.address))::BIGINT AS resource_count,
+    COUNT(DISTINCT dar.repository_id)::BIGINT AS repository_count
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+JOIN git_repository gr ON dar.repository_id = gr.id
+CROSS JOIN LATERAL jsonb_to_recordset(dap.resource_changes) AS rc(address TEXT, action TEXT)
+CROSS JOIN LATERAL substring(rc.address FROM '^(?:module\.[^.\[]+(?:\[[^\]]*\])?\.)*(?:data\.)?([^.\[]+)') AS rt(resource_type)
+WHERE ($1::BIGINT IS NULL OR dar.repository_id = $1)
+  AND ($2::BIGINT IS NULL OR gr.organization_id = $2)
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - ($3::INTEGER || ' days')::INTERVAL
+  AND dap.drifted = true
+  AND dap.succeeded = true
+  AND dap.skipped_due_to_pr = false
+  AND rc.action <> 'READ'
+GROUP BY rt.resource_type
+ORDER BY drift_count DESC, resource_count DESC, resource_type
+LIMIT $4
+`
+
+type GetMostFrequentlyDriftedResourceTypesParams struct {
+	RepositoryID   *int64
+	OrganizationID *int64
+	DaysBack       int32
+	MaxResults     int32
+}
+
+type GetMostFrequentlyDriftedResourceTypesRow struct {
+	ResourceType    string
+	DriftCount      int64
+	ResourceCount   int64
+	RepositoryCount int64
+}
+
+// Returns resource types ranked by how many drifted runs changed a resource of that type (top N),
+// in one repository or across every repository of an organization. The type is the address segment
+// after any module path, e.g. aws_security_group. Only projects that planned and were not skipped
+// count, as in every other drift metric.
+func (q *Queries) GetMostFrequentlyDriftedResourceTypes(ctx context.Context, arg GetMostFrequentlyDriftedResourceTypesParams) ([]GetMostFrequentlyDriftedResourceTypesRow, error) {
+	rows, err := q.db.Query(ctx, getMostFrequentlyDriftedResourceTypes,
+		arg.RepositoryID,
+		arg.OrganizationID,
+		arg.DaysBack,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMostFrequentlyDriftedResourceTypesRow
+	for rows.Next() {
+		var i GetMostFrequentlyDriftedResourceTypesRow
+		if err := rows.Scan(
+			&i.ResourceType,
+			&i.DriftCount,
+			&i.ResourceCount,
+			&i.RepositoryCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMostFrequentlyDriftedResources = `-- name: GetMostFrequentlyDriftedResources :many
+SELECT
+    dap.dir,
+    rc.address::TEXT AS address,
+    COUNT(DISTINCT dar.uuid)::BIGINT AS drift_count
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+CROSS JOIN LATERAL jsonb_to_recordset(dap.resource_changes) AS rc(address TEXT, action TEXT)
+WHERE dar.repository_id = $1
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - ($2::INTEGER || ' days')::INTERVAL
+  AND dap.drifted = true
+  AND dap.succeeded = true
+  AND dap.skipped_due_to_pr = false
+  AND rc.action <> 'READ'
+GROUP BY dap.dir, rc.address
+ORDER BY drift_count DESC, dap.dir, address
+LIMIT $3
+`
+
+type GetMostFrequentlyDriftedResourcesParams struct {
+	RepositoryID int64
+	DaysBack     int32
+	MaxResults   int32
+}
+
+type GetMostFrequentlyDriftedResourcesRow struct {
+	Dir        string
+	Address    string
+	DriftCount int64
+}
+
+// Returns resource addresses ranked by how many drifted runs named them (top N). Data sources read
+// during apply are not drift and are left out, and only projects that planned and were not skipped
+// count.
+func (q *Queries) GetMostFrequentlyDriftedResources(ctx context.Context, arg GetMostFrequentlyDriftedResourcesParams) ([]GetMostFrequentlyDriftedResourcesRow, error) {
+	rows, err := q.db.Query(ctx, getMostFrequentlyDriftedResources, arg.RepositoryID, arg.DaysBack, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMostFrequentlyDriftedResourcesRow
+	for rows.Next() {
+		var i GetMostFrequentlyDriftedResourcesRow
+		if err := rows.Scan(&i.Dir, &i.Address, &i.DriftCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOrganizationVersionInventory = `-- name: GetOrganizationVersionInventory :many
+SELECT DISTINCT ON (gr.id, dap.dir)
+    gr.id AS repository_id,
+    gr.name AS repository_name,
+    dap.dir,
+    dap.type,
+    dap.core_tool,
+    dap.core_version,
+    dap.provider_versions,
+    dar.created_at AS last_run_at
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+JOIN git_repository gr ON dar.repository_id = gr.id
+WHERE gr.organization_id = $1
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - ($2::INTEGER || ' days')::INTERVAL
+  AND (dap.core_version IS NOT NULL OR dap.provider_versions IS NOT NULL)
+ORDER BY gr.id, dap.dir, dar.created_at DESC
+`
+
+type GetOrganizationVersionInventoryParams struct {
+	OrganizationID int64
+	DaysBack       int32
+}
+
+type GetOrganizationVersionInventoryRow struct {
+	RepositoryID     int64
+	RepositoryName   string
+	Dir              string
+	Type             string
+	CoreTool         *string
+	CoreVersion      *string
+	ProviderVersions []byte
+	LastRunAt        time.Time
+}
+
+// Returns the latest core and provider versions reported by each project of an organization's
+// repositories within the window. Projects whose init output named no version are left out.
+func (q *Queries) GetOrganizationVersionInventory(ctx context.Context, arg GetOrganizationVersionInventoryParams) ([]GetOrganizationVersionInventoryRow, error) {
+	rows, err := q.db.Query(ctx, getOrganizationVersionInventory, arg.OrganizationID, arg.DaysBack)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOrganizationVersionInventoryRow
+	for rows.Next() {
+		var i GetOrganizationVersionInventoryRow
+		if err := rows.Scan(
+			&i.RepositoryID,
+			&i.RepositoryName,
+			&i.Dir,
+			&i.Type,
+			&i.CoreTool,
+			&i.CoreVersion,
+			&i.ProviderVersions,
+			&i.LastRunAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPreviousRunDriftFingerprints = `-- name: GetPreviousRunDriftFingerprints :many
+SELECT dap.dir, dap.drift_fingerprint
+FROM drift_analysis_project dap
+WHERE dap.drifted = true
+  AND dap.drift_analysis_run_id = (
+    SELECT prev.uuid
+    FROM drift_analysis_run prev
+    JOIN drift_analysis_run cur ON cur.repository_id = prev.repository_id
+    WHERE cur.uuid = $1
+      AND prev.status = 'COMPLETED'
+      AND prev.created_at < cur.created_at
+    ORDER BY prev.created_at DESC
+    LIMIT 1
+  )
+`
+
+type GetPreviousRunDriftFingerprintsRow struct {
+	Dir              string
+	DriftFingerprint *string
+}
+
+// Returns the drifted projects of the repository's last completed run before the given one, with
+// their fingerprints. Empty when there is no earlier run.
+func (q *Queries) GetPreviousRunDriftFingerprints(ctx context.Context, argUuid uuid.UUID) ([]GetPreviousRunDriftFingerprintsRow, error) {
+	rows, err := q.db.Query(ctx, getPreviousRunDriftFingerprints, argUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPreviousRunDriftFingerprintsRow
+	for rows.Next() {
+		var i GetPreviousRunDriftFingerprintsRow
+		if err := rows.Scan(&i.Dir, &i.DriftFingerprint); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProjectDriftPeriods = `-- name: GetProjectDriftPeriods :many
+WITH project_states AS (
+    SELECT
+        dap.type,
+        dar.created_at,
+        dap.drifted,
+        LAG(dap.drifted) OVER (PARTITION BY dap.type ORDER BY dar.created_at) AS prev_drifted,
+        LEAD(dar.created_at) OVER (PARTITION BY dap.type ORDER BY dar.created_at) AS next_created_at
+    FROM drift_analysis_project dap
+    JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+    WHERE dar.repository_id = $1
+      AND dap.dir = $2
+      AND dar.status = 'COMPLETED'
+      AND dap.succeeded = true
+      AND dap.skipped_due_to_pr = false
+),
+drift_starts AS (
+    SELECT type, created_at AS started_at, ROW_NUMBER() OVER (PARTITION BY type ORDER BY created_at) AS start_num
+    FROM project_states
+    WHERE drifted = true AND (prev_drifted IS NULL OR prev_drifted = false)
+),
+drift_ends AS (
+    SELECT type, ended_at, ROW_NUMBER() OVER (PARTITION BY type ORDER BY ended_at) AS end_num
+    FROM (SELECT type, created_at AS ended_at
+          FROM project_states
+          WHERE drifted = false AND prev_drifted = true
+          UNION ALL
+          SELECT last.type, MIN(other.created_at)
+          FROM project_states last
+          JOIN project_states other ON other.type <> last.type AND other.created_at > last.created_at
+          WHERE last.drifted = true AND last.next_created_at IS NULL
+          GROUP BY last.type) ends
+)
+SELECT s.type, s.started_at, e.ended_at
+FROM drift_starts s
+LEFT JOIN drift_ends e ON e.type = s.type AND e.end_num = s.start_num
+WHERE ($3::VARCHAR IS NULL OR s.type = $3)
+ORDER BY s.started_at DESC, s.type
+`
+
+type GetProjectDriftPeriodsParams struct {
+	RepositoryID int64
+	Dir          string
+	Type         *string
+}
+
+type GetProjectDriftPeriodsRow struct {
+	Type      string
+	StartedAt time.Time
+	EndedAt   *time.Time
+}
+
+// Returns the periods the project in dir stayed drifted, newest first, using the same transitions
+// as GetMeanTimeToResolution. Runs where the project failed or was skipped are left out, since they
+// say nothing about its drift. A dir whose project changed type is tracked per type, so the runs of
+// the old type cannot end or start a drift of the new one; a drift the old type's last run left open
+// ends with the first later run of another type. ended_at is NULL while the drift is ongoing.
+func (q *Queries) GetProjectDriftPeriods(ctx context.Context, arg GetProjectDriftPeriodsParams) ([]GetProjectDriftPeriodsRow, error) {
+	rows, err := q.db.Query(ctx, getProjectDriftPeriods, arg.RepositoryID, arg.Dir, arg.Type)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetProjectDriftPeriodsRow
+	for rows.Next() {
+		var i GetProjectDriftPeriodsRow
+		if err := rows.Scan(&i.Type, &i.StartedAt, &i.EndedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProjectDurationRegressions = `-- name: GetProjectDurationRegressions :many
+WITH project_durations AS (
+    SELECT
+        dap.dir,
+        dar.created_at >= NOW() - ($1::INTEGER * 12 || ' hours')::INTERVAL AS recent,
+        COALESCE(
+            (EXTRACT(EPOCH FROM (dap.finished_at - dap.started_at)) * 1000)::BIGINT,
+            dap.init_duration_millis + dap.plan_duration_millis,
+            dap.plan_duration_millis,
+            dap.init_duration_millis
+        ) AS duration_millis
+    FROM drift_analysis_project dap
+    JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+    WHERE dar.repository_id = $2
+      AND dar.status = 'COMPLETED'
+      AND dar.created_at >= NOW() - ($1::INTEGER || ' days')::INTERVAL
+),
+halves AS (
+    SELECT
+        dir,
+        AVG(duration_millis) FILTER (WHERE NOT recent) AS baseline,
+        AVG(duration_millis) FILTER (WHERE recent) AS latest
+    FROM project_durations
+    WHERE duration_millis IS NOT NULL
+    GROUP BY dir
+)
+SELECT
+    dir,
+    baseline::BIGINT AS baseline_avg_duration_millis,
+    latest::BIGINT AS recent_avg_duration_millis
+FROM halves
+WHERE baseline > 0
+  AND latest >= baseline * (1 + $3::INTEGER / 100.0)
+ORDER BY latest - baseline DESC, dir
+LIMIT $4
+`
+
+type GetProjectDurationRegressionsParams struct {
+	DaysBack           int32
+	RepositoryID       int64
+	MinIncreasePercent int32
+	MaxResults         int32
+}
+
+type GetProjectDurationRegressionsRow struct {
+	Dir                       string
+	BaselineAvgDurationMillis int64
+	RecentAvgDurationMillis   int64
+}
+
+// Returns the projects whose average analysis time in the second half of the window is at least
+// min_increase_percent above the first half, ranked by the increase (top N). Projects measured in
+// only one half are left out.
+func (q *Queries) GetProjectDurationRegressions(ctx context.Context, arg GetProjectDurationRegressionsParams) ([]GetProjectDurationRegressionsRow, error) {
+	rows, err := q.db.Query(ctx, getProjectDurationRegressions,
+		arg.DaysBack,
+		arg.RepositoryID,
+		arg.MinIncreasePercent,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetProjectDurationRegressionsRow
+	for rows.Next() {
+		var i GetProjectDurationRegressionsRow
+		if err := rows.Scan(&i.Dir, &i.BaselineAvgDurationMillis, &i.RecentAvgDurationMillis); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProjectHistory = `-- name: GetProjectHistory :many
+SELECT
+    dar.uuid AS run_id,
+    dar.created_at,
+    dap.type,
+    drift_project_status(dap.skipped_due_to_pr, dap.succeeded, dap.drifted)::VARCHAR AS status,
+    dap.resources_added,
+    dap.resources_changed,
+    dap.resources_destroyed,
+    dap.error_class
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+WHERE dar.repository_id = $1
+  AND dap.dir = $2
+  AND ($3::VARCHAR IS NULL OR dap.type = $3)
+  AND dar.status = 'COMPLETED'
+ORDER BY dar.created_at DESC, dap.type
+LIMIT $4
+`
+
+type GetProjectHistoryParams struct {
+	RepositoryID int64
+	Dir          string
+	Type         *string
+	MaxResults   int32
+}
+
+type GetProjectHistoryRow struct {
+	RunID              uuid.UUID
+	CreatedAt          time.Time
+	Type               string
+	Status             string
+	ResourcesAdded     *int32
+	ResourcesChanged   *int32
+	ResourcesDestroyed *int32
+	ErrorClass         *string
+}
+
+// Returns the outcome of the project in dir in the latest completed runs of a repository, newest
+// first, optionally narrowed to one type
+func (q *Queries) GetProjectHistory(ctx context.Context, arg GetProjectHistoryParams) ([]GetProjectHistoryRow, error) {
+	rows, err := q.db.Query(ctx, getProjectHistory,
+		arg.RepositoryID,
+		arg.Dir,
+		arg.Type,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetProjectHistoryRow
+	for rows.Next() {
+		var i GetProjectHistoryRow
+		if err := rows.Scan(
+			&i.RunID,
+			&i.CreatedAt,
+			&i.Type,
+			&i.Status,
+			&i.ResourcesAdded,
+			&i.ResourcesChanged,
+			&i.ResourcesDestroyed,
+			&i.ErrorClass,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRepeatedWarnings = `-- name: GetRepeatedWarnings :many
+SELECT
+    dap.dir,
+    w.category::TEXT AS category,
+    w.summary::TEXT AS summary,
+    COALESCE(w.address, '')::TEXT AS address,
+    COALESCE(MAX(w.detail), '')::TEXT AS detail,
+    COUNT(DISTINCT dar.uuid)::BIGINT AS run_count,
+    MIN(dar.created_at)::TIMESTAMPTZ AS first_seen_at,
+    MAX(dar.created_at)::TIMESTAMPTZ AS last_seen_at
+FROM drift_analysis_project dap
+JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+CROSS JOIN LATERAL jsonb_to_recordset(dap.warnings) AS w(category TEXT, summary TEXT, detail TEXT, address TEXT)
+WHERE dar.repository_id = $1
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - ($2::INTEGER || ' days')::INTERVAL
+GROUP BY dap.dir, w.category, w.summary, COALESCE(w.address, '')
+HAVING COUNT(DISTINCT dar.uuid) >= 2
+ORDER BY run_count DESC, last_seen_at DESC, dap.dir, summary
+LIMIT $3
+`
+
+type GetRepeatedWarningsParams struct {
+	RepositoryID int64
+	DaysBack     int32
+	MaxResults   int32
+}
+
+type GetRepeatedWarningsRow struct {
+	Dir         string
+	Category    string
+	Summary     string
+	Address     string
+	Detail      string
+	RunCount    int64
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+}
+
+// Returns the warnings a project printed in at least two runs of the window, the most repeated
+// first (top N).
+func (q *Queries) GetRepeatedWarnings(ctx context.Context, arg GetRepeatedWarningsParams) ([]GetRepeatedWarningsRow, error) {
+	rows, err := q.db.Query(ctx, getRepeatedWarnings, arg.RepositoryID, arg.DaysBack, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRepeatedWarningsRow
+	for rows.Next() {
+		var i GetRepeatedWarningsRow
+		if err := rows.Scan(
+			&i.Dir,
+			&i.Category,
+			&i.Summary,
+			&i.Address,
+			&i.Detail,
+			&i.RunCount,
+			&i.FirstSeenAt,
+			&i.LastSeenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRepositoryRunStats = `-- name: GetRepositoryRunStats :one
+SELECT
+    COUNT(*) AS total_runs,
+    COUNT(*) FILTER (WHERE total_projects_drifted > 0) AS runs_with_drift,
+    MAX(created_at) AS last_run_at
+FROM drift_analysis_run
+WHERE repository_id = $1
+`
+
+type GetRepositoryRunStatsRow struct {
+	TotalRuns     int64
+	RunsWithDrift int64
+	LastRunAt     interface{}
+}
+
+func (q *Queries) GetRepositoryRunStats(ctx context.Context, repositoryID int64) (GetRepositoryRunStatsRow, error) {
+	row := q.db.QueryRow(ctx, getRepositoryRunStats, repositoryID)
+	var i GetRepositoryRunStatsRow
+	err := row.Scan(&i.TotalRuns, &i.RunsWithDrift, &i.LastRunAt)
+	return i, err
+}
+
+const getRunCoverageChanges = `-- name: GetRunCoverageChanges :many
+SELECT dir, type, change
+FROM drift_analysis_run_coverage_change
+WHERE drift_analysis_run_id = $1
+ORDER BY change DESC, dir
+`
+
+type GetRunCoverageChangesRow struct {
+	Dir    string
+	Type   string
+	Change string
+}
+
+// Returns the dirs a run added to or dropped from the previous completed run, removals first
+func (q *Queries) GetRunCoverageChanges(ctx context.Context, driftAnalysisRunID uuid.UUID) ([]GetRunCoverageChangesRow, error) {
+	rows, err := q.db.Query(ctx, getRunCoverageChanges, driftAnalysisRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRunCoverageChangesRow
+	for rows.Next() {
+		var i GetRunCoverageChangesRow
+		if err := rows.Scan(&i.Dir, &i.Type, &i.Change); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRunErrorClassBreakdown = `-- name: GetRunErrorClassBreakdown :many
+SELECT
+    COALESCE(error_class, 'UNKNOWN')::TEXT AS error_class,
+    COUNT(*)::BIGINT AS project_count
+FROM drift_analysis_project
+WHERE drift_analysis_run_id = $1
+  AND succeeded = false
+  AND skipped_due_to_pr = false
+GROUP BY COALESCE(error_class, 'UNKNOWN')
+ORDER BY project_count DESC, error_class
+`
+
+type GetRunErrorClassBreakdownRow struct {
+	ErrorClass   string
+	ProjectCount int64
+}
+
+// Returns the number of failed projects of a run per error class.
+func (q *Queries) GetRunErrorClassBreakdown(ctx context.Context, driftAnalysisRunID uuid.UUID) ([]GetRunErrorClassBreakdownRow, error) {
+	rows, err := q.db.Query(ctx, getRunErrorClassBreakdown, driftAnalysisRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRunErrorClassBreakdownRow
+	for rows.Next() {
+		var i GetRunErrorClassBreakdownRow
+		if err := rows.Scan(&i.ErrorClass, &i.ProjectCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRunProjectStatuses = `-- name: GetRunProjectStatuses :many
+SELECT
+    dir,
+    type,
+    drift_project_status(skipped_due_to_pr, succeeded, drifted)::VARCHAR AS status
+FROM drift_analysis_project
+WHERE drift_analysis_run_id = $1
+ORDER BY dir, type
+`
+
+type GetRunProjectStatusesRow struct {
+	Dir    string
+	Type   string
+	Status string
+}
+
+// Returns the dir, type and status of each project of a run.
+func (q *Queries) GetRunProjectStatuses(ctx context.Context, driftAnalysisRunID uuid.UUID) ([]GetRunProjectStatusesRow, error) {
+	rows, err := q.db.Query(ctx, getRunProjectStatuses, driftAnalysisRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRunProjectStatusesRow
+	for rows.Next() {
+		var i GetRunProjectStatusesRow
+		if err := rows.Scan(&i.Dir, &i.Type, &i.Status); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSlowestProjects = `-- name: GetSlowestProjects :many
+WITH project_durations AS (
+    SELECT
+        dap.dir,
+        dap.type,
+        dap.init_duration_millis,
+        dap.plan_duration_millis,
+        dap.retries,
+        COALESCE(
+            (EXTRACT(EPOCH FROM (dap.finished_at - dap.started_at)) * 1000)::BIGINT,
+            dap.init_duration_millis + dap.plan_duration_millis,
+            dap.plan_duration_millis,
+            dap.init_duration_millis
+        ) AS duration_millis
+    FROM drift_analysis_project dap
+    JOIN drift_analysis_run dar ON dap.drift_analysis_run_id = dar.uuid
+    WHERE dar.repository_id = $1
+      AND dar.status = 'COMPLETED'
+      AND dar.created_at >= NOW() - ($2::INTEGER || ' days')::INTERVAL
+)
+SELECT
+    dir,
+    type,
+    COUNT(*)::BIGINT AS run_count,
+    AVG(duration_millis)::BIGINT AS avg_duration_millis,
+    MAX(duration_millis)::BIGINT AS max_duration_millis,
+    COALESCE(AVG(init_duration_millis), 0)::BIGINT AS avg_init_duration_millis,
+    COALESCE(AVG(plan_duration_millis), 0)::BIGINT AS avg_plan_duration_millis,
+    COALESCE(SUM(retries), 0)::BIGINT AS total_retries
+FROM project_durations
+WHERE duration_millis IS NOT NULL
+GROUP BY dir, type
+ORDER BY avg_duration_millis DESC, dir
+LIMIT $3
+`
+
+type GetSlowestProjectsParams struct {
+	RepositoryID int64
+	DaysBack     int32
+	MaxResults   int32
+}
+
+type GetSlowestProjectsRow struct {
+	Dir                   string
+	Type                  string
+	RunCount              int64
+	AvgDurationMillis     int64
+	MaxDurationMillis     int64
+	AvgInitDurationMillis int64
+	AvgPlanDurationMillis int64
+	TotalRetries          int64
+}
+
+// Returns the projects with the longest average analysis time (top N). A project's time is the
+// span from started_at to finished_at when reported, which includes retries, else init plus plan.
+func (q *Queries) GetSlowestProjects(ctx context.Context, arg GetSlowestProjectsParams) ([]GetSlowestProjectsRow, error) {
+	rows, err := q.db.Query(ctx, getSlowestProjects, arg.RepositoryID, arg.DaysBack, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSlowestProjectsRow
+	for rows.Next() {
+		var i GetSlowestProjectsRow
+		if err := rows.Scan(
+			&i.Dir,
+			&i.Type,
+			&i.RunCount,
+			&i.AvgDurationMillis,
+			&i.MaxDurationMillis,
+			&i.AvgInitDurationMillis,
+			&i.AvgPlanDurationMillis,
+			&i.TotalRetries,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWarningCountsPerRun = `-- name: GetWarningCountsPerRun :many
+SELECT
+    dar.uuid AS run_id,
+    dar.created_at,
+    COALESCE(SUM(w.occurrences), 0)::BIGINT AS warning_count
+FROM drift_analysis_run dar
+LEFT JOIN drift_analysis_project dap ON dap.drift_analysis_run_id = dar.uuid
+LEFT JOIN LATERAL jsonb_to_recordset(dap.warnings) AS w(occurrences INT) ON true
+WHERE dar.repository_id = $1
+  AND dar.status = 'COMPLETED'
+  AND dar.created_at >= NOW() - ($2::INTEGER || ' days')::INTERVAL
+GROUP BY dar.uuid, dar.created_at
+ORDER BY dar.created_at
+`
+
+type GetWarningCountsPerRunParams struct {
+	RepositoryID int64
+	DaysBack     int32
+}
+
+type GetWarningCountsPerRunRow struct {
+	RunID        uuid.UUID
+	CreatedAt    time.Time
+	WarningCount int64
+}
+
+// Returns the number of warnings of each completed run in the window, oldest first.
+func (q *Queries) GetWarningCountsPerRun(ctx context.Context, arg GetWarningCountsPerRunParams) ([]GetWarningCountsPerRunRow, error) {
+	rows, err := q.db.Query(ctx, getWarningCountsPerRun, arg.RepositoryID, arg.DaysBack)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetWarningCountsPerRunRow
+	for rows.Next() {
+		var i GetWarningCountsPerRunRow
+		if err := rows.Scan(&i.RunID, &i.CreatedAt, &i.WarningCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markDriftAnalysisRunCompleted = `-- name: MarkDriftAnalysisRunCompleted :exec
+UPDATE drift_analysis_run
+SET total_projects           = $1,
+    total_projects_drifted   = $2,
+    total_projects_errored   = $3,
+    total_projects_skipped   = $4,
     analysis_duration_millis = $5,
     status                   = 'COMPLETED',
     running_projects         = '{}',
@@ -682,6 +2050,265 @@ func (q *Queries) MarkDriftAnalysisRunCompleted(ctx context.Context, arg MarkDri
 	return err
 }
 
+const recordDriftAnalysisRunCoverageChanges = `-- name: RecordDriftAnalysisRunCoverageChanges :exec
+WITH full_runs AS (
+    SELECT r.uuid, r.repository_id, r.status, r.created_at
+    FROM drift_analysis_run r
+    WHERE r.scoped_dirs IS NULL
+      AND (r.expected_shards IS NULL
+        OR (SELECT COUNT(*) FROM drift_analysis_run_shard s WHERE s.drift_analysis_run_id = r.uuid) >= r.expected_shards)
+),
+current_run AS (
+    SELECT uuid, repository_id, created_at
+    FROM full_runs
+    WHERE uuid = $1
+),
+previous_run AS (
+    SELECT prev.uuid
+    FROM full_runs prev
+    JOIN current_run cur ON prev.repository_id = cur.repository_id
+    WHERE prev.status = 'COMPLETED'
+      AND prev.uuid <> cur.uuid
+      AND prev.created_at < cur.created_at
+    ORDER BY prev.created_at DESC
+    LIMIT 1
+),
+current_projects AS (
+    SELECT dap.dir, dap.type
+    FROM drift_analysis_project dap
+    JOIN current_run cur ON dap.drift_analysis_run_id = cur.uuid
+),
+previous_projects AS (
+    SELECT dap.dir, dap.type
+    FROM drift_analysis_project dap
+    JOIN previous_run prev ON dap.drift_analysis_run_id = prev.uuid
+)
+INSERT INTO drift_analysis_run_coverage_change (drift_analysis_run_id, dir, type, change)
+SELECT cur.uuid, c.dir, c.type, 'ADDED'
+FROM current_projects c, current_run cur
+WHERE EXISTS (SELECT 1 FROM previous_run)
+  AND NOT EXISTS (SELECT 1 FROM previous_projects p WHERE p.dir = c.dir)
+UNION ALL
+SELECT cur.uuid, p.dir, p.type, 'REMOVED'
+FROM previous_projects p, current_run cur
+WHERE NOT EXISTS (SELECT 1 FROM current_projects c WHERE c.dir = p.dir)
+ON CONFLICT DO NOTHING
+`
+
+// Records the dirs a run added to or dropped from the previous completed run of its repository.
+// Must run in the transaction that completes the run. The first run of a repository has nothing to
+// compare with and records nothing. Only runs that cover the whole repository are compared: a
+// sharded run missing shards or a run scoped to some dirs records nothing and is never the
+// previous run of another.
+func (q *Queries) RecordDriftAnalysisRunCoverageChanges(ctx context.Context, runID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, recordDriftAnalysisRunCoverageChanges, runID)
+	return err
+}
+
+const recordDriftAnalysisRunShard = `-- name: RecordDriftAnalysisRunShard :exec
+INSERT INTO drift_analysis_run_shard (drift_analysis_run_id, shard_id, total_projects, analysis_duration_millis)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (drift_analysis_run_id, shard_id) DO UPDATE
+SET total_projects           = EXCLUDED.total_projects,
+    analysis_duration_millis = EXCLUDED.analysis_duration_millis,
+    reported_at              = NOW()
+`
+
+type RecordDriftAnalysisRunShardParams struct {
+	DriftAnalysisRunID     uuid.UUID
+	ShardID                string
+	TotalProjects          int32
+	AnalysisDurationMillis int64
+}
+
+// A retried shard overwrites its own row, so the reported count never exceeds the distinct shard ids.
+func (q *Queries) RecordDriftAnalysisRunShard(ctx context.Context, arg RecordDriftAnalysisRunShardParams) error {
+	_, err := q.db.Exec(ctx, recordDriftAnalysisRunShard,
+		arg.DriftAnalysisRunID,
+		arg.ShardID,
+		arg.TotalProjects,
+		arg.AnalysisDurationMillis,
+	)
+	return err
+}
+
+const refreshShardedDriftAnalysisRun = `-- name: RefreshShardedDriftAnalysisRun :one
+UPDATE drift_analysis_run r
+SET total_projects           = GREATEST(s.total_projects, c.total)::INT,
+    total_projects_drifted   = c.drifted::INT,
+    total_projects_errored   = c.errored::INT,
+    total_projects_skipped   = c.skipped::INT,
+    analysis_duration_millis = s.duration_millis::BIGINT,
+    status                   = CASE WHEN s.reported >= r.expected_shards THEN 'COMPLETED' ELSE r.status END,
+    running_projects         = CASE WHEN s.reported >= r.expected_shards THEN '{}' ELSE r.running_projects END,
+    updated_at               = NOW()
+FROM (SELECT COUNT(*)                                                            AS total,
+             COUNT(*) FILTER (WHERE drifted AND succeeded AND NOT skipped_due_to_pr) AS drifted,
+             COUNT(*) FILTER (WHERE NOT succeeded)                                   AS errored,
+             COUNT(*) FILTER (WHERE skipped_due_to_pr)                               AS skipped
+      FROM drift_analysis_project
+      WHERE drift_analysis_run_id = $1) c,
+     (SELECT COUNT(*)                                   AS reported,
+             COALESCE(SUM(total_projects), 0)           AS total_projects,
+             COALESCE(MAX(analysis_duration_millis), 0) AS duration_millis
+      FROM drift_analysis_run_shard
+      WHERE drift_analysis_run_id = $1) s
+WHERE r.uuid = $1 AND r.status = 'RUNNING'
+RETURNING r.status
+`
+
+// Recomputes the counters from the project rows like UpdateDriftAnalysisRunProgress, and completes
+// the run once every expected shard has reported. Shards scan disjoint slices of the repository, so
+// total_projects is the sum of what each shard planned to scan and the duration is the slowest
+// shard's. Returns no row when the run is already COMPLETED.
+func (q *Queries) RefreshShardedDriftAnalysisRun(ctx context.Context, argUuid uuid.UUID) (string, error) {
+	row := q.db.QueryRow(ctx, refreshShardedDriftAnalysisRun, argUuid)
+	var status string
+	err := row.Scan(&status)
+	return status, err
+}
+
+const setDriftAnalysisProjectSummary = `-- name: SetDriftAnalysisProjectSummary :exec
+UPDATE drift_analysis_project
+SET resources_added        = $1,
+    resources_changed      = $2,
+    resources_destroyed    = $3,
+    resources_imported     = $4,
+    resources_forgotten    = $5,
+    resources_moved        = $6,
+    outputs_changed        = $7,
+    summary_parser_version = $8,
+    resource_changes       = $9,
+    drift_fingerprint      = $10,
+    error_class            = $11,
+    core_tool              = $12,
+    core_version           = $13,
+    provider_versions      = $14,
+    warnings               = $15,
+    summary_claimed_at     = NULL
+WHERE id = $16
+`
+
+type SetDriftAnalysisProjectSummaryParams struct {
+	ResourcesAdded       *int32
+	ResourcesChanged     *int32
+	ResourcesDestroyed   *int32
+	ResourcesImported    *int32
+	ResourcesForgotten   *int32
+	ResourcesMoved       *int32
+	OutputsChanged       *int32
+	SummaryParserVersion int16
+	ResourceChanges      []byte
+	DriftFingerprint     *string
+	ErrorClass           *string
+	CoreTool             *string
+	CoreVersion          *string
+	ProviderVersions     []byte
+	Warnings             []byte
+	ID                   int64
+}
+
+func (q *Queries) SetDriftAnalysisProjectSummary(ctx context.Context, arg SetDriftAnalysisProjectSummaryParams) error {
+	_, err := q.db.Exec(ctx, setDriftAnalysisProjectSummary,
+		arg.ResourcesAdded,
+		arg.ResourcesChanged,
+		arg.ResourcesDestroyed,
+		arg.ResourcesImported,
+		arg.ResourcesForgotten,
+		arg.ResourcesMoved,
+		arg.OutputsChanged,
+		arg.SummaryParserVersion,
+		arg.ResourceChanges,
+		arg.DriftFingerprint,
+		arg.ErrorClass,
+		arg.CoreTool,
+		arg.CoreVersion,
+		arg.ProviderVersions,
+		arg.Warnings,
+		arg.ID,
+	)
+	return err
+}
+
+const setDriftAnalysisRunCheckRun = `-- name: SetDriftAnalysisRunCheckRun :execrows
+UPDATE drift_analysis_run
+SET check_run_id = $1
+WHERE uuid = $2 AND check_run_id IS NULL
+`
+
+type SetDriftAnalysisRunCheckRunParams struct {
+	CheckRunID *int64
+	Uuid       uuid.UUID
+}
+
+// Records the GitHub check run reporting a run, unless one was recorded already.
+func (q *Queries) SetDriftAnalysisRunCheckRun(ctx context.Context, arg SetDriftAnalysisRunCheckRunParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setDriftAnalysisRunCheckRun, arg.CheckRunID, arg.Uuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setDriftAnalysisRunCommitSha = `-- name: SetDriftAnalysisRunCommitSha :execrows
+UPDATE drift_analysis_run
+SET commit_sha = $1
+WHERE uuid = $2 AND commit_sha IS NULL
+`
+
+type SetDriftAnalysisRunCommitShaParams struct {
+	CommitSha *string
+	Uuid      uuid.UUID
+}
+
+// Records the commit a run scanned. The first one sent sticks, so a later tick or shard of the run
+// cannot move it to another commit.
+func (q *Queries) SetDriftAnalysisRunCommitSha(ctx context.Context, arg SetDriftAnalysisRunCommitShaParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setDriftAnalysisRunCommitSha, arg.CommitSha, arg.Uuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setDriftAnalysisRunExpectedShards = `-- name: SetDriftAnalysisRunExpectedShards :execrows
+UPDATE drift_analysis_run
+SET expected_shards = $1,
+    updated_at      = NOW()
+WHERE uuid = $2 AND status = 'RUNNING'
+`
+
+type SetDriftAnalysisRunExpectedShardsParams struct {
+	ExpectedShards *int32
+	Uuid           uuid.UUID
+}
+
+// Matches no row once the run completed, so a shard arriving late is turned away before it writes.
+func (q *Queries) SetDriftAnalysisRunExpectedShards(ctx context.Context, arg SetDriftAnalysisRunExpectedShardsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setDriftAnalysisRunExpectedShards, arg.ExpectedShards, arg.Uuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const touchRunningDriftAnalysisRun = `-- name: TouchRunningDriftAnalysisRun :execrows
+UPDATE drift_analysis_run
+SET updated_at = NOW()
+WHERE uuid = $1 AND status = 'RUNNING'
+`
+
+// Marks a RUNNING run as still reporting, which keeps the stale run sweeper off a long upload, and
+// takes its row lock for the rest of the transaction. Matches no row once the run was swept or
+// completed.
+func (q *Queries) TouchRunningDriftAnalysisRun(ctx context.Context, argUuid uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, touchRunningDriftAnalysisRun, argUuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateDriftAnalysisRunProgress = `-- name: UpdateDriftAnalysisRunProgress :exec
 UPDATE drift_analysis_run r
 SET running_projects       = $1,
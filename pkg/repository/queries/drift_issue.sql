@@ -0,0 +1,42 @@
+-- name: ListOpenDriftIssues :many
+SELECT *
+FROM drift_issue
+WHERE repository_id = @repository_id
+  AND closed_at IS NULL
+ORDER BY dir;
+
+-- name: ClaimDriftIssue :one
+-- Reserves the open issue of a dir before it is created on GitHub. Returns no row when the dir
+-- already has one, unless it is a claim whose creator died more than 15 minutes ago.
+INSERT INTO drift_issue (repository_id, dir, last_run_id)
+VALUES (@repository_id, @dir, @run_id)
+ON CONFLICT (repository_id, dir) WHERE closed_at IS NULL DO UPDATE
+    SET last_run_id = EXCLUDED.last_run_id,
+        opened_at   = NOW()
+    WHERE drift_issue.issue_number IS NULL
+      AND drift_issue.opened_at < NOW() - INTERVAL '15 minutes'
+RETURNING *;
+
+-- name: SetDriftIssueNumber :exec
+UPDATE drift_issue
+SET issue_number = @issue_number
+WHERE id = @id;
+
+-- name: AdvanceDriftIssue :execrows
+-- Moves an open issue on to run_id. Affects no row when the issue is still being created or was
+-- already updated from the run, e.g. by another shard of it.
+UPDATE drift_issue
+SET last_run_id = @run_id
+WHERE id = @id
+  AND issue_number IS NOT NULL
+  AND last_run_id <> @run_id;
+
+-- name: CloseDriftIssue :exec
+UPDATE drift_issue
+SET closed_at = NOW()
+WHERE id = @id;
+
+-- name: DeleteDriftIssue :exec
+DELETE
+FROM drift_issue
+WHERE id = @id;
@@ -0,0 +1,55 @@
+-- name: DeleteDriftProjectsWithoutRun :exec
+-- Prunes the catalog projects of a repository whose latest run was deleted, which no remaining run
+-- includes since runs are deleted oldest first.
+DELETE
+FROM drift_project
+WHERE repository_id = @repository_id
+  AND last_run_id IS NULL;
+
+-- name: GetUnscannedProjects :many
+-- Catalog projects of a repository missing from one of its runs, normally the latest completed one,
+-- most recently seen first.
+SELECT dp.dir, dp.type, dp.last_seen_at, dp.last_run_id
+FROM drift_project dp
+WHERE dp.repository_id = @repository_id
+  AND NOT EXISTS (SELECT 1
+                  FROM drift_analysis_project dap
+                  WHERE dap.drift_analysis_run_id = @run_id
+                    AND dap.dir = dp.dir)
+ORDER BY dp.last_seen_at DESC, dp.dir;
+
+-- name: ListDriftProjectsByRepositoryId :many
+-- The catalog of a repository's projects, optionally narrowed to a status, a type and dirs
+-- containing dir_query (case-insensitive).
+SELECT *
+FROM drift_project
+WHERE repository_id = @repository_id
+  AND (sqlc.narg(status)::VARCHAR IS NULL OR status = sqlc.narg(status))
+  AND (sqlc.narg(type)::VARCHAR IS NULL OR type = sqlc.narg(type))
+  AND (sqlc.narg(dir_query)::VARCHAR IS NULL OR STRPOS(LOWER(dir), LOWER(sqlc.narg(dir_query))) > 0)
+ORDER BY dir, type;
+
+-- name: SyncDriftProjectsFromRun :exec
+-- Records the projects of a run in the catalog. Must run in the transaction that completes the run,
+-- so a run still in progress or later deleted as stale never shows in it. A run older than the one a project was last seen in can only move first_seen_at
+-- back and fill in the last drifted and errored times.
+INSERT INTO drift_project (repository_id, dir, type, status, first_seen_at, last_seen_at, last_drifted_at, last_errored_at, last_run_id)
+SELECT r.repository_id,
+       p.dir,
+       p.type,
+       drift_project_status(p.skipped_due_to_pr, p.succeeded, p.drifted),
+       r.created_at,
+       r.created_at,
+       CASE WHEN p.drifted AND p.succeeded AND NOT p.skipped_due_to_pr THEN r.created_at END,
+       CASE WHEN NOT p.succeeded AND NOT p.skipped_due_to_pr THEN r.created_at END,
+       r.uuid
+FROM drift_analysis_project p
+         JOIN drift_analysis_run r ON r.uuid = p.drift_analysis_run_id
+WHERE r.uuid = @run_id
+ON CONFLICT (repository_id, dir, type) DO UPDATE
+    SET status          = CASE WHEN EXCLUDED.last_seen_at >= drift_project.last_seen_at THEN EXCLUDED.status ELSE drift_project.status END,
+        last_run_id     = CASE WHEN EXCLUDED.last_seen_at >= drift_project.last_seen_at THEN EXCLUDED.last_run_id ELSE drift_project.last_run_id END,
+        first_seen_at   = LEAST(drift_project.first_seen_at, EXCLUDED.first_seen_at),
+        last_seen_at    = GREATEST(drift_project.last_seen_at, EXCLUDED.last_seen_at),
+        last_drifted_at = GREATEST(drift_project.last_drifted_at, EXCLUDED.last_drifted_at),
+        last_errored_at = GREATEST(drift_project.last_errored_at, EXCLUDED.last_errored_at);
@@ -59,7 +59,50 @@ SELECT EXISTS(SELECT 1
               WHERE gr.id = @repo_id
                 AND ugo.user_id = @user_id);
 
+-- name: IsUserAdminOfOrganization :one
+SELECT EXISTS(SELECT 1
+              FROM user_git_organization
+              WHERE git_organization_id = @git_organization_id
+                AND user_id = @user_id
+                AND role = 'ADMIN');
+
+-- name: IsUserAdminOfOrganizationByRepoId :one
+SELECT EXISTS(SELECT 1
+              FROM user_git_organization ugo
+                       JOIN git_repository gr
+                            ON ugo.git_organization_id = gr.organization_id
+              WHERE gr.id = @repo_id
+                AND ugo.user_id = @user_id
+                AND ugo.role = 'ADMIN');
+
 -- name: FindAllUserOrganizationIds :many
 SELECT git_organization_id
 FROM user_git_organization
 WHERE user_id = $1;
+
+-- name: FindOrganizationRedactionRules :many
+SELECT *
+FROM organization_redaction_rule
+WHERE organization_id = @organization_id
+ORDER BY name;
+
+-- name: LockGitOrganization :exec
+SELECT id
+FROM git_organization
+WHERE id = @id
+FOR UPDATE;
+
+-- name: CreateOrganizationRedactionRule :one
+-- Inserts nothing once the organization has max_rules rules. Callers lock the organization row
+-- first, so concurrent inserts cannot both pass the count.
+INSERT INTO organization_redaction_rule (organization_id, name, pattern)
+SELECT @organization_id, @name, @pattern
+WHERE (SELECT COUNT(*)
+       FROM organization_redaction_rule
+       WHERE organization_id = @organization_id) < sqlc.arg(max_rules)::INTEGER
+RETURNING *;
+
+-- name: DeleteOrganizationRedactionRule :execrows
+DELETE FROM organization_redaction_rule
+WHERE id = @id
+  AND organization_id = @organization_id;
@@ -46,6 +46,61 @@ func (q *Queries) CreateOrUpdateGitOrganization(ctx context.Context, arg CreateO
 	return i, err
 }
 
+const createOrganizationRedactionRule = `-- name: CreateOrganizationRedactionRule :one
+INSERT INTO organization_redaction_rule (organization_id, name, pattern)
+SELECT $1, $2, $3
+WHERE (SELECT COUNT(*)
+       FROM organization_redaction_rule
+       WHERE organization_id = $1) < $4::INTEGER
+RETURNING id, organization_id, name, pattern, created_at
+`
+
+type CreateOrganizationRedactionRuleParams struct {
+	OrganizationID int64
+	Name           string
+	Pattern        string
+	MaxRules       int32
+}
+
+// Inserts nothing once the organization has max_rules rules. Callers lock the organization row
+// first, so concurrent inserts cannot both pass the count.
+func (q *Queries) CreateOrganizationRedactionRule(ctx context.Context, arg CreateOrganizationRedactionRuleParams) (OrganizationRedactionRule, error) {
+	row := q.db.QueryRow(ctx, createOrganizationRedactionRule,
+		arg.OrganizationID,
+		arg.Name,
+		arg.Pattern,
+		arg.MaxRules,
+	)
+	var i OrganizationRedactionRule
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.Name,
+		&i.Pattern,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteOrganizationRedactionRule = `-- name: DeleteOrganizationRedactionRule :execrows
+DELETE FROM organization_redaction_rule
+WHERE id = $1
+  AND organization_id = $2
+`
+
+type DeleteOrganizationRedactionRuleParams struct {
+	ID             int64
+	OrganizationID int64
+}
+
+func (q *Queries) DeleteOrganizationRedactionRule(ctx context.Context, arg DeleteOrganizationRedactionRuleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrganizationRedactionRule, arg.ID, arg.OrganizationID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const findAllUserOrganizationIds = `-- name: FindAllUserOrganizationIds :many
 SELECT git_organization_id
 FROM user_git_organization
@@ -181,6 +236,81 @@ func (q *Queries) FindGitOrganizationByRepoId(ctx context.Context, id int64) (Gi
 	return i, err
 }
 
+const findOrganizationRedactionRules = `-- name: FindOrganizationRedactionRules :many
+SELECT id, organization_id, name, pattern, created_at
+FROM organization_redaction_rule
+WHERE organization_id = $1
+ORDER BY name
+`
+
+func (q *Queries) FindOrganizationRedactionRules(ctx context.Context, organizationID int64) ([]OrganizationRedactionRule, error) {
+	rows, err := q.db.Query(ctx, findOrganizationRedactionRules, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OrganizationRedactionRule
+	for rows.Next() {
+		var i OrganizationRedactionRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrganizationID,
+			&i.Name,
+			&i.Pattern,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isUserAdminOfOrganization = `-- name: IsUserAdminOfOrganization :one
+SELECT EXISTS(SELECT 1
+              FROM user_git_organization
+              WHERE git_organization_id = $1
+                AND user_id = $2
+                AND role = 'ADMIN')
+`
+
+type IsUserAdminOfOrganizationParams struct {
+	GitOrganizationID int64
+	UserID            int64
+}
+
+func (q *Queries) IsUserAdminOfOrganization(ctx context.Context, arg IsUserAdminOfOrganizationParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isUserAdminOfOrganization, arg.GitOrganizationID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isUserAdminOfOrganizationByRepoId = `-- name: IsUserAdminOfOrganizationByRepoId :one
+SELECT EXISTS(SELECT 1
+              FROM user_git_organization ugo
+                       JOIN git_repository gr
+                            ON ugo.git_organization_id = gr.organization_id
+              WHERE gr.id = $1
+                AND ugo.user_id = $2
+                AND ugo.role = 'ADMIN')
+`
+
+type IsUserAdminOfOrganizationByRepoIdParams struct {
+	RepoID int64
+	UserID int64
+}
+
+func (q *Queries) IsUserAdminOfOrganizationByRepoId(ctx context.Context, arg IsUserAdminOfOrganizationByRepoIdParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isUserAdminOfOrganizationByRepoId, arg.RepoID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const isUserMemberOfOrganization = `-- name: IsUserMemberOfOrganization :one
 SELECT EXISTS(SELECT 1
               FROM user_git_organization
@@ -221,6 +351,18 @@ func (q *Queries) IsUserMemberOfOrganizationByRepoId(ctx context.Context, arg Is
 	return exists, err
 }
 
+const lockGitOrganization = `-- name: LockGitOrganization :exec
+SELECT id
+FROM git_organization
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockGitOrganization(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, lockGitOrganization, id)
+	return err
+}
+
 const updateOrgInstallationID = `-- name: UpdateOrgInstallationID :exec
 UPDATE git_organization
 SET installation_id = $2
@@ -34,9 +34,45 @@ UPDATE git_repository
 SET analysis_token = NULL
 WHERE id = @id;
 
+-- name: UpdateRepositoryDispatchSettings :exec
+UPDATE git_repository
+SET dispatch_workflow   = @dispatch_workflow,
+    remediation_enabled = @remediation_enabled
+WHERE id = @id;
+
+-- name: UpdateRepositoryDriftIssues :exec
+UPDATE git_repository
+SET drift_issues_enabled = @enabled
+WHERE id = @id;
+
+-- name: UpdateRepositoryScanSchedule :exec
+UPDATE git_repository
+SET scan_schedule             = @scan_schedule,
+    scan_max_interval_minutes = @scan_max_interval_minutes,
+    scan_schedule_set_at      = NOW()
+WHERE id = @id;
+
 -- name: FindGitRepositoryByToken :one
 SELECT *
 FROM git_repository
 WHERE analysis_token = $1
   AND analysis_token IS NOT NULL
   AND analysis_token != '';
+
+-- name: FindRepositoryCodeowners :one
+SELECT *
+FROM git_repository_codeowners
+WHERE repository_id = @repository_id;
+
+-- name: UpsertRepositoryCodeowners :exec
+INSERT INTO git_repository_codeowners (repository_id, path, content)
+VALUES (@repository_id, @path, @content)
+ON CONFLICT (repository_id) DO UPDATE
+    SET path      = @path,
+        content   = @content,
+        synced_at = NOW();
+
+-- name: DeleteRepositoryCodeowners :exec
+DELETE
+FROM git_repository_codeowners
+WHERE repository_id = @repository_id;
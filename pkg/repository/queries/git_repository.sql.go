@@ -26,7 +26,7 @@ VALUES ($1, $2, $3, $4)
 ON CONFLICT (organization_id, provider_id) DO UPDATE
     SET name       = $3,
         is_private = $4
-RETURNING id, organization_id, provider_id, name, is_private, analysis_token
+RETURNING id, organization_id, provider_id, name, is_private, analysis_token, drift_issues_enabled, dispatch_workflow, remediation_enabled, scan_schedule, scan_max_interval_minutes, scan_schedule_set_at
 `
 
 type CreateOrUpdateRepositoryParams struct {
@@ -51,12 +51,29 @@ func (q *Queries) CreateOrUpdateRepository(ctx context.Context, arg CreateOrUpda
 		&i.Name,
 		&i.IsPrivate,
 		&i.AnalysisToken,
+		&i.DriftIssuesEnabled,
+		&i.DispatchWorkflow,
+		&i.RemediationEnabled,
+		&i.ScanSchedule,
+		&i.ScanMaxIntervalMinutes,
+		&i.ScanScheduleSetAt,
 	)
 	return i, err
 }
 
+const deleteRepositoryCodeowners = `-- name: DeleteRepositoryCodeowners :exec
+DELETE
+FROM git_repository_codeowners
+WHERE repository_id = $1
+`
+
+func (q *Queries) DeleteRepositoryCodeowners(ctx context.Context, repositoryID int64) error {
+	_, err := q.db.Exec(ctx, deleteRepositoryCodeowners, repositoryID)
+	return err
+}
+
 const findGitRepositoriesByOrgId = `-- name: FindGitRepositoriesByOrgId :many
-SELECT id, organization_id, provider_id, name, is_private, analysis_token
+SELECT id, organization_id, provider_id, name, is_private, analysis_token, drift_issues_enabled, dispatch_workflow, remediation_enabled, scan_schedule, scan_max_interval_minutes, scan_schedule_set_at
 FROM git_repository
 WHERE organization_id = $1
 ORDER BY (analysis_token IS NOT NULL) DESC, name ASC
@@ -78,6 +95,12 @@ func (q *Queries) FindGitRepositoriesByOrgId(ctx context.Context, organizationID
 			&i.Name,
 			&i.IsPrivate,
 			&i.AnalysisToken,
+			&i.DriftIssuesEnabled,
+			&i.DispatchWorkflow,
+			&i.RemediationEnabled,
+			&i.ScanSchedule,
+			&i.ScanMaxIntervalMinutes,
+			&i.ScanScheduleSetAt,
 		); err != nil {
 			return nil, err
 		}
@@ -90,7 +113,7 @@ func (q *Queries) FindGitRepositoriesByOrgId(ctx context.Context, organizationID
 }
 
 const findGitRepositoryById = `-- name: FindGitRepositoryById :one
-SELECT id, organization_id, provider_id, name, is_private, analysis_token
+SELECT id, organization_id, provider_id, name, is_private, analysis_token, drift_issues_enabled, dispatch_workflow, remediation_enabled, scan_schedule, scan_max_interval_minutes, scan_schedule_set_at
 FROM git_repository
 WHERE id = $1
 `
@@ -105,12 +128,18 @@ func (q *Queries) FindGitRepositoryById(ctx context.Context, id int64) (GitRepos
 		&i.Name,
 		&i.IsPrivate,
 		&i.AnalysisToken,
+		&i.DriftIssuesEnabled,
+		&i.DispatchWorkflow,
+		&i.RemediationEnabled,
+		&i.ScanSchedule,
+		&i.ScanMaxIntervalMinutes,
+		&i.ScanScheduleSetAt,
 	)
 	return i, err
 }
 
 const findGitRepositoryByOrgIdAndName = `-- name: FindGitRepositoryByOrgIdAndName :one
-SELECT id, organization_id, provider_id, name, is_private, analysis_token
+SELECT id, organization_id, provider_id, name, is_private, analysis_token, drift_issues_enabled, dispatch_workflow, remediation_enabled, scan_schedule, scan_max_interval_minutes, scan_schedule_set_at
 FROM git_repository
 WHERE organization_id = $1
   AND name = $2
@@ -131,12 +160,18 @@ func (q *Queries) FindGitRepositoryByOrgIdAndName(ctx context.Context, arg FindG
 		&i.Name,
 		&i.IsPrivate,
 		&i.AnalysisToken,
+		&i.DriftIssuesEnabled,
+		&i.DispatchWorkflow,
+		&i.RemediationEnabled,
+		&i.ScanSchedule,
+		&i.ScanMaxIntervalMinutes,
+		&i.ScanScheduleSetAt,
 	)
 	return i, err
 }
 
 const findGitRepositoryByToken = `-- name: FindGitRepositoryByToken :one
-SELECT id, organization_id, provider_id, name, is_private, analysis_token
+SELECT id, organization_id, provider_id, name, is_private, analysis_token, drift_issues_enabled, dispatch_workflow, remediation_enabled, scan_schedule, scan_max_interval_minutes, scan_schedule_set_at
 FROM git_repository
 WHERE analysis_token = $1
   AND analysis_token IS NOT NULL
@@ -153,10 +188,87 @@ func (q *Queries) FindGitRepositoryByToken(ctx context.Context, analysisToken *s
 		&i.Name,
 		&i.IsPrivate,
 		&i.AnalysisToken,
+		&i.DriftIssuesEnabled,
+		&i.DispatchWorkflow,
+		&i.RemediationEnabled,
+		&i.ScanSchedule,
+		&i.ScanMaxIntervalMinutes,
+		&i.ScanScheduleSetAt,
+	)
+	return i, err
+}
+
+const findRepositoryCodeowners = `-- name: FindRepositoryCodeowners :one
+SELECT repository_id, path, content, synced_at
+FROM git_repository_codeowners
+WHERE repository_id = $1
+`
+
+func (q *Queries) FindRepositoryCodeowners(ctx context.Context, repositoryID int64) (GitRepositoryCodeowner, error) {
+	row := q.db.QueryRow(ctx, findRepositoryCodeowners, repositoryID)
+	var i GitRepositoryCodeowner
+	err := row.Scan(
+		&i.RepositoryID,
+		&i.Path,
+		&i.Content,
+		&i.SyncedAt,
 	)
 	return i, err
 }
 
+const updateRepositoryDispatchSettings = `-- name: UpdateRepositoryDispatchSettings :exec
+UPDATE git_repository
+SET dispatch_workflow   = $1,
+    remediation_enabled = $2
+WHERE id = $3
+`
+
+type UpdateRepositoryDispatchSettingsParams struct {
+	DispatchWorkflow   *string
+	RemediationEnabled bool
+	ID                 int64
+}
+
+func (q *Queries) UpdateRepositoryDispatchSettings(ctx context.Context, arg UpdateRepositoryDispatchSettingsParams) error {
+	_, err := q.db.Exec(ctx, updateRepositoryDispatchSettings, arg.DispatchWorkflow, arg.RemediationEnabled, arg.ID)
+	return err
+}
+
+const updateRepositoryDriftIssues = `-- name: UpdateRepositoryDriftIssues :exec
+UPDATE git_repository
+SET drift_issues_enabled = $1
+WHERE id = $2
+`
+
+type UpdateRepositoryDriftIssuesParams struct {
+	Enabled bool
+	ID      int64
+}
+
+func (q *Queries) UpdateRepositoryDriftIssues(ctx context.Context, arg UpdateRepositoryDriftIssuesParams) error {
+	_, err := q.db.Exec(ctx, updateRepositoryDriftIssues, arg.Enabled, arg.ID)
+	return err
+}
+
+const updateRepositoryScanSchedule = `-- name: UpdateRepositoryScanSchedule :exec
+UPDATE git_repository
+SET scan_schedule             = $1,
+    scan_max_interval_minutes = $2,
+    scan_schedule_set_at      = NOW()
+WHERE id = $3
+`
+
+type UpdateRepositoryScanScheduleParams struct {
+	ScanSchedule           *string
+	ScanMaxIntervalMinutes *int32
+	ID                     int64
+}
+
+func (q *Queries) UpdateRepositoryScanSchedule(ctx context.Context, arg UpdateRepositoryScanScheduleParams) error {
+	_, err := q.db.Exec(ctx, updateRepositoryScanSchedule, arg.ScanSchedule, arg.ScanMaxIntervalMinutes, arg.ID)
+	return err
+}
+
 const updateRepositoryToken = `-- name: UpdateRepositoryToken :one
 UPDATE git_repository
 SET analysis_token = $1
@@ -175,3 +287,23 @@ func (q *Queries) UpdateRepositoryToken(ctx context.Context, arg UpdateRepositor
 	err := row.Scan(&analysis_token)
 	return analysis_token, err
 }
+
+const upsertRepositoryCodeowners = `-- name: UpsertRepositoryCodeowners :exec
+INSERT INTO git_repository_codeowners (repository_id, path, content)
+VALUES ($1, $2, $3)
+ON CONFLICT (repository_id) DO UPDATE
+    SET path      = $2,
+        content   = $3,
+        synced_at = NOW()
+`
+
+type UpsertRepositoryCodeownersParams struct {
+	RepositoryID int64
+	Path         string
+	Content      string
+}
+
+func (q *Queries) UpsertRepositoryCodeowners(ctx context.Context, arg UpsertRepositoryCodeownersParams) error {
+	_, err := q.db.Exec(ctx, upsertRepositoryCodeowners, arg.RepositoryID, arg.Path, arg.Content)
+	return err
+}
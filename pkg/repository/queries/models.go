@@ -8,21 +8,69 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type CheckRunTimeout struct {
+	CheckRunID   int64
+	RunID        uuid.UUID
+	RepositoryID int64
+	Attempts     int32
+	RetryAt      time.Time
+	EnqueuedAt   time.Time
+}
+
+type CommandOutput struct {
+	ID             int64
+	RepositoryID   int64
+	Hash           []byte
+	Size           int64
+	CompressedSize int64
+	Data           []byte
+	BlobRef        *string
+	RefCount       int32
+	UnreferencedAt *time.Time
+	CreatedAt      time.Time
+}
+
 type DriftAnalysisProject struct {
-	ID                 int64
-	DriftAnalysisRunID uuid.UUID
-	Dir                string
-	Type               string
-	Drifted            bool
-	Succeeded          bool
-	InitOutput         *string
-	PlanOutput         *string
-	SkippedDueToPr     bool
-	ResourcesAdded     *int32
-	ResourcesChanged   *int32
-	ResourcesDestroyed *int32
+	ID                   int64
+	DriftAnalysisRunID   uuid.UUID
+	Dir                  string
+	Type                 string
+	Drifted              bool
+	Succeeded            bool
+	InitOutput           *string
+	PlanOutput           *string
+	SkippedDueToPr       bool
+	ResourcesAdded       *int32
+	ResourcesChanged     *int32
+	ResourcesDestroyed   *int32
+	InitOutputRef        *string
+	InitOutputSize       *int64
+	PlanOutputRef        *string
+	PlanOutputSize       *int64
+	InitOutputID         *int64
+	PlanOutputID         *int64
+	Redactions           []byte
+	ResourcesImported    *int32
+	ResourcesForgotten   *int32
+	ResourcesMoved       *int32
+	OutputsChanged       *int32
+	SummaryParserVersion int16
+	ResourceChanges      []byte
+	DriftFingerprint     *string
+	InitDurationMillis   *int64
+	PlanDurationMillis   *int64
+	StartedAt            *time.Time
+	FinishedAt           *time.Time
+	Retries              *int32
+	ErrorClass           *string
+	CoreTool             *string
+	CoreVersion          *string
+	ProviderVersions     []byte
+	Warnings             []byte
+	SummaryClaimedAt     *time.Time
 }
 
 type DriftAnalysisRun struct {
@@ -38,6 +86,48 @@ type DriftAnalysisRun struct {
 	IdempotencyKey         *string
 	Status                 string
 	RunningProjects        []string
+	ExpectedShards         *int32
+	CommitSha              *string
+	CheckRunID             *int64
+	ScopedDirs             []string
+}
+
+type DriftAnalysisRunCoverageChange struct {
+	DriftAnalysisRunID uuid.UUID
+	Dir                string
+	Type               string
+	Change             string
+}
+
+type DriftAnalysisRunShard struct {
+	DriftAnalysisRunID     uuid.UUID
+	ShardID                string
+	TotalProjects          int32
+	AnalysisDurationMillis int64
+	ReportedAt             time.Time
+}
+
+type DriftIssue struct {
+	ID           int64
+	RepositoryID int64
+	Dir          string
+	IssueNumber  *int32
+	LastRunID    uuid.UUID
+	OpenedAt     time.Time
+	ClosedAt     *time.Time
+}
+
+type DriftProject struct {
+	ID            int64
+	RepositoryID  int64
+	Dir           string
+	Type          string
+	Status        string
+	FirstSeenAt   time.Time
+	LastSeenAt    time.Time
+	LastDriftedAt *time.Time
+	LastErroredAt *time.Time
+	LastRunID     pgtype.UUID
 }
 
 type GitOrganization struct {
@@ -57,12 +147,57 @@ type GitOrganizationSync struct {
 }
 
 type GitRepository struct {
+	ID                     int64
+	OrganizationID         int64
+	ProviderID             string
+	Name                   string
+	IsPrivate              bool
+	AnalysisToken          *string
+	DriftIssuesEnabled     bool
+	DispatchWorkflow       *string
+	RemediationEnabled     bool
+	ScanSchedule           *string
+	ScanMaxIntervalMinutes *int32
+	ScanScheduleSetAt      *time.Time
+}
+
+type GitRepositoryCodeowner struct {
+	RepositoryID int64
+	Path         string
+	Content      string
+	SyncedAt     time.Time
+}
+
+type OutputBlobDeletion struct {
+	Ref        string
+	EnqueuedAt time.Time
+}
+
+type OrganizationRedactionRule struct {
 	ID             int64
 	OrganizationID int64
-	ProviderID     string
 	Name           string
-	IsPrivate      bool
-	AnalysisToken  *string
+	Pattern        string
+	CreatedAt      time.Time
+}
+
+type ScanDispatch struct {
+	ID           uuid.UUID
+	RepositoryID int64
+	Kind         string
+	Dirs         []string
+	ProjectType  *string
+	RequestedBy  int64
+	CreatedAt    time.Time
+}
+
+type ScanOverdueAlert struct {
+	ID              int64
+	RepositoryID    int64
+	DueAt           time.Time
+	LastCompletedAt *time.Time
+	RaisedAt        time.Time
+	ResolvedAt      *time.Time
 }
 
 type SyncStatusUser struct {
@@ -0,0 +1,24 @@
+-- name: ClaimOutputBlobDeletions :many
+-- Rows stay locked until the caller's transaction ends, so concurrent reapers on other API
+-- instances skip them, and a reaper that fails to delete the blobs rolls back and retries later.
+SELECT ref
+FROM output_blob_deletion
+ORDER BY enqueued_at
+LIMIT @max_rows
+FOR UPDATE SKIP LOCKED;
+
+-- name: DeleteOutputBlobDeletions :exec
+DELETE FROM output_blob_deletion
+WHERE ref = ANY (@refs::VARCHAR[]);
+
+-- name: EnqueueUnreferencedOutputBlobDeletions :exec
+-- Queues blobs written for outputs whose transaction then rolled back. An output a concurrent
+-- writer committed meanwhile references the same blob, so it is left alone.
+INSERT INTO output_blob_deletion (ref)
+SELECT u.ref
+FROM unnest(@refs::VARCHAR[], @hashes::BYTEA[]) AS u(ref, hash)
+WHERE NOT EXISTS (SELECT 1
+                  FROM command_output o
+                  WHERE o.repository_id = @repository_id
+                    AND o.hash = u.hash)
+ON CONFLICT DO NOTHING;
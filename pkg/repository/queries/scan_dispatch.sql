@@ -0,0 +1,27 @@
+-- name: CreateScanDispatch :one
+INSERT INTO scan_dispatch (id, repository_id, kind, dirs, project_type, requested_by)
+VALUES (@id, @repository_id, @kind, @dirs, @project_type, @requested_by)
+RETURNING *;
+
+-- name: DeleteScanDispatch :exec
+DELETE
+FROM scan_dispatch
+WHERE id = @id;
+
+-- name: ListScanDispatches :many
+-- The latest dispatches of a repository with who requested them and the run each resulted in, if it
+-- started uploading yet. Remediation applies don't upload runs.
+SELECT d.id,
+       d.kind,
+       d.dirs,
+       d.project_type,
+       d.created_at,
+       u.username AS requested_by,
+       r.uuid     AS run_id,
+       r.status   AS run_status
+FROM scan_dispatch d
+JOIN users u ON u.id = d.requested_by
+LEFT JOIN drift_analysis_run r ON r.repository_id = d.repository_id AND r.idempotency_key = d.id::text
+WHERE d.repository_id = @repository_id
+ORDER BY d.created_at DESC
+LIMIT @max_rows;
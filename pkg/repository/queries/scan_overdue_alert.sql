@@ -0,0 +1,61 @@
+-- name: GetLastCompletedScanAt :one
+-- When the last completed scan of a repository finished: a run that scanned the whole repository,
+-- not just the dirs a dispatch scoped it to, with at least one project that succeeded. A run that
+-- failed every project proves the pipeline runs, not that it scans.
+SELECT updated_at
+FROM drift_analysis_run r
+WHERE r.repository_id = @repository_id
+  AND r.status = 'COMPLETED'
+  AND r.scoped_dirs IS NULL
+  AND EXISTS (SELECT 1
+              FROM drift_analysis_project p
+              WHERE p.drift_analysis_run_id = r.uuid
+                AND p.succeeded)
+ORDER BY r.updated_at DESC
+LIMIT 1;
+
+-- name: ListScheduledRepositories :many
+-- The repositories expecting scans at a cadence, with when their last completed scan finished, as
+-- GetLastCompletedScanAt tells it, and their open overdue alert, if any.
+SELECT r.id,
+       r.scan_schedule,
+       r.scan_max_interval_minutes,
+       r.scan_schedule_set_at,
+       last_run.updated_at AS last_completed_at,
+       a.id                AS open_alert_id
+FROM git_repository r
+LEFT JOIN LATERAL (SELECT run.updated_at
+                   FROM drift_analysis_run run
+                   WHERE run.repository_id = r.id
+                     AND run.status = 'COMPLETED'
+                     AND run.scoped_dirs IS NULL
+                     AND EXISTS (SELECT 1
+                                 FROM drift_analysis_project p
+                                 WHERE p.drift_analysis_run_id = run.uuid
+                                   AND p.succeeded)
+                   ORDER BY run.updated_at DESC
+                   LIMIT 1) last_run ON TRUE
+LEFT JOIN scan_overdue_alert a ON a.repository_id = r.id AND a.resolved_at IS NULL
+WHERE r.scan_schedule IS NOT NULL
+   OR r.scan_max_interval_minutes IS NOT NULL
+ORDER BY r.id;
+
+-- name: ListScanOverdueAlerts :many
+-- The latest overdue alerts of a repository, the open one first.
+SELECT *
+FROM scan_overdue_alert
+WHERE repository_id = @repository_id
+ORDER BY resolved_at IS NULL DESC, raised_at DESC
+LIMIT @max_rows;
+
+-- name: RaiseScanOverdueAlert :execrows
+-- Does nothing when the repository already has an open alert.
+INSERT INTO scan_overdue_alert (repository_id, due_at, last_completed_at)
+VALUES (@repository_id, @due_at, @last_completed_at)
+ON CONFLICT (repository_id) WHERE resolved_at IS NULL DO NOTHING;
+
+-- name: ResolveScanOverdueAlert :exec
+UPDATE scan_overdue_alert
+SET resolved_at = NOW()
+WHERE repository_id = @repository_id
+  AND resolved_at IS NULL;
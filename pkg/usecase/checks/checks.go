@@ -0,0 +1,233 @@
+// Package checks reports drift scans as GitHub check runs on the commit they scanned, so branch
+// protection can require a scan without drift.
+package checks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"driftive.cloud/api/pkg/repository"
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/utils/parsing"
+	"driftive.cloud/api/pkg/usecase/utils/strutils"
+	"github.com/google/uuid"
+)
+
+// CheckName names the check runs, and is what branch protection rules refer to.
+const CheckName = "Driftive drift scan"
+
+// maxListedProjects bounds the drifted and the errored projects listed in a summary, keeping it
+// well under GitHub's 65535 character limit.
+const maxListedProjects = 50
+
+const (
+	ConclusionSuccess  = "success"
+	ConclusionNeutral  = "neutral"
+	ConclusionFailure  = "failure"
+	ConclusionTimedOut = "timed_out"
+)
+
+// CheckRun is the state of a check run.
+type CheckRun struct {
+	// ExternalID is the uuid of the run.
+	ExternalID string
+	DetailsURL string
+	// Conclusion is empty while the scan is in progress.
+	Conclusion string
+	Title      string
+	Summary    string
+}
+
+// Publisher creates and updates the check runs of one repository.
+type Publisher interface {
+	Create(ctx context.Context, headSHA string, check CheckRun) (int64, error)
+	Update(ctx context.Context, id int64, check CheckRun) error
+}
+
+// PublisherFactory returns the Publisher of a repository.
+type PublisherFactory func(ctx context.Context, org queries.GitOrganization, repo queries.GitRepository) (Publisher, error)
+
+type CheckService struct {
+	repo       repository.DriftAnalysisRepository
+	orgs       repository.GitOrgRepository
+	repos      repository.GitRepositoryRepository
+	publishers PublisherFactory
+}
+
+func NewCheckService(repo repository.DriftAnalysisRepository, orgs repository.GitOrgRepository, repos repository.GitRepositoryRepository, publishers PublisherFactory) *CheckService {
+	return &CheckService{repo: repo, orgs: orgs, repos: repos, publishers: publishers}
+}
+
+// StartRun puts an in-progress check run on the commit a run is scanning.
+func (s *CheckService) StartRun(ctx context.Context, org queries.GitOrganization, repo queries.GitRepository, runId uuid.UUID, headSHA string, detailsURL string) error {
+	publisher, err := s.publishers(ctx, org, repo)
+	if err != nil {
+		return fmt.Errorf("creating the check publisher: %w", err)
+	}
+	id, err := publisher.Create(ctx, headSHA, CheckRun{
+		ExternalID: runId.String(),
+		DetailsURL: detailsURL,
+		Title:      "Scanning for drift",
+		Summary:    fmt.Sprintf("[Follow the scan in the dashboard](%s)", detailsURL),
+	})
+	if err != nil {
+		return fmt.Errorf("creating the check run: %w", err)
+	}
+	recorded, err := s.repo.SetDriftAnalysisRunCheckRun(ctx, runId, id)
+	if err != nil {
+		return fmt.Errorf("recording check run %d: %w", id, err)
+	}
+	if recorded {
+		return nil
+	}
+	// The run completed meanwhile and its completion created a check run of its own: complete
+	// this one too rather than leave it in progress.
+	run, err := s.repo.FindDriftAnalysisRunByUUID(ctx, runId)
+	if err != nil {
+		return fmt.Errorf("finding run %s: %w", runId, err)
+	}
+	return s.complete(ctx, publisher, run, id, detailsURL)
+}
+
+// CompleteRun concludes the check run of a completed run, creating it when the run had none. Does
+// nothing for runs without a commit SHA.
+func (s *CheckService) CompleteRun(ctx context.Context, org queries.GitOrganization, repo queries.GitRepository, runId uuid.UUID, detailsURL string) error {
+	run, err := s.repo.FindDriftAnalysisRunByUUID(ctx, runId)
+	if err != nil {
+		return fmt.Errorf("finding run %s: %w", runId, err)
+	}
+	if run.CommitSha == nil {
+		return nil
+	}
+	publisher, err := s.publishers(ctx, org, repo)
+	if err != nil {
+		return fmt.Errorf("creating the check publisher: %w", err)
+	}
+	if run.CheckRunID != nil {
+		return s.complete(ctx, publisher, run, *run.CheckRunID, detailsURL)
+	}
+
+	check, err := s.report(ctx, run, detailsURL)
+	if err != nil {
+		return err
+	}
+	id, err := publisher.Create(ctx, *run.CommitSha, check)
+	if err != nil {
+		return fmt.Errorf("creating the check run: %w", err)
+	}
+	recorded, err := s.repo.SetDriftAnalysisRunCheckRun(ctx, runId, id)
+	if err != nil {
+		return fmt.Errorf("recording check run %d: %w", id, err)
+	}
+	if recorded {
+		return nil
+	}
+	// StartRun recorded its in-progress check run after the run was read above.
+	if run, err = s.repo.FindDriftAnalysisRunByUUID(ctx, runId); err != nil {
+		return fmt.Errorf("finding run %s: %w", runId, err)
+	}
+	return s.complete(ctx, publisher, run, *run.CheckRunID, detailsURL)
+}
+
+// TimeOutRun concludes the check run of a run deleted after it stopped reporting, so the check
+// does not stay in progress forever. The run is gone, so the check keeps its details URL.
+func (s *CheckService) TimeOutRun(ctx context.Context, org queries.GitOrganization, repo queries.GitRepository, runId uuid.UUID, id int64) error {
+	publisher, err := s.publishers(ctx, org, repo)
+	if err != nil {
+		return fmt.Errorf("creating the check publisher: %w", err)
+	}
+	if err := publisher.Update(ctx, id, CheckRun{
+		ExternalID: runId.String(),
+		Conclusion: ConclusionTimedOut,
+		Title:      "The scan stopped reporting",
+		Summary:    "The scan stopped reporting before it completed, so its results were discarded. Run it again to check this commit for drift.",
+	}); err != nil {
+		return fmt.Errorf("timing out check run %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *CheckService) complete(ctx context.Context, publisher Publisher, run queries.DriftAnalysisRun, id int64, detailsURL string) error {
+	check, err := s.report(ctx, run, detailsURL)
+	if err != nil {
+		return err
+	}
+	if err := publisher.Update(ctx, id, check); err != nil {
+		return fmt.Errorf("completing check run %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *CheckService) report(ctx context.Context, run queries.DriftAnalysisRun, detailsURL string) (CheckRun, error) {
+	projects, err := s.repo.FindDriftAnalysisProjectsByRunId(ctx, run.Uuid)
+	if err != nil {
+		return CheckRun{}, fmt.Errorf("listing the projects of run %s: %w", run.Uuid, err)
+	}
+	return describe(run, projects, detailsURL), nil
+}
+
+// describe concludes a completed run: failure when a project drifted, neutral when none did but
+// some could not be analyzed, success otherwise. Skipped projects count as neither.
+func describe(run queries.DriftAnalysisRun, projects []queries.DriftAnalysisProject, detailsURL string) CheckRun {
+	var drifted, errored []queries.DriftAnalysisProject
+	for _, p := range projects {
+		switch {
+		case p.SkippedDueToPr:
+		case !p.Succeeded:
+			errored = append(errored, p)
+		case p.Drifted:
+			drifted = append(drifted, p)
+		}
+	}
+
+	check := CheckRun{ExternalID: run.Uuid.String(), DetailsURL: detailsURL}
+	total := max(int(run.TotalProjects), len(projects))
+	switch {
+	case len(drifted) > 0:
+		check.Conclusion = ConclusionFailure
+		check.Title = fmt.Sprintf("%d of %d projects drifted", len(drifted), total)
+		if len(errored) > 0 {
+			check.Title += fmt.Sprintf(", %d errored", len(errored))
+		}
+	case len(errored) > 0:
+		check.Conclusion = ConclusionNeutral
+		check.Title = fmt.Sprintf("No drift, but %d of %d projects errored", len(errored), total)
+	default:
+		check.Conclusion = ConclusionSuccess
+		check.Title = fmt.Sprintf("No drift in %d projects", total)
+	}
+	check.Summary = summary(drifted, errored, detailsURL)
+	return check
+}
+
+func summary(drifted, errored []queries.DriftAnalysisProject, detailsURL string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[View the run in the dashboard](%s)\n", detailsURL)
+	if len(drifted) > 0 {
+		sb.WriteString("\n### Drifted projects\n\n| Project | Type | Changes |\n| --- | --- | --- |\n")
+		for _, p := range drifted[:min(len(drifted), maxListedProjects)] {
+			fmt.Fprintf(&sb, "| %s | %s | %s |\n", tableCell(p.Dir), p.Type, parsing.FormatResourceCounts(p))
+		}
+		writeOmitted(&sb, len(drifted))
+	}
+	if len(errored) > 0 {
+		sb.WriteString("\n### Errored projects\n\n| Project | Type | Error |\n| --- | --- | --- |\n")
+		for _, p := range errored[:min(len(errored), maxListedProjects)] {
+			fmt.Fprintf(&sb, "| %s | %s | %s |\n", tableCell(p.Dir), p.Type, tableCell(strutils.OrEmpty(p.ErrorClass)))
+		}
+		writeOmitted(&sb, len(errored))
+	}
+	return sb.String()
+}
+
+func writeOmitted(sb *strings.Builder, count int) {
+	if count > maxListedProjects {
+		fmt.Fprintf(sb, "\nand %d more in the dashboard.\n", count-maxListedProjects)
+	}
+}
+
+// tableCell escapes the pipes of s, which would otherwise split a markdown table cell.
+func tableCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,64 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/google/uuid"
+)
+
+func TestDescribe_Conclusion(t *testing.T) {
+	clean := queries.DriftAnalysisProject{Dir: "clean", Succeeded: true}
+	drifted := queries.DriftAnalysisProject{Dir: "drifted", Succeeded: true, Drifted: true}
+	errored := queries.DriftAnalysisProject{Dir: "errored"}
+	skipped := queries.DriftAnalysisProject{Dir: "skipped", SkippedDueToPr: true, Drifted: true}
+
+	cases := []struct {
+		name     string
+		projects []queries.DriftAnalysisProject
+		want     string
+		title    string
+	}{
+		{"clean", []queries.DriftAnalysisProject{clean, skipped}, ConclusionSuccess, "No drift in 2 projects"},
+		{"errored", []queries.DriftAnalysisProject{clean, errored}, ConclusionNeutral, "No drift, but 1 of 2 projects errored"},
+		{"drifted", []queries.DriftAnalysisProject{clean, drifted, errored}, ConclusionFailure, "1 of 3 projects drifted, 1 errored"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := describe(queries.DriftAnalysisRun{Uuid: uuid.New()}, tc.projects, "http://test.local/run")
+			if check.Conclusion != tc.want || check.Title != tc.title {
+				t.Errorf("describe = %q %q, want %q %q", check.Conclusion, check.Title, tc.want, tc.title)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	added, changed, destroyed := int32(1), int32(2), int32(0)
+	errorClass := "auth"
+	got := summary(
+		[]queries.DriftAnalysisProject{{Dir: "infra/a|b", Type: "terraform", ResourcesAdded: &added, ResourcesChanged: &changed, ResourcesDestroyed: &destroyed}},
+		[]queries.DriftAnalysisProject{{Dir: "infra/c", Type: "tofu", ErrorClass: &errorClass}},
+		"http://test.local/run",
+	)
+	for _, want := range []string{
+		"[View the run in the dashboard](http://test.local/run)",
+		`| infra/a\|b | terraform | 1 to add, 2 to change, 0 to destroy |`,
+		"| infra/c | tofu | auth |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary is missing %q:\n%s", want, got)
+		}
+	}
+
+	var many []queries.DriftAnalysisProject
+	for i := range maxListedProjects + 3 {
+		many = append(many, queries.DriftAnalysisProject{Dir: fmt.Sprintf("dir%d", i)})
+	}
+	got = summary(many, nil, "http://test.local/run")
+	if !strings.HasSuffix(got, "\nand 3 more in the dashboard.\n") || strings.Contains(got, fmt.Sprintf("dir%d ", maxListedProjects)) {
+		t.Errorf("summary of %d projects lists:\n%s", len(many), got)
+	}
+}
@@ -0,0 +1,76 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/utils/gh"
+	"github.com/google/go-github/v88/github"
+)
+
+// GitHubPublisher publishes check runs through the GitHub App installation of the repository's
+// organization.
+type GitHubPublisher struct {
+	client *github.Client
+	owner  string
+	repo   string
+}
+
+// NewGitHubPublisher is the PublisherFactory of GitHub repositories.
+func NewGitHubPublisher(ctx context.Context, org queries.GitOrganization, repo queries.GitRepository) (Publisher, error) {
+	if org.InstallationID == nil {
+		return nil, errors.New("the GitHub App is not installed on the organization")
+	}
+	client, err := gh.NewAppGithubInstallationClient(ctx, *org.InstallationID)
+	if err != nil {
+		return nil, err
+	}
+	return &GitHubPublisher{client: client, owner: org.Name, repo: repo.Name}, nil
+}
+
+func (p *GitHubPublisher) Create(ctx context.Context, headSHA string, check CheckRun) (int64, error) {
+	opts := github.CreateCheckRunOptions{
+		Name:       CheckName,
+		HeadSHA:    headSHA,
+		DetailsURL: &check.DetailsURL,
+		ExternalID: &check.ExternalID,
+		Status:     github.Ptr("in_progress"),
+		Output:     output(check),
+	}
+	if check.Conclusion != "" {
+		opts.Status = github.Ptr("completed")
+		opts.Conclusion = &check.Conclusion
+		opts.CompletedAt = &github.Timestamp{Time: time.Now()}
+	}
+	run, _, err := p.client.Checks.CreateCheckRun(ctx, p.owner, p.repo, opts)
+	if err != nil {
+		return 0, err
+	}
+	return run.GetID(), nil
+}
+
+func (p *GitHubPublisher) Update(ctx context.Context, id int64, check CheckRun) error {
+	opts := github.UpdateCheckRunOptions{
+		Name:       CheckName,
+		DetailsURL: &check.DetailsURL,
+		ExternalID: &check.ExternalID,
+		Status:     github.Ptr("in_progress"),
+		Output:     output(check),
+	}
+	if check.DetailsURL == "" {
+		opts.DetailsURL = nil
+	}
+	if check.Conclusion != "" {
+		opts.Status = github.Ptr("completed")
+		opts.Conclusion = &check.Conclusion
+		opts.CompletedAt = &github.Timestamp{Time: time.Now()}
+	}
+	_, _, err := p.client.Checks.UpdateCheckRun(ctx, p.owner, p.repo, id, opts)
+	return err
+}
+
+func output(check CheckRun) *github.CheckRunOutput {
+	return &github.CheckRunOutput{Title: &check.Title, Summary: &check.Summary}
+}
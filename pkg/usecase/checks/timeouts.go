@@ -0,0 +1,94 @@
+package checks
+
+import (
+	"context"
+	"time"
+
+	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+const (
+	timeoutReportInterval = time.Minute
+	timeoutReportBatch    = 50
+
+	// timeoutRetryMinutes is how much longer each failed attempt to time out a check run waits
+	// before the next one. After maxTimeoutAttempts the check run is given up on.
+	timeoutRetryMinutes = 5
+	maxTimeoutAttempts  = 10
+
+	// timeoutCallTimeout bounds the GitHub call timing out one check run, so an unresponsive
+	// installation cannot hold the claimed batch.
+	timeoutCallTimeout = 30 * time.Second
+)
+
+// StartTimeoutReporter concludes the check runs the stale run sweeper queued as timed out. Safe to
+// run on every API instance at once: ClaimCheckRunTimeouts claims rows with FOR UPDATE SKIP LOCKED.
+func (s *CheckService) StartTimeoutReporter(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("check run timeout reporter shutting down...")
+			return
+		case <-time.After(timeoutReportInterval):
+		}
+
+		for {
+			claimed, err := s.ReportTimeouts(ctx, timeoutReportBatch)
+			if err != nil {
+				log.Errorf("error timing out check runs: %v", err)
+				break
+			}
+			if claimed < timeoutReportBatch {
+				break
+			}
+		}
+	}
+}
+
+// ReportTimeouts concludes up to maxRows queued check runs as timed out and returns how many it
+// claimed. A check run GitHub refused stays queued for a later attempt, unless it has run out of
+// attempts.
+func (s *CheckService) ReportTimeouts(ctx context.Context, maxRows int32) (int, error) {
+	var claimed int
+	err := s.repo.WithTx(ctx, func(ctx context.Context) error {
+		queued, err := s.repo.ClaimCheckRunTimeouts(ctx, maxRows)
+		if err != nil {
+			return err
+		}
+		claimed = len(queued)
+		for _, q := range queued {
+			err := s.timeOut(ctx, q)
+			switch {
+			case err == nil:
+			case q.Attempts+1 < maxTimeoutAttempts:
+				log.Warnf("Timing out check run %d of run %s failed, retrying later: %v", q.CheckRunID, q.RunID, err)
+				if err := s.repo.RetryCheckRunTimeout(ctx, q.CheckRunID, timeoutRetryMinutes); err != nil {
+					return err
+				}
+				continue
+			default:
+				log.Errorf("Timing out check run %d of run %s failed %d times, giving up: %v", q.CheckRunID, q.RunID, maxTimeoutAttempts, err)
+			}
+			if err := s.repo.DeleteCheckRunTimeout(ctx, q.CheckRunID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+func (s *CheckService) timeOut(ctx context.Context, q queries.CheckRunTimeout) error {
+	repo, err := s.repos.FindGitRepositoryById(ctx, q.RepositoryID)
+	if err != nil {
+		return err
+	}
+	org, err := s.orgs.FindGitOrgById(ctx, repo.OrganizationID)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeoutCallTimeout)
+	defer cancel()
+	return s.TimeOutRun(ctx, org, repo, q.RunID, q.CheckRunID)
+}
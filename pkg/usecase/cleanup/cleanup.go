@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"driftive.cloud/api/pkg/blobstore"
 	"driftive.cloud/api/pkg/repository"
 	"github.com/gofiber/fiber/v3/log"
 )
@@ -11,19 +12,24 @@ import (
 const (
 	staleRunSweepInterval = 5 * time.Minute
 	staleRunSweepBatch    = 100
+
+	outputBlobReapInterval = time.Minute
+	outputBlobReapBatch    = 500
 )
 
 type CleanupService struct {
 	driftAnalysisRepo repository.DriftAnalysisRepository
 	maxRunsPerRepo    int32
 	staleRunMinutes   int32
+	blobs             blobstore.BlobStore
 }
 
-func NewCleanupService(driftAnalysisRepo repository.DriftAnalysisRepository, maxRunsPerRepo int32, staleRunMinutes int32) *CleanupService {
+func NewCleanupService(driftAnalysisRepo repository.DriftAnalysisRepository, maxRunsPerRepo int32, staleRunMinutes int32, blobs blobstore.BlobStore) *CleanupService {
 	return &CleanupService{
 		driftAnalysisRepo: driftAnalysisRepo,
 		maxRunsPerRepo:    maxRunsPerRepo,
 		staleRunMinutes:   staleRunMinutes,
+		blobs:             blobs,
 	}
 }
 
@@ -56,3 +62,54 @@ func (s *CleanupService) StartStaleRunSweeper(ctx context.Context) {
 		}
 	}
 }
+
+// StartOutputBlobReaper deletes the output blobs of project rows removed by retention, the stale
+// run sweeper, a repository erase or any cascade; a database trigger queues their references.
+// Only started when a blob store is configured.
+func (s *CleanupService) StartOutputBlobReaper(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("output blob reaper shutting down...")
+			return
+		case <-time.After(outputBlobReapInterval):
+		}
+
+		// Drain the queue a batch at a time rather than waiting an interval per batch, since a
+		// retention pass on a large repository can queue far more than one batch.
+		for {
+			reaped, err := s.ReapOutputBlobs(ctx, outputBlobReapBatch)
+			if err != nil {
+				log.Errorf("error reaping output blobs: %v", err)
+				break
+			}
+			if reaped > 0 {
+				log.Infof("deleted %d output blob(s)", reaped)
+			}
+			if reaped < outputBlobReapBatch {
+				break
+			}
+		}
+	}
+}
+
+// ReapOutputBlobs deletes up to maxRows queued blobs and returns how many it deleted. The queue
+// rows are removed in the same transaction that claimed them, so if any blob delete fails the
+// whole batch stays queued and is retried on the next pass.
+func (s *CleanupService) ReapOutputBlobs(ctx context.Context, maxRows int32) (int, error) {
+	var reaped int
+	err := s.driftAnalysisRepo.WithTx(ctx, func(ctx context.Context) error {
+		refs, err := s.driftAnalysisRepo.ClaimOutputBlobDeletions(ctx, maxRows)
+		if err != nil || len(refs) == 0 {
+			return err
+		}
+		for _, ref := range refs {
+			if err := s.blobs.Delete(ctx, ref); err != nil {
+				return err
+			}
+		}
+		reaped = len(refs)
+		return s.driftAnalysisRepo.DeleteOutputBlobDeletions(ctx, refs)
+	})
+	return reaped, err
+}
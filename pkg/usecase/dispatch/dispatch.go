@@ -0,0 +1,274 @@
+// Package dispatch triggers drift scans and remediation applies from the dashboard by dispatching
+// the repository's GitHub workflow. The workflow uploads its run with the dispatch id as
+// Idempotency-Key, which ties the run back to who requested it.
+package dispatch
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository"
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/utils/auth"
+	"driftive.cloud/api/pkg/usecase/utils/parsing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/google/uuid"
+)
+
+const (
+	KindScan      = "SCAN"
+	KindRemediate = "REMEDIATE"
+
+	// maxDispatchDirs bounds the dirs of a scan dispatch, which are passed to the workflow.
+	maxDispatchDirs = 100
+	// listDispatchesLimit is how many of the latest dispatches are listed.
+	listDispatchesLimit = 50
+)
+
+// Event is what a dispatched workflow is told to do.
+type Event struct {
+	ID   uuid.UUID
+	Kind string
+	// Dirs are the dirs to scan, all of them when empty, or the one dir to remediate.
+	Dirs        []string
+	ProjectType string
+	// RequestedBy is the username of the user who requested it.
+	RequestedBy string
+}
+
+// Dispatcher triggers the workflows of one repository.
+type Dispatcher interface {
+	Dispatch(ctx context.Context, event Event) error
+}
+
+// DispatcherFactory returns the Dispatcher of a repository.
+type DispatcherFactory func(ctx context.Context, org queries.GitOrganization, repo queries.GitRepository) (Dispatcher, error)
+
+type DispatchHandler struct {
+	orgRepository           repository.GitOrgRepository
+	repoRepository          repository.GitRepositoryRepository
+	userRepository          repository.UserRepository
+	driftAnalysisRepository repository.DriftAnalysisRepository
+	dispatchers             DispatcherFactory
+}
+
+func NewDispatchHandler(
+	orgRepository repository.GitOrgRepository,
+	repoRepository repository.GitRepositoryRepository,
+	userRepository repository.UserRepository,
+	driftAnalysisRepository repository.DriftAnalysisRepository,
+	dispatchers DispatcherFactory,
+) *DispatchHandler {
+	return &DispatchHandler{
+		orgRepository:           orgRepository,
+		repoRepository:          repoRepository,
+		userRepository:          userRepository,
+		driftAnalysisRepository: driftAnalysisRepository,
+		dispatchers:             dispatchers,
+	}
+}
+
+// DispatchScan triggers a scan of the repository, of the dirs in the body when there are any. The
+// dirs must be projects of the repository's catalog.
+func (h *DispatchHandler) DispatchScan(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	var req dto.ScanDispatchRequest
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&req); err != nil {
+			return c.SendStatus(fiber.StatusBadRequest)
+		}
+	}
+	dirs := compactDirs(req.Dirs)
+	if len(dirs) > maxDispatchDirs {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	isMember, err := h.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	if len(dirs) > 0 {
+		projects, err := h.catalog(c.Context(), repoId)
+		if err != nil {
+			log.Errorf("Error listing projects of repository %d: %v", repoId, err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		for _, dir := range dirs {
+			if !slices.ContainsFunc(projects, func(p queries.DriftProject) bool { return p.Dir == dir }) {
+				return c.SendStatus(fiber.StatusBadRequest)
+			}
+		}
+	}
+
+	return h.dispatch(c, repoId, *userId, Event{Kind: KindScan, Dirs: dirs})
+}
+
+// DispatchRemediation triggers a remediation apply of one project of the repository's catalog.
+// Only organization admins may, and only for repositories opted in through their dispatch settings;
+// the others get a 403.
+func (h *DispatchHandler) DispatchRemediation(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	var req dto.RemediationDispatchRequest
+	if err := c.Bind().Body(&req); err != nil || strings.TrimSpace(req.Dir) == "" || strings.TrimSpace(req.Type) == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	isMember, err := h.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	isAdmin, err := h.orgRepository.IsUserAdminOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isAdmin {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	repo, err := h.repoRepository.FindGitRepositoryById(c.Context(), repoId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !repo.RemediationEnabled {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	projects, err := h.catalog(c.Context(), repoId)
+	if err != nil {
+		log.Errorf("Error listing projects of repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	dir, projectType := strings.TrimSpace(req.Dir), strings.ToUpper(strings.TrimSpace(req.Type))
+	if !slices.ContainsFunc(projects, func(p queries.DriftProject) bool { return p.Dir == dir && p.Type == projectType }) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	return h.dispatch(c, repoId, *userId, Event{Kind: KindRemediate, Dirs: []string{dir}, ProjectType: projectType})
+}
+
+// ListDispatches returns the latest dispatches of the repository, newest first, with the run each
+// scan resulted in once it started uploading.
+func (h *DispatchHandler) ListDispatches(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	isMember, err := h.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	rows, err := h.repoRepository.ListScanDispatches(c.Context(), repoId, listDispatchesLimit)
+	if err != nil {
+		log.Errorf("Error listing dispatches of repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return c.JSON(parsing.ToScanDispatchDTOs(rows))
+}
+
+// dispatch records the dispatch and triggers the repository's workflow, forgetting the dispatch
+// again when GitHub refuses it.
+func (h *DispatchHandler) dispatch(c fiber.Ctx, repoId int64, userId int64, event Event) error {
+	repo, err := h.repoRepository.FindGitRepositoryById(c.Context(), repoId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	org, err := h.orgRepository.FindGitOrgById(c.Context(), repo.OrganizationID)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	user, err := h.userRepository.FindUserByID(c.Context(), userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	event.ID = uuid.New()
+	event.RequestedBy = user.Username
+
+	var projectType *string
+	if event.ProjectType != "" {
+		projectType = &event.ProjectType
+	}
+	dirs := event.Dirs
+	if dirs == nil {
+		dirs = []string{}
+	}
+	if _, err := h.repoRepository.CreateScanDispatch(c.Context(), queries.CreateScanDispatchParams{
+		ID:           event.ID,
+		RepositoryID: repo.ID,
+		Kind:         event.Kind,
+		Dirs:         dirs,
+		ProjectType:  projectType,
+		RequestedBy:  userId,
+	}); err != nil {
+		log.Errorf("Error recording dispatch for repository %d: %v", repo.ID, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	dispatcher, err := h.dispatchers(c.Context(), org, repo)
+	if err == nil {
+		err = dispatcher.Dispatch(c.Context(), event)
+	}
+	if err != nil {
+		log.Warnf("Error dispatching %s of repository %d: %v", strings.ToLower(event.Kind), repo.ID, err)
+		if deleteErr := h.repoRepository.DeleteScanDispatch(c.Context(), event.ID); deleteErr != nil {
+			log.Errorf("Error deleting failed dispatch %s: %v", event.ID, deleteErr)
+		}
+		return c.SendStatus(fiber.StatusBadGateway)
+	}
+
+	log.Infof("User %d dispatched %s %s of repository %d", userId, strings.ToLower(event.Kind), event.ID, repo.ID)
+	return c.Status(fiber.StatusAccepted).JSON(dto.DispatchResponse{DispatchID: event.ID.String()})
+}
+
+func (h *DispatchHandler) catalog(ctx context.Context, repoId int64) ([]queries.DriftProject, error) {
+	return h.driftAnalysisRepository.ListDriftProjectsByRepositoryId(ctx, queries.ListDriftProjectsByRepositoryIdParams{RepositoryID: repoId})
+}
+
+// compactDirs trims dirs and drops the empty and repeated ones, keeping their order.
+func compactDirs(dirs []string) []string {
+	var result []string
+	for _, dir := range dirs {
+		dir = strings.TrimSpace(dir)
+		if dir != "" && !slices.Contains(result, dir) {
+			result = append(result, dir)
+		}
+	}
+	return result
+}
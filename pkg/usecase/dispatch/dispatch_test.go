@@ -0,0 +1,16 @@
+package dispatch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompactDirs(t *testing.T) {
+	got := compactDirs([]string{" /envs/prod ", "", "/envs/dev", "/envs/prod", "  "})
+	if want := []string{"/envs/prod", "/envs/dev"}; !slices.Equal(got, want) {
+		t.Errorf("compactDirs = %q, want %q", got, want)
+	}
+	if got := compactDirs(nil); got != nil {
+		t.Errorf("compactDirs(nil) = %q, want nil", got)
+	}
+}
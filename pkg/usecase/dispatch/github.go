@@ -0,0 +1,102 @@
+package dispatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/utils/gh"
+	"github.com/google/go-github/v88/github"
+)
+
+// Event types of the repository_dispatch events, for workflows to filter on with
+// `on: repository_dispatch: types: [...]`.
+const (
+	ScanEventType      = "driftive-scan"
+	RemediateEventType = "driftive-remediate"
+)
+
+// GitHubDispatcher triggers workflows through the GitHub App installation of the repository's
+// organization. Repositories with a dispatch workflow get a workflow_dispatch of it on their
+// default branch; it must declare the dispatch_id, action, dirs, project_type and requested_by
+// inputs. The others get a repository_dispatch event carrying the same fields in client_payload.
+type GitHubDispatcher struct {
+	client   *github.Client
+	owner    string
+	repo     string
+	workflow string
+}
+
+// NewGitHubDispatcher is the DispatcherFactory of GitHub repositories.
+func NewGitHubDispatcher(ctx context.Context, org queries.GitOrganization, repo queries.GitRepository) (Dispatcher, error) {
+	if org.InstallationID == nil {
+		return nil, errors.New("the GitHub App is not installed on the organization")
+	}
+	client, err := gh.NewAppGithubInstallationClient(ctx, *org.InstallationID)
+	if err != nil {
+		return nil, err
+	}
+	d := &GitHubDispatcher{client: client, owner: org.Name, repo: repo.Name}
+	if repo.DispatchWorkflow != nil {
+		d.workflow = *repo.DispatchWorkflow
+	}
+	return d, nil
+}
+
+func (d *GitHubDispatcher) Dispatch(ctx context.Context, event Event) error {
+	action := strings.ToLower(event.Kind)
+	dirs := event.Dirs
+	if dirs == nil {
+		dirs = []string{}
+	}
+	if d.workflow == "" {
+		return d.repositoryDispatch(ctx, event, action, dirs)
+	}
+
+	repo, _, err := d.client.Repositories.Get(ctx, d.owner, d.repo)
+	if err != nil {
+		return fmt.Errorf("finding the default branch: %w", err)
+	}
+	// Workflow inputs are strings, so the dirs are passed as JSON for the workflow to fromJSON.
+	encodedDirs, err := json.Marshal(dirs)
+	if err != nil {
+		return err
+	}
+	_, _, err = d.client.Actions.CreateWorkflowDispatchEventByFileName(ctx, d.owner, d.repo, d.workflow, github.CreateWorkflowDispatchEventRequest{
+		Ref: repo.GetDefaultBranch(),
+		Inputs: map[string]any{
+			"dispatch_id":  event.ID.String(),
+			"action":       action,
+			"dirs":         string(encodedDirs),
+			"project_type": strings.ToLower(event.ProjectType),
+			"requested_by": event.RequestedBy,
+		},
+	})
+	return err
+}
+
+func (d *GitHubDispatcher) repositoryDispatch(ctx context.Context, event Event, action string, dirs []string) error {
+	eventType := ScanEventType
+	if event.Kind == KindRemediate {
+		eventType = RemediateEventType
+	}
+	payload, err := json.Marshal(map[string]any{
+		"dispatch_id":  event.ID.String(),
+		"action":       action,
+		"dirs":         dirs,
+		"project_type": strings.ToLower(event.ProjectType),
+		"requested_by": event.RequestedBy,
+	})
+	if err != nil {
+		return err
+	}
+	raw := json.RawMessage(payload)
+	_, _, err = d.client.Repositories.Dispatch(ctx, d.owner, d.repo, github.DispatchRequestOptions{
+		EventType:     eventType,
+		ClientPayload: &raw,
+	})
+	return err
+}
@@ -2,15 +2,23 @@ package drift_stream
 
 import (
 	"context"
+	"driftive.cloud/api/pkg/ansi"
 	"driftive.cloud/api/pkg/config"
+	"driftive.cloud/api/pkg/errclass"
 	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/redact"
 	"driftive.cloud/api/pkg/repository"
 	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/checks"
 	"driftive.cloud/api/pkg/usecase/cleanup"
+	"driftive.cloud/api/pkg/usecase/issues"
+	"driftive.cloud/api/pkg/usecase/outputs"
+	"driftive.cloud/api/pkg/usecase/schedule"
 	"driftive.cloud/api/pkg/usecase/utils/auth"
 	"driftive.cloud/api/pkg/usecase/utils/parsing"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/gofiber/fiber/v3"
@@ -29,18 +37,29 @@ const (
 	runStatusCompleted = "COMPLETED"
 )
 
+// githubSyncTimeout bounds the GitHub calls publishing a run: its check run and drift issues.
+const githubSyncTimeout = 2 * time.Minute
+
 type DriftStateHandler struct {
 	cfg                     *config.Config
 	orgRepository           repository.GitOrgRepository
 	repoRepository          repository.GitRepositoryRepository
 	driftAnalysisRepository repository.DriftAnalysisRepository
 	cleanupService          *cleanup.CleanupService
+	outputs                 *outputs.OutputService
+	issues                  *issues.IssueService
+	checks                  *checks.CheckService
+	classifier              *errclass.Classifier
+	publishing              chan func(context.Context)
 }
 
 // DriftAnalysisResponse is the response returned after a successful drift analysis upload
 type DriftAnalysisResponse struct {
 	RunID        string `json:"run_id"`
 	DashboardURL string `json:"dashboard_url"`
+	// DriftUnchanged is true when the run found drift and all of it is the same as in the previous
+	// run, so there is nothing new to alert on.
+	DriftUnchanged bool `json:"drift_unchanged"`
 }
 
 func NewDriftStateHandler(
@@ -48,13 +67,21 @@ func NewDriftStateHandler(
 	orgRepository repository.GitOrgRepository,
 	repoRepository repository.GitRepositoryRepository,
 	driftAnalysisRepo repository.DriftAnalysisRepository,
-	cleanupService *cleanup.CleanupService) *DriftStateHandler {
+	cleanupService *cleanup.CleanupService,
+	outputService *outputs.OutputService,
+	issueService *issues.IssueService,
+	checkService *checks.CheckService) *DriftStateHandler {
 	return &DriftStateHandler{
 		cfg:                     cfg,
 		orgRepository:           orgRepository,
 		repoRepository:          repoRepository,
 		driftAnalysisRepository: driftAnalysisRepo,
 		cleanupService:          cleanupService,
+		outputs:                 outputService,
+		issues:                  issueService,
+		checks:                  checkService,
+		classifier:              errclass.New(slices.Concat(cfg.ErrorClass.Rules, errclass.Builtins())...),
+		publishing:              make(chan func(context.Context), publishQueueSize),
 	}
 }
 
@@ -75,6 +102,10 @@ func projectTypeToDBString(projectType ProjectType) (string, error) {
 		return "TOFU", nil
 	case Terragrunt:
 		return "TERRAGRUNT", nil
+	case Pulumi:
+		return "PULUMI", nil
+	case CloudFormation:
+		return "CLOUDFORMATION", nil
 	default:
 		return "", errors.New("invalid project type")
 	}
@@ -107,34 +138,122 @@ func (d *DriftStateHandler) resolveRepoAndOrg(c fiber.Ctx) (queries.GitRepositor
 	return repo, org, fiber.StatusOK, true
 }
 
-// toUpsertParams validates every project type and parses each plan summary up front, so the
-// caller can reject a bad payload before opening a transaction.
-func toUpsertParams(runID uuid.UUID, results []DriftProjectResult) ([]queries.UpsertDriftAnalysisProjectParams, error) {
+// toUpsertParams validates every project type and size and parses each plan summary up front, so
+// the caller can reject a bad payload before opening a transaction.
+func (d *DriftStateHandler) toUpsertParams(redactor *redact.Redactor, runID uuid.UUID, results []DriftProjectResult) ([]queries.UpsertDriftAnalysisProjectParams, error) {
 	params := make([]queries.UpsertDriftAnalysisProjectParams, len(results))
 	for i, project := range results {
-		projectType, err := projectTypeToDBString(project.Project.Type)
+		param, err := d.toUpsertParam(redactor, runID, i, project)
 		if err != nil {
-			return nil, fmt.Errorf("project %d (%s): %w", i, project.Project.Dir, err)
-		}
-		added, changed, destroyed := ParsePlanSummary(project.PlanOutput)
-		params[i] = queries.UpsertDriftAnalysisProjectParams{
-			DriftAnalysisRunID: runID,
-			Dir:                project.Project.Dir,
-			Type:               projectType,
-			Drifted:            project.Drifted,
-			Succeeded:          project.Succeeded,
-			InitOutput:         &project.InitOutput,
-			PlanOutput:         &project.PlanOutput,
-			SkippedDueToPr:     project.SkippedDueToPR,
-			ResourcesAdded:     added,
-			ResourcesChanged:   changed,
-			ResourcesDestroyed: destroyed,
+			return nil, err
 		}
+		params[i] = param
 	}
 	return params, nil
 }
 
+// toUpsertParam validates one project and masks secrets in its outputs. The size limit applies to
+// the outputs as sent; the stored sizes are those of the redacted outputs. The parsers read the
+// redacted outputs with their escape codes stripped, while the outputs are stored as printed.
+func (d *DriftStateHandler) toUpsertParam(redactor *redact.Redactor, runID uuid.UUID, index int, project DriftProjectResult) (queries.UpsertDriftAnalysisProjectParams, error) {
+	if err := checkProjectSize(d.cfg.Ingest.MaxProjectBytes, index, project); err != nil {
+		return queries.UpsertDriftAnalysisProjectParams{}, err
+	}
+	projectType, err := projectTypeToDBString(project.Project.Type)
+	if err != nil {
+		return queries.UpsertDriftAnalysisProjectParams{}, fmt.Errorf("project %d (%s): %w", index, project.Project.Dir, err)
+	}
+	initOutput, initRedactions := redactor.Redact(project.InitOutput)
+	planOutput, planRedactions := redactor.Redact(project.PlanOutput)
+	initSize, planSize := int64(len(initOutput)), int64(len(planOutput))
+	plainInit, plainPlan := ansi.Strip(initOutput), ansi.Strip(planOutput)
+	summary := ParseSummary(project.Project.Type, plainPlan)
+	var fingerprint *string
+	if project.Drifted {
+		fingerprint = DriftFingerprint(project.Project.Type, plainPlan)
+	}
+	versions := ParseVersions(project.Project.Type, plainInit)
+	return queries.UpsertDriftAnalysisProjectParams{
+		DriftAnalysisRunID:   runID,
+		Dir:                  project.Project.Dir,
+		Type:                 projectType,
+		Drifted:              project.Drifted,
+		Succeeded:            project.Succeeded,
+		InitOutput:           &initOutput,
+		PlanOutput:           &planOutput,
+		SkippedDueToPr:       project.SkippedDueToPR,
+		ResourcesAdded:       summary.Added,
+		ResourcesChanged:     summary.Changed,
+		ResourcesDestroyed:   summary.Destroyed,
+		InitOutputSize:       &initSize,
+		PlanOutputSize:       &planSize,
+		Redactions:           jsonColumn(initRedactions.Add(planRedactions)),
+		ResourcesImported:    summary.Imported,
+		ResourcesForgotten:   summary.Forgotten,
+		ResourcesMoved:       summary.Moved,
+		OutputsChanged:       summary.OutputsChanged,
+		SummaryParserVersion: SummaryParserVersion,
+		ResourceChanges:      jsonColumn(ParseResourceChanges(project.Project.Type, plainPlan)),
+		DriftFingerprint:     fingerprint,
+		InitDurationMillis:   durationMillis(project.InitDuration),
+		PlanDurationMillis:   durationMillis(project.PlanDuration),
+		StartedAt:            project.StartedAt,
+		FinishedAt:           project.FinishedAt,
+		Retries:              project.Retries,
+		ErrorClass:           d.errorClass(project.Succeeded, project.SkippedDueToPR, plainInit, plainPlan),
+		CoreTool:             versions.CoreTool,
+		CoreVersion:          versions.CoreVersion,
+		ProviderVersions:     jsonColumn(versions.Providers),
+		Warnings:             jsonColumn(ParseWarnings(project.Project.Type, plainInit, plainPlan)),
+	}, nil
+}
+
+// durationMillis converts an optional duration to the milliseconds stored in the database.
+func durationMillis(d *time.Duration) *int64 {
+	if d == nil {
+		return nil
+	}
+	ms := d.Milliseconds()
+	return &ms
+}
+
+// findIdempotentRun looks up the run an Idempotency-Key already points at. If that run completed
+// and holds results, replay is true and the caller returns it without re-inserting, which lets the
+// CLI safely retry transient failures. A run that is still RUNNING (live progress reporting) or
+// holds no project rows is returned for adoption instead, so the results are still written rather
+// than swallowed. A nil run means the key is new.
+func (d *DriftStateHandler) findIdempotentRun(ctx context.Context, repoID int64, idemKey string) (*queries.DriftAnalysisRun, bool, error) {
+	existing, err := d.driftAnalysisRepository.FindRunByRepoAndIdempotencyKey(ctx, repoID, idemKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("looking up run by idempotency key: %w", err)
+	}
+	projectCount, err := d.driftAnalysisRepository.CountDriftAnalysisProjectsByRunId(ctx, existing.Uuid)
+	if err != nil {
+		return nil, false, fmt.Errorf("counting projects for run %s: %w", existing.Uuid, err)
+	}
+	if existing.Status == runStatusCompleted && projectCount > 0 {
+		log.Infof("Idempotent replay for repository %d, key %s -> run %s", repoID, idemKey, existing.Uuid)
+		return &existing, true, nil
+	}
+	log.Infof("Adopting %s run %s for repository %d, key %s", existing.Status, existing.Uuid, repoID, idemKey)
+	return &existing, false, nil
+}
+
+// HandleUpdate ingests a v1 upload, which is rejected with a bare 400 when malformed.
 func (d *DriftStateHandler) HandleUpdate(c fiber.Ctx) error {
+	return d.handleUpdate(c, false)
+}
+
+// HandleUpdateV2 ingests an upload under the v2 contract: the body is checked against the published
+// schema and every offending field is listed in the response.
+func (d *DriftStateHandler) HandleUpdateV2(c fiber.Ctx) error {
+	return d.handleUpdate(c, true)
+}
+
+func (d *DriftStateHandler) handleUpdate(c fiber.Ctx, validate bool) error {
 	log.Info("Handling drift state update")
 
 	repo, org, status, ok := d.resolveRepoAndOrg(c)
@@ -145,39 +264,42 @@ func (d *DriftStateHandler) HandleUpdate(c fiber.Ctx) error {
 
 	idemKey := strings.TrimSpace(c.Get("Idempotency-Key"))
 
-	// If the client sent an Idempotency-Key and we already have a completed run for it that holds
-	// results, return that run without re-inserting. This lets the CLI safely retry transient
-	// failures. A run that is still RUNNING (live progress reporting) or holds no project rows is
-	// adopted instead of replayed, so the results are still written rather than swallowed.
 	var adoptedRunUUID *uuid.UUID
 	if idemKey != "" {
-		existing, err := d.driftAnalysisRepository.FindRunByRepoAndIdempotencyKey(c.Context(), repo.ID, idemKey)
-		switch {
-		case err == nil:
-			projectCount, countErr := d.driftAnalysisRepository.CountDriftAnalysisProjectsByRunId(c.Context(), existing.Uuid)
-			if countErr != nil {
-				log.Errorf("Error counting projects for run %s: %v", existing.Uuid, countErr)
-				return c.SendStatus(fiber.StatusInternalServerError)
-			}
-			if existing.Status == runStatusCompleted && projectCount > 0 {
-				log.Infof("Idempotent replay for repository %d, key %s -> run %s", repo.ID, idemKey, existing.Uuid)
-				return c.JSON(buildAnalysisResponse(d.cfg.Frontend.FrontendURL, org, repo, existing.Uuid))
-			}
-			log.Infof("Adopting %s run %s for repository %d, key %s", existing.Status, existing.Uuid, repo.ID, idemKey)
-			adoptedRunUUID = &existing.Uuid
-		case !errors.Is(err, pgx.ErrNoRows):
-			log.Errorf("Error looking up run by idempotency key: %v", err)
+		existing, replay, err := d.findIdempotentRun(c.Context(), repo.ID, idemKey)
+		if err != nil {
+			log.Errorf("Error resolving idempotency key %s for repository %d: %v", idemKey, repo.ID, err)
 			return c.SendStatus(fiber.StatusInternalServerError)
 		}
+		if replay {
+			return c.JSON(d.completedAnalysisResponse(c.Context(), org, repo, existing.Uuid))
+		}
+		if existing != nil {
+			adoptedRunUUID = &existing.Uuid
+		}
 	}
 
 	var state DriftDetectionResult
-	if err := c.Bind().Body(&state); err != nil {
-		return c.SendStatus(fiber.StatusBadRequest)
+	if err := d.decodeIngestBody(c, &state); err != nil {
+		log.Warnf("Rejecting drift state update for repository %d: %v", repo.ID, err)
+		if validate && isDecodeError(err) {
+			return sendValidationErrors(c, fiber.StatusBadRequest, decodeFieldErrors(err))
+		}
+		return sendIngestError(c, err)
+	}
+	if validate {
+		if errs := validateDetectionResult(state, d.cfg.Ingest.MaxProjects); len(errs) > 0 {
+			log.Warnf("Rejecting drift state update for repository %d: %d invalid fields, first: %s", repo.ID, len(errs), errs[0].Message)
+			return sendValidationErrors(c, fiber.StatusUnprocessableEntity, errs)
+		}
 	}
 
 	log.Debugf("Received drift state update: %v", state)
 
+	if state.Shard != nil {
+		return d.handleShardUpdate(c, repo, org, idemKey, state)
+	}
+
 	// Use sent value or calculate errored count from project results as fallback
 	var totalErrored int32
 	if state.TotalErrored != nil {
@@ -202,12 +324,23 @@ func (d *DriftStateHandler) HandleUpdate(c fiber.Ctx) error {
 		runUUID = *adoptedRunUUID
 	}
 
-	upsertParams, err := toUpsertParams(runUUID, state.ProjectResults)
+	redactor, err := d.redactorFor(c.Context(), org.ID)
+	if err != nil {
+		log.Errorf("Error preparing redaction for repository %d: %v", repo.ID, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	upsertParams, err := d.toUpsertParams(redactor, runUUID, state.ProjectResults)
 	if err != nil {
 		log.Errorf("Rejecting drift state update: %v", err)
-		return c.SendStatus(fiber.StatusBadRequest)
+		return sendIngestError(c, err)
+	}
+	prepared, err := d.prepareOutputs(c.Context(), repo.ID, upsertParams)
+	if err != nil {
+		log.Errorf("Error preparing outputs for run %s: %v", runUUID, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	var finish func(context.Context)
 	err = d.driftAnalysisRepository.WithTx(c.Context(), func(ctx context.Context) error {
 		if adoptedRunUUID != nil {
 			completion := queries.MarkDriftAnalysisRunCompletedParams{
@@ -241,8 +374,16 @@ func (d *DriftStateHandler) HandleUpdate(c fiber.Ctx) error {
 			}
 			log.Info("Created drift analysis run: ", run.Uuid)
 		}
+		if _, err := d.recordCommitSHA(ctx, runUUID, state.CommitSHA); err != nil {
+			log.Errorf("Error recording the commit of run %s: %v", runUUID, err)
+			return err
+		}
 
 		if len(upsertParams) > 0 {
+			if err := d.storeOutputs(ctx, repo.ID, upsertParams, prepared); err != nil {
+				log.Errorf("Error storing outputs for run %s: %v", runUUID, err)
+				return err
+			}
 			if err := d.driftAnalysisRepository.UpsertDriftAnalysisProjects(ctx, upsertParams); err != nil {
 				log.Errorf("Error upserting drift analysis projects: %v", err)
 				return err
@@ -250,10 +391,16 @@ func (d *DriftStateHandler) HandleUpdate(c fiber.Ctx) error {
 			log.Debugf("Upserted %d drift analysis projects for run %s", len(upsertParams), runUUID)
 		}
 
+		done, err := d.completeRun(ctx, org, repo, runUUID)
+		if err != nil {
+			return err
+		}
+		finish = done
 		return nil
 	})
 
 	if err != nil {
+		d.discardOutputs(c.Context(), repo.ID, prepared)
 		// Race: a concurrent retry with the same idempotency key may have inserted first.
 		// The partial unique index on (repository_id, idempotency_key) trips with SQLSTATE 23505;
 		// re-fetch and return the winning row.
@@ -263,7 +410,7 @@ func (d *DriftStateHandler) HandleUpdate(c fiber.Ctx) error {
 				existing, lookupErr := d.driftAnalysisRepository.FindRunByRepoAndIdempotencyKey(c.Context(), repo.ID, idemKey)
 				if lookupErr == nil {
 					log.Infof("Idempotent race resolved for repository %d, key %s -> run %s", repo.ID, idemKey, existing.Uuid)
-					return c.JSON(buildAnalysisResponse(d.cfg.Frontend.FrontendURL, org, repo, existing.Uuid))
+					return c.JSON(d.completedAnalysisResponse(c.Context(), org, repo, existing.Uuid))
 				}
 			}
 		}
@@ -271,14 +418,9 @@ func (d *DriftStateHandler) HandleUpdate(c fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
-	// Trigger cleanup after successful insert (non-blocking, log errors but don't fail the request)
-	if d.cleanupService != nil {
-		if cleanupErr := d.cleanupService.CleanupRepositoryRuns(c.Context(), repo.ID); cleanupErr != nil {
-			log.Warnf("Cleanup failed for repository %d: %v", repo.ID, cleanupErr)
-		}
-	}
+	finish(c.Context())
 
-	return c.JSON(buildAnalysisResponse(d.cfg.Frontend.FrontendURL, org, repo, runUUID))
+	return c.JSON(d.completedAnalysisResponse(c.Context(), org, repo, runUUID))
 }
 
 func buildAnalysisResponse(frontendURL string, org queries.GitOrganization, repo queries.GitRepository, runUUID uuid.UUID) DriftAnalysisResponse {
@@ -295,6 +437,53 @@ func buildAnalysisResponse(frontendURL string, org queries.GitOrganization, repo
 	}
 }
 
+// recordCommitSHA records the commit a run scanned when the payload carries a valid one, reporting
+// whether it was recorded: false when there is none or the run already had one.
+func (d *DriftStateHandler) recordCommitSHA(ctx context.Context, runUUID uuid.UUID, sha *string) (bool, error) {
+	commit := commitSHA(sha)
+	if commit == "" {
+		return false, nil
+	}
+	return d.driftAnalysisRepository.SetDriftAnalysisRunCommitSha(ctx, runUUID, commit)
+}
+
+// startCheckRun queues an in-progress check run on the commit of a running run, for StartPublisher
+// to put on GitHub.
+func (d *DriftStateHandler) startCheckRun(org queries.GitOrganization, repo queries.GitRepository, runUUID uuid.UUID, sha string) {
+	if d.checks == nil {
+		return
+	}
+	dashboardURL := buildAnalysisResponse(d.cfg.Frontend.FrontendURL, org, repo, runUUID).DashboardURL
+	d.enqueuePublish(runUUID, func(ctx context.Context) {
+		if err := d.checks.StartRun(ctx, org, repo, runUUID, sha, dashboardURL); err != nil {
+			log.Warnf("Starting the check run of run %s in repository %d failed: %v", runUUID, repo.ID, err)
+		}
+	})
+}
+
+// publishCompletedRun queues a completed run to be reported to GitHub by StartPublisher, so the CLI
+// doesn't wait on it: the check run of its commit is concluded and the drift issues of the
+// repository synced.
+func (d *DriftStateHandler) publishCompletedRun(org queries.GitOrganization, repo queries.GitRepository, runUUID uuid.UUID) {
+	syncIssues := d.issues != nil && repo.DriftIssuesEnabled
+	if d.checks == nil && !syncIssues {
+		return
+	}
+	dashboardURL := buildAnalysisResponse(d.cfg.Frontend.FrontendURL, org, repo, runUUID).DashboardURL
+	d.enqueuePublish(runUUID, func(ctx context.Context) {
+		if d.checks != nil {
+			if err := d.checks.CompleteRun(ctx, org, repo, runUUID, dashboardURL); err != nil {
+				log.Warnf("Completing the check run of run %s in repository %d failed: %v", runUUID, repo.ID, err)
+			}
+		}
+		if syncIssues {
+			if err := d.issues.SyncRunIssues(ctx, org, repo, runUUID, dashboardURL); err != nil {
+				log.Warnf("Syncing drift issues for repository %d from run %s failed: %v", repo.ID, runUUID, err)
+			}
+		}
+	})
+}
+
 func (d *DriftStateHandler) ListRunsByRepoId(c fiber.Ctx) error {
 	userId, err := auth.MustGetLoggedUserId(c)
 	if err != nil {
@@ -320,7 +509,18 @@ func (d *DriftStateHandler) ListRunsByRepoId(c fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 
-	runs, err := d.driftAnalysisRepository.FindDriftAnalysisRunsByRepositoryID(c.Context(), repoId, page)
+	var runs []queries.DriftAnalysisRun
+	if owner := strings.TrimSpace(c.Query("owner")); owner != "" {
+		// Only the runs in which a project of the owner drifted.
+		dirs, err := d.ownedDirs(c.Context(), repoId, owner)
+		if err != nil {
+			log.Errorf("Error resolving the projects of %s in repository %d: %v", owner, repoId, err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		runs, err = d.driftAnalysisRepository.FindDriftAnalysisRunsByRepositoryIDAndDirs(c.Context(), repoId, dirs, page)
+	} else {
+		runs, err = d.driftAnalysisRepository.FindDriftAnalysisRunsByRepositoryID(c.Context(), repoId, page)
+	}
 	if err != nil {
 		log.Errorf("Error finding drift analysis runs by repository ID: %v", err)
 		return c.SendStatus(fiber.StatusInternalServerError)
@@ -373,8 +573,30 @@ func (d *DriftStateHandler) GetRunById(c fiber.Ctx) error {
 		log.Errorf("Error finding drift analysis projects by run ID: %v", err)
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
+	if err := d.outputs.Hydrate(c.Context(), projects); err != nil {
+		log.Errorf("Error loading outputs for run %s: %v", runId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
 
 	runDTO := parsing.ToDriftAnalysisRunWithProjectsDTO(run, projects)
+	coverageChanges, err := d.driftAnalysisRepository.GetRunCoverageChanges(c.Context(), runId)
+	if err != nil {
+		log.Errorf("Error getting coverage changes of run %s: %v", runId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	runDTO.CoverageChanges = parsing.ToCoverageChanges(coverageChanges)
+	rules, err := d.codeownersFor(c.Context(), run.RepositoryID)
+	if err != nil {
+		log.Errorf("Error loading CODEOWNERS of repository %d: %v", run.RepositoryID, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	for i := range runDTO.Projects {
+		runDTO.Projects[i].Owners = ownersOf(rules, runDTO.Projects[i].Dir)
+	}
+	if err := d.markUnchangedDrift(c.Context(), runId, runDTO.Projects); err != nil {
+		log.Errorf("Error comparing drift of run %s with the previous run: %v", runId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
 	return c.JSON(runDTO)
 }
 
@@ -405,8 +627,10 @@ func (d *DriftStateHandler) GetRepositoryStats(c fiber.Ctx) error {
 	}
 
 	result := dto.RepositoryRunStatsDTO{
-		TotalRuns:     stats.TotalRuns,
-		RunsWithDrift: stats.RunsWithDrift,
+		TotalRuns:             stats.TotalRuns,
+		RunsWithDrift:         stats.RunsWithDrift,
+		LatestRunErrorClasses: []dto.ErrorClassCountDTO{},
+		UnscannedProjects:     []dto.UnscannedProjectDTO{},
 	}
 
 	// Handle last_run_at which can be nil
@@ -416,9 +640,10 @@ func (d *DriftStateHandler) GetRepositoryStats(c fiber.Ctx) error {
 		}
 	}
 
-	// Get latest run details if there are runs
+	// Get latest run details if there are runs. Runs scoped to some dirs by a dispatch don't say
+	// anything about the other projects, so the latest run is the latest full one.
 	if stats.TotalRuns > 0 {
-		latestRun, err := d.driftAnalysisRepository.GetLatestRunForRepository(c.Context(), repoId)
+		latestRun, err := d.driftAnalysisRepository.GetLatestFullRunForRepository(c.Context(), repoId)
 		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 			log.Errorf("Error getting latest run for repository: %v", err)
 			return c.SendStatus(fiber.StatusInternalServerError)
@@ -426,12 +651,64 @@ func (d *DriftStateHandler) GetRepositoryStats(c fiber.Ctx) error {
 		if err == nil {
 			runDTO := parsing.ToDriftAnalysisRunDTO(latestRun)
 			result.LatestRun = &runDTO
+
+			errorClasses, err := d.driftAnalysisRepository.GetRunErrorClassBreakdown(c.Context(), latestRun.Uuid)
+			if err != nil {
+				log.Errorf("Error getting error classes of run %s: %v", latestRun.Uuid, err)
+				return c.SendStatus(fiber.StatusInternalServerError)
+			}
+			result.LatestRunErrorClasses = parsing.ToErrorClassCounts(errorClasses)
+
+			unscanned, err := d.driftAnalysisRepository.GetUnscannedProjects(c.Context(), repoId, latestRun.Uuid)
+			if err != nil {
+				log.Errorf("Error getting projects missing from run %s: %v", latestRun.Uuid, err)
+				return c.SendStatus(fiber.StatusInternalServerError)
+			}
+			result.UnscannedProjects = parsing.ToUnscannedProjects(unscanned)
 		}
 	}
 
+	// Computed here rather than read from the open overdue alert so the flag doesn't wait for the
+	// monitor's next pass.
+	repo, err := d.repoRepository.FindGitRepositoryById(c.Context(), repoId)
+	if err != nil {
+		log.Errorf("Error getting repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	sched, err := schedule.Parse(repo.ScanSchedule, repo.ScanMaxIntervalMinutes)
+	if err != nil {
+		log.Warnf("Invalid scan schedule of repository %d: %v", repoId, err)
+	}
+	completedAt, err := d.repoRepository.GetLastCompletedScanAt(c.Context(), repoId)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Errorf("Error getting the last completed scan of repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	var lastCompletedAt *time.Time
+	if err == nil {
+		lastCompletedAt = &completedAt
+	}
+	if since, ok := schedule.Since(lastCompletedAt, repo.ScanScheduleSetAt); sched != nil && ok {
+		dueAt := sched.DueAt(since)
+		result.ScanDueAt = &dueAt
+		result.ScanOverdue = time.Now().After(dueAt)
+	}
+
 	return c.JSON(result)
 }
 
+// parseDaysBack reads the days_back query param of the trend endpoints (default 30, max 90).
+func parseDaysBack(c fiber.Ctx) int32 {
+	daysBack := int32(fiber.Query[int](c, "days_back", 30))
+	if daysBack < 1 {
+		daysBack = 30
+	}
+	if daysBack > 90 {
+		daysBack = 90
+	}
+	return daysBack
+}
+
 func (d *DriftStateHandler) GetRepositoryTrends(c fiber.Ctx) error {
 	userId, err := auth.MustGetLoggedUserId(c)
 	if err != nil {
@@ -453,14 +730,7 @@ func (d *DriftStateHandler) GetRepositoryTrends(c fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusUnauthorized)
 	}
 
-	// Parse days_back query param (default 30, max 90)
-	daysBack := int32(fiber.Query[int](c, "days_back", 30))
-	if daysBack < 1 {
-		daysBack = 30
-	}
-	if daysBack > 90 {
-		daysBack = 90
-	}
+	daysBack := parseDaysBack(c)
 
 	// Fetch drift rate over time
 	driftRate, err := d.driftAnalysisRepository.GetDriftRateOverTime(c.Context(), repoId, daysBack)
@@ -476,6 +746,20 @@ func (d *DriftStateHandler) GetRepositoryTrends(c fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	// Fetch the resource addresses that drift most often
+	frequentlyDriftedResources, err := d.driftAnalysisRepository.GetMostFrequentlyDriftedResources(c.Context(), repoId, daysBack, 10)
+	if err != nil {
+		log.Errorf("Error getting frequently drifted resources: %v", err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	// Fetch why failed projects errored
+	errorClasses, err := d.driftAnalysisRepository.GetErrorClassBreakdown(c.Context(), repoId, daysBack)
+	if err != nil {
+		log.Errorf("Error getting error classes: %v", err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
 	// Fetch drift-free streak
 	var streakDTO dto.DriftFreeStreakDTO
 	streak, err := d.driftAnalysisRepository.GetDriftFreeStreak(c.Context(), repoId)
@@ -517,12 +801,14 @@ func (d *DriftStateHandler) GetRepositoryTrends(c fiber.Ctx) error {
 	}
 
 	response := dto.RepositoryTrendsDTO{
-		Summary:                   summary,
-		DriftRateOverTime:         driftRateData,
-		FrequentlyDriftedProjects: parsing.ToFrequentlyDriftedProjects(frequentlyDrifted),
-		DriftFreeStreak:           streakDTO,
-		ResolutionTimes:           parsing.ToResolutionTimeDataPoints(resolutionTimes),
-		DaysBack:                  int(daysBack),
+		Summary:                    summary,
+		DriftRateOverTime:          driftRateData,
+		FrequentlyDriftedProjects:  parsing.ToFrequentlyDriftedProjects(frequentlyDrifted),
+		FrequentlyDriftedResources: parsing.ToFrequentlyDriftedResources(frequentlyDriftedResources),
+		DriftFreeStreak:            streakDTO,
+		ResolutionTimes:            parsing.ToResolutionTimeDataPoints(resolutionTimes),
+		ErrorClasses:               parsing.ToErrorClassStats(errorClasses),
+		DaysBack:                   int(daysBack),
 	}
 
 	return c.JSON(response)
@@ -0,0 +1,134 @@
+package drift_stream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/klauspost/compress/zstd"
+)
+
+// errUnsupportedEncoding is returned for a Content-Encoding other than gzip, zstd or identity.
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// ingestLimitError reports an upload that exceeds one of the configured ingest size limits. It is
+// surfaced to the CLI as a 413 with the message, so the message must say which limit was hit.
+type ingestLimitError struct {
+	message string
+}
+
+func (e *ingestLimitError) Error() string {
+	return e.message
+}
+
+// limitedReader fails with an ingestLimitError once more than limit bytes were read, instead of
+// silently truncating like io.LimitReader, so an oversized body can't be mistaken for a short one.
+// Like http.MaxBytesReader it never hands out bytes past the limit, otherwise a decoder could
+// complete a value from the same read that reported the error.
+type limitedReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) <= l.remaining {
+		l.remaining -= int64(n)
+		return n, err
+	}
+	n = int(l.remaining)
+	l.remaining = 0
+	return n, &ingestLimitError{message: fmt.Sprintf("request body exceeds the %d byte limit after decoding", l.limit)}
+}
+
+// ingestBodyReader returns the request body decoded according to its Content-Encoding and capped
+// at maxBytes decoded bytes (zero means no cap). The raw stream is read when the server streams
+// request bodies, so a large upload is never held in memory twice.
+func ingestBodyReader(c fiber.Ctx, maxBytes int64) (io.ReadCloser, error) {
+	var raw io.Reader
+	if stream := c.Request().BodyStream(); stream != nil {
+		raw = stream
+	} else {
+		raw = bytes.NewReader(c.Request().Body())
+	}
+
+	var decoded io.ReadCloser
+	switch encoding := strings.ToLower(strings.TrimSpace(c.Get(fiber.HeaderContentEncoding))); encoding {
+	case "", "identity":
+		decoded = io.NopCloser(raw)
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		decoded = gz
+	case "zstd":
+		zr, err := zstd.NewReader(raw, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd body: %w", err)
+		}
+		decoded = zr.IOReadCloser()
+	default:
+		return nil, fmt.Errorf("%w %q", errUnsupportedEncoding, encoding)
+	}
+
+	if maxBytes <= 0 {
+		return decoded, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{&limitedReader{r: decoded, limit: maxBytes, remaining: maxBytes}, decoded}, nil
+}
+
+// decodeIngestBody decodes a single JSON document from the (possibly compressed) request body.
+func (d *DriftStateHandler) decodeIngestBody(c fiber.Ctx, out any) error {
+	body, err := ingestBodyReader(c, d.cfg.Ingest.MaxRequestBytes)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return json.NewDecoder(body).Decode(out)
+}
+
+// checkProjectSize enforces the per-project output cap, naming the project so the CLI author can
+// tell which stack produced the oversized plan.
+func checkProjectSize(maxBytes int64, index int, project DriftProjectResult) error {
+	if maxBytes <= 0 {
+		return nil
+	}
+	size := int64(len(project.InitOutput) + len(project.PlanOutput))
+	if size > maxBytes {
+		return &ingestLimitError{message: fmt.Sprintf("project %d (%s): init and plan output total %d bytes, over the %d byte per-project limit",
+			index, project.Project.Dir, size, maxBytes)}
+	}
+	return nil
+}
+
+// sendIngestError maps a body decoding or validation failure to its status code. Limit errors get
+// a JSON body because a bare 413 from a proxy and one from us are otherwise indistinguishable.
+func sendIngestError(c fiber.Ctx, err error) error {
+	var limitErr *ingestLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		return c.Status(fiber.StatusRequestEntityTooLarge).
+			JSON(fiber.Map{"status": "error", "message": limitErr.Error(), "data": nil})
+	case errors.Is(err, errUnsupportedEncoding):
+		return c.Status(fiber.StatusUnsupportedMediaType).
+			JSON(fiber.Map{"status": "error", "message": err.Error(), "data": nil})
+	default:
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+}
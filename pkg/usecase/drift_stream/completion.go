@@ -0,0 +1,104 @@
+package drift_stream
+
+import (
+	"context"
+	"time"
+
+	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/google/uuid"
+)
+
+const (
+	shardFinalizeInterval = time.Minute
+	shardFinalizeBatch    = 100
+)
+
+// completeRun records what only becomes known once a run completes, its projects in the catalog
+// and its coverage changes, and returns what is left once the transaction completing it commits:
+// retention cleanup of the repository, then the check run and drift issues published in the
+// background. Must be called in that transaction; every path that completes a run goes through it.
+func (d *DriftStateHandler) completeRun(ctx context.Context, org queries.GitOrganization, repo queries.GitRepository, runUUID uuid.UUID) (func(context.Context), error) {
+	if err := d.driftAnalysisRepository.SyncDriftProjectsFromRun(ctx, runUUID); err != nil {
+		log.Errorf("Error updating the project catalog for run %s: %v", runUUID, err)
+		return nil, err
+	}
+	if err := d.driftAnalysisRepository.RecordDriftAnalysisRunCoverageChanges(ctx, runUUID); err != nil {
+		log.Errorf("Error recording coverage changes for run %s: %v", runUUID, err)
+		return nil, err
+	}
+	return func(ctx context.Context) {
+		// Cleanup is best effort: a failure is logged, not reported to the client.
+		if d.cleanupService != nil {
+			if err := d.cleanupService.CleanupRepositoryRuns(ctx, repo.ID); err != nil {
+				log.Warnf("Cleanup failed for repository %d: %v", repo.ID, err)
+			}
+		}
+		d.publishCompletedRun(org, repo, runUUID)
+	}, nil
+}
+
+// StartShardFinalizer completes sharded runs whose missing shards stopped reporting, so a matrix
+// job that crashed or was cancelled cannot hold the run open forever. Unlike the stale run
+// sweeper it keeps the run: the shards that did report hold real results. Safe to run on every
+// API instance at once: CompleteTimedOutShardedRuns claims runs with FOR UPDATE SKIP LOCKED.
+func (d *DriftStateHandler) StartShardFinalizer(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("shard finalizer shutting down...")
+			return
+		case <-time.After(shardFinalizeInterval):
+		}
+
+		if _, err := d.FinalizeTimedOutShardedRuns(ctx, d.cfg.Ingest.ShardTimeoutMinutes, shardFinalizeBatch); err != nil {
+			log.Errorf("error finalizing timed out sharded runs: %v", err)
+		}
+	}
+}
+
+// FinalizeTimedOutShardedRuns completes up to maxRuns sharded runs that have had no report for
+// timeoutMinutes, the same way the last shard would have, and returns how many it completed. Each
+// run is completed in its own transaction, so a run that fails doesn't roll back the others.
+func (d *DriftStateHandler) FinalizeTimedOutShardedRuns(ctx context.Context, timeoutMinutes int32, maxRuns int32) (int, error) {
+	for finalized := 0; finalized < int(maxRuns); finalized++ {
+		found, err := d.finalizeTimedOutShardedRun(ctx, timeoutMinutes)
+		if err != nil || !found {
+			return finalized, err
+		}
+	}
+	return int(maxRuns), nil
+}
+
+// finalizeTimedOutShardedRun completes the sharded run that has gone longest without a report, if
+// it timed out. Returns false when there is none left.
+func (d *DriftStateHandler) finalizeTimedOutShardedRun(ctx context.Context, timeoutMinutes int32) (bool, error) {
+	var finish func(context.Context)
+	err := d.driftAnalysisRepository.WithTx(ctx, func(ctx context.Context) error {
+		finish = nil
+		completed, err := d.driftAnalysisRepository.CompleteTimedOutShardedRuns(ctx, timeoutMinutes, 1)
+		if err != nil || len(completed) == 0 {
+			return err
+		}
+		run := completed[0]
+		repo, err := d.repoRepository.FindGitRepositoryById(ctx, run.RepositoryID)
+		if err != nil {
+			return err
+		}
+		org, err := d.orgRepository.FindGitOrgById(ctx, repo.OrganizationID)
+		if err != nil {
+			return err
+		}
+		finish, err = d.completeRun(ctx, org, repo, run.Uuid)
+		if err != nil {
+			return err
+		}
+		log.Warnf("finalized sharded run %s for repository %d after missing shards timed out", run.Uuid, run.RepositoryID)
+		return nil
+	})
+	if err != nil || finish == nil {
+		return false, err
+	}
+	finish(ctx)
+	return true, nil
+}
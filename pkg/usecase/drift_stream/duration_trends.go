@@ -0,0 +1,57 @@
+package drift_stream
+
+import (
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/usecase/utils/auth"
+	"driftive.cloud/api/pkg/usecase/utils/parsing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+const (
+	maxDurationTrendResults = 20
+	// durationRegressionPercent is how much slower a project must have become, comparing the two
+	// halves of the window, to be reported as a regression.
+	durationRegressionPercent = 20
+)
+
+// GetRepositoryDurationTrends ranks the projects that take longest to analyze and those that got
+// slower within the days_back window, using the timings the CLI reports per project.
+func (d *DriftStateHandler) GetRepositoryDurationTrends(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	isMember, err := d.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	daysBack := parseDaysBack(c)
+	slowest, err := d.driftAnalysisRepository.GetSlowestProjects(c.Context(), repoId, daysBack, maxDurationTrendResults)
+	if err != nil {
+		log.Errorf("Error getting slowest projects: %v", err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	regressions, err := d.driftAnalysisRepository.GetProjectDurationRegressions(c.Context(), repoId, daysBack, durationRegressionPercent, maxDurationTrendResults)
+	if err != nil {
+		log.Errorf("Error getting project duration regressions: %v", err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.JSON(dto.DurationTrendsDTO{
+		SlowestProjects: parsing.ToSlowProjects(slowest),
+		Regressions:     parsing.ToDurationRegressions(regressions),
+		DaysBack:        int(daysBack),
+	})
+}
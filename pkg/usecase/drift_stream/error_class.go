@@ -0,0 +1,11 @@
+package drift_stream
+
+// errorClass classifies why a project failed from its redacted outputs. Returns nil for projects
+// that succeeded or were skipped, which have no error to classify.
+func (d *DriftStateHandler) errorClass(succeeded, skipped bool, initOutput, planOutput string) *string {
+	if succeeded || skipped {
+		return nil
+	}
+	class := string(d.classifier.Classify(initOutput, planOutput))
+	return &class
+}
@@ -0,0 +1,99 @@
+package drift_stream
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// timestampRegex matches RFC 3339 style timestamps, which change on every run in refreshed
+	// attributes such as last_modified.
+	timestampRegex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
+	// changeLineRegex matches a line marking a change: an attribute of a Terraform resource block
+	// or a Pulumi step.
+	changeLineRegex = regexp.MustCompile(`^\s*(?:[-+~]|-/\+|\+/-|<=)\s`)
+	// whitespaceRegex matches the alignment padding, which shifts when a sibling attribute is renamed.
+	whitespaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// DriftFingerprint hashes the changes in a drifted project's plan output, ignoring what differs
+// between two plans of the same drift: timestamps, alignment and the order resources and attributes
+// are printed in. planOutput is expected stripped of escape codes (see ansi.Strip). Returns nil when
+// the output holds no change to fingerprint.
+func DriftFingerprint(projectType ProjectType, planOutput string) *string {
+	var parts []string
+	switch projectType {
+	case Terraform, Tofu, Terragrunt:
+		parts = terraformChangeBlocks(planOutput)
+	case Pulumi:
+		parts = normalizedLines(planOutput, changeLineRegex.MatchString)
+	default:
+		// CloudFormation prints one drift record per resource; the timestamps are masked and the
+		// records' order is made irrelevant by sorting.
+		parts = normalizedLines(planOutput, nil)
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	slices.Sort(parts)
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	fingerprint := hex.EncodeToString(sum[:])
+	return &fingerprint
+}
+
+// terraformChangeBlocks returns one entry per resource header of a plan holding the header and
+// its sorted change lines, plus one for the "Changes to Outputs:" section.
+func terraformChangeBlocks(planOutput string) []string {
+	var blocks [][]string
+	var current []string
+	flush := func() {
+		if current != nil {
+			slices.Sort(current[1:])
+			blocks = append(blocks, current)
+		}
+		current = nil
+	}
+	for _, line := range strings.Split(planOutput, "\n") {
+		line = normalizeLine(line)
+		switch {
+		case resourceHeaderRegex.MatchString(line) || line == "Changes to Outputs:":
+			flush()
+			current = []string{line}
+		case strings.HasPrefix(line, "Plan:"):
+			flush()
+		case current != nil && changeLineRegex.MatchString(line):
+			current = append(current, line)
+		}
+	}
+	flush()
+
+	parts := make([]string, len(blocks))
+	for i, block := range blocks {
+		parts[i] = strings.Join(block, "\n")
+	}
+	return parts
+}
+
+// normalizedLines returns the normalized non-empty lines of output accepted by keep, or all of
+// them when keep is nil.
+func normalizedLines(output string, keep func(string) bool) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = normalizeLine(line)
+		if line == "" || (keep != nil && !keep(line)) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// normalizeLine masks timestamps and trims and collapses whitespace.
+func normalizeLine(line string) string {
+	line = timestampRegex.ReplaceAllString(line, "<timestamp>")
+	line = whitespaceRegex.ReplaceAllString(strings.TrimSpace(line), " ")
+	return line
+}
@@ -0,0 +1,125 @@
+package drift_stream
+
+import (
+	"strings"
+	"testing"
+
+	"driftive.cloud/api/pkg/ansi"
+	"driftive.cloud/api/pkg/repository/queries"
+)
+
+const fingerprintPlan = `Refreshing state... [id=i-0123456789]
+
+Terraform will perform the following actions:
+
+  # aws_instance.web will be updated in-place
+  ~ resource "aws_instance" "web" {
+        id            = "i-0123456789"
+      ~ instance_type = "t3.micro" -> "t3.small"
+      ~ tags          = {
+          + "Owner" = "platform"
+          ~ "Team"  = "a" -> "b"
+        }
+    }
+
+  # aws_s3_bucket.logs will be updated in-place
+  ~ resource "aws_s3_bucket" "logs" {
+      ~ last_modified = "2026-10-01T10:00:00Z" -> (known after apply)
+    }
+
+Plan: 0 to add, 2 to change, 0 to destroy.
+`
+
+// Same drift as fingerprintPlan: colored, refreshed at another time, with the resources and tags
+// printed in another order and different alignment.
+const fingerprintPlanReordered = "Refreshing state... [id=i-9999]\n\n" +
+	"  \x1b[1m# aws_s3_bucket.logs\x1b[0m will be updated in-place\n" +
+	"  \x1b[33m~\x1b[0m resource \"aws_s3_bucket\" \"logs\" {\n" +
+	"      \x1b[33m~\x1b[0m last_modified = \"2026-10-18T23:59:59.123Z\" -> (known after apply)\n" +
+	"    }\n\n" +
+	"  \x1b[1m# aws_instance.web\x1b[0m will be updated in-place\n" +
+	"  \x1b[33m~\x1b[0m resource \"aws_instance\" \"web\" {\n" +
+	"      \x1b[33m~\x1b[0m tags = {\n" +
+	"          \x1b[33m~\x1b[0m \"Team\" = \"a\" -> \"b\"\n" +
+	"          \x1b[32m+\x1b[0m \"Owner\" = \"platform\"\n" +
+	"        }\n" +
+	"      \x1b[33m~\x1b[0m instance_type = \"t3.micro\" -> \"t3.small\"\n" +
+	"    }\n\n" +
+	"Plan: 0 to add, 2 to change, 0 to destroy.\n"
+
+func TestDriftFingerprint_IgnoresNoise(t *testing.T) {
+	a := DriftFingerprint(Terraform, fingerprintPlan)
+	b := DriftFingerprint(Terraform, ansi.Strip(fingerprintPlanReordered))
+	if a == nil || b == nil {
+		t.Fatalf("expected fingerprints, got %v and %v", a, b)
+	}
+	if *a != *b {
+		t.Errorf("same drift fingerprinted differently: %s != %s", *a, *b)
+	}
+	if len(*a) != 64 {
+		t.Errorf("expected a hex SHA-256, got %q", *a)
+	}
+}
+
+func TestDriftFingerprint_DetectsNewDrift(t *testing.T) {
+	base := *DriftFingerprint(Terraform, fingerprintPlan)
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{"changed value", replaceOnce(fingerprintPlan, `"t3.small"`, `"t3.large"`)},
+		{"another resource", replaceOnce(fingerprintPlan, "aws_s3_bucket.logs will", "aws_s3_bucket.data will")},
+		{"another action", replaceOnce(fingerprintPlan, "aws_instance.web will be updated in-place", "aws_instance.web must be replaced")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DriftFingerprint(Terraform, tt.output)
+			if got == nil || *got == base {
+				t.Errorf("expected a different fingerprint, got %v", got)
+			}
+		})
+	}
+}
+
+func TestDriftFingerprint_NothingToFingerprint(t *testing.T) {
+	for _, output := range []string{"", "No changes. Your infrastructure matches the configuration.", "Error: Invalid provider configuration"} {
+		if got := DriftFingerprint(Terraform, output); got != nil {
+			t.Errorf("DriftFingerprint(%q) = %s, want nil", output, *got)
+		}
+	}
+}
+
+func TestDriftFingerprint_Pulumi(t *testing.T) {
+	a := DriftFingerprint(Pulumi, "Previewing update (dev)\n\n ~   └─ aws:s3:Bucket  logs  update  [diff: ~tags]\n\nDuration: 3s\n")
+	b := DriftFingerprint(Pulumi, "Previewing update (dev)\n\n ~ └─ aws:s3:Bucket logs update [diff: ~tags]\n\nDuration: 12s\n")
+	if a == nil || b == nil || *a != *b {
+		t.Errorf("expected equal fingerprints ignoring durations and alignment, got %v and %v", a, b)
+	}
+}
+
+func TestRunDriftUnchanged(t *testing.T) {
+	fp, other := "fp-a", "fp-b"
+	previous := map[string]string{"/a": fp}
+	tests := []struct {
+		name     string
+		projects []queries.DriftAnalysisProject
+		want     bool
+	}{
+		{"same drift", []queries.DriftAnalysisProject{{Dir: "/a", Drifted: true, DriftFingerprint: &fp}, {Dir: "/b"}}, true},
+		{"changed drift", []queries.DriftAnalysisProject{{Dir: "/a", Drifted: true, DriftFingerprint: &other}}, false},
+		{"new drifted project", []queries.DriftAnalysisProject{{Dir: "/a", Drifted: true, DriftFingerprint: &fp}, {Dir: "/b", Drifted: true, DriftFingerprint: &fp}}, false},
+		{"no fingerprint", []queries.DriftAnalysisProject{{Dir: "/a", Drifted: true}}, false},
+		{"no drift", []queries.DriftAnalysisProject{{Dir: "/a"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runDriftUnchanged(tt.projects, previous); got != tt.want {
+				t.Errorf("runDriftUnchanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func replaceOnce(s, old, new string) string {
+	return strings.Replace(s, old, new, 1)
+}
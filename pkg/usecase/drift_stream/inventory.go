@@ -0,0 +1,135 @@
+package drift_stream
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/usecase/utils/auth"
+	"driftive.cloud/api/pkg/usecase/utils/parsing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// GetOrganizationVersionInventory lists the core and provider versions each project of an
+// organization reported in its latest run within days_back, and how many projects run each version.
+func (d *DriftStateHandler) GetOrganizationVersionInventory(c fiber.Ctx) error {
+	orgId := parsing.StringToInt64(c.Params("org_id"))
+	if err := auth.MustHavePermission(c, orgId); err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	daysBack := parseDaysBack(c)
+	rows, err := d.driftAnalysisRepository.GetOrganizationVersionInventory(c.Context(), orgId, daysBack)
+	if err != nil {
+		log.Errorf("Error getting version inventory for org %d: %v", orgId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	projects := parsing.ToProjectVersions(rows)
+	var coreVersions, providerVersions []dto.VersionUsageDTO
+	for _, p := range projects {
+		if p.CoreTool != nil && p.CoreVersion != nil {
+			coreVersions = countVersionUsage(coreVersions, *p.CoreTool, *p.CoreVersion)
+		}
+		for _, provider := range p.ProviderVersions {
+			providerVersions = countVersionUsage(providerVersions, provider.Source, provider.Version)
+		}
+	}
+
+	return c.JSON(dto.VersionInventoryDTO{
+		CoreVersions:     sortVersionUsage(coreVersions),
+		ProviderVersions: sortVersionUsage(providerVersions),
+		Projects:         projects,
+		DaysBack:         int(daysBack),
+	})
+}
+
+// GetOrganizationOutdatedVersions lists the projects of an organization whose latest run within
+// days_back used a version of component below min_version. component is "terraform", "opentofu" or
+// a provider source such as hashicorp/aws.
+func (d *DriftStateHandler) GetOrganizationOutdatedVersions(c fiber.Ctx) error {
+	orgId := parsing.StringToInt64(c.Params("org_id"))
+	if err := auth.MustHavePermission(c, orgId); err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	component := strings.ToLower(strings.TrimSpace(c.Query("component")))
+	minVersion := strings.TrimSpace(c.Query("min_version"))
+	if component == "" || !IsValidVersion(minVersion) {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	coreTool := ""
+	switch component {
+	case "terraform":
+		coreTool = CoreToolTerraform
+	case "opentofu", "tofu":
+		coreTool = CoreToolOpenTofu
+	}
+
+	daysBack := parseDaysBack(c)
+	rows, err := d.driftAnalysisRepository.GetOrganizationVersionInventory(c.Context(), orgId, daysBack)
+	if err != nil {
+		log.Errorf("Error getting version inventory for org %d: %v", orgId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	outdated := []dto.OutdatedProjectDTO{}
+	for _, p := range parsing.ToProjectVersions(rows) {
+		version := ""
+		if coreTool != "" {
+			if p.CoreTool != nil && *p.CoreTool == coreTool && p.CoreVersion != nil {
+				version = *p.CoreVersion
+			}
+		} else {
+			for _, provider := range p.ProviderVersions {
+				if strings.ToLower(provider.Source) == component {
+					version = provider.Version
+				}
+			}
+		}
+		if version == "" || CompareVersions(version, minVersion) >= 0 {
+			continue
+		}
+		outdated = append(outdated, dto.OutdatedProjectDTO{
+			RepositoryID:   p.RepositoryID,
+			RepositoryName: p.RepositoryName,
+			Dir:            p.Dir,
+			Type:           p.Type,
+			Version:        version,
+			LastRunAt:      p.LastRunAt,
+		})
+	}
+	// Furthest behind first.
+	slices.SortStableFunc(outdated, func(a, b dto.OutdatedProjectDTO) int { return CompareVersions(a.Version, b.Version) })
+
+	return c.JSON(dto.OutdatedProjectsDTO{
+		Component:  component,
+		MinVersion: minVersion,
+		Projects:   outdated,
+		DaysBack:   int(daysBack),
+	})
+}
+
+// countVersionUsage adds one project to the usage of component at version.
+func countVersionUsage(usage []dto.VersionUsageDTO, component, version string) []dto.VersionUsageDTO {
+	for i := range usage {
+		if usage[i].Component == component && usage[i].Version == version {
+			usage[i].ProjectCount++
+			return usage
+		}
+	}
+	return append(usage, dto.VersionUsageDTO{Component: component, Version: version, ProjectCount: 1})
+}
+
+// sortVersionUsage orders usage by component, newest version first, and never returns nil.
+func sortVersionUsage(usage []dto.VersionUsageDTO) []dto.VersionUsageDTO {
+	if usage == nil {
+		return []dto.VersionUsageDTO{}
+	}
+	slices.SortFunc(usage, func(a, b dto.VersionUsageDTO) int {
+		return cmp.Or(strings.Compare(a.Component, b.Component), CompareVersions(b.Version, a.Version))
+	})
+	return usage
+}
@@ -0,0 +1,15 @@
+package drift_stream
+
+import "encoding/json"
+
+// jsonColumn encodes values for a JSONB column such as warnings or redactions, which is NULL
+// rather than an empty array or object when there is nothing to store. The columns hold slices of
+// plain structs and maps of strings to integers, which always marshal.
+func jsonColumn[T any](values T) []byte {
+	data, _ := json.Marshal(values)
+	switch string(data) {
+	case "null", "[]", "{}":
+		return nil
+	}
+	return data
+}
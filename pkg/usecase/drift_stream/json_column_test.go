@@ -0,0 +1,23 @@
+package drift_stream
+
+import (
+	"testing"
+
+	"driftive.cloud/api/pkg/redact"
+)
+
+func TestJSONColumn(t *testing.T) {
+	if data := jsonColumn[[]ResourceChange](nil); data != nil {
+		t.Errorf("expected NULL for no changes, got %s", data)
+	}
+	if data := jsonColumn([]PlanWarning{}); data != nil {
+		t.Errorf("expected NULL for no warnings, got %s", data)
+	}
+	if data := jsonColumn(redact.Counts{}); data != nil {
+		t.Errorf("expected NULL for no redactions, got %s", data)
+	}
+	data := jsonColumn([]ResourceChange{{"aws_instance.web", ResourceActionUpdate}})
+	if string(data) != `[{"address":"aws_instance.web","action":"UPDATE"}]` {
+		t.Errorf("jsonColumn() = %s", data)
+	}
+}
@@ -20,6 +20,9 @@ type DriftProgressRequest struct {
 	TotalProjects  int32                `json:"total_projects"`
 	Running        []string             `json:"running"`
 	ProjectResults []DriftProjectResult `json:"project_results"`
+	// CommitSHA is the commit being scanned. The first tick sending it puts an in-progress check
+	// run on it.
+	CommitSHA *string `json:"commit_sha,omitempty"`
 }
 
 // HandleProgress records incremental progress for an in-flight run, creating the run on the first
@@ -38,8 +41,9 @@ func (d *DriftStateHandler) HandleProgress(c fiber.Ctx) error {
 	}
 
 	var req DriftProgressRequest
-	if err := c.Bind().Body(&req); err != nil {
-		return c.SendStatus(fiber.StatusBadRequest)
+	if err := d.decodeIngestBody(c, &req); err != nil {
+		log.Warnf("Rejecting drift progress for repository %d: %v", repo.ID, err)
+		return sendIngestError(c, err)
 	}
 
 	// pgx encodes a nil slice as NULL, which running_projects rejects.
@@ -59,14 +63,35 @@ func (d *DriftStateHandler) HandleProgress(c fiber.Ctx) error {
 		return c.JSON(buildAnalysisResponse(d.cfg.Frontend.FrontendURL, org, repo, run.Uuid))
 	}
 
-	upsertParams, err := toUpsertParams(run.Uuid, req.ProjectResults)
+	redactor, err := d.redactorFor(c.Context(), org.ID)
+	if err != nil {
+		log.Errorf("Error preparing redaction for repository %d: %v", repo.ID, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	upsertParams, err := d.toUpsertParams(redactor, run.Uuid, req.ProjectResults)
 	if err != nil {
 		log.Errorf("Rejecting drift progress: %v", err)
-		return c.SendStatus(fiber.StatusBadRequest)
+		return sendIngestError(c, err)
+	}
+	prepared, err := d.prepareOutputs(c.Context(), repo.ID, upsertParams)
+	if err != nil {
+		log.Errorf("Error preparing outputs for run %s: %v", run.Uuid, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	// Only the tick recording the commit starts its check run, so retries don't create more.
+	var commitRecorded bool
 	err = d.driftAnalysisRepository.WithTx(c.Context(), func(ctx context.Context) error {
+		recorded, err := d.recordCommitSHA(ctx, run.Uuid, req.CommitSHA)
+		if err != nil {
+			return err
+		}
+		commitRecorded = recorded
 		if len(upsertParams) > 0 {
+			if err := d.storeOutputs(ctx, repo.ID, upsertParams, prepared); err != nil {
+				log.Errorf("Error storing outputs for run %s: %v", run.Uuid, err)
+				return err
+			}
 			if err := d.driftAnalysisRepository.UpsertDriftAnalysisProjects(ctx, upsertParams); err != nil {
 				log.Errorf("Error upserting drift analysis projects for run %s: %v", run.Uuid, err)
 				return err
@@ -80,11 +105,15 @@ func (d *DriftStateHandler) HandleProgress(c fiber.Ctx) error {
 		})
 	})
 	if err != nil {
+		d.discardOutputs(c.Context(), repo.ID, prepared)
 		log.Errorf("Error recording drift progress for run %s: %v", run.Uuid, err)
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
 	log.Debugf("Recorded drift progress for run %s: %d result(s), %d running", run.Uuid, len(upsertParams), len(running))
+	if commitRecorded {
+		d.startCheckRun(org, repo, run.Uuid, commitSHA(req.CommitSHA))
+	}
 	return c.JSON(buildAnalysisResponse(d.cfg.Frontend.FrontendURL, org, repo, run.Uuid))
 }
 
@@ -11,6 +11,8 @@ const (
 	Terraform ProjectType = iota
 	Tofu
 	Terragrunt
+	Pulumi
+	CloudFormation
 )
 
 type ProjectDriftAnalysisState struct {
@@ -28,7 +30,7 @@ type DriftAnalysisState struct {
 	ProjectStates []ProjectDriftAnalysisState
 }
 
-// TypedProject represents a TF/Tofu/Terragrunt project to be analyzed
+// TypedProject represents a TF/Tofu/Terragrunt/Pulumi/CloudFormation project to be analyzed
 type TypedProject struct {
 	Dir  string      `json:"dir" yaml:"dir"`
 	Type ProjectType `json:"type" yaml:"type"`
@@ -43,6 +45,21 @@ type DriftProjectResult struct {
 	PlanOutput string `json:"plan_output"`
 	// SkippedDueToPR is true if the drift was skipped because there are open PRs modifying the drifted files
 	SkippedDueToPR bool `json:"skipped_due_to_pr"`
+	// Timing of the project's analysis, left unset by CLIs that don't report it. StartedAt and
+	// FinishedAt span every attempt; the durations are those of the last attempt.
+	InitDuration *time.Duration `json:"init_duration,omitempty"`
+	PlanDuration *time.Duration `json:"plan_duration,omitempty"`
+	StartedAt    *time.Time     `json:"started_at,omitempty"`
+	FinishedAt   *time.Time     `json:"finished_at,omitempty"`
+	// Retries is how many times the analysis was retried after a failed attempt.
+	Retries *int32 `json:"retries,omitempty"`
+}
+
+// DriftShard identifies one slice of a scan split across parallel CI jobs. Every shard of a scan
+// sends the same Idempotency-Key and Count; the run completes once Count distinct IDs reported.
+type DriftShard struct {
+	ID    string `json:"id"`
+	Count int32  `json:"count"`
 }
 
 type DriftDetectionResult struct {
@@ -53,4 +70,9 @@ type DriftDetectionResult struct {
 	TotalProjects  int32                `json:"total_projects"`
 	TotalChecked   int32                `json:"total_checked"`
 	Duration       time.Duration        `json:"duration"`
+	// CommitSHA is the commit scanned, reported on with a GitHub check run when set.
+	CommitSHA *string `json:"commit_sha,omitempty"`
+	// Shard is set when this result covers only part of the repository. Totals and Duration then
+	// describe the shard, not the whole run.
+	Shard *DriftShard `json:"shard,omitempty"`
 }
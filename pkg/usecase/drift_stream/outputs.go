@@ -0,0 +1,71 @@
+package drift_stream
+
+import (
+	"context"
+	"fmt"
+
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/outputs"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// preparedOutputs are the non-empty init and plan outputs of a set of upsert params, compressed,
+// hashed and uploaded ahead of the transaction that stores them.
+type preparedOutputs struct {
+	outputs []outputs.Output
+	// init and plan map each param to its index in outputs, or -1 when its output stays inline.
+	init, plan []int
+}
+
+// prepareOutputs compresses the outputs of params, clears them from the params, which only carry
+// them again as ids once storeOutputs has run, and writes their blobs. Empty outputs stay inline:
+// they cost nothing and are sent by every skipped project. Must run outside any transaction, and
+// be followed by discardOutputs if the transaction calling storeOutputs fails.
+func (d *DriftStateHandler) prepareOutputs(ctx context.Context, repoID int64, params []queries.UpsertDriftAnalysisProjectParams) (preparedOutputs, error) {
+	prepared := preparedOutputs{init: make([]int, len(params)), plan: make([]int, len(params))}
+	prepare := func(output **string) int {
+		if *output == nil || **output == "" {
+			return -1
+		}
+		prepared.outputs = append(prepared.outputs, outputs.Prepare(**output))
+		*output = nil
+		return len(prepared.outputs) - 1
+	}
+	for i := range params {
+		prepared.init[i] = prepare(&params[i].InitOutput)
+		prepared.plan[i] = prepare(&params[i].PlanOutput)
+	}
+	if err := d.outputs.Upload(ctx, repoID, prepared.outputs); err != nil {
+		return preparedOutputs{}, fmt.Errorf("uploading outputs: %w", err)
+	}
+	return prepared, nil
+}
+
+// storeOutputs saves prepared outputs to command_output and points params at them. Must run in the
+// transaction that upserts params.
+func (d *DriftStateHandler) storeOutputs(ctx context.Context, repoID int64, params []queries.UpsertDriftAnalysisProjectParams, prepared preparedOutputs) error {
+	if len(prepared.outputs) == 0 {
+		return nil
+	}
+	ids, err := d.outputs.Save(ctx, repoID, prepared.outputs)
+	if err != nil {
+		return fmt.Errorf("storing outputs: %w", err)
+	}
+	for i := range params {
+		if j := prepared.init[i]; j >= 0 {
+			params[i].InitOutputID = &ids[j]
+		}
+		if j := prepared.plan[i]; j >= 0 {
+			params[i].PlanOutputID = &ids[j]
+		}
+	}
+	return nil
+}
+
+// discardOutputs releases the blobs prepareOutputs wrote once the transaction that was to store
+// them has failed. A failure only leaks the blobs, so it is logged rather than returned.
+func (d *DriftStateHandler) discardOutputs(ctx context.Context, repoID int64, prepared preparedOutputs) {
+	if err := d.outputs.Discard(ctx, repoID, prepared.outputs); err != nil {
+		log.Warnf("Error discarding outputs of repository %d: %v", repoID, err)
+	}
+}
@@ -0,0 +1,172 @@
+package drift_stream
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"driftive.cloud/api/pkg/codeowners"
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/utils/auth"
+	"driftive.cloud/api/pkg/usecase/utils/parsing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/jackc/pgx/v5"
+)
+
+// ListRepositoryOwners counts the catalog projects of a repository by status for each owner its
+// CODEOWNERS assigns, most drifted first, and for the projects nobody owns. A project with several
+// owners counts for each.
+func (d *DriftStateHandler) ListRepositoryOwners(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	isMember, err := d.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	projects, err := d.driftAnalysisRepository.ListDriftProjectsByRepositoryId(c.Context(), queries.ListDriftProjectsByRepositoryIdParams{RepositoryID: repoId})
+	if err != nil {
+		log.Errorf("Error listing projects of repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	rules, err := d.codeownersFor(c.Context(), repoId)
+	if err != nil {
+		log.Errorf("Error loading CODEOWNERS of repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	var unowned dto.ProjectStatusCountsDTO
+	// Keyed by the lowercased owner, as GitHub handles are case-insensitive.
+	byOwner := map[string]*dto.OwnerSummaryDTO{}
+	for _, p := range projects {
+		owners := rules.Owners(p.Dir)
+		if len(owners) == 0 {
+			countStatus(&unowned, p.Status)
+			continue
+		}
+		for _, owner := range owners {
+			key := strings.ToLower(owner)
+			if byOwner[key] == nil {
+				byOwner[key] = &dto.OwnerSummaryDTO{Owner: &owner}
+			}
+			countStatus(&byOwner[key].Counts, p.Status)
+		}
+	}
+
+	result := make([]dto.OwnerSummaryDTO, 0, len(byOwner)+1)
+	for _, summary := range byOwner {
+		result = append(result, *summary)
+	}
+	slices.SortFunc(result, func(a, b dto.OwnerSummaryDTO) int {
+		if n := cmp.Compare(b.Counts.Drifted, a.Counts.Drifted); n != 0 {
+			return n
+		}
+		return strings.Compare(*a.Owner, *b.Owner)
+	})
+	if unowned.Total > 0 {
+		result = append(result, dto.OwnerSummaryDTO{Counts: unowned})
+	}
+	return c.JSON(result)
+}
+
+// GetOwnerTrends returns the daily drift rate of the catalog projects the repository's CODEOWNERS
+// assigns to the owner query param (e.g. @acme/platform) within the days_back window.
+func (d *DriftStateHandler) GetOwnerTrends(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	repoIdStr := c.Params("repo_id")
+	owner := strings.TrimSpace(c.Query("owner"))
+	if repoIdStr == "" || owner == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	isMember, err := d.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	dirs, err := d.ownedDirs(c.Context(), repoId, owner)
+	if err != nil {
+		log.Errorf("Error resolving the projects of %s in repository %d: %v", owner, repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	daysBack := parseDaysBack(c)
+	rows, err := d.driftAnalysisRepository.GetDirsDriftOverTime(c.Context(), repoId, daysBack, dirs)
+	if err != nil {
+		log.Errorf("Error getting drift of the projects of %s in repository %d: %v", owner, repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.JSON(dto.OwnerTrendsDTO{
+		Owner:             owner,
+		DriftRateOverTime: parsing.ToScopedDriftDataPoints(rows),
+		DaysBack:          int(daysBack),
+	})
+}
+
+// codeownersFor returns the CODEOWNERS rules of a repository stored by the last organization sync,
+// an empty ruleset owning nothing when there are none.
+func (d *DriftStateHandler) codeownersFor(ctx context.Context, repoId int64) (*codeowners.Ruleset, error) {
+	file, err := d.repoRepository.FindRepositoryCodeowners(ctx, repoId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &codeowners.Ruleset{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return codeowners.Parse(file.Content), nil
+}
+
+// ownedDirs returns the dirs of the catalog projects of a repository owned by owner.
+func (d *DriftStateHandler) ownedDirs(ctx context.Context, repoId int64, owner string) ([]string, error) {
+	projects, err := d.driftAnalysisRepository.ListDriftProjectsByRepositoryId(ctx, queries.ListDriftProjectsByRepositoryIdParams{RepositoryID: repoId})
+	if err != nil {
+		return nil, err
+	}
+	rules, err := d.codeownersFor(ctx, repoId)
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{}
+	for _, p := range projects {
+		// The catalog holds a row per type of a dir, ordered by dir.
+		if len(dirs) > 0 && dirs[len(dirs)-1] == p.Dir {
+			continue
+		}
+		if codeowners.HasOwner(rules.Owners(p.Dir), owner) {
+			dirs = append(dirs, p.Dir)
+		}
+	}
+	return dirs, nil
+}
+
+// ownersOf returns the owners of dir, [] rather than nil when nobody owns it.
+func ownersOf(rules *codeowners.Ruleset, dir string) []string {
+	if owners := rules.Owners(dir); owners != nil {
+		return owners
+	}
+	return []string{}
+}
@@ -3,18 +3,185 @@ package drift_stream
 import (
 	"regexp"
 	"strconv"
+	"strings"
 )
 
-var planSummaryRegex = regexp.MustCompile(`Plan:\s+(\d+) to add,\s+(\d+) to change,\s+(\d+) to destroy`)
+// The version each field derived from the outputs was last changed at. A parser that starts
+// reading something new moves the version of the fields it derives to SummaryParserVersion+1, and
+// SummaryParserVersion with it, so the summary backfill re-derives only those fields of the
+// projects already stored rather than everything it ever parsed.
+const (
+	// countsParserVersion covers the parsers below.
+	countsParserVersion          int16 = 2
+	resourceChangesParserVersion int16 = 3
+	fingerprintParserVersion     int16 = 4
+	// errorClassParserVersion covers the error classifier's built-in rules.
+	errorClassParserVersion int16 = 5
+	versionsParserVersion   int16 = 6
+	warningsParserVersion   int16 = 7
+)
+
+// SummaryParserVersion is the latest version of any derived field, which new projects are stored
+// at.
+const SummaryParserVersion = warningsParserVersion
+
+var (
+	// planSummaryRegex matches the closing line of a Terraform or OpenTofu plan. The import and
+	// forget counts are only printed when non-zero, by versions that support them.
+	planSummaryRegex = regexp.MustCompile(`Plan:\s+(?:(\d+) to import,\s+)?(\d+) to add,\s+(\d+) to change,\s+(\d+) to destroy(?:,\s+(\d+) to forget)?`)
+	// planMovedRegex matches a resource moved by a moved block, which the summary line leaves out.
+	planMovedRegex = regexp.MustCompile(`(?m)^\s*# \S+ has moved to \S+`)
+	// planOutputsRegex finds the "Changes to Outputs:" section, printed without a summary line when
+	// only outputs change.
+	planOutputsRegex = regexp.MustCompile(`(?m)^Changes to Outputs:\s*$`)
+	// planOutputChangeRegex matches one output of that section, e.g. "  ~ bucket_arn = ...".
+	planOutputChangeRegex = regexp.MustCompile(`^\s+[-+~] \S+\s+=`)
+
+	// pulumiResourcesRegex finds the "Resources:" block that closes a pulumi preview or refresh.
+	pulumiResourcesRegex = regexp.MustCompile(`(?m)^Resources:\s*$`)
+	// pulumiStepRegex matches one line of that block, e.g. "    +-1 to replace" or "    ~ 2 updated".
+	pulumiStepRegex = regexp.MustCompile(`^\s*[-+~=]*\s*(\d+) (to create|created|to update|updated|to delete|deleted|to replace|replaced|to import|imported)\s*$`)
+
+	// cfnDriftStatusRegex matches a resource's drift status in describe-stack-resource-drifts
+	// output, whether printed as JSON or as text.
+	cfnDriftStatusRegex = regexp.MustCompile(`StackResourceDriftStatus"?\s*[:=]?\s*"?(MODIFIED|DELETED|IN_SYNC|NOT_CHECKED)\b`)
+)
+
+// PlanSummary holds the counts parsed from a project's plan output. A nil count means the output
+// doesn't say, e.g. because the plan failed or the tool has no such category.
+type PlanSummary struct {
+	Added, Changed, Destroyed  *int32
+	Imported, Forgotten, Moved *int32
+	OutputsChanged             *int32
+}
+
+// ParseSummary extracts the counts from a project's plan output using the parser for its tool.
+func ParseSummary(projectType ProjectType, planOutput string) PlanSummary {
+	switch projectType {
+	case Pulumi:
+		return ParsePulumiSummary(planOutput)
+	case CloudFormation:
+		return ParseCloudFormationDrift(planOutput)
+	default:
+		return ParsePlanSummary(planOutput)
+	}
+}
+
+// ParsePlanSummary extracts the counts from a Terraform or OpenTofu plan. Resource counts come from
+// the summary line; moves and output changes are counted from the plan body. A plan that only
+// moves resources or changes outputs has no summary line and gets zero resource counts. Returns
+// nil counts when there is nothing to count (e.g. init output or "No changes").
+func ParsePlanSummary(planOutput string) PlanSummary {
+	moved := int32(len(planMovedRegex.FindAllStringIndex(planOutput, -1)))
+	outputs, hasOutputs := countOutputChanges(planOutput)
 
-// ParsePlanSummary extracts the add/change/destroy counts from a plan's summary
-// line. Returns nil pointers when the line is absent (e.g. init output or "No changes").
-func ParsePlanSummary(planOutput string) (added, changed, destroyed *int32) {
 	m := planSummaryRegex.FindStringSubmatch(planOutput)
 	if m == nil {
-		return nil, nil, nil
+		if moved == 0 && !hasOutputs {
+			return PlanSummary{}
+		}
+		m = []string{"", "0", "0", "0", "0", "0"}
+	}
+	return PlanSummary{
+		Added:          atoi32(m[2]),
+		Changed:        atoi32(m[3]),
+		Destroyed:      atoi32(m[4]),
+		Imported:       atoi32OrZero(m[1]),
+		Forgotten:      atoi32OrZero(m[5]),
+		Moved:          &moved,
+		OutputsChanged: &outputs,
+	}
+}
+
+// countOutputChanges counts the outputs listed under "Changes to Outputs:", and reports whether
+// the section is present at all.
+func countOutputChanges(planOutput string) (int32, bool) {
+	loc := planOutputsRegex.FindStringIndex(planOutput)
+	if loc == nil {
+		return 0, false
+	}
+	var n int32
+	for _, line := range strings.Split(planOutput[loc[1]:], "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			// The section is separated from the summary by a blank line, but multi-line values are
+			// not, so stop at the first blank line once something was counted.
+			if n > 0 {
+				break
+			}
+			continue
+		}
+		if planOutputChangeRegex.MatchString(line) {
+			n++
+		} else if !strings.HasPrefix(line, " ") {
+			break
+		}
+	}
+	return n, true
+}
+
+// ParsePulumiSummary extracts the counts from the "Resources:" block of a pulumi preview or
+// refresh. A replacement counts as one add and one destroy, as Terraform counts it. Returns nil
+// counts when the block is absent, e.g. when the command failed.
+func ParsePulumiSummary(output string) PlanSummary {
+	loc := pulumiResourcesRegex.FindStringIndex(output)
+	if loc == nil {
+		return PlanSummary{}
+	}
+	var add, change, destroy, imported int32
+	for _, line := range strings.Split(output[loc[1]:], "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		m := pulumiStepRegex.FindStringSubmatch(line)
+		if m == nil {
+			// "N unchanged" and anything after the block, such as the duration.
+			if strings.HasSuffix(line, "unchanged") {
+				continue
+			}
+			break
+		}
+		n := atoi32(m[1])
+		if n == nil {
+			continue
+		}
+		switch m[2] {
+		case "to create", "created":
+			add += *n
+		case "to update", "updated":
+			change += *n
+		case "to delete", "deleted":
+			destroy += *n
+		case "to replace", "replaced":
+			add += *n
+			destroy += *n
+		case "to import", "imported":
+			imported += *n
+		}
 	}
-	return atoi32(m[1]), atoi32(m[2]), atoi32(m[3])
+	return PlanSummary{Added: &add, Changed: &change, Destroyed: &destroy, Imported: &imported}
+}
+
+// ParseCloudFormationDrift counts the drifted resources in describe-stack-resource-drifts output.
+// CloudFormation only detects drift on resources it manages, so a modified resource counts as a
+// change, a deleted one as a destroy, and nothing is ever added. Returns nil counts when no drift
+// status is present.
+func ParseCloudFormationDrift(output string) PlanSummary {
+	matches := cfnDriftStatusRegex.FindAllStringSubmatch(output, -1)
+	if matches == nil {
+		return PlanSummary{}
+	}
+	var add, change, destroy int32
+	for _, m := range matches {
+		switch m[1] {
+		case "MODIFIED":
+			change++
+		case "DELETED":
+			destroy++
+		}
+	}
+	return PlanSummary{Added: &add, Changed: &change, Destroyed: &destroy}
 }
 
 func atoi32(s string) *int32 {
@@ -25,3 +192,12 @@ func atoi32(s string) *int32 {
 	v := int32(n)
 	return &v
 }
+
+// atoi32OrZero is atoi32 for an optional group of a match, which is empty when the group didn't
+// participate.
+func atoi32OrZero(s string) *int32 {
+	if s == "" {
+		s = "0"
+	}
+	return atoi32(s)
+}
@@ -1,6 +1,9 @@
 package drift_stream
 
-import "testing"
+import (
+	"slices"
+	"testing"
+)
 
 func TestParsePlanSummary(t *testing.T) {
 	tests := []struct {
@@ -35,7 +38,8 @@ func TestParsePlanSummary(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			added, changed, destroyed := ParsePlanSummary(tt.output)
+			summary := ParsePlanSummary(tt.output)
+			added, changed, destroyed := summary.Added, summary.Changed, summary.Destroyed
 			if !tt.wantMatch {
 				if added != nil || changed != nil || destroyed != nil {
 					t.Fatalf("expected nil counts, got %v/%v/%v", added, changed, destroyed)
@@ -51,3 +55,188 @@ func TestParsePlanSummary(t *testing.T) {
 		})
 	}
 }
+
+func counts(s PlanSummary) []int32 {
+	if s.Added == nil || s.Changed == nil || s.Destroyed == nil {
+		return nil
+	}
+	return []int32{*s.Added, *s.Changed, *s.Destroyed}
+}
+
+// extended returns the import, forget, move and output counts, with -1 for a nil count.
+func extended(s PlanSummary) []int32 {
+	var out []int32
+	for _, n := range []*int32{s.Imported, s.Forgotten, s.Moved, s.OutputsChanged} {
+		if n == nil {
+			out = append(out, -1)
+		} else {
+			out = append(out, *n)
+		}
+	}
+	return out
+}
+
+func TestParsePlanSummary_ExtendedCategories(t *testing.T) {
+	tests := []struct {
+		name         string
+		output       string
+		wantCounts   []int32
+		wantExtended []int32
+	}{
+		{
+			name:         "import",
+			output:       "  # aws_s3_bucket.logs will be imported\n\nPlan: 1 to import, 0 to add, 1 to change, 0 to destroy.\n",
+			wantCounts:   []int32{0, 1, 0},
+			wantExtended: []int32{1, 0, 0, 0},
+		},
+		{
+			name:         "import and forget",
+			output:       "Plan: 2 to import, 1 to add, 0 to change, 0 to destroy, 3 to forget.",
+			wantCounts:   []int32{1, 0, 0},
+			wantExtended: []int32{2, 3, 0, 0},
+		},
+		{
+			name:         "forget only",
+			output:       "Plan: 0 to add, 0 to change, 0 to destroy, 1 to forget.",
+			wantCounts:   []int32{0, 0, 0},
+			wantExtended: []int32{0, 1, 0, 0},
+		},
+		{
+			name: "moves",
+			output: "Terraform will perform the following actions:\n\n" +
+				"  # aws_instance.old has moved to aws_instance.new\n" +
+				"    resource \"aws_instance\" \"new\" {\n    }\n\n" +
+				"  # module.a.aws_iam_role.r[0] has moved to module.b.aws_iam_role.r[\"x\"]\n\n" +
+				"Plan: 0 to add, 0 to change, 0 to destroy.\n",
+			wantCounts:   []int32{0, 0, 0},
+			wantExtended: []int32{0, 0, 2, 0},
+		},
+		{
+			name: "outputs only",
+			output: "Changes to Outputs:\n" +
+				"  + bucket_arn = \"arn:aws:s3:::logs\"\n" +
+				"  ~ endpoint   = \"a.example.com\" -> \"b.example.com\"\n" +
+				"  - legacy     = \"x\" -> null\n\n" +
+				"You can apply this plan to save these new output values to the Terraform state,\n" +
+				"without changing any real infrastructure.\n",
+			wantCounts:   []int32{0, 0, 0},
+			wantExtended: []int32{0, 0, 0, 3},
+		},
+		{
+			name: "resources and outputs",
+			output: "Plan: 1 to add, 0 to change, 0 to destroy.\n\n" +
+				"Changes to Outputs:\n  + id = (known after apply)\n",
+			wantCounts:   []int32{1, 0, 0},
+			wantExtended: []int32{0, 0, 0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := ParsePlanSummary(tt.output)
+			if got := counts(summary); !slices.Equal(got, tt.wantCounts) {
+				t.Errorf("counts = %v, want %v", got, tt.wantCounts)
+			}
+			if got := extended(summary); !slices.Equal(got, tt.wantExtended) {
+				t.Errorf("import/forget/moved/outputs = %v, want %v", got, tt.wantExtended)
+			}
+		})
+	}
+}
+
+func TestParsePulumiSummary(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []int32
+	}{
+		{
+			name: "preview with every kind of step",
+			output: "Previewing update (dev):\n" +
+				"     Type                 Name        Plan\n" +
+				" +   aws:s3:Bucket        logs        create\n" +
+				"\nResources:\n" +
+				"    + 2 to create\n" +
+				"    ~ 1 to update\n" +
+				"    - 1 to delete\n" +
+				"    +-1 to replace\n" +
+				"    5 unchanged\n" +
+				"\nDuration: 3s\n",
+			want: []int32{3, 1, 2},
+		},
+		{
+			name:   "refresh finding drift",
+			output: "Refreshing (prod):\n\nResources:\n    ~ 2 updated\n    - 1 deleted\n    7 unchanged\n",
+			want:   []int32{0, 2, 1},
+		},
+		{
+			name:   "import",
+			output: "Resources:\n    = 1 to import\n    + 1 to create\n",
+			want:   []int32{1, 0, 0},
+		},
+		{
+			name:   "no changes",
+			output: "Resources:\r\n    12 unchanged\r\n",
+			want:   []int32{0, 0, 0},
+		},
+		{
+			name:   "failed preview",
+			output: "error: could not load plugin for aws provider",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := counts(ParsePulumiSummary(tt.output))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCloudFormationDrift(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []int32
+	}{
+		{
+			name: "json",
+			output: `{"StackResourceDrifts": [
+				{"LogicalResourceId": "Queue", "StackResourceDriftStatus": "MODIFIED"},
+				{"LogicalResourceId": "Topic", "StackResourceDriftStatus": "DELETED"},
+				{"LogicalResourceId": "Bucket", "StackResourceDriftStatus": "IN_SYNC"},
+				{"LogicalResourceId": "Role", "StackResourceDriftStatus": "MODIFIED"}
+			]}`,
+			want: []int32{0, 2, 1},
+		},
+		{
+			name:   "text",
+			output: "STACKRESOURCEDRIFTS\tQueue\tAWS::SQS::Queue\nStackResourceDriftStatus\tIN_SYNC\n",
+			want:   []int32{0, 0, 0},
+		},
+		{
+			name:   "no drift report",
+			output: "An error occurred (ValidationError): Stack with id app does not exist",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := counts(ParseCloudFormationDrift(tt.output))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSummary_DispatchesOnProjectType(t *testing.T) {
+	planOutput := "Plan: 1 to add, 0 to change, 0 to destroy."
+	if got := counts(ParseSummary(Pulumi, planOutput)); got != nil {
+		t.Errorf("a Terraform summary in Pulumi output should not parse, got %v", got)
+	}
+	if got := counts(ParseSummary(Terragrunt, planOutput)); !slices.Equal(got, []int32{1, 0, 0}) {
+		t.Errorf("got %v, want [1 0 0]", got)
+	}
+}
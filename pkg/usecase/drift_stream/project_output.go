@@ -0,0 +1,154 @@
+package drift_stream
+
+import (
+	"errors"
+
+	"driftive.cloud/api/pkg/ansi"
+	"driftive.cloud/api/pkg/linediff"
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/utils/auth"
+	"driftive.cloud/api/pkg/usecase/utils/strutils"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	OutputFormatText = "text"
+	OutputFormatHTML = "html"
+
+	// diffContextLines is the number of unchanged lines shown around each change of an output diff.
+	diffContextLines = 3
+)
+
+// GetRunProjectOutput returns the plan output of the project of a run in the dir query param.
+// format=text (the default) strips the escape sequences, format=html escapes the output and
+// converts its colors to spans.
+func (d *DriftStateHandler) GetRunProjectOutput(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	runId, err := uuid.Parse(c.Params("run_id"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	dir := c.Query("dir")
+	format := c.Query("format", OutputFormatText)
+	if dir == "" || (format != OutputFormatText && format != OutputFormatHTML) {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	_, project, ferr := d.findRunProject(c, *userId, runId, dir)
+	if ferr != nil {
+		return c.SendStatus(ferr.Code)
+	}
+
+	output := ansi.Strip(strutils.OrEmpty(project.PlanOutput))
+	if format == OutputFormatHTML {
+		output = ansi.ToHTML(strutils.OrEmpty(project.PlanOutput))
+	}
+	return c.JSON(dto.ProjectOutputDTO{
+		RunID:  runId.String(),
+		Dir:    dir,
+		Format: format,
+		Output: output,
+	})
+}
+
+// GetRunProjectOutputDiff returns the unified line diff of the plan output of the project in the
+// dir query param, from the run in base_run_id to this run. Both runs must be of the same
+// repository. Escape sequences are stripped before comparing, so a change of colors alone isn't
+// reported.
+func (d *DriftStateHandler) GetRunProjectOutputDiff(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	runId, err := uuid.Parse(c.Params("run_id"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	baseRunId, err := uuid.Parse(c.Query("base_run_id"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	dir := c.Query("dir")
+	if dir == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	run, project, ferr := d.findRunProject(c, *userId, runId, dir)
+	if ferr != nil {
+		return c.SendStatus(ferr.Code)
+	}
+	baseRun, baseProject, ferr := d.findRunProject(c, *userId, baseRunId, dir)
+	if ferr != nil {
+		return c.SendStatus(ferr.Code)
+	}
+	if baseRun.RepositoryID != run.RepositoryID {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	edits := linediff.Diff(
+		linediff.Lines(ansi.Strip(strutils.OrEmpty(baseProject.PlanOutput))),
+		linediff.Lines(ansi.Strip(strutils.OrEmpty(project.PlanOutput))),
+	)
+	result := dto.ProjectOutputDiffDTO{
+		Dir:       dir,
+		BaseRunID: baseRunId.String(),
+		RunID:     runId.String(),
+		Diff:      linediff.Unified(baseRunId.String(), runId.String(), edits, diffContextLines),
+	}
+	for _, e := range edits {
+		switch e.Op {
+		case linediff.Insert:
+			result.Added++
+		case linediff.Delete:
+			result.Removed++
+		}
+	}
+	return c.JSON(result)
+}
+
+// findRunProject loads a run and its project in dir, with the project's outputs, once userId is
+// checked to be a member of the run's organization. The returned error carries the status to
+// respond with.
+func (d *DriftStateHandler) findRunProject(c fiber.Ctx, userId int64, runId uuid.UUID, dir string) (queries.DriftAnalysisRun, queries.DriftAnalysisProject, *fiber.Error) {
+	run, err := d.driftAnalysisRepository.FindDriftAnalysisRunByUUID(c.Context(), runId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return run, queries.DriftAnalysisProject{}, fiber.ErrNotFound
+		}
+		log.Errorf("Error finding drift analysis run by ID: %v", err)
+		return run, queries.DriftAnalysisProject{}, fiber.ErrInternalServerError
+	}
+
+	isMember, err := d.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), run.RepositoryID, userId)
+	if err != nil {
+		return run, queries.DriftAnalysisProject{}, fiber.ErrInternalServerError
+	}
+	if !isMember {
+		return run, queries.DriftAnalysisProject{}, fiber.ErrUnauthorized
+	}
+
+	projects, err := d.driftAnalysisRepository.FindDriftAnalysisProjectsByRunId(c.Context(), runId)
+	if err != nil {
+		log.Errorf("Error finding drift analysis projects by run ID: %v", err)
+		return run, queries.DriftAnalysisProject{}, fiber.ErrInternalServerError
+	}
+	for i := range projects {
+		if projects[i].Dir != dir {
+			continue
+		}
+		// Only this project's outputs are needed.
+		if err := d.outputs.Hydrate(c.Context(), projects[i:i+1]); err != nil {
+			log.Errorf("Error loading outputs for run %s: %v", runId, err)
+			return run, queries.DriftAnalysisProject{}, fiber.ErrInternalServerError
+		}
+		return run, projects[i], nil
+	}
+	return run, queries.DriftAnalysisProject{}, fiber.ErrNotFound
+}
@@ -0,0 +1,204 @@
+package drift_stream
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"driftive.cloud/api/pkg/codeowners"
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/utils/auth"
+	"driftive.cloud/api/pkg/usecase/utils/parsing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetProjectTree rolls the project statuses of the latest completed run that scanned the whole
+// repository up the directory tree, with the counts of every status at each dir. prefix returns the
+// subtree of that dir alone.
+func (d *DriftStateHandler) GetProjectTree(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	isMember, err := d.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	run, err := d.driftAnalysisRepository.GetLatestFullRunForRepository(c.Context(), repoId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		log.Errorf("Error getting latest run for repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	projects, err := d.driftAnalysisRepository.GetRunProjectStatuses(c.Context(), run.Uuid)
+	if err != nil {
+		log.Errorf("Error getting the project statuses of run %s: %v", run.Uuid, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	root := buildProjectTree(projects)
+	node := findTreeNode(&root, codeowners.NormalizeDir(c.Query("prefix")))
+	if node == nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return c.JSON(dto.ProjectTreeDTO{
+		RunID:     run.Uuid.String(),
+		CreatedAt: run.CreatedAt,
+		Root:      *node,
+	})
+}
+
+// GetSubtreeTrends returns the daily drift rate of the projects under the dir in the prefix query
+// param within the days_back window, so a subtree such as envs/prod can be watched apart from the
+// rest of the repository.
+func (d *DriftStateHandler) GetSubtreeTrends(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	repoIdStr := c.Params("repo_id")
+	// The whole repository is covered by the trends endpoint.
+	prefix := codeowners.NormalizeDir(c.Query("prefix"))
+	if repoIdStr == "" || prefix == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	isMember, err := d.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	dirs, err := d.subtreeDirs(c.Context(), repoId, prefix)
+	if err != nil {
+		log.Errorf("Error resolving the projects under %s in repository %d: %v", prefix, repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	daysBack := parseDaysBack(c)
+	rows, err := d.driftAnalysisRepository.GetDirsDriftOverTime(c.Context(), repoId, daysBack, dirs)
+	if err != nil {
+		log.Errorf("Error getting drift of subtree %s in repository %d: %v", prefix, repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.JSON(dto.SubtreeTrendsDTO{
+		Prefix:            prefix,
+		DriftRateOverTime: parsing.ToScopedDriftDataPoints(rows),
+		DaysBack:          int(daysBack),
+	})
+}
+
+// subtreeDirs returns the dirs of the catalog projects of a repository under prefix, a normalized
+// dir. A prefix matches whole dir segments only.
+func (d *DriftStateHandler) subtreeDirs(ctx context.Context, repoId int64, prefix string) ([]string, error) {
+	projects, err := d.driftAnalysisRepository.ListDriftProjectsByRepositoryId(ctx, queries.ListDriftProjectsByRepositoryIdParams{RepositoryID: repoId})
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{}
+	for _, p := range projects {
+		// The catalog holds a row per type of a dir, ordered by dir.
+		if len(dirs) > 0 && dirs[len(dirs)-1] == p.Dir {
+			continue
+		}
+		if strings.HasPrefix(codeowners.NormalizeDir(p.Dir)+"/", prefix+"/") {
+			dirs = append(dirs, p.Dir)
+		}
+	}
+	return dirs, nil
+}
+
+func countStatus(counts *dto.ProjectStatusCountsDTO, status string) {
+	counts.Total++
+	switch status {
+	case ProjectStatusInSync:
+		counts.InSync++
+	case ProjectStatusDrifted:
+		counts.Drifted++
+	case ProjectStatusErrored:
+		counts.Errored++
+	case ProjectStatusSkipped:
+		counts.Skipped++
+	}
+}
+
+// buildProjectTree builds the tree of the dirs of projects, each node counting the projects at or
+// under it. Children are sorted by name.
+func buildProjectTree(projects []queries.GetRunProjectStatusesRow) dto.ProjectTreeNodeDTO {
+	root := dto.ProjectTreeNodeDTO{Children: []dto.ProjectTreeNodeDTO{}}
+	for _, p := range projects {
+		status := p.Status
+		node := &root
+		countStatus(&node.Counts, status)
+		path := codeowners.NormalizeDir(p.Dir)
+		if path != "" {
+			for _, name := range strings.Split(path, "/") {
+				node = childNode(node, name)
+				countStatus(&node.Counts, status)
+			}
+		}
+		node.Project = &dto.ProjectTreeLeafDTO{Type: p.Type, Status: status}
+	}
+	return root
+}
+
+// childNode returns the child of node called name, inserting it in name order if missing.
+func childNode(node *dto.ProjectTreeNodeDTO, name string) *dto.ProjectTreeNodeDTO {
+	i, found := childIndex(node, name)
+	if !found {
+		path := name
+		if node.Path != "" {
+			path = node.Path + "/" + name
+		}
+		node.Children = slices.Insert(node.Children, i, dto.ProjectTreeNodeDTO{
+			Path:     path,
+			Name:     name,
+			Children: []dto.ProjectTreeNodeDTO{},
+		})
+	}
+	return &node.Children[i]
+}
+
+// findTreeNode returns the node of root at path, a normalized dir, or nil if no project is under it.
+func findTreeNode(root *dto.ProjectTreeNodeDTO, path string) *dto.ProjectTreeNodeDTO {
+	if path == "" {
+		return root
+	}
+	node := root
+	for _, name := range strings.Split(path, "/") {
+		i, found := childIndex(node, name)
+		if !found {
+			return nil
+		}
+		node = &node.Children[i]
+	}
+	return node
+}
+
+// childIndex returns the position of the child of node called name, or where it would be inserted.
+func childIndex(node *dto.ProjectTreeNodeDTO, name string) (int, bool) {
+	return slices.BinarySearchFunc(node.Children, name, func(n dto.ProjectTreeNodeDTO, name string) int {
+		return strings.Compare(n.Name, name)
+	})
+}
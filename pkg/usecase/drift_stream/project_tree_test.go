@@ -0,0 +1,52 @@
+package drift_stream
+
+import (
+	"testing"
+
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository/queries"
+)
+
+func TestBuildProjectTree(t *testing.T) {
+	projects := []queries.GetRunProjectStatusesRow{
+		{Dir: "./envs/prod/vpc", Type: "TERRAFORM", Status: ProjectStatusDrifted},
+		{Dir: "envs/prod/db", Type: "TERRAFORM", Status: ProjectStatusErrored},
+		{Dir: "envs/dev/vpc", Type: "TOFU", Status: ProjectStatusInSync},
+		{Dir: "envs/dev/db", Type: "TOFU", Status: ProjectStatusSkipped},
+		{Dir: "envs/prod", Type: "TERRAGRUNT", Status: ProjectStatusInSync},
+	}
+
+	root := buildProjectTree(projects)
+	if want := (dto.ProjectStatusCountsDTO{Total: 5, InSync: 2, Drifted: 1, Errored: 1, Skipped: 1}); root.Counts != want {
+		t.Errorf("root counts = %+v, want %+v", root.Counts, want)
+	}
+
+	prod := findTreeNode(&root, "envs/prod")
+	if prod == nil {
+		t.Fatal("envs/prod not found")
+	}
+	if want := (dto.ProjectStatusCountsDTO{Total: 3, InSync: 1, Drifted: 1, Errored: 1}); prod.Counts != want {
+		t.Errorf("envs/prod counts = %+v, want %+v", prod.Counts, want)
+	}
+	if prod.Project == nil || prod.Project.Type != "TERRAGRUNT" || prod.Project.Status != ProjectStatusInSync {
+		t.Errorf("envs/prod project = %+v, want an in sync TERRAGRUNT project", prod.Project)
+	}
+	var names []string
+	for _, child := range prod.Children {
+		names = append(names, child.Path)
+	}
+	if len(names) != 2 || names[0] != "envs/prod/db" || names[1] != "envs/prod/vpc" {
+		t.Errorf("envs/prod children = %v, want [envs/prod/db envs/prod/vpc]", names)
+	}
+
+	vpc := findTreeNode(&root, "envs/dev/vpc")
+	if vpc == nil || vpc.Project == nil || vpc.Project.Status != ProjectStatusInSync || len(vpc.Children) != 0 {
+		t.Errorf("envs/dev/vpc = %+v, want an in sync leaf", vpc)
+	}
+	if db := findTreeNode(&root, "envs/dev/db"); db == nil || db.Project.Status != ProjectStatusSkipped {
+		t.Errorf("envs/dev/db = %+v, want a skipped leaf", db)
+	}
+	if node := findTreeNode(&root, "envs/staging"); node != nil {
+		t.Errorf("envs/staging = %+v, want nil", node)
+	}
+}
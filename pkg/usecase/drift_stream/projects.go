@@ -0,0 +1,198 @@
+package drift_stream
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"driftive.cloud/api/pkg/codeowners"
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/utils/auth"
+	"driftive.cloud/api/pkg/usecase/utils/parsing"
+	"driftive.cloud/api/pkg/usecase/utils/strutils"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// Statuses of a project in a run, derived by the drift_project_status SQL function. The catalog
+// holds the status of the latest run each project was seen in.
+const (
+	ProjectStatusInSync  = "IN_SYNC"
+	ProjectStatusDrifted = "DRIFTED"
+	ProjectStatusErrored = "ERRORED"
+	ProjectStatusSkipped = "SKIPPED"
+)
+
+// projectTypes lists the values of the type query param, as stored.
+var projectTypes = []string{"TERRAFORM", "TOFU", "TERRAGRUNT", "PULUMI", "CLOUDFORMATION"}
+
+const (
+	defaultProjectHistoryRuns = 50
+	maxProjectHistoryRuns     = 200
+)
+
+// projectSortKeys maps the sort query param of ListRepositoryProjects to the timestamp it orders
+// by. dir is handled apart.
+var projectSortKeys = map[string]func(dto.ProjectDTO) *time.Time{
+	"first_seen_at":   func(p dto.ProjectDTO) *time.Time { return &p.FirstSeenAt },
+	"last_seen_at":    func(p dto.ProjectDTO) *time.Time { return &p.LastSeenAt },
+	"last_drifted_at": func(p dto.ProjectDTO) *time.Time { return p.LastDriftedAt },
+	"last_errored_at": func(p dto.ProjectDTO) *time.Time { return p.LastErroredAt },
+}
+
+// ListRepositoryProjects lists the projects a repository has reported across its runs, with their
+// CODEOWNERS owners. Query params: status, type and owner filter (e.g. status=drifted,
+// type=terraform, owner=@acme/platform), q matches part of the dir, sort is dir (the default),
+// first_seen_at, last_seen_at, last_drifted_at or last_errored_at, and order is asc or desc (the
+// default for timestamps, newest first).
+func (d *DriftStateHandler) ListRepositoryProjects(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	isMember, err := d.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	params := queries.ListDriftProjectsByRepositoryIdParams{
+		RepositoryID: repoId,
+		Status:       strutils.OrNil(strings.ToUpper(c.Query("status"))),
+		Type:         strutils.OrNil(strings.ToUpper(c.Query("type"))),
+		DirQuery:     strutils.OrNil(strings.TrimSpace(c.Query("q"))),
+	}
+	if params.Status != nil && !slices.Contains([]string{ProjectStatusInSync, ProjectStatusDrifted, ProjectStatusErrored, ProjectStatusSkipped}, *params.Status) {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	if params.Type != nil && !slices.Contains(projectTypes, *params.Type) {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	sortBy := c.Query("sort", "dir")
+	sortKey, ok := projectSortKeys[sortBy]
+	if !ok && sortBy != "dir" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	order := c.Query("order")
+	if order == "" {
+		order = "desc"
+		if sortBy == "dir" {
+			order = "asc"
+		}
+	}
+	if order != "asc" && order != "desc" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	rows, err := d.driftAnalysisRepository.ListDriftProjectsByRepositoryId(c.Context(), params)
+	if err != nil {
+		log.Errorf("Error listing projects of repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	rules, err := d.codeownersFor(c.Context(), repoId)
+	if err != nil {
+		log.Errorf("Error loading CODEOWNERS of repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	owner := strings.TrimSpace(c.Query("owner"))
+
+	projects := parsing.ToProjectDTOs(rows)
+	for i := range projects {
+		projects[i].Owners = ownersOf(rules, projects[i].Dir)
+	}
+	if owner != "" {
+		projects = slices.DeleteFunc(projects, func(p dto.ProjectDTO) bool {
+			return !codeowners.HasOwner(p.Owners, owner)
+		})
+	}
+	// The query orders by dir.
+	if sortKey != nil {
+		slices.SortStableFunc(projects, func(a, b dto.ProjectDTO) int {
+			ta, tb := sortKey(a), sortKey(b)
+			// Projects that never drifted or errored go last either way.
+			switch {
+			case ta == nil && tb == nil:
+				return 0
+			case ta == nil:
+				return 1
+			case tb == nil:
+				return -1
+			case order == "desc":
+				return tb.Compare(*ta)
+			default:
+				return ta.Compare(*tb)
+			}
+		})
+	} else if order == "desc" {
+		slices.Reverse(projects)
+	}
+	return c.JSON(projects)
+}
+
+// GetProjectHistory returns the outcome of the project in the dir query param across the latest
+// runs of a repository (limit, default 50, max 200), newest first, and the periods it stayed
+// drifted over the whole retained history. A dir whose project changed type, say from TERRAFORM to
+// TOFU, has its periods tracked per type; the type query param narrows the history to one of them.
+func (d *DriftStateHandler) GetProjectHistory(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	repoIdStr := c.Params("repo_id")
+	dir := c.Query("dir")
+	if repoIdStr == "" || dir == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	isMember, err := d.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	limit := int32(fiber.Query[int](c, "limit", defaultProjectHistoryRuns))
+	if limit < 1 {
+		limit = defaultProjectHistoryRuns
+	}
+	limit = min(limit, maxProjectHistoryRuns)
+
+	projectType := strutils.OrNil(strings.ToUpper(c.Query("type")))
+	if projectType != nil && !slices.Contains(projectTypes, *projectType) {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	runs, err := d.driftAnalysisRepository.GetProjectHistory(c.Context(), repoId, dir, projectType, limit)
+	if err != nil {
+		log.Errorf("Error getting history of project %s in repository %d: %v", dir, repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if len(runs) == 0 {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	periods, err := d.driftAnalysisRepository.GetProjectDriftPeriods(c.Context(), repoId, dir, projectType)
+	if err != nil {
+		log.Errorf("Error getting drift periods of project %s in repository %d: %v", dir, repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.JSON(dto.ProjectHistoryDTO{
+		Dir:          dir,
+		Runs:         parsing.ToProjectRuns(runs),
+		DriftPeriods: parsing.ToDriftPeriods(periods),
+	})
+}
@@ -0,0 +1,45 @@
+package drift_stream
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/google/uuid"
+)
+
+// publishQueueSize bounds the runs waiting to be published to GitHub. A run that finds the queue
+// full is not published.
+const publishQueueSize = 256
+
+// StartPublisher makes the GitHub calls queued by enqueuePublish, each in its own goroutine bounded
+// by githubSyncTimeout, until ctx is cancelled. Shutting down cancels the calls in flight and drops
+// the queued ones.
+func (d *DriftStateHandler) StartPublisher(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("run publisher shutting down...")
+			return
+		case publish := <-d.publishing:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(ctx, githubSyncTimeout)
+				defer cancel()
+				publish(ctx)
+			}()
+		}
+	}
+}
+
+// enqueuePublish queues publish for StartPublisher without blocking the request completing the run.
+func (d *DriftStateHandler) enqueuePublish(runUUID uuid.UUID, publish func(context.Context)) {
+	select {
+	case d.publishing <- publish:
+	default:
+		log.Warnf("The publish queue is full, run %s is not published to GitHub", runUUID)
+	}
+}
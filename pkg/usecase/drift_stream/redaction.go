@@ -0,0 +1,33 @@
+package drift_stream
+
+import (
+	"context"
+	"fmt"
+
+	"driftive.cloud/api/pkg/redact"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// redactorFor builds the redactor applied to an organization's uploads: the enabled built-in
+// detectors followed by the organization's own rules. It returns nil, which redacts nothing, when
+// redaction is disabled.
+func (d *DriftStateHandler) redactorFor(ctx context.Context, orgID int64) (*redact.Redactor, error) {
+	if !d.cfg.Redaction.Enabled {
+		return nil, nil
+	}
+	rules, err := d.orgRepository.FindOrganizationRedactionRules(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("loading redaction rules of organization %d: %w", orgID, err)
+	}
+	detectors := redact.Builtins(d.cfg.Redaction.DisabledDetectors...)
+	for _, rule := range rules {
+		// Rules are validated on creation, so this only trips if the validation got stricter since.
+		detector, err := redact.NewDetector(rule.Name, rule.Pattern)
+		if err != nil {
+			log.Warnf("Skipping redaction rule %d of organization %d: %v", rule.ID, orgID, err)
+			continue
+		}
+		detectors = append(detectors, detector)
+	}
+	return redact.New(detectors...), nil
+}
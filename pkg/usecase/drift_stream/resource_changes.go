@@ -0,0 +1,71 @@
+package drift_stream
+
+import "regexp"
+
+// Resource change actions, named after the plan header they are parsed from. ChangedOutside and
+// DeletedOutside come from the "Objects have changed outside of Terraform" section, the drift
+// itself rather than the plan to undo it.
+const (
+	ResourceActionCreate         = "CREATE"
+	ResourceActionUpdate         = "UPDATE"
+	ResourceActionReplace        = "REPLACE"
+	ResourceActionDestroy        = "DESTROY"
+	ResourceActionRead           = "READ"
+	ResourceActionImport         = "IMPORT"
+	ResourceActionForget         = "FORGET"
+	ResourceActionMove           = "MOVE"
+	ResourceActionChangedOutside = "CHANGED_OUTSIDE"
+	ResourceActionDeletedOutside = "DELETED_OUTSIDE"
+)
+
+// resourceHeaderRegex matches the "# <address> <verb>" line that opens each resource in a
+// human-readable plan. Index keys may contain spaces, so brackets are matched as a whole.
+var resourceHeaderRegex = regexp.MustCompile(`(?m)^\s*# ((?:[^\s\[]|\[[^\]\n]*\])+)(?: \(deposed object \w+\))? ` +
+	`(will be created|will be updated in-place|must be replaced|will be replaced, as requested|will be destroyed|` +
+	`will be read during apply|will be imported|will no longer be managed by \w+|will be removed from the (?:\w+ )?state|` +
+	`has moved to ((?:[^\s\[]|\[[^\]\n]*\])+)|has changed|has been deleted)`)
+
+// ResourceChange is one resource named in a plan and what the plan does to it.
+type ResourceChange struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+}
+
+// ParseResourceChanges lists the resources named in the headers of a Terraform, OpenTofu or
+// Terragrunt plan, in plan order. A resource may appear twice, once as drifted outside of Terraform
+// and once with the action that reverts it. Returns nil for other tools and for plans without
+// resource headers.
+func ParseResourceChanges(projectType ProjectType, planOutput string) []ResourceChange {
+	if projectType != Terraform && projectType != Tofu && projectType != Terragrunt {
+		return nil
+	}
+	var changes []ResourceChange
+	for _, m := range resourceHeaderRegex.FindAllStringSubmatch(planOutput, -1) {
+		change := ResourceChange{Address: m[1]}
+		switch verb := m[2]; {
+		case verb == "will be created":
+			change.Action = ResourceActionCreate
+		case verb == "will be updated in-place":
+			change.Action = ResourceActionUpdate
+		case verb == "must be replaced" || verb == "will be replaced, as requested":
+			change.Action = ResourceActionReplace
+		case verb == "will be destroyed":
+			change.Action = ResourceActionDestroy
+		case verb == "will be read during apply":
+			change.Action = ResourceActionRead
+		case verb == "will be imported":
+			change.Action = ResourceActionImport
+		case m[3] != "":
+			// Recorded under the new address, which is the one later runs will use.
+			change.Address, change.Action = m[3], ResourceActionMove
+		case verb == "has changed":
+			change.Action = ResourceActionChangedOutside
+		case verb == "has been deleted":
+			change.Action = ResourceActionDeletedOutside
+		default:
+			change.Action = ResourceActionForget
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
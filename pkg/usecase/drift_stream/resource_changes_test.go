@@ -0,0 +1,87 @@
+package drift_stream
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseResourceChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []ResourceChange
+	}{
+		{
+			name: "plan actions",
+			output: "Terraform will perform the following actions:\n\n" +
+				"  # aws_instance.web will be updated in-place\n" +
+				"  ~ resource \"aws_instance\" \"web\" {\n" +
+				"  # aws_s3_bucket.logs must be replaced\n" +
+				"  # module.net.aws_subnet.a[0] will be created\n" +
+				"  # aws_iam_role.old will be destroyed\n" +
+				"  # data.aws_ami.ubuntu will be read during apply\n" +
+				"  # aws_vpc.main will be imported\n" +
+				"  # aws_sqs_queue.jobs will no longer be managed by Terraform\n\n" +
+				"Plan: 1 to import, 1 to add, 1 to change, 2 to destroy, 1 to forget.\n",
+			want: []ResourceChange{
+				{"aws_instance.web", ResourceActionUpdate},
+				{"aws_s3_bucket.logs", ResourceActionReplace},
+				{"module.net.aws_subnet.a[0]", ResourceActionCreate},
+				{"aws_iam_role.old", ResourceActionDestroy},
+				{"data.aws_ami.ubuntu", ResourceActionRead},
+				{"aws_vpc.main", ResourceActionImport},
+				{"aws_sqs_queue.jobs", ResourceActionForget},
+			},
+		},
+		{
+			name:   "index keys with spaces",
+			output: "  # aws_route53_record.this[\"www example\"] will be destroyed\n",
+			want:   []ResourceChange{{`aws_route53_record.this["www example"]`, ResourceActionDestroy}},
+		},
+		{
+			name:   "deposed object",
+			output: "  # aws_instance.web (deposed object 1a2b3c4d) will be destroyed\n",
+			want:   []ResourceChange{{"aws_instance.web", ResourceActionDestroy}},
+		},
+		{
+			name:   "moved resource uses the new address",
+			output: "  # aws_instance.old has moved to aws_instance.new\n",
+			want:   []ResourceChange{{"aws_instance.new", ResourceActionMove}},
+		},
+		{
+			name: "changes outside of terraform",
+			output: "Note: Objects have changed outside of Terraform\n\n" +
+				"  # aws_security_group.db has changed\n" +
+				"  # aws_eip.nat has been deleted\n\n" +
+				"  # aws_security_group.db will be updated in-place\n",
+			want: []ResourceChange{
+				{"aws_security_group.db", ResourceActionChangedOutside},
+				{"aws_eip.nat", ResourceActionDeletedOutside},
+				{"aws_security_group.db", ResourceActionUpdate},
+			},
+		},
+		{
+			name:   "no changes",
+			output: "No changes. Your infrastructure matches the configuration.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseResourceChanges(Terraform, tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseResourceChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseResourceChanges_OtherTools(t *testing.T) {
+	output := "  # aws_instance.web will be updated in-place\n"
+	if got := ParseResourceChanges(Pulumi, output); got != nil {
+		t.Errorf("expected nil for Pulumi, got %v", got)
+	}
+	if got := ParseResourceChanges(Terragrunt, output); len(got) != 1 {
+		t.Errorf("expected Terragrunt plans to be parsed, got %v", got)
+	}
+}
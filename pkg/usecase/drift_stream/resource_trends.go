@@ -0,0 +1,88 @@
+package drift_stream
+
+import (
+	"context"
+
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/utils/auth"
+	"driftive.cloud/api/pkg/usecase/utils/parsing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// maxResourceTrendResults caps each ranking of the resource trends endpoints.
+const maxResourceTrendResults = 20
+
+// GetRepositoryResourceTrends ranks the resource types and providers that drift most often in a
+// repository over the same days_back window as GetRepositoryTrends.
+func (d *DriftStateHandler) GetRepositoryResourceTrends(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	isMember, err := d.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	trends, err := d.resourceTrends(c.Context(), &repoId, nil, parseDaysBack(c))
+	if err != nil {
+		log.Errorf("Error getting resource trends for repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return c.JSON(trends)
+}
+
+// GetOrganizationResourceTrends is GetRepositoryResourceTrends across every repository of an
+// organization.
+func (d *DriftStateHandler) GetOrganizationResourceTrends(c fiber.Ctx) error {
+	orgId := parsing.StringToInt64(c.Params("org_id"))
+	if err := auth.MustHavePermission(c, orgId); err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	trends, err := d.resourceTrends(c.Context(), nil, &orgId, parseDaysBack(c))
+	if err != nil {
+		log.Errorf("Error getting resource trends for org %d: %v", orgId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return c.JSON(trends)
+}
+
+// resourceTrends ranks resource types and providers in the repository or the organization given.
+func (d *DriftStateHandler) resourceTrends(ctx context.Context, repoId, orgId *int64, daysBack int32) (dto.ResourceTrendsDTO, error) {
+	resourceTypes, err := d.driftAnalysisRepository.GetMostFrequentlyDriftedResourceTypes(ctx, queries.GetMostFrequentlyDriftedResourceTypesParams{
+		RepositoryID:   repoId,
+		OrganizationID: orgId,
+		DaysBack:       daysBack,
+		MaxResults:     maxResourceTrendResults,
+	})
+	if err != nil {
+		return dto.ResourceTrendsDTO{}, err
+	}
+	providers, err := d.driftAnalysisRepository.GetMostFrequentlyDriftedProviders(ctx, queries.GetMostFrequentlyDriftedProvidersParams{
+		RepositoryID:   repoId,
+		OrganizationID: orgId,
+		DaysBack:       daysBack,
+		MaxResults:     maxResourceTrendResults,
+	})
+	if err != nil {
+		return dto.ResourceTrendsDTO{}, err
+	}
+	return dto.ResourceTrendsDTO{
+		ResourceTypes: parsing.ToResourceTypeDriftStats(resourceTypes),
+		Providers:     parsing.ToProviderDriftStats(providers),
+		DaysBack:      int(daysBack),
+	}, nil
+}
@@ -0,0 +1,123 @@
+package drift_stream
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// errShardRunCompleted rejects a shard whose run was already completed, by its last shard or by
+// the finalizer. Merging it would change the projects of a run whose totals, coverage changes and
+// check run were already published.
+var errShardRunCompleted = errors.New("the sharded run already completed")
+
+// handleShardUpdate merges one shard's results into the run shared by every shard of the scan.
+// The run stays RUNNING until all expected shards reported; the last one completes it. Shards
+// that never report are handled by StartShardFinalizer.
+func (d *DriftStateHandler) handleShardUpdate(c fiber.Ctx, repo queries.GitRepository, org queries.GitOrganization, idemKey string, state DriftDetectionResult) error {
+	shardID := strings.TrimSpace(state.Shard.ID)
+	if idemKey == "" || shardID == "" || state.Shard.Count < 1 {
+		log.Warnf("Rejecting shard for repository %d: needs an Idempotency-Key, a shard id and a positive shard count", repo.ID)
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	run, err := d.findOrCreateRunningRun(c.Context(), repo.ID, idemKey, 0, []string{})
+	if err != nil {
+		log.Errorf("Error resolving sharded run for repository %d, key %s: %v", repo.ID, idemKey, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if run.Status == runStatusCompleted {
+		return sendShardRunCompleted(c, shardID, run.Uuid.String())
+	}
+
+	redactor, err := d.redactorFor(c.Context(), org.ID)
+	if err != nil {
+		log.Errorf("Error preparing redaction for repository %d: %v", repo.ID, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	upsertParams, err := d.toUpsertParams(redactor, run.Uuid, state.ProjectResults)
+	if err != nil {
+		log.Errorf("Rejecting shard %s: %v", shardID, err)
+		return sendIngestError(c, err)
+	}
+	prepared, err := d.prepareOutputs(c.Context(), repo.ID, upsertParams)
+	if err != nil {
+		log.Errorf("Error preparing outputs for run %s: %v", run.Uuid, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	var status string
+	var finish func(context.Context)
+	err = d.driftAnalysisRepository.WithTx(c.Context(), func(ctx context.Context) error {
+		// Locks the run row before anything else; see SetDriftAnalysisRunExpectedShards.
+		running, err := d.driftAnalysisRepository.SetDriftAnalysisRunExpectedShards(ctx, run.Uuid, state.Shard.Count)
+		if err != nil {
+			return err
+		}
+		if !running {
+			// A concurrent shard or the finalizer completed the run since it was looked up.
+			return errShardRunCompleted
+		}
+		if _, err := d.recordCommitSHA(ctx, run.Uuid, state.CommitSHA); err != nil {
+			return err
+		}
+		if len(upsertParams) > 0 {
+			if err := d.storeOutputs(ctx, repo.ID, upsertParams, prepared); err != nil {
+				log.Errorf("Error storing outputs for run %s: %v", run.Uuid, err)
+				return err
+			}
+			if err := d.driftAnalysisRepository.UpsertDriftAnalysisProjects(ctx, upsertParams); err != nil {
+				log.Errorf("Error upserting drift analysis projects for run %s: %v", run.Uuid, err)
+				return err
+			}
+		}
+		if err := d.driftAnalysisRepository.RecordDriftAnalysisRunShard(ctx, queries.RecordDriftAnalysisRunShardParams{
+			DriftAnalysisRunID:     run.Uuid,
+			ShardID:                shardID,
+			TotalProjects:          state.TotalProjects,
+			AnalysisDurationMillis: state.Duration.Milliseconds(),
+		}); err != nil {
+			return err
+		}
+		// The row lock taken above keeps the run RUNNING until this transaction commits.
+		status, err = d.driftAnalysisRepository.RefreshShardedDriftAnalysisRun(ctx, run.Uuid)
+		if err != nil || status != runStatusCompleted {
+			return err
+		}
+		// The last shard completes the run.
+		done, err := d.completeRun(ctx, org, repo, run.Uuid)
+		if err != nil {
+			return err
+		}
+		finish = done
+		return nil
+	})
+	if err != nil {
+		d.discardOutputs(c.Context(), repo.ID, prepared)
+	}
+	if errors.Is(err, errShardRunCompleted) {
+		return sendShardRunCompleted(c, shardID, run.Uuid.String())
+	}
+	if err != nil {
+		log.Errorf("Error recording shard %s for run %s: %v", shardID, run.Uuid, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	log.Infof("Recorded shard %s/%d for run %s (%s)", shardID, state.Shard.Count, run.Uuid, status)
+	// Nil until the last shard reported.
+	if finish != nil {
+		finish(c.Context())
+	}
+
+	return c.JSON(buildAnalysisResponse(d.cfg.Frontend.FrontendURL, org, repo, run.Uuid))
+}
+
+func sendShardRunCompleted(c fiber.Ctx, shardID string, runID string) error {
+	log.Warnf("Rejecting shard %s for completed run %s", shardID, runID)
+	return c.Status(fiber.StatusConflict).
+		JSON(fiber.Map{"status": "error", "message": errShardRunCompleted.Error(), "data": nil})
+}
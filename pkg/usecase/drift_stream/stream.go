@@ -0,0 +1,236 @@
+package drift_stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"driftive.cloud/api/pkg/redact"
+	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// streamUpsertBatchSize is how many projects are buffered before they are written, bounding the
+// memory a streamed upload holds to a batch of outputs rather than the whole payload, and the
+// work of each of its transactions.
+const streamUpsertBatchSize = 50
+
+// DriftStreamRecord is one line of an NDJSON drift analysis upload. Exactly one field is set:
+// every project result is its own line, and a single summary line closes the upload.
+type DriftStreamRecord struct {
+	Project *DriftProjectResult `json:"project,omitempty"`
+	Summary *DriftStreamSummary `json:"summary,omitempty"`
+}
+
+// DriftStreamSummary closes a streamed upload. Drifted/errored/skipped counts are recomputed from
+// the streamed projects, so only what they cannot tell is sent.
+type DriftStreamSummary struct {
+	TotalProjects int32         `json:"total_projects"`
+	Duration      time.Duration `json:"duration"`
+	CommitSHA     *string       `json:"commit_sha,omitempty"`
+}
+
+// errBadStream marks stream errors caused by the client, as opposed to database failures.
+var errBadStream = errors.New("invalid drift stream")
+
+// HandleStream ingests a drift analysis as NDJSON, writing projects in batches while the body is
+// still being read. Each batch commits on its own, so a long upload holds no transaction open; the
+// run stays RUNNING until the summary line completes it. An upload cut short with an
+// Idempotency-Key is resumed by its retry, which upserts over the batches already written; one
+// without a key cannot be resumed, and its run is dropped.
+func (d *DriftStateHandler) HandleStream(c fiber.Ctx) error {
+	repo, org, status, ok := d.resolveRepoAndOrg(c)
+	if !ok {
+		return c.SendStatus(status)
+	}
+
+	idemKey := strings.TrimSpace(c.Get("Idempotency-Key"))
+
+	var adoptedRunUUID *uuid.UUID
+	if idemKey != "" {
+		existing, replay, err := d.findIdempotentRun(c.Context(), repo.ID, idemKey)
+		if err != nil {
+			log.Errorf("Error resolving idempotency key %s for repository %d: %v", idemKey, repo.ID, err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		if replay {
+			return c.JSON(d.completedAnalysisResponse(c.Context(), org, repo, existing.Uuid))
+		}
+		if existing != nil {
+			adoptedRunUUID = &existing.Uuid
+		}
+	}
+
+	body, err := ingestBodyReader(c, d.cfg.Ingest.MaxRequestBytes)
+	if err != nil {
+		log.Warnf("Rejecting drift stream for repository %d: %v", repo.ID, err)
+		return sendIngestError(c, err)
+	}
+	defer body.Close()
+
+	redactor, err := d.redactorFor(c.Context(), org.ID)
+	if err != nil {
+		log.Errorf("Error preparing redaction for repository %d: %v", repo.ID, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	runUUID := uuid.New()
+	if adoptedRunUUID != nil {
+		runUUID = *adoptedRunUUID
+	} else {
+		var idemKeyPtr *string
+		if idemKey != "" {
+			idemKeyPtr = &idemKey
+		}
+		if _, err := d.driftAnalysisRepository.CreateRunningDriftAnalysisRun(c.Context(), queries.CreateRunningDriftAnalysisRunParams{
+			Uuid:            runUUID,
+			RepositoryID:    repo.ID,
+			IdempotencyKey:  idemKeyPtr,
+			RunningProjects: []string{},
+		}); err != nil {
+			// Same race as HandleUpdate: a concurrent retry with this key created the run first.
+			var pgErr *pgconn.PgError
+			if idemKey != "" && errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+				existing, lookupErr := d.driftAnalysisRepository.FindRunByRepoAndIdempotencyKey(c.Context(), repo.ID, idemKey)
+				if lookupErr == nil {
+					log.Infof("Idempotent race resolved for repository %d, key %s -> run %s", repo.ID, idemKey, existing.Uuid)
+					return c.JSON(d.completedAnalysisResponse(c.Context(), org, repo, existing.Uuid))
+				}
+			}
+			log.Errorf("Error creating drift analysis run: %v", err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+	}
+
+	summary, streamed, err := d.streamProjects(c.Context(), json.NewDecoder(body), redactor, repo.ID, runUUID)
+	if err == nil {
+		var finish func(context.Context)
+		err = d.driftAnalysisRepository.WithTx(c.Context(), func(ctx context.Context) error {
+			if err := d.lockStreamedRun(ctx, runUUID); err != nil {
+				return err
+			}
+			if _, err := d.recordCommitSHA(ctx, runUUID, summary.CommitSHA); err != nil {
+				log.Errorf("Error recording the commit of run %s: %v", runUUID, err)
+				return err
+			}
+			done, err := d.completeRun(ctx, org, repo, runUUID)
+			if err != nil {
+				return err
+			}
+			finish = done
+
+			return d.driftAnalysisRepository.CompleteDriftAnalysisRunFromProjects(ctx, queries.CompleteDriftAnalysisRunFromProjectsParams{
+				TotalProjects:          summary.TotalProjects,
+				AnalysisDurationMillis: summary.Duration.Milliseconds(),
+				Uuid:                   runUUID,
+			})
+		})
+		if err == nil {
+			log.Infof("Streamed %d drift analysis projects into run %s", streamed, runUUID)
+			finish(c.Context())
+			return c.JSON(d.completedAnalysisResponse(c.Context(), org, repo, runUUID))
+		}
+	}
+
+	if idemKey == "" {
+		if delErr := d.driftAnalysisRepository.DeleteRunningDriftAnalysisRun(c.Context(), runUUID); delErr != nil {
+			log.Warnf("Dropping run %s of a failed drift stream failed: %v", runUUID, delErr)
+		}
+	}
+	if errors.Is(err, errBadStream) {
+		log.Warnf("Rejecting drift stream for repository %d: %v", repo.ID, err)
+		return sendIngestError(c, err)
+	}
+	log.Errorf("Error handling drift stream for run %s: %v", runUUID, err)
+	return c.SendStatus(fiber.StatusInternalServerError)
+}
+
+// lockStreamedRun starts each transaction of a streamed upload, failing it once the run is no
+// longer RUNNING: swept as stale, or completed by a concurrent retry.
+func (d *DriftStateHandler) lockStreamedRun(ctx context.Context, runID uuid.UUID) error {
+	running, err := d.driftAnalysisRepository.TouchRunningDriftAnalysisRun(ctx, runID)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return fmt.Errorf("run %s is no longer running", runID)
+	}
+	return nil
+}
+
+// streamProjects reads records until the summary line, committing projects every
+// streamUpsertBatchSize records. Records after the summary are rejected rather than ignored, since
+// they would otherwise be silently dropped.
+func (d *DriftStateHandler) streamProjects(ctx context.Context, dec *json.Decoder, redactor *redact.Redactor, repoID int64, runID uuid.UUID) (DriftStreamSummary, int, error) {
+	batch := make([]queries.UpsertDriftAnalysisProjectParams, 0, streamUpsertBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		prepared, err := d.prepareOutputs(ctx, repoID, batch)
+		if err != nil {
+			log.Errorf("Error preparing outputs for run %s: %v", runID, err)
+			return err
+		}
+		if err := d.driftAnalysisRepository.WithTx(ctx, func(ctx context.Context) error {
+			if err := d.lockStreamedRun(ctx, runID); err != nil {
+				return err
+			}
+			if err := d.storeOutputs(ctx, repoID, batch, prepared); err != nil {
+				log.Errorf("Error storing outputs for run %s: %v", runID, err)
+				return err
+			}
+			if err := d.driftAnalysisRepository.UpsertDriftAnalysisProjects(ctx, batch); err != nil {
+				log.Errorf("Error upserting drift analysis projects for run %s: %v", runID, err)
+				return err
+			}
+			return nil
+		}); err != nil {
+			d.discardOutputs(ctx, repoID, prepared)
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	count := 0
+	for line := 1; ; line++ {
+		var record DriftStreamRecord
+		if err := dec.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				// Usually the CLI was killed mid-upload.
+				return DriftStreamSummary{}, count, fmt.Errorf("%w: stream ended without a summary record", errBadStream)
+			}
+			return DriftStreamSummary{}, count, fmt.Errorf("%w: record %d: %w", errBadStream, line, err)
+		}
+
+		switch {
+		case record.Project != nil && record.Summary == nil:
+			param, err := d.toUpsertParam(redactor, runID, count, *record.Project)
+			if err != nil {
+				return DriftStreamSummary{}, count, fmt.Errorf("%w: record %d: %w", errBadStream, line, err)
+			}
+			batch = append(batch, param)
+			count++
+			if len(batch) == streamUpsertBatchSize {
+				if err := flush(); err != nil {
+					return DriftStreamSummary{}, count, err
+				}
+			}
+		case record.Summary != nil && record.Project == nil:
+			if dec.More() {
+				return DriftStreamSummary{}, count, fmt.Errorf("%w: record %d: summary must be the last record", errBadStream, line)
+			}
+			return *record.Summary, count, flush()
+		default:
+			return DriftStreamSummary{}, count, fmt.Errorf("%w: record %d: exactly one of project or summary must be set", errBadStream, line)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package drift_stream
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"driftive.cloud/api/pkg/ansi"
+	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+const (
+	summaryBackfillInterval = time.Minute
+	summaryBackfillBatch    = 100
+	// summaryBackfillLeaseMinutes is how long a claimed batch is kept from other instances; far
+	// longer than fetching and parsing a batch takes.
+	summaryBackfillLeaseMinutes = 10
+)
+
+// projectTypeFromDBString is the inverse of projectTypeToDBString.
+func projectTypeFromDBString(projectType string) (ProjectType, error) {
+	for _, t := range []ProjectType{Terraform, Tofu, Terragrunt, Pulumi, CloudFormation} {
+		if s, _ := projectTypeToDBString(t); s == projectType {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown project type %q", projectType)
+}
+
+// StartSummaryBackfill re-parses the plan outputs of projects stored by an older summary parser,
+// a batch at a time, so counts the parser learned to read fill in for past runs too. Once every
+// row is current each pass is a lookup on an empty index range.
+func (d *DriftStateHandler) StartSummaryBackfill(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("plan summary backfill shutting down...")
+			return
+		case <-time.After(summaryBackfillInterval):
+		}
+
+		for {
+			updated, err := d.BackfillSummaries(ctx, summaryBackfillBatch)
+			if err != nil {
+				log.Errorf("error backfilling plan summaries: %v", err)
+				break
+			}
+			if updated > 0 {
+				log.Infof("re-parsed the plan summaries of %d project(s)", updated)
+			}
+			if updated < summaryBackfillBatch || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+}
+
+// BackfillSummaries re-parses up to maxRows outdated projects and returns how many it updated. Only
+// the fields whose parser changed since a project was stored are re-derived; the others keep what
+// was stored. A project whose outputs can no longer be read keeps what was parsed before but is
+// still marked current, otherwise it would be claimed again on every pass.
+//
+// The batch is claimed under a lease rather than a lock, so no transaction is held open while its
+// outputs are fetched and parsed; each project is then written on its own. A batch that fails part
+// way is claimed again once its lease expires.
+func (d *DriftStateHandler) BackfillSummaries(ctx context.Context, maxRows int32) (int, error) {
+	projects, err := d.driftAnalysisRepository.ClaimOutdatedSummaryProjects(ctx, SummaryParserVersion, summaryBackfillLeaseMinutes, maxRows)
+	if err != nil || len(projects) == 0 {
+		return 0, err
+	}
+	if err := d.outputs.Hydrate(ctx, projects); err != nil {
+		return 0, err
+	}
+
+	for i, p := range projects {
+		params, err := d.reparseSummary(p)
+		if err != nil {
+			return i, fmt.Errorf("project %d: %w", p.ID, err)
+		}
+		if err := d.driftAnalysisRepository.SetDriftAnalysisProjectSummary(ctx, params); err != nil {
+			return i, err
+		}
+	}
+	return len(projects), nil
+}
+
+// reparseSummary returns what p stored, with the fields whose parser changed since re-derived from
+// its outputs, which are stripped of escape codes once for all the parsers.
+func (d *DriftStateHandler) reparseSummary(p queries.DriftAnalysisProject) (queries.SetDriftAnalysisProjectSummaryParams, error) {
+	params := storedSummary(p)
+	projectType, err := projectTypeFromDBString(p.Type)
+	if err != nil {
+		return params, err
+	}
+	outdated := func(fieldVersion int16) bool { return p.SummaryParserVersion < fieldVersion }
+	var initOutput, planOutput string
+	if p.InitOutput != nil {
+		initOutput = ansi.Strip(*p.InitOutput)
+	}
+	if p.PlanOutput != nil {
+		planOutput = ansi.Strip(*p.PlanOutput)
+		reparsePlanFields(&params, outdated, p.Drifted, projectType, planOutput)
+	}
+	if p.InitOutput != nil && outdated(versionsParserVersion) {
+		reparseVersions(&params, projectType, initOutput)
+	}
+	if p.InitOutput != nil || p.PlanOutput != nil {
+		d.reparseOutputFields(&params, outdated, p, projectType, initOutput, planOutput)
+	}
+	return params, nil
+}
+
+// storedSummary returns the params that write back what p stores, marked current.
+func storedSummary(p queries.DriftAnalysisProject) queries.SetDriftAnalysisProjectSummaryParams {
+	return queries.SetDriftAnalysisProjectSummaryParams{
+		ResourcesAdded:       p.ResourcesAdded,
+		ResourcesChanged:     p.ResourcesChanged,
+		ResourcesDestroyed:   p.ResourcesDestroyed,
+		ResourcesImported:    p.ResourcesImported,
+		ResourcesForgotten:   p.ResourcesForgotten,
+		ResourcesMoved:       p.ResourcesMoved,
+		OutputsChanged:       p.OutputsChanged,
+		SummaryParserVersion: SummaryParserVersion,
+		ResourceChanges:      p.ResourceChanges,
+		DriftFingerprint:     p.DriftFingerprint,
+		ErrorClass:           p.ErrorClass,
+		CoreTool:             p.CoreTool,
+		CoreVersion:          p.CoreVersion,
+		ProviderVersions:     p.ProviderVersions,
+		Warnings:             p.Warnings,
+		ID:                   p.ID,
+	}
+}
+
+// reparsePlanFields re-derives the fields read from the plan output alone.
+func reparsePlanFields(params *queries.SetDriftAnalysisProjectSummaryParams, outdated func(int16) bool, drifted bool, projectType ProjectType, planOutput string) {
+	if outdated(countsParserVersion) {
+		summary := ParseSummary(projectType, planOutput)
+		params.ResourcesAdded = summary.Added
+		params.ResourcesChanged = summary.Changed
+		params.ResourcesDestroyed = summary.Destroyed
+		params.ResourcesImported = summary.Imported
+		params.ResourcesForgotten = summary.Forgotten
+		params.ResourcesMoved = summary.Moved
+		params.OutputsChanged = summary.OutputsChanged
+	}
+	if outdated(resourceChangesParserVersion) {
+		params.ResourceChanges = jsonColumn(ParseResourceChanges(projectType, planOutput))
+	}
+	if outdated(fingerprintParserVersion) && drifted {
+		params.DriftFingerprint = DriftFingerprint(projectType, planOutput)
+	}
+}
+
+// reparseVersions re-derives the tool and provider versions read from the init output.
+func reparseVersions(params *queries.SetDriftAnalysisProjectSummaryParams, projectType ProjectType, initOutput string) {
+	versions := ParseVersions(projectType, initOutput)
+	params.CoreTool = versions.CoreTool
+	params.CoreVersion = versions.CoreVersion
+	params.ProviderVersions = jsonColumn(versions.Providers)
+}
+
+// reparseOutputFields re-derives the fields read from both outputs, either of which may be missing
+// and then empty.
+func (d *DriftStateHandler) reparseOutputFields(params *queries.SetDriftAnalysisProjectSummaryParams, outdated func(int16) bool, p queries.DriftAnalysisProject, projectType ProjectType, initOutput, planOutput string) {
+	if outdated(errorClassParserVersion) {
+		params.ErrorClass = d.errorClass(p.Succeeded, p.SkippedDueToPr, initOutput, planOutput)
+	}
+	if outdated(warningsParserVersion) {
+		params.Warnings = jsonColumn(ParseWarnings(projectType, initOutput, planOutput))
+	}
+}
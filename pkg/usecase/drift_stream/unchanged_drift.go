@@ -0,0 +1,84 @@
+package drift_stream
+
+import (
+	"context"
+
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/google/uuid"
+)
+
+// previousFingerprints returns the drift fingerprints of the repository's last completed run
+// before runID, by project dir.
+func (d *DriftStateHandler) previousFingerprints(ctx context.Context, runID uuid.UUID) (map[string]string, error) {
+	rows, err := d.driftAnalysisRepository.GetPreviousRunDriftFingerprints(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	fingerprints := make(map[string]string, len(rows))
+	for _, row := range rows {
+		if row.DriftFingerprint != nil {
+			fingerprints[row.Dir] = *row.DriftFingerprint
+		}
+	}
+	return fingerprints, nil
+}
+
+// isDriftUnchanged reports whether a drifted project has the same drift as in the previous run.
+// Projects without a fingerprint never count as unchanged, so their drift is always reported.
+func isDriftUnchanged(previous map[string]string, dir string, drifted bool, fingerprint *string) bool {
+	if !drifted || fingerprint == nil {
+		return false
+	}
+	prev, ok := previous[dir]
+	return ok && prev == *fingerprint
+}
+
+// markUnchangedDrift sets DriftUnchanged on the projects of a run.
+func (d *DriftStateHandler) markUnchangedDrift(ctx context.Context, runID uuid.UUID, projects []dto.DriftAnalysisProjectDTO) error {
+	previous, err := d.previousFingerprints(ctx, runID)
+	if err != nil {
+		return err
+	}
+	for i := range projects {
+		p := &projects[i]
+		p.DriftUnchanged = isDriftUnchanged(previous, p.Dir, p.Drifted, p.DriftFingerprint)
+	}
+	return nil
+}
+
+// runDriftUnchanged reports whether a run found drift and all of it is unchanged since the
+// previous run. Drift that went away doesn't count as a change.
+func runDriftUnchanged(projects []queries.DriftAnalysisProject, previous map[string]string) bool {
+	drifted := false
+	for _, p := range projects {
+		if !p.Drifted {
+			continue
+		}
+		if !isDriftUnchanged(previous, p.Dir, p.Drifted, p.DriftFingerprint) {
+			return false
+		}
+		drifted = true
+	}
+	return drifted
+}
+
+// completedAnalysisResponse is buildAnalysisResponse for a completed run, telling the CLI whether
+// its drift is unchanged so it can skip notifying about it again. A lookup failure is logged and
+// reported as changed, which at worst repeats a notification.
+func (d *DriftStateHandler) completedAnalysisResponse(ctx context.Context, org queries.GitOrganization, repo queries.GitRepository, runUUID uuid.UUID) DriftAnalysisResponse {
+	response := buildAnalysisResponse(d.cfg.Frontend.FrontendURL, org, repo, runUUID)
+	projects, err := d.driftAnalysisRepository.FindDriftAnalysisProjectsByRunId(ctx, runUUID)
+	if err != nil {
+		log.Warnf("Error loading projects of run %s to compare drift: %v", runUUID, err)
+		return response
+	}
+	previous, err := d.previousFingerprints(ctx, runUUID)
+	if err != nil {
+		log.Warnf("Error loading previous drift of run %s: %v", runUUID, err)
+		return response
+	}
+	response.DriftUnchanged = runDriftUnchanged(projects, previous)
+	return response
+}
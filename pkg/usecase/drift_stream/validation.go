@@ -0,0 +1,215 @@
+package drift_stream
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// maxDirLength matches drift_analysis_project.dir, a VARCHAR(1500), which counts characters.
+const maxDirLength = 1500
+
+// commitSHARegex matches the full SHA-1 or SHA-256 name of a git commit.
+var commitSHARegex = regexp.MustCompile(`^([0-9a-fA-F]{40}|[0-9a-fA-F]{64})$`)
+
+// schemaPath is where the v2 ingest schema is served, referenced from every validation error.
+const schemaPath = "/api/v2/drift_analysis/schema"
+
+//go:embed schema/drift_analysis.v2.json
+var driftAnalysisSchema []byte
+
+// FieldError describes one offending field of a v2 upload. Field is a path into the body such as
+// project_results[3].project.dir, or empty when the body as a whole is at fault.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorData is the data of a rejected v2 upload.
+type ValidationErrorData struct {
+	Schema string       `json:"schema"`
+	Errors []FieldError `json:"errors"`
+}
+
+// validateDetectionResult checks an upload against the v2 contract and returns every violation
+// rather than the first, so a CLI author can fix a payload in one round trip.
+func validateDetectionResult(state DriftDetectionResult, maxProjects int) []FieldError {
+	var errs []FieldError
+	add := func(field, code, format string, args ...any) {
+		errs = append(errs, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if state.ProjectResults == nil {
+		add("project_results", "required", "project_results is required, send [] for a scan without projects")
+	}
+	if maxProjects > 0 && len(state.ProjectResults) > maxProjects {
+		add("project_results", "too_many", "%d project results, over the limit of %d", len(state.ProjectResults), maxProjects)
+	}
+
+	var drifted, errored int32
+	seen := make(map[string]int, len(state.ProjectResults))
+	for i, result := range state.ProjectResults {
+		item := fmt.Sprintf("project_results[%d]", i)
+		field := item + ".project"
+		dir := result.Project.Dir
+		switch {
+		case dir == "":
+			add(field+".dir", "required", "dir must not be empty")
+		case !utf8.ValidString(dir):
+			add(field+".dir", "invalid_encoding", "dir must be valid UTF-8")
+		case utf8.RuneCountInString(dir) > maxDirLength:
+			add(field+".dir", "too_long", "dir is %d characters, over the limit of %d", utf8.RuneCountInString(dir), maxDirLength)
+		}
+		if first, ok := seen[dir]; ok && dir != "" {
+			add(field+".dir", "duplicate", "dir %q was already sent as project_results[%d]", dir, first)
+		} else {
+			seen[dir] = i
+		}
+		if _, err := projectTypeToDBString(result.Project.Type); err != nil {
+			add(field+".type", "invalid_value", "unknown project type %d", result.Project.Type)
+		}
+		if result.InitDuration != nil && *result.InitDuration < 0 {
+			add(item+".init_duration", "out_of_range", "init_duration must not be negative")
+		}
+		if result.PlanDuration != nil && *result.PlanDuration < 0 {
+			add(item+".plan_duration", "out_of_range", "plan_duration must not be negative")
+		}
+		if result.StartedAt != nil && result.FinishedAt != nil && result.FinishedAt.Before(*result.StartedAt) {
+			add(item+".finished_at", "inconsistent", "finished_at is before started_at")
+		}
+		if result.Retries != nil && *result.Retries < 0 {
+			add(item+".retries", "out_of_range", "retries must not be negative")
+		}
+		// Counted the way the run's total_projects_drifted is recomputed on completion: a project that
+		// failed or was skipped has no drift to report, whatever its flag says.
+		if result.Drifted && result.Succeeded && !result.SkippedDueToPR {
+			drifted++
+		}
+		if !result.Succeeded {
+			errored++
+		}
+	}
+
+	type total struct {
+		field string
+		value int32
+	}
+	totals := []total{
+		{"total_projects", state.TotalProjects},
+		{"total_drifted", state.TotalDrifted},
+		{"total_skipped", state.TotalSkipped},
+		{"total_checked", state.TotalChecked},
+	}
+	if state.TotalErrored != nil {
+		totals = append(totals, total{"total_errored", *state.TotalErrored})
+	}
+	for _, total := range totals {
+		if total.value < 0 {
+			add(total.field, "out_of_range", "%s must not be negative", total.field)
+		} else if total.field != "total_projects" && total.value > state.TotalProjects {
+			add(total.field, "inconsistent", "%s is %d, more than total_projects (%d)", total.field, total.value, state.TotalProjects)
+		}
+	}
+	if state.Duration < 0 {
+		add("duration", "out_of_range", "duration must not be negative")
+	}
+	if state.CommitSHA != nil && !commitSHARegex.MatchString(*state.CommitSHA) {
+		add("commit_sha", "invalid_value", "commit_sha must be the full 40 or 64 character hex SHA of a commit")
+	}
+
+	count := int32(len(state.ProjectResults))
+	if count > state.TotalProjects {
+		add("total_projects", "inconsistent", "total_projects is %d but %d project results were sent", state.TotalProjects, count)
+	}
+	// When the results cover every project the totals are fully determined by them.
+	if count == state.TotalProjects {
+		if state.TotalDrifted != drifted {
+			add("total_drifted", "inconsistent", "total_drifted is %d but %d succeeded, unskipped project results drifted", state.TotalDrifted, drifted)
+		}
+		if state.TotalErrored != nil && *state.TotalErrored != errored {
+			add("total_errored", "inconsistent", "total_errored is %d but %d project results did not succeed", *state.TotalErrored, errored)
+		}
+	}
+
+	if state.Shard != nil {
+		if state.Shard.ID == "" {
+			add("shard.id", "required", "shard.id must not be empty")
+		}
+		if state.Shard.Count < 1 {
+			add("shard.count", "out_of_range", "shard.count must be at least 1")
+		}
+	}
+	return errs
+}
+
+// decodeFieldErrors describes a body that could not be decoded into the upload at all.
+func decodeFieldErrors(err error) []FieldError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return []FieldError{{Code: "required", Message: "request body is empty"}}
+	case errors.As(err, &syntaxErr):
+		return []FieldError{{Code: "invalid_json", Message: fmt.Sprintf("malformed JSON at offset %d: %v", syntaxErr.Offset, syntaxErr)}}
+	case errors.As(err, &typeErr):
+		return []FieldError{{Field: bracketIndices(typeErr.Field), Code: "invalid_type", Message: fmt.Sprintf("expected %s, got JSON %s", typeErr.Type, typeErr.Value)}}
+	default:
+		return []FieldError{{Code: "invalid_body", Message: err.Error()}}
+	}
+}
+
+// bracketIndices rewrites a decoder path such as project_results.3.project.dir into the
+// project_results[3].project.dir form used by the validation errors.
+func bracketIndices(path string) string {
+	var b strings.Builder
+	for i, part := range strings.Split(path, ".") {
+		if _, err := strconv.Atoi(part); err == nil && i > 0 {
+			b.WriteString("[" + part + "]")
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+func sendValidationErrors(c fiber.Ctx, status int, errs []FieldError) error {
+	return c.Status(status).JSON(fiber.Map{
+		"status":  "error",
+		"message": fmt.Sprintf("drift analysis rejected: %d invalid field(s)", len(errs)),
+		"data":    ValidationErrorData{Schema: schemaPath, Errors: errs},
+	})
+}
+
+// GetSchema serves the JSON Schema of the v2 drift analysis upload.
+func (d *DriftStateHandler) GetSchema(c fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "application/schema+json")
+	return c.Send(driftAnalysisSchema)
+}
+
+// isDecodeError reports whether err came from decoding the JSON itself, as opposed to the size
+// limits or an unsupported encoding, which keep their own status codes.
+func isDecodeError(err error) bool {
+	var limitErr *ingestLimitError
+	return !errors.As(err, &limitErr) && !errors.Is(err, errUnsupportedEncoding)
+}
+
+// commitSHA returns the lowercased commit SHA of an upload, or "" when none or an invalid one was
+// sent. Uploads other than v2 are not validated, so a malformed SHA is dropped rather than rejected.
+func commitSHA(sha *string) string {
+	if sha == nil || !commitSHARegex.MatchString(*sha) {
+		return ""
+	}
+	return strings.ToLower(*sha)
+}
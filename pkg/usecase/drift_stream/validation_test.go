@@ -0,0 +1,208 @@
+package drift_stream
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validState() DriftDetectionResult {
+	errored := int32(1)
+	return DriftDetectionResult{
+		ProjectResults: []DriftProjectResult{
+			{Project: TypedProject{Dir: "infra/a", Type: Terraform}, Drifted: true, Succeeded: true},
+			{Project: TypedProject{Dir: "infra/b", Type: Terragrunt}, Succeeded: false},
+		},
+		TotalProjects: 2,
+		TotalDrifted:  1,
+		TotalErrored:  &errored,
+		TotalChecked:  2,
+	}
+}
+
+func fieldCodes(errs []FieldError) map[string]string {
+	codes := make(map[string]string, len(errs))
+	for _, e := range errs {
+		codes[e.Field] = e.Code
+	}
+	return codes
+}
+
+func TestValidateDetectionResult_Valid(t *testing.T) {
+	if errs := validateDetectionResult(validState(), 10); len(errs) != 0 {
+		t.Errorf("expected no errors, got %+v", errs)
+	}
+
+	// Results covering part of the scan only bound the totals.
+	partial := validState()
+	partial.TotalProjects = 5
+	partial.TotalDrifted = 3
+	if errs := validateDetectionResult(partial, 10); len(errs) != 0 {
+		t.Errorf("expected no errors for partial results, got %+v", errs)
+	}
+}
+
+func TestValidateDetectionResult_ReportsEveryField(t *testing.T) {
+	state := validState()
+	state.ProjectResults = append(state.ProjectResults,
+		DriftProjectResult{Project: TypedProject{Dir: "infra/a", Type: Tofu}, Succeeded: true},
+		DriftProjectResult{Project: TypedProject{Dir: strings.Repeat("d", maxDirLength+1), Type: ProjectType(9)}, Succeeded: true},
+		DriftProjectResult{Project: TypedProject{Dir: "", Type: Terraform}, Succeeded: true},
+	)
+	state.TotalDrifted = 7
+	state.TotalSkipped = -1
+
+	got := fieldCodes(validateDetectionResult(state, 0))
+	want := map[string]string{
+		"project_results[2].project.dir":  "duplicate",
+		"project_results[3].project.dir":  "too_long",
+		"project_results[3].project.type": "invalid_value",
+		"project_results[4].project.dir":  "required",
+		"total_projects":                  "inconsistent",
+		"total_drifted":                   "inconsistent",
+		"total_skipped":                   "out_of_range",
+	}
+	for field, code := range want {
+		if got[field] != code {
+			t.Errorf("%s: code %q, want %q (all: %v)", field, got[field], code, got)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d fields, want %d: %v", len(got), len(want), got)
+	}
+}
+
+func TestValidateDetectionResult_TotalsMustMatchCompleteResults(t *testing.T) {
+	state := validState()
+	errored := int32(0)
+	state.TotalErrored = &errored
+	got := fieldCodes(validateDetectionResult(state, 0))
+	if got["total_errored"] != "inconsistent" || len(got) != 1 {
+		t.Errorf("expected only total_errored to be inconsistent, got %v", got)
+	}
+}
+
+func TestValidateDetectionResult_TotalDriftedIgnoresFailedAndSkipped(t *testing.T) {
+	state := validState()
+	state.ProjectResults[1].Drifted = true
+	state.ProjectResults = append(state.ProjectResults,
+		DriftProjectResult{Project: TypedProject{Dir: "infra/c", Type: Tofu}, Drifted: true, Succeeded: true, SkippedDueToPR: true},
+	)
+	state.TotalProjects = 3
+	state.TotalSkipped = 1
+	state.TotalChecked = 3
+	if errs := validateDetectionResult(state, 0); len(errs) != 0 {
+		t.Errorf("expected no errors, got %+v", errs)
+	}
+
+	state.TotalDrifted = 3
+	if got := fieldCodes(validateDetectionResult(state, 0)); got["total_drifted"] != "inconsistent" || len(got) != 1 {
+		t.Errorf("expected only total_drifted to be inconsistent, got %v", got)
+	}
+}
+
+func TestValidateDetectionResult_ProjectLimit(t *testing.T) {
+	if got := fieldCodes(validateDetectionResult(validState(), 1)); got["project_results"] != "too_many" {
+		t.Errorf("expected too_many, got %v", got)
+	}
+}
+
+func TestValidateDetectionResult_DirLengthCountsCharacters(t *testing.T) {
+	state := validState()
+	// 1500 characters but 3000 bytes, which fits the VARCHAR(1500) column.
+	state.ProjectResults[0].Project.Dir = strings.Repeat("é", maxDirLength)
+	if errs := validateDetectionResult(state, 0); len(errs) != 0 {
+		t.Errorf("expected no errors, got %+v", errs)
+	}
+}
+
+func TestValidateDetectionResult_Timing(t *testing.T) {
+	state := validState()
+	started := time.Date(2026, 10, 1, 2, 0, 0, 0, time.UTC)
+	finished := started.Add(-time.Minute)
+	negative, retries := -time.Second, int32(-1)
+	state.ProjectResults[0].StartedAt = &started
+	state.ProjectResults[0].FinishedAt = &finished
+	state.ProjectResults[0].PlanDuration = &negative
+	state.ProjectResults[1].Retries = &retries
+
+	got := fieldCodes(validateDetectionResult(state, 0))
+	want := map[string]string{
+		"project_results[0].finished_at":   "inconsistent",
+		"project_results[0].plan_duration": "out_of_range",
+		"project_results[1].retries":       "out_of_range",
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for field, code := range want {
+		if got[field] != code {
+			t.Errorf("%s: code %q, want %q", field, got[field], code)
+		}
+	}
+}
+
+func TestValidateDetectionResult_CommitSHA(t *testing.T) {
+	state := validState()
+	sha := strings.Repeat("A1", 20)
+	state.CommitSHA = &sha
+	if errs := validateDetectionResult(state, 0); len(errs) != 0 {
+		t.Errorf("expected no errors, got %+v", errs)
+	}
+	if got := commitSHA(&sha); got != strings.Repeat("a1", 20) {
+		t.Errorf("commitSHA = %q, want it lowercased", got)
+	}
+
+	short := "a1b2c3d"
+	state.CommitSHA = &short
+	if got := fieldCodes(validateDetectionResult(state, 0)); got["commit_sha"] != "invalid_value" || len(got) != 1 {
+		t.Errorf("expected commit_sha to be invalid, got %v", got)
+	}
+	if got := commitSHA(&short); got != "" {
+		t.Errorf("commitSHA of an abbreviated SHA = %q, want empty", got)
+	}
+}
+
+func TestDecodeFieldErrors(t *testing.T) {
+	var state DriftDetectionResult
+	err := json.Unmarshal([]byte(`{"project_results": [{"project": {"dir": 5}}]}`), &state)
+	errs := decodeFieldErrors(err)
+	if len(errs) != 1 || errs[0].Code != "invalid_type" || errs[0].Field != "project_results[0].project.dir" {
+		t.Errorf("unexpected errors %+v", errs)
+	}
+}
+
+// The published schema is hand-written; keep the limits it states in line with the checks.
+func TestDriftAnalysisSchema(t *testing.T) {
+	var schema struct {
+		Defs struct {
+			ProjectResult struct {
+				Properties struct {
+					Project struct {
+						Properties struct {
+							Dir struct {
+								MaxLength int `json:"maxLength"`
+							} `json:"dir"`
+							Type struct {
+								Enum []int `json:"enum"`
+							} `json:"type"`
+						} `json:"properties"`
+					} `json:"project"`
+				} `json:"properties"`
+			} `json:"project_result"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(driftAnalysisSchema, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	project := schema.Defs.ProjectResult.Properties.Project.Properties
+	if project.Dir.MaxLength != maxDirLength {
+		t.Errorf("schema dir maxLength = %d, want %d", project.Dir.MaxLength, maxDirLength)
+	}
+	for _, projectType := range project.Type.Enum {
+		if _, err := projectTypeToDBString(ProjectType(projectType)); err != nil {
+			t.Errorf("schema allows project type %d, which is rejected", projectType)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package drift_stream
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Core tools a project's version can belong to. Terragrunt wraps either of them.
+const (
+	CoreToolTerraform = "TERRAFORM"
+	CoreToolOpenTofu  = "OPENTOFU"
+)
+
+// semverPattern matches a version such as 1.6.0 or 1.7.0-beta1, without the leading "v". The
+// pre-release may contain dots but not end with one, so the "..." after a version is left out.
+const semverPattern = `\d+\.\d+\.\d+(?:-[0-9A-Za-z]+(?:\.[0-9A-Za-z]+)*)?`
+
+var (
+	// coreVersionRegex matches the core version as printed by "terraform version", the version
+	// constraint errors and the debug log, e.g. "Terraform v1.6.0" or "OpenTofu version: 1.6.2".
+	coreVersionRegex = regexp.MustCompile(`\b(Terraform|OpenTofu)(?: v| version:? v?)(` + semverPattern + `)\b`)
+	// providerVersionRegex matches the lines init prints per provider, e.g.
+	// "- Installing hashicorp/aws v5.31.0..." or "- Using previously-installed hashicorp/aws v5.31.0".
+	providerVersionRegex = regexp.MustCompile(`(?m)^[\s│]*- (?:Installing|Installed|Using previously-installed|Using) ([\w.-]+(?:/[\w.-]+){1,2}) v(` + semverPattern + `)`)
+	// validVersionRegex matches a minimum version given by a user.
+	validVersionRegex = regexp.MustCompile(`^v?\d+(?:\.\d+){0,2}(?:-[0-9A-Za-z]+(?:\.[0-9A-Za-z]+)*)?$`)
+)
+
+// ProviderVersion is one provider a project installed during init.
+type ProviderVersion struct {
+	Source  string `json:"source"`
+	Version string `json:"version"`
+}
+
+// VersionInventory holds the core and provider versions parsed from a project's init output. Nil or
+// empty fields mean the output doesn't say.
+type VersionInventory struct {
+	CoreTool    *string
+	CoreVersion *string
+	Providers   []ProviderVersion
+}
+
+// ParseVersions extracts the core and provider versions from the init output of a Terraform,
+// OpenTofu or Terragrunt project. Providers are sorted by source. Returns an empty inventory for
+// other tools.
+func ParseVersions(projectType ProjectType, initOutput string) VersionInventory {
+	if projectType != Terraform && projectType != Tofu && projectType != Terragrunt {
+		return VersionInventory{}
+	}
+	var inv VersionInventory
+	if m := coreVersionRegex.FindStringSubmatch(initOutput); m != nil {
+		tool := CoreToolTerraform
+		if m[1] == "OpenTofu" {
+			tool = CoreToolOpenTofu
+		}
+		inv.CoreTool, inv.CoreVersion = &tool, &m[2]
+	}
+	// "Installing" and "Installed" both name the same provider; keep one entry per source.
+	seen := map[string]bool{}
+	for _, m := range providerVersionRegex.FindAllStringSubmatch(initOutput, -1) {
+		if seen[m[1]] {
+			continue
+		}
+		seen[m[1]] = true
+		inv.Providers = append(inv.Providers, ProviderVersion{Source: m[1], Version: m[2]})
+	}
+	slices.SortFunc(inv.Providers, func(a, b ProviderVersion) int { return strings.Compare(a.Source, b.Source) })
+	return inv
+}
+
+// CompareVersions orders two versions by major, minor and patch, then a pre-release before its
+// release and pre-releases by their identifiers. A missing minor or patch counts as 0. Returns -1,
+// 0 or 1 like strings.Compare.
+func CompareVersions(a, b string) int {
+	aCore, aPre, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	bCore, bPre, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+	if c := compareIdentifiers(coreParts(aCore), coreParts(bCore)); c != 0 {
+		return c
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return compareIdentifiers(strings.Split(aPre, "."), strings.Split(bPre, "."))
+}
+
+// coreParts splits major.minor.patch, padding the parts left out with zeros.
+func coreParts(core string) []string {
+	parts := strings.Split(core, ".")
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+	return parts
+}
+
+// compareIdentifiers compares dot-separated identifiers pairwise, numerically when both are numbers
+// and lexically otherwise. A shorter list that is a prefix of the other sorts first.
+func compareIdentifiers(a, b []string) int {
+	for i := range min(len(a), len(b)) {
+		an, aErr := strconv.Atoi(a[i])
+		bn, bErr := strconv.Atoi(b[i])
+		var c int
+		if aErr == nil && bErr == nil {
+			c = an - bn
+		} else {
+			c = strings.Compare(a[i], b[i])
+		}
+		if c != 0 {
+			return max(-1, min(c, 1))
+		}
+	}
+	return max(-1, min(len(a)-len(b), 1))
+}
+
+// IsValidVersion reports whether v is a version CompareVersions understands. Minor and patch may be
+// left out, e.g. "1.6", and a leading "v" is accepted.
+func IsValidVersion(v string) bool {
+	return validVersionRegex.MatchString(v)
+}
@@ -0,0 +1,87 @@
+package drift_stream
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVersions(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	tests := []struct {
+		name        string
+		projectType ProjectType
+		output      string
+		want        VersionInventory
+	}{
+		{
+			name:        "terraform init",
+			projectType: Terraform,
+			output: "Terraform v1.6.6\non linux_amd64\n\n" +
+				"Initializing the backend...\n\nInitializing provider plugins...\n" +
+				"- Finding hashicorp/aws versions matching \"~> 5.0\"...\n" +
+				"- Installing hashicorp/random v3.6.0...\n" +
+				"- Installed hashicorp/random v3.6.0 (signed by HashiCorp)\n" +
+				"- Using previously-installed hashicorp/aws v5.31.0\n\n" +
+				"Terraform has been successfully initialized!\n",
+			want: VersionInventory{
+				CoreTool:    strPtr(CoreToolTerraform),
+				CoreVersion: strPtr("1.6.6"),
+				Providers: []ProviderVersion{
+					{Source: "hashicorp/aws", Version: "5.31.0"},
+					{Source: "hashicorp/random", Version: "3.6.0"},
+				},
+			},
+		},
+		{
+			name:        "terragrunt wrapping opentofu with a custom registry",
+			projectType: Terragrunt,
+			output: "OpenTofu v1.7.0-beta1\n" +
+				"- Installing registry.example.com/acme/internal v0.4.2...\n",
+			want: VersionInventory{
+				CoreTool:    strPtr(CoreToolOpenTofu),
+				CoreVersion: strPtr("1.7.0-beta1"),
+				Providers:   []ProviderVersion{{Source: "registry.example.com/acme/internal", Version: "0.4.2"}},
+			},
+		},
+		{
+			name:        "providers only",
+			projectType: Tofu,
+			output:      "│ - Using hashicorp/google v5.10.0 from the shared cache directory\n",
+			want:        VersionInventory{Providers: []ProviderVersion{{Source: "hashicorp/google", Version: "5.10.0"}}},
+		},
+		{
+			name:        "not terraform",
+			projectType: Pulumi,
+			output:      "Terraform v1.6.6\n",
+			want:        VersionInventory{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseVersions(tt.projectType, tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseVersions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.6.0", "1.6.0", 0},
+		{"1.5.7", "1.6.0", -1},
+		{"1.10.0", "1.9.9", 1},
+		{"v5.31.0", "5.4.0", 1},
+		{"1.6", "1.6.0", 0},
+		{"1.7.0-beta1", "1.7.0", -1},
+		{"1.7.0-beta2", "1.7.0-beta10", 1},
+		{"1.7.0-rc.2", "1.7.0-rc.10", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
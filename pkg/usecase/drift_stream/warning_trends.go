@@ -0,0 +1,53 @@
+package drift_stream
+
+import (
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/usecase/utils/auth"
+	"driftive.cloud/api/pkg/usecase/utils/parsing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+// maxRepeatedWarningResults caps the repeated warnings returned by the warning trends endpoint.
+const maxRepeatedWarningResults = 50
+
+// GetRepositoryWarningTrends returns the number of warnings per run within the days_back window
+// and the warnings projects keep printing, which are the ones left unaddressed.
+func (d *DriftStateHandler) GetRepositoryWarningTrends(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	isMember, err := d.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	daysBack := parseDaysBack(c)
+	perRun, err := d.driftAnalysisRepository.GetWarningCountsPerRun(c.Context(), repoId, daysBack)
+	if err != nil {
+		log.Errorf("Error getting warning counts per run: %v", err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	repeated, err := d.driftAnalysisRepository.GetRepeatedWarnings(c.Context(), repoId, daysBack, maxRepeatedWarningResults)
+	if err != nil {
+		log.Errorf("Error getting repeated warnings: %v", err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.JSON(dto.WarningTrendsDTO{
+		WarningsPerRun:   parsing.ToRunWarningCounts(perRun),
+		RepeatedWarnings: parsing.ToRepeatedWarnings(repeated),
+		DaysBack:         int(daysBack),
+	})
+}
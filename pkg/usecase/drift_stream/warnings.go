@@ -0,0 +1,150 @@
+package drift_stream
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Warning categories. The deprecations are the ones that turn into errors on a later provider or
+// core upgrade.
+const (
+	WarningDeprecatedAttribute = "DEPRECATED_ATTRIBUTE"
+	WarningDeprecatedResource  = "DEPRECATED_RESOURCE"
+	WarningDeprecatedProvider  = "DEPRECATED_PROVIDER"
+	WarningDeprecated          = "DEPRECATED"
+	WarningUndeclaredVariable  = "UNDECLARED_VARIABLE"
+	WarningOther               = "OTHER"
+)
+
+// maxWarningDetailLength caps the detail kept per warning; some providers print whole migration
+// guides.
+const maxWarningDetailLength = 500
+
+var (
+	// diagnosticPrefixRegex matches the box drawn around a diagnostic, "╷", "│" and "╵", and the
+	// single space after it.
+	diagnosticPrefixRegex = regexp.MustCompile(`^\s*[╷│╵] ?`)
+	// warningHeaderRegex matches the line opening a warning diagnostic, e.g. "Warning: Argument is
+	// deprecated".
+	warningHeaderRegex = regexp.MustCompile(`^Warning: (.+)$`)
+	// diagnosticEndRegex matches a line that ends the previous diagnostic when it isn't boxed: the
+	// next diagnostic or the next section of the init or plan output.
+	diagnosticEndRegex = regexp.MustCompile(`^(?:Warning|Error): |^(?:Terraform|OpenTofu) (?:will perform|used the selected|has been successfully)|^Initializing |^Plan: |^No changes\.|^Changes to Outputs:`)
+	// warningAddressRegex matches the "with <address>," line naming the resource a warning is about.
+	warningAddressRegex = regexp.MustCompile(`^\s*with ([^\s,]+),$`)
+	// warningSourceRegex matches the "on main.tf line 12" location and the quoted source lines below it.
+	warningSourceRegex = regexp.MustCompile(`^\s*on \S+ line \d+|^\s*\d+:`)
+	// warningMoreRegex matches the count Terraform prints instead of repeating a warning.
+	warningMoreRegex = regexp.MustCompile(`^\(and (\d+) more similar warnings? elsewhere\)$`)
+)
+
+// PlanWarning is one warning diagnostic printed by init or plan. Occurrences counts the similar
+// warnings Terraform folded into it.
+type PlanWarning struct {
+	Category    string `json:"category"`
+	Summary     string `json:"summary"`
+	Detail      string `json:"detail,omitempty"`
+	Address     string `json:"address,omitempty"`
+	Occurrences int32  `json:"occurrences"`
+}
+
+// ParseWarnings extracts the warning diagnostics from the init and plan output of a Terraform,
+// OpenTofu or Terragrunt project, init's first. The outputs are expected stripped of escape codes
+// (see ansi.Strip). Returns nil for other tools and for outputs without warnings.
+func ParseWarnings(projectType ProjectType, initOutput, planOutput string) []PlanWarning {
+	if projectType != Terraform && projectType != Tofu && projectType != Terragrunt {
+		return nil
+	}
+	return append(parseWarnings(initOutput), parseWarnings(planOutput)...)
+}
+
+func parseWarnings(output string) []PlanWarning {
+	var warnings []PlanWarning
+	var current *PlanWarning
+	var detail []string
+	boxed, blanks := false, 0
+	flush := func() {
+		if current != nil {
+			current.Detail = truncateDetail(strings.Join(detail, " "))
+			current.Category = warningCategory(current.Summary, current.Detail)
+			warnings = append(warnings, *current)
+		}
+		current, detail = nil, nil
+	}
+
+	for _, raw := range strings.Split(output, "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		prefix := diagnosticPrefixRegex.FindString(raw)
+		line := strings.TrimSpace(raw[len(prefix):])
+
+		if m := warningHeaderRegex.FindStringSubmatch(line); m != nil {
+			flush()
+			current = &PlanWarning{Summary: m[1], Occurrences: 1}
+			boxed, blanks = prefix != "", 0
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if boxed && (strings.Contains(prefix, "╵") || prefix == "") || !boxed && diagnosticEndRegex.MatchString(line) {
+			flush()
+			continue
+		}
+		if line == "" {
+			blanks++
+			if !boxed && blanks >= 2 {
+				flush()
+			}
+			continue
+		}
+		blanks = 0
+		switch m := warningAddressRegex.FindStringSubmatch(line); {
+		case m != nil:
+			current.Address = m[1]
+		case warningSourceRegex.MatchString(line):
+		case warningMoreRegex.MatchString(line):
+			n, _ := strconv.Atoi(warningMoreRegex.FindStringSubmatch(line)[1])
+			current.Occurrences += int32(n)
+		default:
+			detail = append(detail, line)
+		}
+	}
+	flush()
+	return warnings
+}
+
+// warningCategory sorts a warning by its summary, falling back to the detail for deprecations
+// the summary doesn't qualify, such as a provider deprecated in the registry.
+func warningCategory(summary, detail string) string {
+	s, d := strings.ToLower(summary), strings.ToLower(detail)
+	switch {
+	case strings.Contains(s, "undeclared variable"):
+		return WarningUndeclaredVariable
+	case !strings.Contains(s, "deprecat") && !strings.Contains(d, "deprecat"):
+		return WarningOther
+	case strings.Contains(s, "resource") || strings.Contains(s, "data source"):
+		return WarningDeprecatedResource
+	case strings.Contains(s, "provider"):
+		return WarningDeprecatedProvider
+	case strings.Contains(s, "argument") || strings.Contains(s, "attribute") || strings.Contains(s, "block"):
+		return WarningDeprecatedAttribute
+	case strings.Contains(d, "provider is deprecated") || strings.Contains(d, "provider has been deprecated"):
+		return WarningDeprecatedProvider
+	default:
+		return WarningDeprecated
+	}
+}
+
+func truncateDetail(detail string) string {
+	if len(detail) <= maxWarningDetailLength {
+		return detail
+	}
+	cut := maxWarningDetailLength
+	// Don't split a multi-byte character.
+	for cut > 0 && !utf8.RuneStart(detail[cut]) {
+		cut--
+	}
+	return detail[:cut] + "…"
+}
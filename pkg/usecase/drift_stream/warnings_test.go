@@ -0,0 +1,109 @@
+package drift_stream
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"driftive.cloud/api/pkg/ansi"
+)
+
+func TestParseWarnings(t *testing.T) {
+	tests := []struct {
+		name        string
+		projectType ProjectType
+		init, plan  string
+		want        []PlanWarning
+	}{
+		{
+			name:        "boxed deprecated argument folded with similar ones",
+			projectType: Terraform,
+			plan: "Plan: 0 to add, 1 to change, 0 to destroy.\n" +
+				"╷\n" +
+				"│ Warning: Argument is deprecated\n" +
+				"│ \n" +
+				"│   with aws_s3_bucket.logs,\n" +
+				"│   on main.tf line 12, in resource \"aws_s3_bucket\" \"logs\":\n" +
+				"│   12:   acl = \"private\"\n" +
+				"│ \n" +
+				"│ Use the aws_s3_bucket_acl resource instead\n" +
+				"│ \n" +
+				"│ (and 2 more similar warnings elsewhere)\n" +
+				"╵\n",
+			want: []PlanWarning{{
+				Category:    WarningDeprecatedAttribute,
+				Summary:     "Argument is deprecated",
+				Detail:      "Use the aws_s3_bucket_acl resource instead",
+				Address:     "aws_s3_bucket.logs",
+				Occurrences: 3,
+			}},
+		},
+		{
+			name:        "deprecated provider in init and undeclared variable in plan",
+			projectType: Tofu,
+			init: "Initializing provider plugins...\n" +
+				"╷\n" +
+				"│ Warning: Additional provider information from registry\n" +
+				"│ \n" +
+				"│ The remote registry returned warnings for registry.opentofu.org/hashicorp/template:\n" +
+				"│ - This provider is deprecated. Use the templatefile function instead.\n" +
+				"╵\n",
+			plan: "\x1b[33mWarning: \x1b[0mValue for undeclared variable\n\n" +
+				"The root module does not declare a variable named \"region\".\n\n\n" +
+				"No changes. Your infrastructure matches the configuration.\n",
+			want: []PlanWarning{
+				{
+					Category:    WarningDeprecatedProvider,
+					Summary:     "Additional provider information from registry",
+					Detail:      "The remote registry returned warnings for registry.opentofu.org/hashicorp/template: - This provider is deprecated. Use the templatefile function instead.",
+					Occurrences: 1,
+				},
+				{
+					Category:    WarningUndeclaredVariable,
+					Summary:     "Value for undeclared variable",
+					Detail:      "The root module does not declare a variable named \"region\".",
+					Occurrences: 1,
+				},
+			},
+		},
+		{
+			name:        "deprecated resource next to an unrelated warning",
+			projectType: Terragrunt,
+			plan: "│ Warning: Deprecated Resource\n" +
+				"│ \n" +
+				"│   with data.aws_subnet_ids.private,\n" +
+				"╵\n" +
+				"╷\n" +
+				"│ Warning: Resource targeting is in effect\n" +
+				"╵\n",
+			want: []PlanWarning{
+				{Category: WarningDeprecatedResource, Summary: "Deprecated Resource", Address: "data.aws_subnet_ids.private", Occurrences: 1},
+				{Category: WarningOther, Summary: "Resource targeting is in effect", Occurrences: 1},
+			},
+		},
+		{
+			name:        "no warnings",
+			projectType: Terraform,
+			plan:        "No changes. Your infrastructure matches the configuration.\n",
+		},
+		{
+			name:        "not terraform",
+			projectType: Pulumi,
+			plan:        "Warning: Argument is deprecated\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseWarnings(tt.projectType, ansi.Strip(tt.init), ansi.Strip(tt.plan)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseWarnings() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWarnings_TruncatesDetail(t *testing.T) {
+	got := ParseWarnings(Terraform, "", "│ Warning: Argument is deprecated\n│ \n│ "+strings.Repeat("é", maxWarningDetailLength)+"\n╵\n")
+	if len(got) != 1 || len(got[0].Detail) > maxWarningDetailLength+len("…") || !strings.HasSuffix(got[0].Detail, "é…") {
+		t.Errorf("detail not truncated on a character boundary: %+v", got)
+	}
+}
@@ -0,0 +1,81 @@
+package issues
+
+import (
+	"context"
+	"errors"
+
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/utils/gh"
+	"github.com/google/go-github/v88/github"
+)
+
+// GitHubTracker files issues through the GitHub App installation of the repository's organization.
+type GitHubTracker struct {
+	client *github.Client
+	owner  string
+	repo   string
+}
+
+// NewGitHubTracker is the TrackerFactory of GitHub repositories.
+func NewGitHubTracker(ctx context.Context, org queries.GitOrganization, repo queries.GitRepository) (Tracker, error) {
+	if org.InstallationID == nil {
+		return nil, errors.New("the GitHub App is not installed on the organization")
+	}
+	client, err := gh.NewAppGithubInstallationClient(ctx, *org.InstallationID)
+	if err != nil {
+		return nil, err
+	}
+	return &GitHubTracker{client: client, owner: org.Name, repo: repo.Name}, nil
+}
+
+// Find looks for the issue among the open issues labelled IssueLabel.
+func (t *GitHubTracker) Find(ctx context.Context, title string) (int, error) {
+	opts := &github.IssueListByRepoOptions{
+		State:       "open",
+		Labels:      []string{IssueLabel},
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		issues, resp, err := t.client.Issues.ListByRepo(ctx, t.owner, t.repo, opts)
+		if err != nil {
+			return 0, err
+		}
+		for _, issue := range issues {
+			if !issue.IsPullRequest() && issue.GetTitle() == title {
+				return issue.GetNumber(), nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return 0, nil
+		}
+		opts.ListOptions.Page = resp.NextPage
+	}
+}
+
+func (t *GitHubTracker) Open(ctx context.Context, title, body string) (int, error) {
+	issue, _, err := t.client.Issues.Create(ctx, t.owner, t.repo, &github.IssueRequest{
+		Title:  &title,
+		Body:   &body,
+		Labels: &[]string{IssueLabel},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return issue.GetNumber(), nil
+}
+
+func (t *GitHubTracker) Comment(ctx context.Context, number int, body string) error {
+	_, _, err := t.client.Issues.CreateComment(ctx, t.owner, t.repo, number, &github.IssueComment{Body: &body})
+	return err
+}
+
+func (t *GitHubTracker) Close(ctx context.Context, number int, body string) error {
+	if err := t.Comment(ctx, number, body); err != nil {
+		return err
+	}
+	_, _, err := t.client.Issues.Edit(ctx, t.owner, t.repo, number, &github.IssueRequest{
+		State:       github.Ptr("closed"),
+		StateReason: github.Ptr("completed"),
+	})
+	return err
+}
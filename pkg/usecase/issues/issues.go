@@ -0,0 +1,377 @@
+// Package issues keeps a GitHub issue open for each drifted project of the repositories that opt in:
+// opened when a project starts drifting, commented on when its drift changes and closed once a run
+// finds the project clean or no longer scans it.
+package issues
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"driftive.cloud/api/pkg/ansi"
+	"driftive.cloud/api/pkg/repository"
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/outputs"
+	"driftive.cloud/api/pkg/usecase/utils/parsing"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxExcerptLines and maxExcerptBytes bound the plan excerpt of each project, keeping an issue
+	// with a few projects in a dir well under GitHub's 65536 character body limit.
+	maxExcerptLines = 80
+	maxExcerptBytes = 12 * 1024
+
+	// planActionsMarker starts the part of a Terraform or OpenTofu plan listing the changes.
+	planActionsMarker = "will perform the following actions"
+
+	// IssueLabel is put on every drift issue, so the open ones can be found on GitHub.
+	IssueLabel = "drift"
+)
+
+// Tracker files the issues of one repository.
+type Tracker interface {
+	// Find returns the number of an open issue with the title, or 0 when there is none.
+	Find(ctx context.Context, title string) (int, error)
+	// Open creates an issue labelled IssueLabel and returns its number.
+	Open(ctx context.Context, title, body string) (int, error)
+	Comment(ctx context.Context, number int, body string) error
+	// Close comments on an issue and closes it as completed.
+	Close(ctx context.Context, number int, body string) error
+}
+
+// TrackerFactory returns the Tracker of a repository.
+type TrackerFactory func(ctx context.Context, org queries.GitOrganization, repo queries.GitRepository) (Tracker, error)
+
+type IssueService struct {
+	repo     repository.DriftAnalysisRepository
+	outputs  *outputs.OutputService
+	trackers TrackerFactory
+}
+
+func NewIssueService(repo repository.DriftAnalysisRepository, outputService *outputs.OutputService, trackers TrackerFactory) *IssueService {
+	return &IssueService{repo: repo, outputs: outputService, trackers: trackers}
+}
+
+// dirState is what a run says about the projects of a dir.
+type dirState struct {
+	drifted []queries.DriftAnalysisProject
+	// unknown is set when a project of the dir was skipped or failed, so the run cannot tell
+	// whether the drift was fixed.
+	unknown bool
+}
+
+// SyncRunIssues updates the drift issues of a repository from one of its completed runs:
+//   - a drifted dir without an open issue gets one, with the plan excerpt and resource counts;
+//   - a drifted dir with an open issue gets a comment, unless its drift is unchanged since the
+//     previous run;
+//   - a clean dir with an open issue gets it closed, and so does a dir the run no longer scans,
+//     unless the run misses shards that may have scanned it or a dispatch scoped it to some dirs.
+//
+// Only the latest completed run of the repository is synced, so a run ingested late cannot reopen
+// or close issues out of order, and each issue is updated once per run. Does nothing unless the
+// repository enabled drift issues. Errors of single dirs don't stop the others; they are joined.
+func (s *IssueService) SyncRunIssues(ctx context.Context, org queries.GitOrganization, repo queries.GitRepository, runId uuid.UUID, dashboardURL string) error {
+	if !repo.DriftIssuesEnabled {
+		return nil
+	}
+	latest, err := s.repo.GetLatestRunForRepository(ctx, repo.ID)
+	if err != nil {
+		return fmt.Errorf("finding the latest run: %w", err)
+	}
+	if latest.Uuid != runId {
+		return nil
+	}
+	projects, err := s.repo.FindDriftAnalysisProjectsByRunId(ctx, runId)
+	if err != nil {
+		return fmt.Errorf("listing the projects of run %s: %w", runId, err)
+	}
+	openIssues, err := s.repo.ListOpenDriftIssues(ctx, repo.ID)
+	if err != nil {
+		return fmt.Errorf("listing open drift issues: %w", err)
+	}
+	previous, err := s.previousFingerprints(ctx, runId)
+	if err != nil {
+		return fmt.Errorf("listing the drift of the previous run: %w", err)
+	}
+	partial, err := s.missesShards(ctx, latest)
+	if err != nil {
+		return fmt.Errorf("counting the shards of run %s: %w", runId, err)
+	}
+
+	states := map[string]*dirState{}
+	for _, p := range projects {
+		state := states[p.Dir]
+		if state == nil {
+			state = &dirState{}
+			states[p.Dir] = state
+		}
+		switch {
+		case p.SkippedDueToPr || !p.Succeeded:
+			state.unknown = true
+		case p.Drifted:
+			state.drifted = append(state.drifted, p)
+		}
+	}
+	issueByDir := make(map[string]queries.DriftIssue, len(openIssues))
+	for _, issue := range openIssues {
+		issueByDir[issue.Dir] = issue
+	}
+
+	dirs := make([]string, 0, len(states))
+	for dir := range states {
+		dirs = append(dirs, dir)
+	}
+	if len(projects) > 0 && !partial && latest.ScopedDirs == nil {
+		for dir := range issueByDir {
+			if _, scanned := states[dir]; !scanned {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	slices.Sort(dirs)
+
+	var tracker Tracker
+	var errs []error
+	for _, dir := range dirs {
+		state, scanned := states[dir]
+		issue, hasIssue := issueByDir[dir]
+		// An issue without a number is still being created by another sync, or was abandoned by
+		// one, in which case ClaimDriftIssue takes it over.
+		opened := hasIssue && issue.IssueNumber != nil
+		drifted := scanned && len(state.drifted) > 0
+		if !drifted && (!opened || (scanned && state.unknown)) {
+			continue
+		}
+		if tracker == nil {
+			if tracker, err = s.trackers(ctx, org, repo); err != nil {
+				return fmt.Errorf("creating the issue tracker: %w", err)
+			}
+		}
+
+		switch {
+		case !scanned:
+			err = s.updateIssue(ctx, tracker, issue, runId, true, dirUnscannedComment(dir, dashboardURL))
+		case !opened:
+			err = s.openIssue(ctx, tracker, repo, runId, dir, state.drifted, dashboardURL)
+		case drifted && driftUnchanged(state.drifted, previous):
+			// Repeating the same drift on every run is noise: the issue moves on without a comment.
+			err = s.updateIssue(ctx, tracker, issue, runId, false, "")
+		case drifted:
+			err = s.updateIssue(ctx, tracker, issue, runId, false, driftPersistsComment(state.drifted, dashboardURL))
+		default:
+			err = s.updateIssue(ctx, tracker, issue, runId, true, driftResolvedComment(dashboardURL))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("dir %s: %w", dir, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// previousFingerprints returns the drift fingerprints of the repository's last completed run
+// before runId, by project dir.
+func (s *IssueService) previousFingerprints(ctx context.Context, runId uuid.UUID) (map[string]string, error) {
+	rows, err := s.repo.GetPreviousRunDriftFingerprints(ctx, runId)
+	if err != nil {
+		return nil, err
+	}
+	fingerprints := make(map[string]string, len(rows))
+	for _, row := range rows {
+		if row.DriftFingerprint != nil {
+			fingerprints[row.Dir] = *row.DriftFingerprint
+		}
+	}
+	return fingerprints, nil
+}
+
+// driftUnchanged reports whether every drifted project of a dir has the same drift as in the
+// previous run. Projects without a fingerprint never count as unchanged.
+func driftUnchanged(drifted []queries.DriftAnalysisProject, previous map[string]string) bool {
+	for _, p := range drifted {
+		if p.DriftFingerprint == nil || previous[p.Dir] != *p.DriftFingerprint {
+			return false
+		}
+	}
+	return len(drifted) > 0
+}
+
+// missesShards reports whether a sharded run was completed by the shard finalizer without the
+// results of some of its shards.
+func (s *IssueService) missesShards(ctx context.Context, run queries.DriftAnalysisRun) (bool, error) {
+	if run.ExpectedShards == nil {
+		return false, nil
+	}
+	reported, err := s.repo.CountDriftAnalysisRunShards(ctx, run.Uuid)
+	if err != nil {
+		return false, err
+	}
+	return reported < int64(*run.ExpectedShards), nil
+}
+
+func (s *IssueService) openIssue(ctx context.Context, tracker Tracker, repo queries.GitRepository, runId uuid.UUID, dir string, drifted []queries.DriftAnalysisProject, dashboardURL string) error {
+	claim, err := s.repo.ClaimDriftIssue(ctx, repo.ID, dir, runId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// A concurrent sync of the run opened it.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("claiming the issue: %w", err)
+	}
+
+	number, err := s.findOrOpenIssue(ctx, tracker, repo, dir, drifted, dashboardURL)
+	if err != nil {
+		if deleteErr := s.repo.DeleteDriftIssue(ctx, claim.ID); deleteErr != nil {
+			return errors.Join(err, fmt.Errorf("releasing the claim: %w", deleteErr))
+		}
+		return err
+	}
+	if err := s.repo.SetDriftIssueNumber(ctx, claim.ID, int32(number)); err != nil {
+		return fmt.Errorf("recording issue #%d: %w", number, err)
+	}
+	return nil
+}
+
+// findOrOpenIssue returns the number of the open issue of a dir, commenting on it when one was
+// left open on GitHub without being tracked, e.g. after the repository data was erased. Otherwise
+// it opens one.
+func (s *IssueService) findOrOpenIssue(ctx context.Context, tracker Tracker, repo queries.GitRepository, dir string, drifted []queries.DriftAnalysisProject, dashboardURL string) (int, error) {
+	title := issueTitle(dir)
+	number, err := tracker.Find(ctx, title)
+	if err != nil {
+		return 0, fmt.Errorf("finding an open issue: %w", err)
+	}
+	if number != 0 {
+		if err := tracker.Comment(ctx, number, driftPersistsComment(drifted, dashboardURL)); err != nil {
+			return 0, fmt.Errorf("commenting on issue #%d: %w", number, err)
+		}
+		return number, nil
+	}
+
+	// Plans may hold sensitive values, so the issues of public repositories only link to the run.
+	if repo.IsPrivate {
+		if err := s.outputs.Hydrate(ctx, drifted); err != nil {
+			// The issue still links to the run, only the plan excerpt is missing.
+			log.Warnf("Error loading the plan outputs of %s in repository %d: %v", dir, repo.ID, err)
+		}
+	}
+	number, err = tracker.Open(ctx, title, issueBody(repo, dir, drifted, dashboardURL))
+	if err != nil {
+		return 0, fmt.Errorf("opening the issue: %w", err)
+	}
+	return number, nil
+}
+
+// updateIssue comments on an open issue, closing it when resolve is set, unless it was already
+// updated from the run. An empty comment only moves the issue on to the run.
+func (s *IssueService) updateIssue(ctx context.Context, tracker Tracker, issue queries.DriftIssue, runId uuid.UUID, resolve bool, comment string) error {
+	advanced, err := s.repo.AdvanceDriftIssue(ctx, issue.ID, runId)
+	if err != nil {
+		return fmt.Errorf("updating issue #%d: %w", *issue.IssueNumber, err)
+	}
+	if !advanced {
+		return nil
+	}
+	number := int(*issue.IssueNumber)
+	if !resolve {
+		if comment == "" {
+			return nil
+		}
+		if err := tracker.Comment(ctx, number, comment); err != nil {
+			return fmt.Errorf("commenting on issue #%d: %w", number, err)
+		}
+		return nil
+	}
+	if err := tracker.Close(ctx, number, comment); err != nil {
+		return fmt.Errorf("closing issue #%d: %w", number, err)
+	}
+	if err := s.repo.CloseDriftIssue(ctx, issue.ID); err != nil {
+		return fmt.Errorf("recording issue #%d closed: %w", number, err)
+	}
+	return nil
+}
+
+func displayDir(dir string) string {
+	if d := strings.Trim(strings.TrimPrefix(dir, "./"), "/"); d != "" && d != "." {
+		return d
+	}
+	return "the repository root"
+}
+
+func issueTitle(dir string) string {
+	return fmt.Sprintf("Drift detected in %s", displayDir(dir))
+}
+
+func issueBody(repo queries.GitRepository, dir string, drifted []queries.DriftAnalysisProject, dashboardURL string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Driftive detected infrastructure drift in `%s` of %s.\n\n", displayDir(dir), repo.Name)
+	fmt.Fprintf(&sb, "[View the run in the dashboard](%s)\n", dashboardURL)
+	for _, p := range drifted {
+		fmt.Fprintf(&sb, "\n### %s\n\n", p.Type)
+		if counts := parsing.FormatResourceCounts(p); counts != "" {
+			fmt.Fprintf(&sb, "%s\n\n", counts)
+		}
+		if repo.IsPrivate && p.PlanOutput != nil && *p.PlanOutput != "" {
+			sb.WriteString(codeBlock(planExcerpt(*p.PlanOutput)))
+		}
+	}
+	sb.WriteString("\nThis issue is updated by every run and closed once the drift is resolved.\n")
+	return sb.String()
+}
+
+func driftPersistsComment(drifted []queries.DriftAnalysisProject, dashboardURL string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Drift persists in the [latest run](%s).\n", dashboardURL)
+	for _, p := range drifted {
+		if counts := parsing.FormatResourceCounts(p); counts != "" {
+			fmt.Fprintf(&sb, "\n- %s: %s", p.Type, counts)
+		}
+	}
+	return sb.String()
+}
+
+func driftResolvedComment(dashboardURL string) string {
+	return fmt.Sprintf("The [latest run](%s) found no drift. Closing.", dashboardURL)
+}
+
+func dirUnscannedComment(dir string, dashboardURL string) string {
+	return fmt.Sprintf("The [latest run](%s) no longer scans %s. Closing.", dashboardURL, displayDir(dir))
+}
+
+// planExcerpt returns the changes of a plan without escape sequences: the output from the line
+// announcing the actions when there is one, cut to maxExcerptLines and maxExcerptBytes.
+func planExcerpt(plan string) string {
+	plan = ansi.Strip(plan)
+	if i := strings.Index(plan, planActionsMarker); i >= 0 {
+		plan = plan[strings.LastIndex(plan[:i], "\n")+1:]
+	}
+	lines := strings.Split(strings.TrimRight(plan, "\n"), "\n")
+	size := 0
+	for i, line := range lines {
+		size += len(line) + 1
+		if i == maxExcerptLines || size > maxExcerptBytes {
+			return strings.Join(lines[:i], "\n") + fmt.Sprintf("\n... %d more lines in the dashboard", len(lines)-i)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// codeBlock fences s with more backticks than any run of them in s, so a plan containing a fence
+// cannot end the block early.
+func codeBlock(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + "\n" + s + "\n" + fence + "\n"
+}
@@ -0,0 +1,64 @@
+package issues
+
+import (
+	"strings"
+	"testing"
+
+	"driftive.cloud/api/pkg/repository/queries"
+)
+
+func TestPlanExcerpt(t *testing.T) {
+	plan := "\x1b[0m\x1b[1maws_s3_bucket.logs: Refreshing state...\x1b[0m\n\n" +
+		"Terraform will perform the following actions:\n\n" +
+		"  \x1b[33m~\x1b[0m resource \"aws_s3_bucket\" \"logs\" {\n" +
+		"Plan: 0 to add, 1 to change, 0 to destroy.\n"
+	want := "Terraform will perform the following actions:\n\n" +
+		"  ~ resource \"aws_s3_bucket\" \"logs\" {\n" +
+		"Plan: 0 to add, 1 to change, 0 to destroy."
+	if got := planExcerpt(plan); got != want {
+		t.Errorf("planExcerpt =\n%s\nwant\n%s", got, want)
+	}
+
+	long := strings.Repeat("  + attribute = true\n", maxExcerptLines+20)
+	got := planExcerpt(long)
+	if lines := strings.Split(got, "\n"); len(lines) != maxExcerptLines+1 || lines[maxExcerptLines] != "... 20 more lines in the dashboard" {
+		t.Errorf("planExcerpt of a long plan ends with %q after %d lines", lines[len(lines)-1], len(lines))
+	}
+}
+
+func TestCodeBlock(t *testing.T) {
+	if got := codeBlock("plan"); got != "```\nplan\n```\n" {
+		t.Errorf("codeBlock = %q", got)
+	}
+	// A heredoc holding a markdown fence must not end the block.
+	if got := codeBlock("x = <<EOT\n````\nEOT"); !strings.HasPrefix(got, "`````\n") || !strings.HasSuffix(got, "\n`````\n") {
+		t.Errorf("codeBlock = %q, want a fence of 5 backticks", got)
+	}
+}
+
+func TestDriftUnchanged(t *testing.T) {
+	a, b := "fp-a", "fp-b"
+	previous := map[string]string{"/envs/prod": a}
+	cases := []struct {
+		name    string
+		drifted []queries.DriftAnalysisProject
+		want    bool
+	}{
+		{name: "same", drifted: []queries.DriftAnalysisProject{{Dir: "/envs/prod", DriftFingerprint: &a}}, want: true},
+		{name: "changed", drifted: []queries.DriftAnalysisProject{{Dir: "/envs/prod", DriftFingerprint: &b}}},
+		{name: "new", drifted: []queries.DriftAnalysisProject{{Dir: "/envs/dev", DriftFingerprint: &a}}},
+		{name: "no fingerprint", drifted: []queries.DriftAnalysisProject{{Dir: "/envs/prod"}}},
+		{name: "one of two changed", drifted: []queries.DriftAnalysisProject{
+			{Dir: "/envs/prod", DriftFingerprint: &a},
+			{Dir: "/envs/prod", DriftFingerprint: &b},
+		}},
+		{name: "none"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := driftUnchanged(tc.drifted, previous); got != tc.want {
+				t.Errorf("driftUnchanged = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
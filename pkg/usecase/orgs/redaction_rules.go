@@ -0,0 +1,134 @@
+package orgs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/redact"
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/utils/auth"
+	"driftive.cloud/api/pkg/usecase/utils/parsing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// maxRedactionRules caps the custom rules per organization. Every rule is another pass over every
+// output ingested for the organization.
+const maxRedactionRules = 50
+
+// pgUniqueViolation is the SQLSTATE code for unique_violation.
+const pgUniqueViolation = "23505"
+
+func sendRuleError(c fiber.Ctx, status int, message string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"status":  "error",
+		"message": message,
+		"data":    nil,
+	})
+}
+
+// checkRedactionAdmin returns the status to reject a change to the organization's redaction rules
+// with, or 0 when the caller is one of its admins. The rules decide what every ingest keeps of
+// its outputs, so members may only list them.
+func (h *GitOrganizationHandler) checkRedactionAdmin(c fiber.Ctx, orgId int64) int {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return fiber.StatusUnauthorized
+	}
+	if err := auth.MustHavePermission(c, orgId); err != nil {
+		return fiber.StatusUnauthorized
+	}
+	isAdmin, err := h.gitOrgRepository.IsUserAdminOfOrg(c.Context(), orgId, *userId)
+	if err != nil {
+		log.Errorf("Error checking the role of user %d in organization %d: %v", *userId, orgId, err)
+		return fiber.StatusInternalServerError
+	}
+	if !isAdmin {
+		return fiber.StatusForbidden
+	}
+	return 0
+}
+
+// ListRedactionRules returns the built-in detectors enabled on this instance and the
+// organization's custom rules.
+func (h *GitOrganizationHandler) ListRedactionRules(c fiber.Ctx) error {
+	orgId := parsing.StringToInt64(c.Params("org_id"))
+	if err := auth.MustHavePermission(c, orgId); err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	rules, err := h.gitOrgRepository.FindOrganizationRedactionRules(c.Context(), orgId)
+	if err != nil {
+		log.Errorf("Error fetching redaction rules of organization %d: %v", orgId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	builtins := []string{}
+	if h.cfg.Redaction.Enabled {
+		for _, d := range redact.Builtins(h.cfg.Redaction.DisabledDetectors...) {
+			builtins = append(builtins, d.Name)
+		}
+	}
+	return c.JSON(parsing.ToRedactionRulesDTO(builtins, rules))
+}
+
+// CreateRedactionRule adds a custom detector. The pattern is compiled here so a broken rule is
+// rejected up front instead of being skipped on every ingest.
+func (h *GitOrganizationHandler) CreateRedactionRule(c fiber.Ctx) error {
+	orgId := parsing.StringToInt64(c.Params("org_id"))
+	if status := h.checkRedactionAdmin(c, orgId); status != 0 {
+		return c.SendStatus(status)
+	}
+
+	var req dto.CreateRedactionRuleRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return sendRuleError(c, fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if redact.IsBuiltin(req.Name) {
+		return sendRuleError(c, fiber.StatusBadRequest, fmt.Sprintf("name %q is taken by a built-in detector", req.Name))
+	}
+	if _, err := redact.NewDetector(req.Name, req.Pattern); err != nil {
+		return sendRuleError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	rule, err := h.gitOrgRepository.CreateOrganizationRedactionRule(c.Context(), queries.CreateOrganizationRedactionRuleParams{
+		OrganizationID: orgId,
+		Name:           req.Name,
+		Pattern:        req.Pattern,
+		MaxRules:       maxRedactionRules,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sendRuleError(c, fiber.StatusUnprocessableEntity, fmt.Sprintf("organizations are limited to %d redaction rules", maxRedactionRules))
+	}
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return sendRuleError(c, fiber.StatusConflict, fmt.Sprintf("a rule named %q already exists", req.Name))
+		}
+		log.Errorf("Error creating redaction rule for organization %d: %v", orgId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return c.Status(fiber.StatusCreated).JSON(parsing.ToRedactionRuleDTO(rule))
+}
+
+// DeleteRedactionRule removes a custom rule. Outputs already stored stay as they were redacted.
+func (h *GitOrganizationHandler) DeleteRedactionRule(c fiber.Ctx) error {
+	orgId := parsing.StringToInt64(c.Params("org_id"))
+	if status := h.checkRedactionAdmin(c, orgId); status != 0 {
+		return c.SendStatus(status)
+	}
+
+	deleted, err := h.gitOrgRepository.DeleteOrganizationRedactionRule(c.Context(), orgId, parsing.StringToInt64(c.Params("rule_id")))
+	if err != nil {
+		log.Errorf("Error deleting redaction rule of organization %d: %v", orgId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !deleted {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
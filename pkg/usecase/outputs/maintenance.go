@@ -0,0 +1,185 @@
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+const (
+	collectInterval = 10 * time.Minute
+	collectBatch    = 1000
+	// unreferencedGraceMinutes keeps an output whose last project was just deleted, so a run
+	// that is re-sent right after retention removed it does not store its outputs all over again.
+	unreferencedGraceMinutes = 60
+
+	legacyMigrationInterval = time.Minute
+	legacyMigrationBatch    = 100
+)
+
+// StartGarbageCollector deletes outputs no project references any more. Their blobs, if any, are
+// queued for the output blob reaper by a trigger. Safe to run on every API instance at once:
+// DeleteUnreferencedCommandOutputs claims rows with FOR UPDATE SKIP LOCKED.
+func (s *OutputService) StartGarbageCollector(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("command output collector shutting down...")
+			return
+		case <-time.After(collectInterval):
+		}
+
+		for {
+			deleted, err := s.repo.DeleteUnreferencedCommandOutputs(ctx, unreferencedGraceMinutes, collectBatch)
+			if err != nil {
+				log.Errorf("error collecting unreferenced command outputs: %v", err)
+				break
+			}
+			if deleted > 0 {
+				log.Infof("deleted %d unreferenced command output(s)", deleted)
+			}
+			if deleted < collectBatch {
+				break
+			}
+		}
+	}
+}
+
+// StartLegacyMigration moves outputs written before command_output existed, inline or behind a
+// per-project blob reference, into command_output a batch at a time. Once every row is migrated
+// each pass is a lookup on an empty partial index.
+func (s *OutputService) StartLegacyMigration(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("legacy output migration shutting down...")
+			return
+		case <-time.After(legacyMigrationInterval):
+		}
+
+		for {
+			migrated, err := s.MigrateLegacyOutputs(ctx, legacyMigrationBatch)
+			if err != nil {
+				log.Errorf("error migrating legacy outputs: %v", err)
+				break
+			}
+			if migrated > 0 {
+				log.Infof("migrated the outputs of %d project(s) to command_output", migrated)
+			}
+			if migrated < legacyMigrationBatch || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+}
+
+// MigrateLegacyOutputs migrates up to maxRows project rows and returns how many it migrated. The
+// legacy blobs are read and the new ones written before the transaction opens, so it is never held
+// across blob store requests; each batch then commits as a whole, and a failure leaves it to be
+// retried on the next pass. Rows whose outputs sit in the blob store are left alone while no blob
+// store is configured.
+func (s *OutputService) MigrateLegacyOutputs(ctx context.Context, maxRows int32) (int, error) {
+	// Outside a transaction the rows are not locked, so another instance may migrate them too; it
+	// stores the same content under the same hashes, and the second update writes the same values.
+	rows, err := s.repo.ClaimLegacyOutputProjects(ctx, s.blobs != nil, maxRows)
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+
+	projects := make([]legacyProject, len(rows))
+	prepared := make(map[int64][]Output)
+	for i, row := range rows {
+		p := legacyProject{id: row.ID, repoID: row.RepositoryID}
+		if p.init, err = s.loadLegacyOutput(ctx, row.InitOutput, row.InitOutputRef); err != nil {
+			return 0, fmt.Errorf("project %d init output: %w", row.ID, err)
+		}
+		if p.plan, err = s.loadLegacyOutput(ctx, row.PlanOutput, row.PlanOutputRef); err != nil {
+			return 0, fmt.Errorf("project %d plan output: %w", row.ID, err)
+		}
+		for _, output := range []*legacyOutput{&p.init, &p.plan} {
+			if output.content != nil {
+				output.index = len(prepared[p.repoID])
+				prepared[p.repoID] = append(prepared[p.repoID], Prepare(*output.content))
+			}
+		}
+		projects[i] = p
+	}
+	for repoID, outputs := range prepared {
+		if err := s.Upload(ctx, repoID, outputs); err != nil {
+			return 0, err
+		}
+	}
+
+	err = s.repo.WithTx(ctx, func(ctx context.Context) error {
+		ids := make(map[int64][]int64, len(prepared))
+		for repoID, outputs := range prepared {
+			if ids[repoID], err = s.Save(ctx, repoID, outputs); err != nil {
+				return err
+			}
+		}
+		for _, p := range projects {
+			params := queries.SetDriftAnalysisProjectOutputsParams{ID: p.id}
+			params.InitOutput, params.InitOutputRef, params.InitOutputID = p.init.values(ids[p.repoID])
+			params.PlanOutput, params.PlanOutputRef, params.PlanOutputID = p.plan.values(ids[p.repoID])
+			// Clearing a reference queues its per-project blob for deletion.
+			if err := s.repo.SetDriftAnalysisProjectOutputs(ctx, params); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for repoID, outputs := range prepared {
+			if discardErr := s.Discard(ctx, repoID, outputs); discardErr != nil {
+				log.Warnf("error discarding migrated outputs of repository %d: %v", repoID, discardErr)
+			}
+		}
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// legacyProject is a project row whose outputs are being migrated.
+type legacyProject struct {
+	id, repoID int64
+	init, plan legacyOutput
+}
+
+// legacyOutput is one output of a legacy row: either content to store in command_output, or the
+// inline value and blob reference it keeps.
+type legacyOutput struct {
+	content     *string
+	index       int
+	inline, ref *string
+}
+
+// values returns the new inline value, blob reference and output id of the output, given the ids
+// its repository's prepared outputs were saved under.
+func (o legacyOutput) values(ids []int64) (*string, *string, *int64) {
+	if o.content == nil {
+		return o.inline, o.ref, nil
+	}
+	return nil, nil, &ids[o.index]
+}
+
+// loadLegacyOutput reads one output of a legacy row. Empty outputs stay inline; they cost nothing
+// and are sent by every skipped project.
+func (s *OutputService) loadLegacyOutput(ctx context.Context, inline, ref *string) (legacyOutput, error) {
+	content := inline
+	if ref != nil {
+		if s.blobs == nil {
+			return legacyOutput{inline: inline, ref: ref}, nil
+		}
+		var err error
+		if content, err = s.fetchLegacy(ctx, *ref); err != nil {
+			return legacyOutput{}, err
+		}
+	}
+	if content == nil || *content == "" {
+		return legacyOutput{inline: content}, nil
+	}
+	return legacyOutput{content: content}, nil
+}
@@ -0,0 +1,357 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"driftive.cloud/api/pkg/blobstore"
+	"driftive.cloud/api/pkg/repository"
+	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/gofiber/fiber/v3/log"
+	"github.com/jackc/pgx/v5"
+	"github.com/klauspost/compress/zstd"
+)
+
+// blobConcurrency bounds the blob store requests a single run view has in flight.
+const blobConcurrency = 8
+
+// The encoder and decoder are safe for concurrent EncodeAll and DecodeAll calls.
+var (
+	encoder, _ = zstd.NewWriter(nil)
+	decoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+)
+
+// Output is an init or plan output ready to be stored: hashed and zstd-compressed.
+type Output struct {
+	Hash       [sha256.Size]byte
+	Size       int64
+	Compressed []byte
+	// uploaded is set by Upload once the output's blob is written.
+	uploaded bool
+}
+
+// Prepare hashes and compresses an output. It does the CPU work of storing an output, so callers
+// run it before opening the transaction that calls Save.
+func Prepare(content string) Output {
+	return Output{
+		Hash:       sha256.Sum256([]byte(content)),
+		Size:       int64(len(content)),
+		Compressed: encoder.EncodeAll([]byte(content), nil),
+	}
+}
+
+// OutputService stores init and plan outputs once per repository and content hash in
+// command_output. Project rows reference them by id and a trigger keeps the reference counts, so
+// an output repeated by every run costs one row.
+type OutputService struct {
+	repo repository.DriftAnalysisRepository
+	// blobs holds the compressed outputs when configured; nil keeps them in Postgres.
+	blobs blobstore.BlobStore
+}
+
+func NewOutputService(repo repository.DriftAnalysisRepository, blobs blobstore.BlobStore) *OutputService {
+	return &OutputService{repo: repo, blobs: blobs}
+}
+
+// outputKey names the blob of an output. Keys are derived from the content, so an output that is
+// collected and later sent again gets the same key; Upload handles that by taking the key off the
+// deletion queue before writing the blob.
+func outputKey(repoID int64, hash [sha256.Size]byte) string {
+	return fmt.Sprintf("outputs/%d/%s.zst", repoID, hex.EncodeToString(hash[:]))
+}
+
+// Upload writes the blobs of the outputs the repository does not hold yet. It runs before the
+// transaction that calls Save, so no transaction is held open across blob store requests; if that
+// transaction fails, Discard queues the blobs for deletion. Without a blob store it does nothing.
+func (s *OutputService) Upload(ctx context.Context, repoID int64, outputs []Output) error {
+	if s.blobs == nil || len(outputs) == 0 {
+		return nil
+	}
+	hashes := make([][]byte, len(outputs))
+	for i := range outputs {
+		hashes[i] = outputs[i].Hash[:]
+	}
+	held, err := s.repo.FindCommandOutputHashes(ctx, repoID, hashes)
+	if err != nil {
+		return fmt.Errorf("looking up command outputs: %w", err)
+	}
+	skip := make(map[[sha256.Size]byte]bool, len(outputs))
+	for _, hash := range held {
+		skip[[sha256.Size]byte(hash)] = true
+	}
+
+	// The first output of each missing hash carries the blob; the others share its key.
+	var missing []int
+	for i, output := range outputs {
+		if !skip[output.Hash] {
+			skip[output.Hash] = true
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	keys := make([]string, len(missing))
+	for k, i := range missing {
+		keys[k] = outputKey(repoID, outputs[i].Hash)
+	}
+
+	// A collected copy of an output may still be queued for deletion under the same key. Dequeuing
+	// waits for a reaper holding the entry, so the blob is written after any delete of it, never
+	// before.
+	if err := s.repo.DeleteOutputBlobDeletions(ctx, keys); err != nil {
+		return fmt.Errorf("dequeuing output blob deletions: %w", err)
+	}
+	if err := forEachConcurrently(len(missing), func(k int) error {
+		if err := s.blobs.Put(ctx, keys[k], outputs[missing[k]].Compressed); err != nil {
+			return fmt.Errorf("storing output blob %s: %w", keys[k], err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	uploaded := make(map[[sha256.Size]byte]bool, len(missing))
+	for _, i := range missing {
+		uploaded[outputs[i].Hash] = true
+	}
+	for i := range outputs {
+		outputs[i].uploaded = uploaded[outputs[i].Hash]
+	}
+	return nil
+}
+
+// Discard queues the blobs Upload wrote for deletion, once the transaction that was to reference
+// them has rolled back. Blobs an output committed by a concurrent writer references are kept.
+func (s *OutputService) Discard(ctx context.Context, repoID int64, outputs []Output) error {
+	var keys []string
+	var hashes [][]byte
+	seen := make(map[[sha256.Size]byte]bool, len(outputs))
+	for _, output := range outputs {
+		if output.uploaded && !seen[output.Hash] {
+			seen[output.Hash] = true
+			keys = append(keys, outputKey(repoID, output.Hash))
+			hashes = append(hashes, output.Hash[:])
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := s.repo.EnqueueUnreferencedOutputBlobDeletions(ctx, repoID, keys, hashes); err != nil {
+		return fmt.Errorf("queuing output blob deletions: %w", err)
+	}
+	return nil
+}
+
+// Save stores outputs for a repository, skipping those it already holds, and returns their ids in
+// the order given. Must run inside WithTx, in the transaction that writes the project rows
+// referencing them: the returned rows stay locked until it ends, which keeps the garbage collector
+// off them. Outputs are locked in hash order so concurrent writers cannot deadlock each other.
+// With a blob store, outputs must have gone through Upload first.
+func (s *OutputService) Save(ctx context.Context, repoID int64, outputs []Output) ([]int64, error) {
+	order := make([]int, len(outputs))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return bytes.Compare(outputs[a].Hash[:], outputs[b].Hash[:])
+	})
+
+	ids := make([]int64, len(outputs))
+	for k, i := range order {
+		if k > 0 && outputs[order[k-1]].Hash == outputs[i].Hash {
+			ids[i] = ids[order[k-1]]
+			continue
+		}
+		id, err := s.save(ctx, repoID, outputs[i])
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func (s *OutputService) save(ctx context.Context, repoID int64, output Output) (int64, error) {
+	// The usual case is an output the repository already holds. Otherwise insert it; losing the
+	// insert to a concurrent writer, or finding the row just collected, means looking again.
+	for attempt := 0; attempt < 3; attempt++ {
+		id, err := s.repo.FindCommandOutputId(ctx, repoID, output.Hash[:])
+		if err == nil {
+			return id, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("looking up command output: %w", err)
+		}
+
+		params := queries.InsertCommandOutputParams{
+			RepositoryID:   repoID,
+			Hash:           output.Hash[:],
+			Size:           output.Size,
+			CompressedSize: int64(len(output.Compressed)),
+		}
+		if s.blobs == nil {
+			params.Data = output.Compressed
+		} else {
+			// Upload skipped the blob because the repository held the output, which the garbage
+			// collector has deleted since. Writing the blob here would hold the transaction open
+			// across the blob store, so the caller retries instead.
+			if !output.uploaded {
+				return 0, fmt.Errorf("command output %x was collected while being stored", output.Hash[:8])
+			}
+			key := outputKey(repoID, output.Hash)
+			params.BlobRef = &key
+		}
+		id, err = s.repo.InsertCommandOutput(ctx, params)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("inserting command output: %w", err)
+		}
+
+		if params.BlobRef != nil {
+			// A concurrent writer whose transaction rolled back may have queued the blob since
+			// Upload dequeued it.
+			if err := s.repo.DeleteOutputBlobDeletions(ctx, []string{*params.BlobRef}); err != nil {
+				return 0, fmt.Errorf("dequeuing output blob deletion: %w", err)
+			}
+		}
+		return id, nil
+	}
+	return 0, fmt.Errorf("command output for repository %d kept changing under a concurrent writer", repoID)
+}
+
+// Hydrate fills in the init and plan outputs of projects from command_output, or from the
+// per-project blob references of rows not yet migrated. An output whose blob is gone is logged and
+// left empty rather than failing the whole run view.
+func (s *OutputService) Hydrate(ctx context.Context, projects []queries.DriftAnalysisProject) error {
+	var ids []int64
+	for _, p := range projects {
+		for _, id := range []*int64{p.InitOutputID, p.PlanOutputID} {
+			if id != nil && !slices.Contains(ids, *id) {
+				ids = append(ids, *id)
+			}
+		}
+	}
+
+	contents := make(map[int64]*string, len(ids))
+	if len(ids) > 0 {
+		rows, err := s.repo.FindCommandOutputsByIds(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("fetching command outputs: %w", err)
+		}
+		decoded := make([]*string, len(rows))
+		if err := forEachConcurrently(len(rows), func(i int) error {
+			output, err := s.decode(ctx, rows[i])
+			decoded[i] = output
+			return err
+		}); err != nil {
+			return err
+		}
+		for i, row := range rows {
+			contents[row.ID] = decoded[i]
+		}
+	}
+
+	return forEachConcurrently(len(projects), func(i int) error {
+		p := &projects[i]
+		if p.InitOutputID != nil {
+			p.InitOutput = contents[*p.InitOutputID]
+		} else if p.InitOutputRef != nil {
+			output, err := s.fetchLegacy(ctx, *p.InitOutputRef)
+			if err != nil {
+				return err
+			}
+			p.InitOutput = output
+		}
+		if p.PlanOutputID != nil {
+			p.PlanOutput = contents[*p.PlanOutputID]
+		} else if p.PlanOutputRef != nil {
+			output, err := s.fetchLegacy(ctx, *p.PlanOutputRef)
+			if err != nil {
+				return err
+			}
+			p.PlanOutput = output
+		}
+		return nil
+	})
+}
+
+func (s *OutputService) decode(ctx context.Context, row queries.FindCommandOutputsByIdsRow) (*string, error) {
+	compressed := row.Data
+	if row.BlobRef != nil {
+		data, err := s.fetchBlob(ctx, *row.BlobRef)
+		if data == nil || err != nil {
+			return nil, err
+		}
+		compressed = data
+	}
+	data, err := decoder.DecodeAll(compressed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing command output %d: %w", row.ID, err)
+	}
+	output := string(data)
+	return &output, nil
+}
+
+// fetchLegacy reads an uncompressed output written under a per-project blob reference.
+func (s *OutputService) fetchLegacy(ctx context.Context, ref string) (*string, error) {
+	data, err := s.fetchBlob(ctx, ref)
+	if data == nil || err != nil {
+		return nil, err
+	}
+	output := string(data)
+	return &output, nil
+}
+
+// fetchBlob returns nil data, without an error, for a blob that is missing or unreadable because
+// no blob store is configured.
+func (s *OutputService) fetchBlob(ctx context.Context, ref string) ([]byte, error) {
+	if s.blobs == nil {
+		log.Errorf("Output blob %s referenced but no blob store is configured", ref)
+		return nil, nil
+	}
+	data, err := s.blobs.Get(ctx, ref)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		log.Warnf("Output blob %s is missing", ref)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching output blob %s: %w", ref, err)
+	}
+	return data, nil
+}
+
+// forEachConcurrently calls fn for 0..n-1 with at most blobConcurrency calls in flight and returns
+// the first error.
+func forEachConcurrently(n int, fn func(i int) error) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, blobConcurrency)
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(i); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
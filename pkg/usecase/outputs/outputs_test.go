@@ -0,0 +1,39 @@
+package outputs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrepare_RoundTrip(t *testing.T) {
+	content := strings.Repeat("Refreshing state... [id=i-0123456789]\n", 200)
+	output := Prepare(content)
+
+	if output.Size != int64(len(content)) {
+		t.Errorf("Size = %d, want %d", output.Size, len(content))
+	}
+	if len(output.Compressed) >= len(content) {
+		t.Errorf("expected repetitive output to compress, got %d bytes from %d", len(output.Compressed), len(content))
+	}
+	decoded, err := decoder.DecodeAll(output.Compressed, nil)
+	if err != nil || string(decoded) != content {
+		t.Fatalf("DecodeAll = %q, %v", decoded, err)
+	}
+}
+
+func TestPrepare_HashesByContent(t *testing.T) {
+	if Prepare("plan-a").Hash != Prepare("plan-a").Hash {
+		t.Error("identical outputs must hash the same")
+	}
+	if Prepare("plan-a").Hash == Prepare("plan-b").Hash {
+		t.Error("different outputs must hash differently")
+	}
+}
+
+func TestOutputKey(t *testing.T) {
+	key := outputKey(42, Prepare("").Hash)
+	want := "outputs/42/e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855.zst"
+	if key != want {
+		t.Errorf("outputKey = %q, want %q", key, want)
+	}
+}
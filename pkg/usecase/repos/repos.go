@@ -2,9 +2,13 @@ package repos
 
 import (
 	"context"
+	"regexp"
+	"strings"
 
+	"driftive.cloud/api/pkg/model/dto"
 	"driftive.cloud/api/pkg/repository"
 	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/schedule"
 	"driftive.cloud/api/pkg/usecase/utils/auth"
 	"driftive.cloud/api/pkg/usecase/utils/parsing"
 	"github.com/gofiber/fiber/v3"
@@ -12,6 +16,12 @@ import (
 	"github.com/google/uuid"
 )
 
+// listScanAlertsLimit bounds the overdue alerts listed for a repository.
+const listScanAlertsLimit = 50
+
+// workflowFileRegex matches the file name of a workflow under .github/workflows.
+var workflowFileRegex = regexp.MustCompile(`^[A-Za-z0-9._-]+\.ya?ml$`)
+
 type GitRepositoryHandler struct {
 	userRepository          repository.UserRepository
 	repoRepository          repository.GitRepositoryRepository
@@ -144,6 +154,190 @@ func (h *GitRepositoryHandler) EraseRepositoryData(c fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// UpdateDriftIssues turns on or off the GitHub issue opened for each project of the repository that
+// starts drifting, and closed once it is clean again. Turning it off leaves the open issues alone.
+// Only organization admins may change it.
+func (h *GitRepositoryHandler) UpdateDriftIssues(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	var req dto.UpdateDriftIssuesRequest
+	if err := c.Bind().Body(&req); err != nil || req.Enabled == nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	// Check if user is a member of the organization
+	isMember, err := h.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	// Issues are opened in the repository on behalf of the GitHub App, so only organization admins
+	// may opt in.
+	isAdmin, err := h.orgRepository.IsUserAdminOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isAdmin {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	if err := h.repoRepository.UpdateRepositoryDriftIssues(c.Context(), repoId, *req.Enabled); err != nil {
+		log.Errorf("Error updating drift issues for repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// UpdateDispatchSettings sets the workflow the dashboard dispatches to scan the repository, or
+// repository_dispatch events when none, and whether it may dispatch remediation applies. Only
+// organization admins may change them.
+func (h *GitRepositoryHandler) UpdateDispatchSettings(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	var req dto.UpdateDispatchSettingsRequest
+	if err := c.Bind().Body(&req); err != nil || req.RemediationEnabled == nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	var workflow *string
+	if req.Workflow != nil && strings.TrimSpace(*req.Workflow) != "" {
+		name := strings.TrimSpace(*req.Workflow)
+		if !workflowFileRegex.MatchString(name) {
+			return c.SendStatus(fiber.StatusBadRequest)
+		}
+		workflow = &name
+	}
+
+	// Check if user is a member of the organization
+	isMember, err := h.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	// Remediation applies change real infrastructure, so only organization admins may opt in.
+	isAdmin, err := h.orgRepository.IsUserAdminOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isAdmin {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	if err := h.repoRepository.UpdateRepositoryDispatchSettings(c.Context(), queries.UpdateRepositoryDispatchSettingsParams{
+		DispatchWorkflow:   workflow,
+		RemediationEnabled: *req.RemediationEnabled,
+		ID:                 repoId,
+	}); err != nil {
+		log.Errorf("Error updating dispatch settings for repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// UpdateScanSchedule sets the cadence the repository expects its scans at, which the overdue
+// monitor checks its completed runs against. The open overdue alert is resolved, and raised again
+// on the monitor's next pass if the repository is still overdue.
+func (h *GitRepositoryHandler) UpdateScanSchedule(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	var req dto.UpdateScanScheduleRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	var cronExpr *string
+	if req.Cron != nil && strings.TrimSpace(*req.Cron) != "" {
+		expr := strings.TrimSpace(*req.Cron)
+		cronExpr = &expr
+	}
+	if _, err := schedule.Parse(cronExpr, req.MaxIntervalMinutes); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Check if user is a member of the organization
+	isMember, err := h.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	err = h.driftAnalysisRepository.WithTx(c.Context(), func(ctx context.Context) error {
+		if err := h.repoRepository.UpdateRepositoryScanSchedule(ctx, queries.UpdateRepositoryScanScheduleParams{
+			ScanSchedule:           cronExpr,
+			ScanMaxIntervalMinutes: req.MaxIntervalMinutes,
+			ID:                     repoId,
+		}); err != nil {
+			log.Errorf("Error updating scan schedule for repository %d: %v", repoId, err)
+			return err
+		}
+		if err := h.repoRepository.ResolveScanOverdueAlert(ctx, repoId); err != nil {
+			log.Errorf("Error resolving overdue alert for repository %d: %v", repoId, err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListScanAlerts returns the latest overdue alerts of the repository, the open one first.
+func (h *GitRepositoryHandler) ListScanAlerts(c fiber.Ctx) error {
+	userId, err := auth.MustGetLoggedUserId(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	repoIdStr := c.Params("repo_id")
+	if repoIdStr == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	repoId := parsing.StringToInt64(repoIdStr)
+
+	isMember, err := h.orgRepository.IsUserMemberOfOrganizationByRepoId(c.Context(), repoId, *userId)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !isMember {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	alerts, err := h.repoRepository.ListScanOverdueAlerts(c.Context(), repoId, listScanAlertsLimit)
+	if err != nil {
+		log.Errorf("Error listing overdue alerts of repository %d: %v", repoId, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return c.JSON(parsing.ToScanOverdueAlertDTOs(alerts))
+}
+
 func (h *GitRepositoryHandler) RegenerateToken(c fiber.Ctx) error {
 	userId, err := auth.MustGetLoggedUserId(c)
 	if err != nil {
@@ -0,0 +1,89 @@
+package schedule
+
+import (
+	"context"
+	"time"
+
+	"driftive.cloud/api/pkg/observability"
+	"driftive.cloud/api/pkg/repository"
+	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+const overdueCheckInterval = 5 * time.Minute
+
+type OverdueMonitor struct {
+	repoRepository repository.GitRepositoryRepository
+}
+
+func NewOverdueMonitor(repoRepository repository.GitRepositoryRepository) *OverdueMonitor {
+	return &OverdueMonitor{repoRepository: repoRepository}
+}
+
+// StartOverdueMonitor periodically raises an alert for the repositories whose last completed scan is
+// older than their schedule allows, and resolves it once they complete one again. Safe to run on
+// every API instance at once: a repository has at most one open alert, so it is raised once.
+func (m *OverdueMonitor) StartOverdueMonitor(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("scan schedule monitor shutting down...")
+			return
+		case <-time.After(overdueCheckInterval):
+		}
+
+		if err := m.CheckOverdue(ctx, time.Now()); err != nil {
+			log.Errorf("error checking for overdue scans: %v", err)
+		}
+	}
+}
+
+// CheckOverdue raises or resolves the overdue alerts of the scheduled repositories as of now.
+func (m *OverdueMonitor) CheckOverdue(ctx context.Context, now time.Time) error {
+	rows, err := m.repoRepository.ListScheduledRepositories(ctx)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		sched, err := Parse(row.ScanSchedule, row.ScanMaxIntervalMinutes)
+		if err != nil || sched == nil {
+			log.Warnf("skipping the invalid scan schedule of repository %d: %v", row.ID, err)
+			continue
+		}
+		since, ok := Since(row.LastCompletedAt, row.ScanScheduleSetAt)
+		if !ok {
+			continue
+		}
+		dueAt := sched.DueAt(since)
+
+		if !now.After(dueAt) {
+			if row.OpenAlertID != nil {
+				if err := m.repoRepository.ResolveScanOverdueAlert(ctx, row.ID); err != nil {
+					log.Errorf("error resolving the overdue alert of repository %d: %v", row.ID, err)
+					continue
+				}
+				log.Infof("repository %d completed a scan again, resolved its overdue alert", row.ID)
+			}
+			continue
+		}
+		if row.OpenAlertID != nil {
+			continue
+		}
+		raised, err := m.repoRepository.RaiseScanOverdueAlert(ctx, queries.RaiseScanOverdueAlertParams{
+			RepositoryID:    row.ID,
+			DueAt:           dueAt,
+			LastCompletedAt: row.LastCompletedAt,
+		})
+		if err != nil {
+			log.Errorf("error raising the overdue alert of repository %d: %v", row.ID, err)
+			continue
+		}
+		if raised {
+			log.Warnf("repository %d is overdue for a scan: a completed run was expected by %s", row.ID, dueAt.Format(time.RFC3339))
+			if metrics := observability.GetMetrics(); metrics != nil && metrics.ScansOverdueTotal != nil {
+				metrics.ScansOverdueTotal.Add(ctx, 1)
+			}
+		}
+	}
+	return nil
+}
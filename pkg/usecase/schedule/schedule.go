@@ -0,0 +1,79 @@
+// Package schedule watches that repositories keep scanning at the cadence they declare, so a
+// silently broken scan pipeline is told apart from one that finds no drift.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// cronGrace is how long after a scheduled time its run has to complete.
+	cronGrace = time.Hour
+	// MaxIntervalMinutes bounds the declared max interval between completed runs, to 90 days.
+	MaxIntervalMinutes = 90 * 24 * 60
+	// maxCronLength is the size of the scan_schedule column.
+	maxCronLength = 100
+)
+
+// Schedule is the cadence a repository expects its scans at: either a standard cron expression of
+// when they are scheduled, or the longest time allowed between completed runs.
+type Schedule struct {
+	cron        cron.Schedule
+	maxInterval time.Duration
+}
+
+// Parse returns the schedule of a repository, nil when it declares none. At most one of the cron
+// expression and max interval may be set.
+func Parse(cronExpr *string, maxIntervalMinutes *int32) (*Schedule, error) {
+	switch {
+	case cronExpr != nil && maxIntervalMinutes != nil:
+		return nil, errors.New("a schedule is either a cron expression or a max interval")
+	case cronExpr != nil:
+		if len(*cronExpr) > maxCronLength {
+			return nil, fmt.Errorf("cron expression is longer than %d characters", maxCronLength)
+		}
+		sched, err := cron.ParseStandard(*cronExpr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression: %w", err)
+		}
+		// Like GitHub Actions schedules, expressions are in UTC unless they name their time zone.
+		if spec, ok := sched.(*cron.SpecSchedule); ok && !strings.HasPrefix(*cronExpr, "CRON_TZ=") && !strings.HasPrefix(*cronExpr, "TZ=") {
+			spec.Location = time.UTC
+		}
+		return &Schedule{cron: sched}, nil
+	case maxIntervalMinutes != nil:
+		if *maxIntervalMinutes <= 0 || *maxIntervalMinutes > MaxIntervalMinutes {
+			return nil, fmt.Errorf("max interval must be between 1 and %d minutes", MaxIntervalMinutes)
+		}
+		return &Schedule{maxInterval: time.Duration(*maxIntervalMinutes) * time.Minute}, nil
+	}
+	return nil, nil
+}
+
+// DueAt returns when the next run is expected to have completed by, given the last one completed
+// at since: the first scheduled time after it plus cronGrace, or since plus the max interval.
+func (s *Schedule) DueAt(since time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.Next(since).Add(cronGrace)
+	}
+	return since.Add(s.maxInterval)
+}
+
+// Since returns the time a repository's cadence counts from: its last completed run, or when the
+// schedule was declared if that is later or there is no completed run.
+func Since(lastCompletedAt *time.Time, scheduleSetAt *time.Time) (time.Time, bool) {
+	switch {
+	case lastCompletedAt == nil && scheduleSetAt == nil:
+		return time.Time{}, false
+	case lastCompletedAt == nil:
+		return *scheduleSetAt, true
+	case scheduleSetAt == nil || lastCompletedAt.After(*scheduleSetAt):
+		return *lastCompletedAt, true
+	}
+	return *scheduleSetAt, true
+}
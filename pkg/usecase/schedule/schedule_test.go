@@ -0,0 +1,93 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	nightly := "0 2 * * *"
+	invalid := "0 2 * *"
+	tooLong := strings.Repeat("*", maxCronLength+1)
+	day, zero, tooBig := int32(24*60), int32(0), int32(MaxIntervalMinutes+1)
+
+	cases := []struct {
+		name     string
+		cron     *string
+		interval *int32
+		wantNil  bool
+		wantErr  bool
+	}{
+		{name: "none", wantNil: true},
+		{name: "cron", cron: &nightly},
+		{name: "interval", interval: &day},
+		{name: "both", cron: &nightly, interval: &day, wantErr: true},
+		{name: "invalid cron", cron: &invalid, wantErr: true},
+		{name: "long cron", cron: &tooLong, wantErr: true},
+		{name: "zero interval", interval: &zero, wantErr: true},
+		{name: "large interval", interval: &tooBig, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sched, err := Parse(tc.cron, tc.interval)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Parse error = %v, want error %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && (sched == nil) != tc.wantNil {
+				t.Errorf("Parse = %v, want nil %v", sched, tc.wantNil)
+			}
+		})
+	}
+}
+
+func TestDueAt(t *testing.T) {
+	nightly := "0 2 * * *"
+	day := int32(24 * 60)
+	since := time.Date(2026, 3, 10, 2, 7, 0, 0, time.UTC)
+
+	cronSched, err := Parse(&nightly, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cronSched.DueAt(since), time.Date(2026, 3, 11, 3, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("cron DueAt = %s, want %s", got, want)
+	}
+
+	zoned := "CRON_TZ=Europe/Lisbon 0 2 * * *"
+	zonedSched, err := Parse(&zoned, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lisbon is on UTC in March, an hour ahead in July.
+	summer := time.Date(2026, 7, 10, 12, 0, 0, 0, time.UTC)
+	if got, want := zonedSched.DueAt(summer), time.Date(2026, 7, 11, 2, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("zoned cron DueAt = %s, want %s", got, want)
+	}
+
+	intervalSched, err := Parse(nil, &day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := intervalSched.DueAt(since), since.Add(24*time.Hour); !got.Equal(want) {
+		t.Errorf("interval DueAt = %s, want %s", got, want)
+	}
+}
+
+func TestSince(t *testing.T) {
+	earlier := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+
+	if _, ok := Since(nil, nil); ok {
+		t.Error("Since without a run nor a schedule should not count")
+	}
+	if got, _ := Since(nil, &earlier); !got.Equal(earlier) {
+		t.Errorf("Since without a run = %s, want the schedule time", got)
+	}
+	if got, _ := Since(&later, &earlier); !got.Equal(later) {
+		t.Errorf("Since of a run after the schedule = %s, want the run", got)
+	}
+	if got, _ := Since(&earlier, &later); !got.Equal(later) {
+		t.Errorf("Since of a run before the schedule = %s, want the schedule time", got)
+	}
+}
@@ -3,6 +3,7 @@ package github
 import (
 	"context"
 	"database/sql"
+	"driftive.cloud/api/pkg/codeowners"
 	"driftive.cloud/api/pkg/repository"
 	"driftive.cloud/api/pkg/repository/queries"
 	"driftive.cloud/api/pkg/usecase/utils/gh"
@@ -11,6 +12,7 @@ import (
 	"github.com/gofiber/fiber/v3/log"
 	"github.com/google/go-github/v88/github"
 	"github.com/jackc/pgx/v5"
+	"net/http"
 	"time"
 )
 
@@ -134,11 +136,46 @@ func (so SyncOrganization) SyncOrganizationRepositories(ctx context.Context, org
 			continue
 		}
 		log.Info("repo synced: ", updatedRepo.Name)
+
+		so.syncCodeowners(ctx, ghClient, org.Name, updatedRepo)
 	}
 
 	log.Debug("repos: ", allRepos)
 }
 
+// syncCodeowners stores the CODEOWNERS file of a repository, read from the first location GitHub
+// looks in, and forgets the stored one when the repository has none. A failed fetch keeps the
+// stored file.
+func (so SyncOrganization) syncCodeowners(ctx context.Context, ghClient *github.Client, orgName string, repo queries.GitRepository) {
+	for _, path := range codeowners.Paths {
+		file, _, resp, err := ghClient.Repositories.GetContents(ctx, orgName, repo.Name, path, nil)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			log.Error("error fetching CODEOWNERS for repo: ", repo.Name, ": ", err)
+			return
+		}
+		if file == nil {
+			// path is a directory
+			continue
+		}
+		content, err := file.GetContent()
+		if err != nil {
+			log.Error("error decoding CODEOWNERS for repo: ", repo.Name, ": ", err)
+			return
+		}
+		if err := so.repoRepository.UpsertRepositoryCodeowners(ctx, repo.ID, path, content); err != nil {
+			log.Error("error storing CODEOWNERS for repo: ", repo.Name, ": ", err)
+		}
+		return
+	}
+
+	if err := so.repoRepository.DeleteRepositoryCodeowners(ctx, repo.ID); err != nil {
+		log.Error("error clearing CODEOWNERS for repo: ", repo.Name, ": ", err)
+	}
+}
+
 func (so SyncOrganization) SyncInstallationIdByOrgId(ctx context.Context, orgId int64) {
 	ghClient, err := gh.NewAppGithubClient(ctx)
 	if err != nil {
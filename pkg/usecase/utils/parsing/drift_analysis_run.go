@@ -1,8 +1,13 @@
 package parsing
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
 	"driftive.cloud/api/pkg/model/dto"
 	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/gofiber/fiber/v3/log"
 )
 
 func ToDriftAnalysisRunDTO(run queries.DriftAnalysisRun) dto.DriftAnalysisRunDTO {
@@ -15,6 +20,7 @@ func ToDriftAnalysisRunDTO(run queries.DriftAnalysisRun) dto.DriftAnalysisRunDTO
 		TotalProjectsSkipped: run.TotalProjectsSkipped,
 		DurationMillis:       run.AnalysisDurationMillis,
 		Status:               run.Status,
+		CommitSha:            run.CommitSha,
 		CreatedAt:            run.CreatedAt,
 		UpdatedAt:            run.UpdatedAt,
 	}
@@ -42,7 +48,53 @@ func ToDriftAnalysisProjectDTO(project queries.DriftAnalysisProject) dto.DriftAn
 		ResourcesAdded:     project.ResourcesAdded,
 		ResourcesChanged:   project.ResourcesChanged,
 		ResourcesDestroyed: project.ResourcesDestroyed,
+		InitOutputSize:     project.InitOutputSize,
+		PlanOutputSize:     project.PlanOutputSize,
+		ResourcesImported:  project.ResourcesImported,
+		ResourcesForgotten: project.ResourcesForgotten,
+		ResourcesMoved:     project.ResourcesMoved,
+		OutputsChanged:     project.OutputsChanged,
+		Redactions:         toRedactionCounts(project.Redactions),
+		ResourceChanges:    toResourceChanges(project.ResourceChanges),
+		DriftFingerprint:   project.DriftFingerprint,
+		InitDurationMillis: project.InitDurationMillis,
+		PlanDurationMillis: project.PlanDurationMillis,
+		StartedAt:          project.StartedAt,
+		FinishedAt:         project.FinishedAt,
+		Retries:            project.Retries,
+		ErrorClass:         project.ErrorClass,
+		CoreTool:           project.CoreTool,
+		CoreVersion:        project.CoreVersion,
+		ProviderVersions:   toProviderVersions(project.ProviderVersions),
+		Warnings:           toWarnings(project.Warnings),
+		Owners:             []string{},
+	}
+}
+
+// toResourceChanges decodes the resource_changes column, which is NULL for plans naming no
+// resources, into a slice that serializes as [] rather than null.
+func toResourceChanges(data []byte) []dto.ResourceChangeDTO {
+	changes := []dto.ResourceChangeDTO{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &changes); err != nil {
+			log.Warnf("Ignoring malformed resource changes: %v", err)
+			return []dto.ResourceChangeDTO{}
+		}
 	}
+	return changes
+}
+
+// toRedactionCounts decodes the redactions column, which is NULL for projects with nothing masked,
+// into a map that serializes as {} rather than null.
+func toRedactionCounts(data []byte) map[string]int32 {
+	counts := map[string]int32{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &counts); err != nil {
+			log.Warnf("Ignoring malformed redaction counts: %v", err)
+			return map[string]int32{}
+		}
+	}
+	return counts
 }
 
 func ToDriftAnalysisProjectDTOs(projects []queries.DriftAnalysisProject) []dto.DriftAnalysisProjectDTO {
@@ -59,9 +111,55 @@ func ToDriftAnalysisRunWithProjectsDTO(run queries.DriftAnalysisRun, projects []
 	if runningProjects == nil {
 		runningProjects = []string{}
 	}
+	projectDTOs := ToDriftAnalysisProjectDTOs(projects)
+	var warningCount int64
+	warningCounts := map[string]int64{}
+	for _, p := range projectDTOs {
+		for _, w := range p.Warnings {
+			warningCount += int64(w.Occurrences)
+			warningCounts[w.Category] += int64(w.Occurrences)
+		}
+	}
 	return dto.DriftAnalysisRunWithProjectsDTO{
 		DriftAnalysisRunDTO: ToDriftAnalysisRunDTO(run),
 		RunningProjects:     runningProjects,
-		Projects:            ToDriftAnalysisProjectDTOs(projects),
+		Projects:            projectDTOs,
+		WarningCount:        warningCount,
+		WarningCounts:       warningCounts,
+	}
+}
+
+// toWarnings decodes the warnings column, which is NULL for outputs without warnings, into a slice
+// that serializes as [] rather than null.
+func toWarnings(data []byte) []dto.WarningDTO {
+	warnings := []dto.WarningDTO{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &warnings); err != nil {
+			log.Warnf("Ignoring malformed warnings: %v", err)
+			return []dto.WarningDTO{}
+		}
+	}
+	return warnings
+}
+
+// FormatResourceCounts summarizes the changes of a project's plan like the plan's closing line,
+// e.g. "1 to add, 2 to change, 0 to destroy", or returns "" when the counts were not parsed.
+func FormatResourceCounts(p queries.DriftAnalysisProject) string {
+	if p.ResourcesAdded == nil || p.ResourcesChanged == nil || p.ResourcesDestroyed == nil {
+		return ""
+	}
+	parts := []string{
+		fmt.Sprintf("%d to add", *p.ResourcesAdded),
+		fmt.Sprintf("%d to change", *p.ResourcesChanged),
+		fmt.Sprintf("%d to destroy", *p.ResourcesDestroyed),
+	}
+	for _, c := range []struct {
+		n    *int32
+		verb string
+	}{{p.ResourcesImported, "to import"}, {p.ResourcesMoved, "to move"}, {p.ResourcesForgotten, "to forget"}} {
+		if c.n != nil && *c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", *c.n, c.verb))
+		}
 	}
+	return strings.Join(parts, ", ")
 }
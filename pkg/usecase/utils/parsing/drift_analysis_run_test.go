@@ -122,3 +122,34 @@ func TestToDriftAnalysisRunDTO_IncludesStatus(t *testing.T) {
 		t.Errorf("status = %q, want RUNNING", got.Status)
 	}
 }
+
+// Projects with nothing masked store NULL; the UI reads the counts as an object either way.
+func TestToDriftAnalysisProjectDTO_Redactions(t *testing.T) {
+	masked := ToDriftAnalysisProjectDTO(queries.DriftAnalysisProject{Redactions: []byte(`{"jwt":2}`)})
+	if masked.Redactions["jwt"] != 2 {
+		t.Errorf("Redactions = %v, want jwt: 2", masked.Redactions)
+	}
+
+	body, err := json.Marshal(ToDriftAnalysisProjectDTO(queries.DriftAnalysisProject{}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, ok := got["redactions"].(map[string]any); !ok {
+		t.Errorf("redactions = %v, want {}", got["redactions"])
+	}
+}
+
+func TestFormatResourceCounts(t *testing.T) {
+	n := func(v int32) *int32 { return &v }
+	p := queries.DriftAnalysisProject{ResourcesAdded: n(1), ResourcesChanged: n(2), ResourcesDestroyed: n(0), ResourcesImported: n(0), ResourcesMoved: n(3)}
+	if got, want := FormatResourceCounts(p), "1 to add, 2 to change, 0 to destroy, 3 to move"; got != want {
+		t.Errorf("FormatResourceCounts = %q, want %q", got, want)
+	}
+	if got := FormatResourceCounts(queries.DriftAnalysisProject{}); got != "" {
+		t.Errorf("FormatResourceCounts without parsed counts = %q, want empty", got)
+	}
+}
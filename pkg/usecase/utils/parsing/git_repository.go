@@ -7,12 +7,17 @@ import (
 
 func ToGitRepositoryDTO(repository queries.GitRepository) dto.GitRepositoryDTO {
 	return dto.GitRepositoryDTO{
-		ID:               repository.ID,
-		OrganizationID:   repository.OrganizationID,
-		ProviderID:       repository.ProviderID,
-		Name:             repository.Name,
-		IsPrivate:        repository.IsPrivate,
-		HasAnalysisToken: repository.AnalysisToken != nil,
+		ID:                     repository.ID,
+		OrganizationID:         repository.OrganizationID,
+		ProviderID:             repository.ProviderID,
+		Name:                   repository.Name,
+		IsPrivate:              repository.IsPrivate,
+		HasAnalysisToken:       repository.AnalysisToken != nil,
+		DriftIssuesEnabled:     repository.DriftIssuesEnabled,
+		DispatchWorkflow:       repository.DispatchWorkflow,
+		RemediationEnabled:     repository.RemediationEnabled,
+		ScanSchedule:           repository.ScanSchedule,
+		ScanMaxIntervalMinutes: repository.ScanMaxIntervalMinutes,
 	}
 }
 
@@ -23,3 +28,34 @@ func ToGitRepositoryDTOs(repositories []queries.GitRepository) []dto.GitReposito
 	}
 	return repoDTOs
 }
+
+func ToScanDispatchDTOs(rows []queries.ListScanDispatchesRow) []dto.ScanDispatchDTO {
+	result := make([]dto.ScanDispatchDTO, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.ScanDispatchDTO{
+			ID:          row.ID.String(),
+			Kind:        row.Kind,
+			Dirs:        row.Dirs,
+			ProjectType: row.ProjectType,
+			RequestedBy: row.RequestedBy,
+			CreatedAt:   row.CreatedAt,
+			RunID:       uuidString(row.RunID),
+			RunStatus:   row.RunStatus,
+		})
+	}
+	return result
+}
+
+func ToScanOverdueAlertDTOs(alerts []queries.ScanOverdueAlert) []dto.ScanOverdueAlertDTO {
+	result := make([]dto.ScanOverdueAlertDTO, 0, len(alerts))
+	for _, alert := range alerts {
+		result = append(result, dto.ScanOverdueAlertDTO{
+			ID:              alert.ID,
+			DueAt:           alert.DueAt,
+			LastCompletedAt: alert.LastCompletedAt,
+			RaisedAt:        alert.RaisedAt,
+			ResolvedAt:      alert.ResolvedAt,
+		})
+	}
+	return result
+}
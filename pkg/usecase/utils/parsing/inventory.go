@@ -0,0 +1,39 @@
+package parsing
+
+import (
+	"encoding/json"
+
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/gofiber/fiber/v3/log"
+)
+
+func ToProjectVersions(rows []queries.GetOrganizationVersionInventoryRow) []dto.ProjectVersionsDTO {
+	result := make([]dto.ProjectVersionsDTO, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.ProjectVersionsDTO{
+			RepositoryID:     row.RepositoryID,
+			RepositoryName:   row.RepositoryName,
+			Dir:              row.Dir,
+			Type:             row.Type,
+			CoreTool:         row.CoreTool,
+			CoreVersion:      row.CoreVersion,
+			ProviderVersions: toProviderVersions(row.ProviderVersions),
+			LastRunAt:        row.LastRunAt,
+		})
+	}
+	return result
+}
+
+// toProviderVersions decodes the provider_versions column, which is NULL when init named no
+// providers, into a slice that serializes as [] rather than null.
+func toProviderVersions(data []byte) []dto.ProviderVersionDTO {
+	providers := []dto.ProviderVersionDTO{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &providers); err != nil {
+			log.Warnf("Ignoring malformed provider versions: %v", err)
+			return []dto.ProviderVersionDTO{}
+		}
+	}
+	return providers
+}
@@ -22,3 +22,20 @@ func ToOrganizationDTOs(organizations []queries.GitOrganization) []dto.Organizat
 	}
 	return orgDTOs
 }
+
+func ToRedactionRuleDTO(rule queries.OrganizationRedactionRule) dto.RedactionRuleDTO {
+	return dto.RedactionRuleDTO{
+		ID:        rule.ID,
+		Name:      rule.Name,
+		Pattern:   rule.Pattern,
+		CreatedAt: rule.CreatedAt,
+	}
+}
+
+func ToRedactionRulesDTO(builtins []string, rules []queries.OrganizationRedactionRule) dto.RedactionRulesDTO {
+	ruleDTOs := make([]dto.RedactionRuleDTO, 0, len(rules))
+	for _, rule := range rules {
+		ruleDTOs = append(ruleDTOs, ToRedactionRuleDTO(rule))
+	}
+	return dto.RedactionRulesDTO{Builtins: builtins, Rules: ruleDTOs}
+}
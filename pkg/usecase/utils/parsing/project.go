@@ -0,0 +1,92 @@
+package parsing
+
+import (
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository/queries"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func ToProjectDTOs(projects []queries.DriftProject) []dto.ProjectDTO {
+	result := make([]dto.ProjectDTO, 0, len(projects))
+	for _, p := range projects {
+		result = append(result, dto.ProjectDTO{
+			ID:            p.ID,
+			Dir:           p.Dir,
+			Type:          p.Type,
+			Status:        p.Status,
+			FirstSeenAt:   p.FirstSeenAt,
+			LastSeenAt:    p.LastSeenAt,
+			LastDriftedAt: p.LastDriftedAt,
+			LastErroredAt: p.LastErroredAt,
+			LastRunID:     uuidString(p.LastRunID),
+			Owners:        []string{},
+		})
+	}
+	return result
+}
+
+// ToProjectRuns maps the history of a project. The status follows the catalog's: a skipped project
+// is SKIPPED even if it failed, a failed one ERRORED even if it reported drift.
+func ToProjectRuns(rows []queries.GetProjectHistoryRow) []dto.ProjectRunDTO {
+	result := make([]dto.ProjectRunDTO, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.ProjectRunDTO{
+			RunID:              row.RunID.String(),
+			Status:             row.Status,
+			Type:               row.Type,
+			ResourcesAdded:     row.ResourcesAdded,
+			ResourcesChanged:   row.ResourcesChanged,
+			ResourcesDestroyed: row.ResourcesDestroyed,
+			ErrorClass:         row.ErrorClass,
+			CreatedAt:          row.CreatedAt,
+		})
+	}
+	return result
+}
+
+func ToDriftPeriods(rows []queries.GetProjectDriftPeriodsRow) []dto.DriftPeriodDTO {
+	result := make([]dto.DriftPeriodDTO, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.DriftPeriodDTO{
+			Type:      row.Type,
+			StartedAt: row.StartedAt,
+			EndedAt:   row.EndedAt,
+		})
+	}
+	return result
+}
+
+func ToCoverageChanges(rows []queries.GetRunCoverageChangesRow) []dto.CoverageChangeDTO {
+	result := make([]dto.CoverageChangeDTO, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.CoverageChangeDTO{
+			Dir:    row.Dir,
+			Type:   row.Type,
+			Change: row.Change,
+		})
+	}
+	return result
+}
+
+func ToUnscannedProjects(rows []queries.GetUnscannedProjectsRow) []dto.UnscannedProjectDTO {
+	result := make([]dto.UnscannedProjectDTO, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.UnscannedProjectDTO{
+			Dir:        row.Dir,
+			Type:       row.Type,
+			LastSeenAt: row.LastSeenAt,
+			LastRunID:  uuidString(row.LastRunID),
+		})
+	}
+	return result
+}
+
+// uuidString formats a nullable uuid column, nil when NULL.
+func uuidString(id pgtype.UUID) *string {
+	if !id.Valid {
+		return nil
+	}
+	s := uuid.UUID(id.Bytes).String()
+	return &s
+}
@@ -1,6 +1,8 @@
 package parsing
 
 import (
+	"strings"
+
 	"driftive.cloud/api/pkg/model/dto"
 	"driftive.cloud/api/pkg/repository/queries"
 )
@@ -22,6 +24,26 @@ func ToDriftRateDataPoints(rows []queries.GetDriftRateOverTimeRow) []dto.DriftRa
 	return result
 }
 
+func ToScopedDriftDataPoints(rows []queries.GetDirsDriftOverTimeRow) []dto.ScopedDriftDataPoint {
+	result := make([]dto.ScopedDriftDataPoint, 0, len(rows))
+	for _, row := range rows {
+		driftRatePercent := float64(0)
+		if row.TotalRuns > 0 {
+			driftRatePercent = float64(row.RunsWithDrift) / float64(row.TotalRuns) * 100
+		}
+		result = append(result, dto.ScopedDriftDataPoint{
+			Date:             row.Date.Time.Format("2006-01-02"),
+			TotalRuns:        row.TotalRuns,
+			RunsWithDrift:    row.RunsWithDrift,
+			DriftRatePercent: driftRatePercent,
+			ProjectsScanned:  row.ProjectsScanned,
+			ProjectsDrifted:  row.ProjectsDrifted,
+			ProjectsErrored:  row.ProjectsErrored,
+		})
+	}
+	return result
+}
+
 func ToFrequentlyDriftedProjects(rows []queries.GetMostFrequentlyDriftedProjectsRow) []dto.FrequentlyDriftedProject {
 	result := make([]dto.FrequentlyDriftedProject, 0, len(rows))
 	for _, row := range rows {
@@ -40,6 +62,52 @@ func ToFrequentlyDriftedProjects(rows []queries.GetMostFrequentlyDriftedProjects
 	return result
 }
 
+func ToFrequentlyDriftedResources(rows []queries.GetMostFrequentlyDriftedResourcesRow) []dto.FrequentlyDriftedResource {
+	result := make([]dto.FrequentlyDriftedResource, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.FrequentlyDriftedResource{
+			Dir:        row.Dir,
+			Address:    row.Address,
+			DriftCount: row.DriftCount,
+		})
+	}
+	return result
+}
+
+func ToSlowProjects(rows []queries.GetSlowestProjectsRow) []dto.SlowProjectDTO {
+	result := make([]dto.SlowProjectDTO, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.SlowProjectDTO{
+			Dir:                   row.Dir,
+			Type:                  row.Type,
+			RunCount:              row.RunCount,
+			AvgDurationMillis:     row.AvgDurationMillis,
+			MaxDurationMillis:     row.MaxDurationMillis,
+			AvgInitDurationMillis: row.AvgInitDurationMillis,
+			AvgPlanDurationMillis: row.AvgPlanDurationMillis,
+			TotalRetries:          row.TotalRetries,
+		})
+	}
+	return result
+}
+
+func ToDurationRegressions(rows []queries.GetProjectDurationRegressionsRow) []dto.DurationRegressionDTO {
+	result := make([]dto.DurationRegressionDTO, 0, len(rows))
+	for _, row := range rows {
+		increasePercent := float64(0)
+		if row.BaselineAvgDurationMillis > 0 {
+			increasePercent = float64(row.RecentAvgDurationMillis-row.BaselineAvgDurationMillis) / float64(row.BaselineAvgDurationMillis) * 100
+		}
+		result = append(result, dto.DurationRegressionDTO{
+			Dir:                       row.Dir,
+			BaselineAvgDurationMillis: row.BaselineAvgDurationMillis,
+			RecentAvgDurationMillis:   row.RecentAvgDurationMillis,
+			IncreasePercent:           increasePercent,
+		})
+	}
+	return result
+}
+
 func ToDriftFreeStreakDTO(row queries.GetDriftFreeStreakRow) dto.DriftFreeStreakDTO {
 	return dto.DriftFreeStreakDTO{
 		StreakCount: row.StreakCount,
@@ -58,3 +126,90 @@ func ToResolutionTimeDataPoints(rows []queries.GetMeanTimeToResolutionRow) []dto
 	}
 	return result
 }
+
+func ToResourceTypeDriftStats(rows []queries.GetMostFrequentlyDriftedResourceTypesRow) []dto.ResourceTypeDriftStat {
+	result := make([]dto.ResourceTypeDriftStat, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.ResourceTypeDriftStat{
+			ResourceType:    row.ResourceType,
+			Provider:        providerOf(row.ResourceType),
+			DriftCount:      row.DriftCount,
+			ResourceCount:   row.ResourceCount,
+			RepositoryCount: row.RepositoryCount,
+		})
+	}
+	return result
+}
+
+func ToProviderDriftStats(rows []queries.GetMostFrequentlyDriftedProvidersRow) []dto.ProviderDriftStat {
+	result := make([]dto.ProviderDriftStat, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.ProviderDriftStat{
+			Provider:        row.Provider,
+			DriftCount:      row.DriftCount,
+			ResourceCount:   row.ResourceCount,
+			RepositoryCount: row.RepositoryCount,
+		})
+	}
+	return result
+}
+
+// providerOf returns the provider a resource type belongs to, its prefix up to the first
+// underscore, matching the provider column of the trends queries.
+func providerOf(resourceType string) string {
+	provider, _, _ := strings.Cut(resourceType, "_")
+	return provider
+}
+
+func ToErrorClassStats(rows []queries.GetErrorClassBreakdownRow) []dto.ErrorClassStat {
+	result := make([]dto.ErrorClassStat, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.ErrorClassStat{
+			ErrorClass:   row.ErrorClass,
+			ProjectCount: row.ProjectCount,
+			RunCount:     row.RunCount,
+			LastSeenAt:   row.LastSeenAt,
+		})
+	}
+	return result
+}
+
+func ToErrorClassCounts(rows []queries.GetRunErrorClassBreakdownRow) []dto.ErrorClassCountDTO {
+	result := make([]dto.ErrorClassCountDTO, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.ErrorClassCountDTO{
+			ErrorClass:   row.ErrorClass,
+			ProjectCount: row.ProjectCount,
+		})
+	}
+	return result
+}
+
+func ToRunWarningCounts(rows []queries.GetWarningCountsPerRunRow) []dto.RunWarningCountDTO {
+	result := make([]dto.RunWarningCountDTO, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.RunWarningCountDTO{
+			RunID:        row.RunID.String(),
+			CreatedAt:    row.CreatedAt,
+			WarningCount: row.WarningCount,
+		})
+	}
+	return result
+}
+
+func ToRepeatedWarnings(rows []queries.GetRepeatedWarningsRow) []dto.RepeatedWarningDTO {
+	result := make([]dto.RepeatedWarningDTO, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, dto.RepeatedWarningDTO{
+			Dir:         row.Dir,
+			Category:    row.Category,
+			Summary:     row.Summary,
+			Address:     row.Address,
+			Detail:      row.Detail,
+			RunCount:    row.RunCount,
+			FirstSeenAt: row.FirstSeenAt,
+			LastSeenAt:  row.LastSeenAt,
+		})
+	}
+	return result
+}
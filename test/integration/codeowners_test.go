@@ -0,0 +1,106 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+
+	"driftive.cloud/api/pkg/config"
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository"
+)
+
+const sampleCodeowners = `*            @acme/platform
+/projects/a/ @acme/team-a
+/projects/c
+`
+
+// TestCodeowners_OwnersAndFilters stores a CODEOWNERS file giving /projects/a to @acme/team-a,
+// the rest to @acme/platform and leaving /projects/c unowned, ingests the sample state, and checks
+// the owners on projects and runs and the owner filters.
+func TestCodeowners_OwnersAndFilters(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+
+	repos := repository.NewRepository(testDB, &config.Config{})
+	if err := repos.GitRepoRepository().UpsertRepositoryCodeowners(context.Background(), repoID, ".github/CODEOWNERS", sampleCodeowners); err != nil {
+		t.Fatalf("store CODEOWNERS: %v", err)
+	}
+
+	app := newIngestApp(t)
+	status, body := postIngest(t, app, seedAnalysisToken, "", sampleState())
+	if status != http.StatusOK {
+		t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+	}
+	runID := runIDFromResponse(t, body)
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+	base := "/api/v1/repo/" + strconv.FormatInt(repoID, 10)
+
+	var projects []dto.ProjectDTO
+	if status := getJSON(t, dashboard, base+"/projects", token, &projects); status != http.StatusOK {
+		t.Fatalf("ListRepositoryProjects: expected 200, got %d", status)
+	}
+	wantOwners := map[string][]string{
+		"/projects/a": {"@acme/team-a"},
+		"/projects/b": {"@acme/platform"},
+		"/projects/c": {},
+	}
+	for _, p := range projects {
+		if !slices.Equal(p.Owners, wantOwners[p.Dir]) {
+			t.Errorf("%s owners = %v, want %v", p.Dir, p.Owners, wantOwners[p.Dir])
+		}
+	}
+	if status := getJSON(t, dashboard, base+"/projects?owner="+url.QueryEscape("@ACME/team-a"), token, &projects); status != http.StatusOK {
+		t.Fatalf("ListRepositoryProjects owner: expected 200, got %d", status)
+	}
+	if len(projects) != 1 || projects[0].Dir != "/projects/a" {
+		t.Errorf("owner=@ACME/team-a: got %+v, want /projects/a alone", projects)
+	}
+
+	var run dto.DriftAnalysisRunWithProjectsDTO
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+runID, token, &run); status != http.StatusOK {
+		t.Fatalf("GetRunById: expected 200, got %d", status)
+	}
+	for _, p := range run.Projects {
+		if !slices.Equal(p.Owners, wantOwners[p.Dir]) {
+			t.Errorf("run project %s owners = %v, want %v", p.Dir, p.Owners, wantOwners[p.Dir])
+		}
+	}
+
+	var owners []dto.OwnerSummaryDTO
+	if status := getJSON(t, dashboard, base+"/owners", token, &owners); status != http.StatusOK {
+		t.Fatalf("ListRepositoryOwners: expected 200, got %d", status)
+	}
+	if len(owners) != 3 || owners[0].Owner == nil || *owners[0].Owner != "@acme/team-a" || owners[0].Counts.Drifted != 1 ||
+		owners[1].Owner == nil || *owners[1].Owner != "@acme/platform" || owners[1].Counts.InSync != 1 ||
+		owners[2].Owner != nil || owners[2].Counts.Skipped != 1 {
+		t.Errorf("owners = %+v, want team-a with the drift, platform, then the unowned skipped project", owners)
+	}
+
+	var runs []dto.DriftAnalysisRunDTO
+	if status := getJSON(t, dashboard, base+"/runs?owner="+url.QueryEscape("@acme/team-a"), token, &runs); status != http.StatusOK {
+		t.Fatalf("ListRunsByRepoId owner: expected 200, got %d", status)
+	}
+	if len(runs) != 1 {
+		t.Errorf("runs of @acme/team-a = %d, want the drifted run", len(runs))
+	}
+	if status := getJSON(t, dashboard, base+"/runs?owner="+url.QueryEscape("@acme/platform"), token, &runs); status != http.StatusOK {
+		t.Fatalf("ListRunsByRepoId owner: expected 200, got %d", status)
+	}
+	if len(runs) != 0 {
+		t.Errorf("runs of @acme/platform = %d, want none: its project didn't drift", len(runs))
+	}
+
+	var trends dto.OwnerTrendsDTO
+	if status := getJSON(t, dashboard, base+"/trends/owner?owner="+url.QueryEscape("@acme/team-a"), token, &trends); status != http.StatusOK {
+		t.Fatalf("GetOwnerTrends: expected 200, got %d", status)
+	}
+	if len(trends.DriftRateOverTime) != 1 || trends.DriftRateOverTime[0].ProjectsDrifted != 1 || trends.DriftRateOverTime[0].ProjectsScanned != 1 {
+		t.Errorf("team-a trends = %+v, want one day with its drifted project", trends.DriftRateOverTime)
+	}
+}
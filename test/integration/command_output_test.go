@@ -0,0 +1,139 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"driftive.cloud/api/pkg/usecase/outputs"
+	"github.com/google/uuid"
+)
+
+// storedOutputs returns the init and plan outputs of a run's projects by dir, read back through
+// command_output the same way GetRunById reads them.
+func storedOutputs(t *testing.T, runUUID string) map[string][2]*string {
+	t.Helper()
+	ctx := context.Background()
+	repo := newDriftRepo(t)
+	projects, err := repo.FindDriftAnalysisProjectsByRunId(ctx, uuid.MustParse(runUUID))
+	if err != nil {
+		t.Fatalf("FindDriftAnalysisProjectsByRunId: %v", err)
+	}
+	if err := outputs.NewOutputService(repo, nil).Hydrate(ctx, projects); err != nil {
+		t.Fatalf("Hydrate: %v", err)
+	}
+	byDir := make(map[string][2]*string, len(projects))
+	for _, p := range projects {
+		byDir[p.Dir] = [2]*string{p.InitOutput, p.PlanOutput}
+	}
+	return byDir
+}
+
+// commandOutputRefs returns the number of command_output rows and the sum of their ref counts.
+func commandOutputRefs(t *testing.T) (rows, refs int) {
+	t.Helper()
+	if err := withPool(t).QueryRow(context.Background(),
+		`SELECT count(*), COALESCE(sum(ref_count), 0) FROM command_output`).Scan(&rows, &refs); err != nil {
+		t.Fatalf("count command outputs: %v", err)
+	}
+	return rows, refs
+}
+
+// TestCommandOutput_RepeatedRunsShareOutputs ingests the same results twice and checks the second
+// run only adds references, then that deleting both runs drops the counts back to zero.
+func TestCommandOutput_RepeatedRunsShareOutputs(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	app := newIngestApp(t)
+
+	state := sampleState()
+	// Project b's init output is identical to a's, as it usually is within a run.
+	state.ProjectResults[1].InitOutput = "init-a"
+	for i := 0; i < 2; i++ {
+		if status, body := postIngest(t, app, seedAnalysisToken, "", state); status != http.StatusOK {
+			t.Fatalf("ingest %d: expected 200, got %d: %s", i, status, body)
+		}
+	}
+
+	// init-a, plan-a and plan-b; project c sent empty outputs, which stay inline.
+	rows, refs := commandOutputRefs(t)
+	if rows != 3 || refs != 8 {
+		t.Fatalf("expected 3 outputs with 8 references, got %d with %d", rows, refs)
+	}
+
+	// Deleted the way retention deletes them; erasing the repository skips the grace period.
+	if _, err := withPool(t).Exec(context.Background(), `DELETE FROM drift_analysis_run WHERE repository_id = $1`, repoID); err != nil {
+		t.Fatalf("delete runs: %v", err)
+	}
+	if rows, refs := commandOutputRefs(t); rows != 3 || refs != 0 {
+		t.Errorf("expected 3 unreferenced outputs kept for the grace period, got %d with %d references", rows, refs)
+	}
+	deleted, err := newDriftRepo(t).DeleteUnreferencedCommandOutputs(context.Background(), 0, 100)
+	if err != nil || deleted != 3 {
+		t.Fatalf("DeleteUnreferencedCommandOutputs = %d, %v; want 3", deleted, err)
+	}
+}
+
+// TestCommandOutput_LegacyRowsAreMigrated writes a project row the way it was stored before
+// command_output and checks the background migration moves its outputs without changing them.
+func TestCommandOutput_LegacyRowsAreMigrated(t *testing.T) {
+	truncateAll(t)
+	seedOrgAndRepo(t)
+
+	status, body := postIngest(t, newIngestApp(t), seedAnalysisToken, "", sampleState())
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	runID := runIDFromResponse(t, body)
+	if _, err := withPool(t).Exec(context.Background(),
+		`UPDATE drift_analysis_project
+		 SET init_output = 'legacy-init', plan_output = 'legacy-plan', init_output_id = NULL, plan_output_id = NULL
+		 WHERE drift_analysis_run_id = $1::uuid AND dir = '/projects/a'`, runID); err != nil {
+		t.Fatalf("rewrite project as legacy: %v", err)
+	}
+
+	svc := outputs.NewOutputService(newDriftRepo(t), nil)
+	migrated, err := svc.MigrateLegacyOutputs(context.Background(), 100)
+	if err != nil || migrated != 1 {
+		t.Fatalf("MigrateLegacyOutputs = %d, %v; want 1", migrated, err)
+	}
+	if migrated, err := svc.MigrateLegacyOutputs(context.Background(), 100); err != nil || migrated != 0 {
+		t.Errorf("second pass: MigrateLegacyOutputs = %d, %v; want 0", migrated, err)
+	}
+
+	var inline *string
+	if err := withPool(t).QueryRow(context.Background(),
+		`SELECT plan_output FROM drift_analysis_project
+		 WHERE drift_analysis_run_id = $1::uuid AND dir = '/projects/a'`, runID).Scan(&inline); err != nil {
+		t.Fatalf("fetch project: %v", err)
+	}
+	if inline != nil {
+		t.Errorf("expected the inline plan output to be cleared, got %q", *inline)
+	}
+	got := storedOutputs(t, runID)["/projects/a"]
+	if !strPtrEq(got[0], ptr("legacy-init")) || !strPtrEq(got[1], ptr("legacy-plan")) {
+		t.Errorf("expected migrated outputs, got init %v plan %v", got[0], got[1])
+	}
+}
+
+// TestCommandOutput_OutputsAreScopedToTheRepository checks two repositories sending the same output
+// each get their own row, so neither can reach or hold back the other's.
+func TestCommandOutput_OutputsAreScopedToTheRepository(t *testing.T) {
+	truncateAll(t)
+	seedOrgAndRepo(t)
+	if _, err := withPool(t).Exec(context.Background(),
+		`INSERT INTO git_repository (organization_id, provider_id, name, is_private, analysis_token)
+		 SELECT organization_id, '778', 'other', false, 'other-token' FROM git_repository`); err != nil {
+		t.Fatalf("seed second repo: %v", err)
+	}
+
+	app := newIngestApp(t)
+	for _, token := range []string{seedAnalysisToken, "other-token"} {
+		if status, body := postIngest(t, app, token, "", sampleState()); status != http.StatusOK {
+			t.Fatalf("ingest with %s: expected 200, got %d: %s", token, status, body)
+		}
+	}
+	if rows, refs := commandOutputRefs(t); rows != 8 || refs != 8 {
+		t.Errorf("expected 8 outputs with one reference each, got %d with %d", rows, refs)
+	}
+}
@@ -0,0 +1,130 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"driftive.cloud/api/pkg/config"
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/usecase/drift_stream"
+)
+
+// TestCoverageChanges_RecordedAtFinalize ingests a run scanning /projects/a, b and c, then one that
+// drops /projects/b and adds /projects/d, and checks the second run records both changes and the
+// stats list /projects/b as no longer scanned.
+func TestCoverageChanges_RecordedAtFinalize(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+
+	app := newIngestApp(t)
+	status, body := postIngest(t, app, seedAnalysisToken, "", sampleState())
+	if status != http.StatusOK {
+		t.Fatalf("first ingest: expected 200, got %d: %s", status, body)
+	}
+	firstRunID := runIDFromResponse(t, body)
+
+	state := sampleState()
+	state.ProjectResults = slices.Delete(state.ProjectResults, 1, 2)
+	state.ProjectResults = append(state.ProjectResults, drift_stream.DriftProjectResult{
+		Project:   drift_stream.TypedProject{Dir: "/projects/d", Type: drift_stream.Terraform},
+		Succeeded: true,
+	})
+	status, body = postIngest(t, app, seedAnalysisToken, "", state)
+	if status != http.StatusOK {
+		t.Fatalf("second ingest: expected 200, got %d: %s", status, body)
+	}
+	secondRunID := runIDFromResponse(t, body)
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+
+	var run dto.DriftAnalysisRunWithProjectsDTO
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+firstRunID, token, &run); status != http.StatusOK {
+		t.Fatalf("GetRunById: expected 200, got %d", status)
+	}
+	if len(run.CoverageChanges) != 0 {
+		t.Errorf("first run coverage_changes = %+v, want none", run.CoverageChanges)
+	}
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+secondRunID, token, &run); status != http.StatusOK {
+		t.Fatalf("GetRunById: expected 200, got %d", status)
+	}
+	wantChanges := []dto.CoverageChangeDTO{
+		{Dir: "/projects/b", Type: "TOFU", Change: "REMOVED"},
+		{Dir: "/projects/d", Type: "TERRAFORM", Change: "ADDED"},
+	}
+	if !slices.Equal(run.CoverageChanges, wantChanges) {
+		t.Errorf("coverage_changes = %+v, want %+v", run.CoverageChanges, wantChanges)
+	}
+
+	var stats dto.RepositoryRunStatsDTO
+	if status := getJSON(t, dashboard, "/api/v1/repo/"+strconv.FormatInt(repoID, 10)+"/stats", token, &stats); status != http.StatusOK {
+		t.Fatalf("GetRepositoryStats: expected 200, got %d", status)
+	}
+	if len(stats.UnscannedProjects) != 1 {
+		t.Fatalf("unscanned_projects = %+v, want /projects/b", stats.UnscannedProjects)
+	}
+	if p := stats.UnscannedProjects[0]; p.Dir != "/projects/b" || p.LastRunID == nil || *p.LastRunID != firstRunID {
+		t.Errorf("unscanned project = %+v, want /projects/b last seen in run %s", p, firstRunID)
+	}
+}
+
+// TestCoverageChanges_SkippedForPartialRuns completes a sharded run whose second shard never
+// reports through the finalizer, and checks it records no coverage change for the dirs only that
+// shard would have sent, and that the next full run is compared with the last full one.
+func TestCoverageChanges_SkippedForPartialRuns(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	ctx := context.Background()
+
+	app := newIngestApp(t)
+	if status, body := postIngest(t, app, seedAnalysisToken, "", sampleState()); status != http.StatusOK {
+		t.Fatalf("first ingest: expected 200, got %d: %s", status, body)
+	}
+	state := sampleState()
+	status, body := postIngest(t, app, seedAnalysisToken, "matrix-finalized",
+		shardState("0", 2, 1, time.Second, state.ProjectResults[0]))
+	if status != http.StatusOK {
+		t.Fatalf("shard 0: expected 200, got %d: %s", status, body)
+	}
+	partialRunID := runIDFromResponse(t, body)
+
+	if _, err := withPool(t).Exec(ctx,
+		`UPDATE drift_analysis_run SET updated_at = NOW() - INTERVAL '2 hours' WHERE uuid = $1::uuid`, partialRunID); err != nil {
+		t.Fatalf("age run: %v", err)
+	}
+	finalized, err := newDriftStateHandler(t, config.Config{}, nil).FinalizeTimedOutShardedRuns(ctx, 60, 100)
+	if err != nil {
+		t.Fatalf("FinalizeTimedOutShardedRuns: %v", err)
+	}
+	if finalized != 1 {
+		t.Fatalf("finalized %d run(s), want 1", finalized)
+	}
+
+	state.ProjectResults = state.ProjectResults[:2]
+	status, body = postIngest(t, app, seedAnalysisToken, "", state)
+	if status != http.StatusOK {
+		t.Fatalf("third ingest: expected 200, got %d: %s", status, body)
+	}
+	lastRunID := runIDFromResponse(t, body)
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+	var run dto.DriftAnalysisRunWithProjectsDTO
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+partialRunID, token, &run); status != http.StatusOK {
+		t.Fatalf("GetRunById: expected 200, got %d", status)
+	}
+	if len(run.CoverageChanges) != 0 {
+		t.Errorf("partial run coverage_changes = %+v, want none", run.CoverageChanges)
+	}
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+lastRunID, token, &run); status != http.StatusOK {
+		t.Fatalf("GetRunById: expected 200, got %d", status)
+	}
+	wantChanges := []dto.CoverageChangeDTO{{Dir: "/projects/c", Type: "TERRAGRUNT", Change: "REMOVED"}}
+	if !slices.Equal(run.CoverageChanges, wantChanges) {
+		t.Errorf("coverage_changes = %+v, want %+v", run.CoverageChanges, wantChanges)
+	}
+}
@@ -0,0 +1,266 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"driftive.cloud/api/pkg/config"
+	"driftive.cloud/api/pkg/middleware/perms"
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository"
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/dispatch"
+	"driftive.cloud/api/pkg/usecase/repos"
+	jwtware "github.com/gofiber/contrib/v3/jwt"
+	"github.com/gofiber/fiber/v3"
+)
+
+// fakeDispatcher records the dispatched events, failing them when err is set.
+type fakeDispatcher struct {
+	events []dispatch.Event
+	err    error
+}
+
+func (f *fakeDispatcher) Dispatch(_ context.Context, event dispatch.Event) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.events = append(f.events, event)
+	return nil
+}
+
+// newDispatchApp builds a Fiber app exposing the dispatch endpoints behind the same JWT and perms
+// middleware as main.go.
+func newDispatchApp(t *testing.T, dispatcher dispatch.Dispatcher) *fiber.App {
+	t.Helper()
+	r := repository.NewRepository(testDB, &config.Config{})
+	handler := dispatch.NewDispatchHandler(r.GitOrgRepository(), r.GitRepoRepository(), r.UserRepository(), r.DriftAnalysisRepository(),
+		func(context.Context, queries.GitOrganization, queries.GitRepository) (dispatch.Dispatcher, error) {
+			return dispatcher, nil
+		})
+	repoHandler := repos.NewGitRepositoryHandler(r.GitOrgRepository(), r.GitRepoRepository(), r.UserRepository(), r.DriftAnalysisRepository())
+	app := fiber.New()
+	app.Use(jwtware.New(jwtware.Config{SigningKey: jwtware.SigningKey{Key: []byte(testJWTSecret)}}))
+	app.Use(perms.New(r.GitOrgRepository()))
+	app.Put("/api/v1/repo/:repo_id/dispatch_settings", func(c fiber.Ctx) error { return repoHandler.UpdateDispatchSettings(c) })
+	app.Get("/api/v1/repo/:repo_id/dispatches", func(c fiber.Ctx) error { return handler.ListDispatches(c) })
+	app.Post("/api/v1/repo/:repo_id/dispatches/scan", func(c fiber.Ctx) error { return handler.DispatchScan(c) })
+	app.Post("/api/v1/repo/:repo_id/dispatches/remediation", func(c fiber.Ctx) error { return handler.DispatchRemediation(c) })
+	return app
+}
+
+// TestDispatch_ScanCorrelatesRun dispatches a scan of a catalog dir and checks the run uploaded
+// with the dispatch id as Idempotency-Key is listed with the dispatch.
+func TestDispatch_ScanCorrelatesRun(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	token := seedMember(t, repoID)
+	ingest := newIngestApp(t)
+	if status, body := postIngest(t, ingest, seedAnalysisToken, "", singleProjectState(driftedProject("/envs/prod", "plan"))); status != http.StatusOK {
+		t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+	}
+
+	dispatcher := &fakeDispatcher{}
+	app := newDispatchApp(t, dispatcher)
+	scanPath := fmt.Sprintf("/api/v1/repo/%d/dispatches/scan", repoID)
+	if status, _ := sendJSON(t, app, http.MethodPost, scanPath, token, dto.ScanDispatchRequest{Dirs: []string{"/envs/unknown"}}); status != http.StatusBadRequest {
+		t.Fatalf("scan of an unknown dir: expected 400, got %d", status)
+	}
+	status, body := sendJSON(t, app, http.MethodPost, scanPath, token, dto.ScanDispatchRequest{Dirs: []string{" /envs/prod", "/envs/prod"}})
+	if status != http.StatusAccepted {
+		t.Fatalf("scan: expected 202, got %d: %s", status, body)
+	}
+	var resp dto.DispatchResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("decode dispatch response: %v", err)
+	}
+	if len(dispatcher.events) != 1 {
+		t.Fatalf("events = %+v, want one", dispatcher.events)
+	}
+	event := dispatcher.events[0]
+	if event.ID.String() != resp.DispatchID || event.Kind != dispatch.KindScan || len(event.Dirs) != 1 || event.Dirs[0] != "/envs/prod" || event.RequestedBy != "member" {
+		t.Errorf("event = %+v, want a scan of /envs/prod by member with id %s", event, resp.DispatchID)
+	}
+
+	listPath := fmt.Sprintf("/api/v1/repo/%d/dispatches", repoID)
+	var dispatches []dto.ScanDispatchDTO
+	if status := getJSON(t, app, listPath, token, &dispatches); status != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d", status)
+	}
+	if len(dispatches) != 1 || dispatches[0].RunID != nil || dispatches[0].RequestedBy != "member" {
+		t.Fatalf("dispatches before the upload = %+v", dispatches)
+	}
+
+	status, body = postIngest(t, ingest, seedAnalysisToken, resp.DispatchID, singleProjectState(cleanProject("/envs/prod")))
+	if status != http.StatusOK {
+		t.Fatalf("dispatched ingest: expected 200, got %d: %s", status, body)
+	}
+	runID := runIDFromResponse(t, body)
+	if status := getJSON(t, app, listPath, token, &dispatches); status != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d", status)
+	}
+	if len(dispatches) != 1 || dispatches[0].RunID == nil || *dispatches[0].RunID != runID || dispatches[0].RunStatus == nil || *dispatches[0].RunStatus != "COMPLETED" {
+		t.Errorf("dispatches after the upload = %+v, want run %s", dispatches, runID)
+	}
+
+	// A dispatch GitHub refuses is not kept.
+	dispatcher.err = errors.New("workflow not found")
+	if status, _ := sendJSON(t, app, http.MethodPost, scanPath, token, dto.ScanDispatchRequest{}); status != http.StatusBadGateway {
+		t.Fatalf("failed dispatch: expected 502, got %d", status)
+	}
+	if status := getJSON(t, app, listPath, token, &dispatches); status != http.StatusOK || len(dispatches) != 1 {
+		t.Errorf("dispatches after a failed one = %+v", dispatches)
+	}
+}
+
+// TestDispatch_ScopedRunIsNotFull uploads a run for a scan dispatch scoped to /projects/a and
+// checks it keeps the dispatch dirs, records no coverage change for the dirs it didn't scan and
+// leaves the stats on the last full run.
+func TestDispatch_ScopedRunIsNotFull(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	token := seedMember(t, repoID)
+	ingest := newIngestApp(t)
+	status, body := postIngest(t, ingest, seedAnalysisToken, "", sampleState())
+	if status != http.StatusOK {
+		t.Fatalf("full ingest: expected 200, got %d: %s", status, body)
+	}
+	fullRunID := runIDFromResponse(t, body)
+
+	app := newDispatchApp(t, &fakeDispatcher{})
+	status, body = sendJSON(t, app, http.MethodPost, fmt.Sprintf("/api/v1/repo/%d/dispatches/scan", repoID), token,
+		dto.ScanDispatchRequest{Dirs: []string{"/projects/a"}})
+	if status != http.StatusAccepted {
+		t.Fatalf("scan: expected 202, got %d: %s", status, body)
+	}
+	var resp dto.DispatchResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("decode dispatch response: %v", err)
+	}
+	state := sampleState()
+	state.ProjectResults = state.ProjectResults[:1]
+	status, body = postIngest(t, ingest, seedAnalysisToken, resp.DispatchID, state)
+	if status != http.StatusOK {
+		t.Fatalf("scoped ingest: expected 200, got %d: %s", status, body)
+	}
+	scopedRunID := runIDFromResponse(t, body)
+
+	var scopedDirs []string
+	if err := withPool(t).QueryRow(context.Background(),
+		`SELECT scoped_dirs FROM drift_analysis_run WHERE uuid = $1::uuid`, scopedRunID).Scan(&scopedDirs); err != nil {
+		t.Fatalf("read scoped_dirs: %v", err)
+	}
+	if len(scopedDirs) != 1 || scopedDirs[0] != "/projects/a" {
+		t.Errorf("scoped_dirs = %v, want [/projects/a]", scopedDirs)
+	}
+
+	dashboard := newDashboardApp(t, nil)
+	var run dto.DriftAnalysisRunWithProjectsDTO
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+scopedRunID, token, &run); status != http.StatusOK {
+		t.Fatalf("GetRunById: expected 200, got %d", status)
+	}
+	if len(run.CoverageChanges) != 0 {
+		t.Errorf("scoped run coverage_changes = %+v, want none", run.CoverageChanges)
+	}
+	var stats dto.RepositoryRunStatsDTO
+	if status := getJSON(t, dashboard, fmt.Sprintf("/api/v1/repo/%d/stats", repoID), token, &stats); status != http.StatusOK {
+		t.Fatalf("GetRepositoryStats: expected 200, got %d", status)
+	}
+	if stats.LatestRun == nil || stats.LatestRun.Uuid != fullRunID {
+		t.Errorf("latest_run = %+v, want the full run %s", stats.LatestRun, fullRunID)
+	}
+	if len(stats.UnscannedProjects) != 0 {
+		t.Errorf("unscanned_projects = %+v, want none", stats.UnscannedProjects)
+	}
+}
+
+// TestDispatch_RemediationOptIn checks remediation applies are refused until the repository opts
+// in, and only dispatched for catalog projects.
+func TestDispatch_RemediationOptIn(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	token := seedAdmin(t, repoID)
+	ingest := newIngestApp(t)
+	if status, body := postIngest(t, ingest, seedAnalysisToken, "", singleProjectState(driftedProject("/envs/prod", "plan"))); status != http.StatusOK {
+		t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+	}
+
+	dispatcher := &fakeDispatcher{}
+	app := newDispatchApp(t, dispatcher)
+	remediatePath := fmt.Sprintf("/api/v1/repo/%d/dispatches/remediation", repoID)
+	settingsPath := fmt.Sprintf("/api/v1/repo/%d/dispatch_settings", repoID)
+	project := dto.RemediationDispatchRequest{Dir: "/envs/prod", Type: "terraform"}
+
+	if status, _ := sendJSON(t, app, http.MethodPost, remediatePath, token, project); status != http.StatusForbidden {
+		t.Fatalf("remediation before opting in: expected 403, got %d", status)
+	}
+	enabled := true
+	badWorkflow := "../drift.yml"
+	if status, _ := sendJSON(t, app, http.MethodPut, settingsPath, token, dto.UpdateDispatchSettingsRequest{Workflow: &badWorkflow, RemediationEnabled: &enabled}); status != http.StatusBadRequest {
+		t.Fatalf("settings with a bad workflow: expected 400, got %d", status)
+	}
+	workflow := "drift.yml"
+	if status, body := sendJSON(t, app, http.MethodPut, settingsPath, token, dto.UpdateDispatchSettingsRequest{Workflow: &workflow, RemediationEnabled: &enabled}); status != http.StatusNoContent {
+		t.Fatalf("settings: expected 204, got %d: %s", status, body)
+	}
+
+	if status, _ := sendJSON(t, app, http.MethodPost, remediatePath, token, dto.RemediationDispatchRequest{Dir: "/envs/prod", Type: "pulumi"}); status != http.StatusNotFound {
+		t.Fatalf("remediation of an unknown project: expected 404, got %d", status)
+	}
+	if status, body := sendJSON(t, app, http.MethodPost, remediatePath, token, project); status != http.StatusAccepted {
+		t.Fatalf("remediation: expected 202, got %d: %s", status, body)
+	}
+	if len(dispatcher.events) != 1 || dispatcher.events[0].Kind != dispatch.KindRemediate || dispatcher.events[0].ProjectType != "TERRAFORM" {
+		t.Errorf("events = %+v, want one terraform remediation", dispatcher.events)
+	}
+
+	r := repository.NewRepository(testDB, &config.Config{})
+	repo, err := r.GitRepoRepository().FindGitRepositoryById(context.Background(), repoID)
+	if err != nil {
+		t.Fatalf("FindGitRepositoryById: %v", err)
+	}
+	if repo.DispatchWorkflow == nil || *repo.DispatchWorkflow != workflow || !repo.RemediationEnabled {
+		t.Errorf("repository settings = %v %v", repo.DispatchWorkflow, repo.RemediationEnabled)
+	}
+}
+
+// TestDispatch_RemediationRequiresAdmin checks a member who isn't an organization admin can neither
+// opt the repository in to remediation applies nor dispatch one.
+func TestDispatch_RemediationRequiresAdmin(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	memberToken := seedMember(t, repoID)
+	adminToken := seedAdmin(t, repoID)
+	ingest := newIngestApp(t)
+	if status, body := postIngest(t, ingest, seedAnalysisToken, "", singleProjectState(driftedProject("/envs/prod", "plan"))); status != http.StatusOK {
+		t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+	}
+
+	dispatcher := &fakeDispatcher{}
+	app := newDispatchApp(t, dispatcher)
+	remediatePath := fmt.Sprintf("/api/v1/repo/%d/dispatches/remediation", repoID)
+	settingsPath := fmt.Sprintf("/api/v1/repo/%d/dispatch_settings", repoID)
+	project := dto.RemediationDispatchRequest{Dir: "/envs/prod", Type: "terraform"}
+	enabled := true
+
+	if status, _ := sendJSON(t, app, http.MethodPut, settingsPath, memberToken, dto.UpdateDispatchSettingsRequest{RemediationEnabled: &enabled}); status != http.StatusForbidden {
+		t.Fatalf("settings by a member: expected 403, got %d", status)
+	}
+	if status, body := sendJSON(t, app, http.MethodPut, settingsPath, adminToken, dto.UpdateDispatchSettingsRequest{RemediationEnabled: &enabled}); status != http.StatusNoContent {
+		t.Fatalf("settings by an admin: expected 204, got %d: %s", status, body)
+	}
+	if status, _ := sendJSON(t, app, http.MethodPost, remediatePath, memberToken, project); status != http.StatusForbidden {
+		t.Fatalf("remediation by a member: expected 403, got %d", status)
+	}
+	if len(dispatcher.events) != 0 {
+		t.Errorf("events = %+v, want none", dispatcher.events)
+	}
+	// Scans stay open to every member.
+	if status, body := sendJSON(t, app, http.MethodPost, fmt.Sprintf("/api/v1/repo/%d/dispatches/scan", repoID), memberToken, dto.ScanDispatchRequest{}); status != http.StatusAccepted {
+		t.Fatalf("scan by a member: expected 202, got %d: %s", status, body)
+	}
+}
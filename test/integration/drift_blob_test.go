@@ -0,0 +1,296 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"driftive.cloud/api/pkg/blobstore"
+	"driftive.cloud/api/pkg/config"
+	"driftive.cloud/api/pkg/middleware/perms"
+	"driftive.cloud/api/pkg/model/auth"
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository"
+	"driftive.cloud/api/pkg/usecase/cleanup"
+	"driftive.cloud/api/pkg/usecase/drift_stream"
+	"driftive.cloud/api/pkg/usecase/outputs"
+	jwtutil "driftive.cloud/api/pkg/usecase/utils/jwt"
+	jwtware "github.com/gofiber/contrib/v3/jwt"
+	"github.com/gofiber/fiber/v3"
+)
+
+const testJWTSecret = "integration-secret-that-is-at-least-32-chars"
+
+// seedMember inserts user "member", a non-admin member of the organization that owns repoID, and
+// returns a JWT for it, accepted by newDashboardApp.
+func seedMember(t *testing.T, repoID int64) string {
+	t.Helper()
+	return seedOrgUser(t, repoID, "member", "900", "MEMBER")
+}
+
+// seedAdmin is seedMember for an admin of the organization, user "admin".
+func seedAdmin(t *testing.T, repoID int64) string {
+	t.Helper()
+	return seedOrgUser(t, repoID, "admin", "901", "ADMIN")
+}
+
+func seedOrgUser(t *testing.T, repoID int64, username, providerID, role string) string {
+	t.Helper()
+	ctx := context.Background()
+	pool := withPool(t)
+
+	var userID int64
+	err := pool.QueryRow(ctx,
+		`INSERT INTO users (provider, provider_id, name, username, email, access_token, refresh_token)
+		 VALUES ('GITHUB', $1, $2, $2, $2 || '@test', 'at', 'rt') RETURNING id`, providerID, username).Scan(&userID)
+	if err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+	if _, err := pool.Exec(ctx,
+		`INSERT INTO user_git_organization (user_id, git_organization_id, role)
+		 SELECT $1, organization_id, $3 FROM git_repository WHERE id = $2`, userID, repoID, role); err != nil {
+		t.Fatalf("seed membership: %v", err)
+	}
+
+	token, err := jwtutil.GenerateJWTToken(auth.UserToken{ID: userID, Provider: "GITHUB"}, testJWTSecret)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	return token
+}
+
+// newDashboardApp builds a Fiber app exposing the authenticated drift read endpoints behind the
+// same JWT and perms middleware as main.go.
+func newDashboardApp(t *testing.T, blobs blobstore.BlobStore) *fiber.App {
+	t.Helper()
+	repos := repository.NewRepository(testDB, &config.Config{})
+	handler := drift_stream.NewDriftStateHandler(
+		&config.Config{Frontend: config.FrontendConfig{FrontendURL: "http://test.local"}},
+		repos.GitOrgRepository(),
+		repos.GitRepoRepository(),
+		repos.DriftAnalysisRepository(),
+		nil,
+		outputs.NewOutputService(repos.DriftAnalysisRepository(), blobs),
+		nil,
+		nil,
+	)
+	app := fiber.New()
+	app.Use(jwtware.New(jwtware.Config{SigningKey: jwtware.SigningKey{Key: []byte(testJWTSecret)}}))
+	app.Use(perms.New(repos.GitOrgRepository()))
+	app.Get("/api/v1/repo/:repo_id/runs", func(c fiber.Ctx) error { return handler.ListRunsByRepoId(c) })
+	app.Get("/api/v1/repo/:repo_id/projects", func(c fiber.Ctx) error { return handler.ListRepositoryProjects(c) })
+	app.Get("/api/v1/repo/:repo_id/projects/history", func(c fiber.Ctx) error { return handler.GetProjectHistory(c) })
+	app.Get("/api/v1/repo/:repo_id/projects/tree", func(c fiber.Ctx) error { return handler.GetProjectTree(c) })
+	app.Get("/api/v1/repo/:repo_id/projects/tree/trends", func(c fiber.Ctx) error { return handler.GetSubtreeTrends(c) })
+	app.Get("/api/v1/repo/:repo_id/owners", func(c fiber.Ctx) error { return handler.ListRepositoryOwners(c) })
+	app.Get("/api/v1/repo/:repo_id/stats", func(c fiber.Ctx) error { return handler.GetRepositoryStats(c) })
+	app.Get("/api/v1/repo/:repo_id/trends", func(c fiber.Ctx) error { return handler.GetRepositoryTrends(c) })
+	app.Get("/api/v1/repo/:repo_id/trends/resources", func(c fiber.Ctx) error { return handler.GetRepositoryResourceTrends(c) })
+	app.Get("/api/v1/repo/:repo_id/trends/durations", func(c fiber.Ctx) error { return handler.GetRepositoryDurationTrends(c) })
+	app.Get("/api/v1/repo/:repo_id/trends/warnings", func(c fiber.Ctx) error { return handler.GetRepositoryWarningTrends(c) })
+	app.Get("/api/v1/repo/:repo_id/trends/owner", func(c fiber.Ctx) error { return handler.GetOwnerTrends(c) })
+	app.Get("/api/v1/org/:org_id/trends/resources", func(c fiber.Ctx) error { return handler.GetOrganizationResourceTrends(c) })
+	app.Get("/api/v1/org/:org_id/inventory/versions", func(c fiber.Ctx) error { return handler.GetOrganizationVersionInventory(c) })
+	app.Get("/api/v1/org/:org_id/inventory/outdated", func(c fiber.Ctx) error { return handler.GetOrganizationOutdatedVersions(c) })
+	app.Get("/api/v1/analysis/run/:run_id", func(c fiber.Ctx) error { return handler.GetRunById(c) })
+	app.Get("/api/v1/analysis/run/:run_id/output", func(c fiber.Ctx) error { return handler.GetRunProjectOutput(c) })
+	app.Get("/api/v1/analysis/run/:run_id/diff", func(c fiber.Ctx) error { return handler.GetRunProjectOutputDiff(c) })
+	return app
+}
+
+// getJSON performs an authenticated GET and decodes a 200 response into out.
+func getJSON(t *testing.T, app *fiber.App, path, token string, out any) int {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 30 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusOK && out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			t.Fatalf("decode %s: %v\n%s", path, err, body)
+		}
+	}
+	return resp.StatusCode
+}
+
+// sendJSON performs an authenticated request with a JSON body.
+func sendJSON(t *testing.T, app *fiber.App, method, path, token string, body any) (int, []byte) {
+	t.Helper()
+	buf, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("marshal body: %v", err)
+	}
+	req := httptest.NewRequestWithContext(context.Background(), method, path, bytes.NewReader(buf))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 30 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, respBody
+}
+
+func countQueuedBlobDeletions(t *testing.T) int {
+	t.Helper()
+	var n int
+	if err := withPool(t).QueryRow(context.Background(),
+		`SELECT count(*) FROM output_blob_deletion`).Scan(&n); err != nil {
+		t.Fatalf("count queued blob deletions: %v", err)
+	}
+	return n
+}
+
+// TestBlobStore_OutputsAreOffloadedAndServedLazily checks that with a blob store configured only
+// references and sizes reach Postgres, and GetRunById still returns the full outputs.
+func TestBlobStore_OutputsAreOffloadedAndServedLazily(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	blobs, err := blobstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+
+	status, body := postIngest(t, newIngestAppWithOptions(t, config.IngestConfig{}, blobs), seedAnalysisToken, "", sampleState())
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	runID := runIDFromResponse(t, body)
+
+	var inlinePlan, blobRef *string
+	var data []byte
+	var planSize *int64
+	if err := withPool(t).QueryRow(context.Background(),
+		`SELECT p.plan_output, o.data, o.blob_ref, p.plan_output_size
+		 FROM drift_analysis_project p JOIN command_output o ON o.id = p.plan_output_id
+		 WHERE p.drift_analysis_run_id = $1 AND p.dir = '/projects/a'`, runID).
+		Scan(&inlinePlan, &data, &blobRef, &planSize); err != nil {
+		t.Fatalf("fetch project: %v", err)
+	}
+	if inlinePlan != nil || data != nil || blobRef == nil {
+		t.Fatalf("expected only a reference in Postgres, got inline %v data %d bytes ref %v", inlinePlan, len(data), blobRef)
+	}
+	if planSize == nil || *planSize != int64(len("plan-a")) {
+		t.Errorf("expected plan_output_size %d, got %v", len("plan-a"), planSize)
+	}
+
+	var run dto.DriftAnalysisRunWithProjectsDTO
+	if status := getJSON(t, newDashboardApp(t, blobs), "/api/v1/analysis/run/"+runID, seedMember(t, repoID), &run); status != http.StatusOK {
+		t.Fatalf("GetRunById: expected 200, got %d", status)
+	}
+	for _, p := range run.Projects {
+		if p.Dir == "/projects/a" {
+			if p.PlanOutput == nil || *p.PlanOutput != "plan-a" || p.InitOutput == nil || *p.InitOutput != "init-a" {
+				t.Errorf("expected hydrated outputs, got init %v plan %v", p.InitOutput, p.PlanOutput)
+			}
+			return
+		}
+	}
+	t.Fatalf("project /projects/a missing from %+v", run.Projects)
+}
+
+// TestBlobStore_DeletedRunsReleaseTheirBlobs erases a repository's runs and checks that the
+// cascade queues every blob and the reaper removes them from the store.
+func TestBlobStore_DeletedRunsReleaseTheirBlobs(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	blobs, err := blobstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+
+	status, body := postIngest(t, newIngestAppWithOptions(t, config.IngestConfig{}, blobs), seedAnalysisToken, "", sampleState())
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+
+	var refs []string
+	rows, err := withPool(t).Query(context.Background(), `SELECT blob_ref FROM command_output`)
+	if err != nil {
+		t.Fatalf("list refs: %v", err)
+	}
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			t.Fatalf("scan ref: %v", err)
+		}
+		refs = append(refs, ref)
+	}
+	rows.Close()
+	// Project c was skipped and sent empty outputs, which stay inline.
+	if len(refs) != 4 {
+		t.Fatalf("expected 4 blob references, got %v", refs)
+	}
+
+	driftRepo := newDriftRepo(t)
+	if err := driftRepo.DeleteDriftAnalysisRunsByRepositoryId(context.Background(), repoID); err != nil {
+		t.Fatalf("DeleteDriftAnalysisRunsByRepositoryId: %v", err)
+	}
+	if n := countQueuedBlobDeletions(t); n != 4 {
+		t.Fatalf("expected 4 queued blob deletions, got %d", n)
+	}
+
+	reaped, err := cleanup.NewCleanupService(driftRepo, 400, 15, blobs).ReapOutputBlobs(context.Background(), 100)
+	if err != nil || reaped != 4 {
+		t.Fatalf("ReapOutputBlobs = %d, %v; want 4", reaped, err)
+	}
+	if n := countQueuedBlobDeletions(t); n != 0 {
+		t.Errorf("expected an empty queue after reaping, got %d", n)
+	}
+	for _, ref := range refs {
+		if _, err := blobs.Get(context.Background(), ref); !errors.Is(err, blobstore.ErrNotFound) {
+			t.Errorf("blob %s should be gone, got %v", ref, err)
+		}
+	}
+}
+
+// TestBlobStore_ResentOutputSurvivesQueuedDeletion collects an output, then sends it again before
+// the reaper runs: the new write must take the key off the queue so the blob is not deleted.
+func TestBlobStore_ResentOutputSurvivesQueuedDeletion(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	blobs, err := blobstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	app := newIngestAppWithOptions(t, config.IngestConfig{}, blobs)
+
+	if status, body := postIngest(t, app, seedAnalysisToken, "", sampleState()); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	driftRepo := newDriftRepo(t)
+	if err := driftRepo.DeleteDriftAnalysisRunsByRepositoryId(context.Background(), repoID); err != nil {
+		t.Fatalf("DeleteDriftAnalysisRunsByRepositoryId: %v", err)
+	}
+
+	status, body := postIngest(t, app, seedAnalysisToken, "", sampleState())
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	if n := countQueuedBlobDeletions(t); n != 0 {
+		t.Fatalf("expected the re-sent outputs to be dequeued, got %d queued deletions", n)
+	}
+	if _, err := cleanup.NewCleanupService(driftRepo, 400, 15, blobs).ReapOutputBlobs(context.Background(), 100); err != nil {
+		t.Fatalf("ReapOutputBlobs: %v", err)
+	}
+	var ref string
+	if err := withPool(t).QueryRow(context.Background(),
+		`SELECT o.blob_ref FROM drift_analysis_project p JOIN command_output o ON o.id = p.plan_output_id
+		 WHERE p.drift_analysis_run_id = $1 AND p.dir = '/projects/a'`, runIDFromResponse(t, body)).Scan(&ref); err != nil {
+		t.Fatalf("fetch blob ref: %v", err)
+	}
+	if _, err := blobs.Get(context.Background(), ref); err != nil {
+		t.Errorf("blob %s should still exist, got %v", ref, err)
+	}
+}
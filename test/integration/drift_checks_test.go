@@ -0,0 +1,234 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"driftive.cloud/api/pkg/config"
+	"driftive.cloud/api/pkg/repository"
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/checks"
+	"driftive.cloud/api/pkg/usecase/drift_stream"
+	"github.com/google/uuid"
+)
+
+// fakePublisher records the check run calls, numbering created check runs from 101. Updates fail
+// with fail while it is set.
+type fakePublisher struct {
+	calls  []string
+	checks []checks.CheckRun
+	nextID int64
+	fail   error
+}
+
+func (f *fakePublisher) Create(_ context.Context, headSHA string, check checks.CheckRun) (int64, error) {
+	f.nextID++
+	id := 100 + f.nextID
+	f.calls = append(f.calls, fmt.Sprintf("create %d %s %s", id, headSHA, checkStatus(check)))
+	f.checks = append(f.checks, check)
+	return id, nil
+}
+
+func (f *fakePublisher) Update(_ context.Context, id int64, check checks.CheckRun) error {
+	if f.fail != nil {
+		return f.fail
+	}
+	f.calls = append(f.calls, fmt.Sprintf("update %d %s", id, checkStatus(check)))
+	f.checks = append(f.checks, check)
+	return nil
+}
+
+func checkStatus(check checks.CheckRun) string {
+	if check.Conclusion == "" {
+		return "in_progress"
+	}
+	return check.Conclusion
+}
+
+func newCheckService(repos repository.Repository, publisher checks.Publisher) *checks.CheckService {
+	return checks.NewCheckService(repos.DriftAnalysisRepository(), repos.GitOrgRepository(), repos.GitRepoRepository(),
+		func(context.Context, queries.GitOrganization, queries.GitRepository) (checks.Publisher, error) {
+			return publisher, nil
+		})
+}
+
+func orgAndRepo(t *testing.T, repos repository.Repository, repoID int64) (queries.GitOrganization, queries.GitRepository) {
+	t.Helper()
+	ctx := context.Background()
+	repo, err := repos.GitRepoRepository().FindGitRepositoryById(ctx, repoID)
+	if err != nil {
+		t.Fatalf("FindGitRepositoryById: %v", err)
+	}
+	org, err := repos.GitOrgRepository().FindGitOrgById(ctx, repo.OrganizationID)
+	if err != nil {
+		t.Fatalf("FindGitOrgById: %v", err)
+	}
+	return org, repo
+}
+
+// TestDriftChecks_ProgressThenComplete starts a check run from the first progress tick carrying the
+// commit, then completes it from the final upload of the same scan.
+func TestDriftChecks_ProgressThenComplete(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	ctx := context.Background()
+	repos := repository.NewRepository(testDB, &config.Config{})
+	org, repo := orgAndRepo(t, repos, repoID)
+	publisher := &fakePublisher{}
+	svc := newCheckService(repos, publisher)
+	app := newIngestApp(t)
+
+	sha := strings.Repeat("ab", 20)
+	status, body := postProgress(t, app, seedAnalysisToken, "scan-1", drift_stream.DriftProgressRequest{
+		TotalProjects: 2,
+		Running:       []string{"/envs/prod"},
+		CommitSHA:     &sha,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("progress: expected 200, got %d: %s", status, body)
+	}
+	runID := uuid.MustParse(runIDFromResponse(t, body))
+	if err := svc.StartRun(ctx, org, repo, runID, sha, "http://test.local/run"); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+
+	state := singleProjectState(driftedProject("/envs/prod", "plan"))
+	state.ProjectResults = append(state.ProjectResults, cleanProject("/envs/dev"))
+	state.TotalProjects, state.TotalChecked = 2, 2
+	status, body = postIngest(t, app, seedAnalysisToken, "scan-1", state)
+	if status != http.StatusOK {
+		t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+	}
+	if err := svc.CompleteRun(ctx, org, repo, runID, "http://test.local/run"); err != nil {
+		t.Fatalf("CompleteRun: %v", err)
+	}
+
+	want := []string{"create 101 " + sha + " in_progress", "update 101 failure"}
+	if strings.Join(publisher.calls, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("calls = %v, want %v", publisher.calls, want)
+	}
+	final := publisher.checks[1]
+	if final.Title != "1 of 2 projects drifted" || final.ExternalID != runID.String() || !strings.Contains(final.Summary, "| /envs/prod | terraform |") {
+		t.Errorf("completed check = %+v", final)
+	}
+
+	run, err := repos.DriftAnalysisRepository().FindDriftAnalysisRunByUUID(ctx, runID)
+	if err != nil {
+		t.Fatalf("FindDriftAnalysisRunByUUID: %v", err)
+	}
+	if run.CommitSha == nil || *run.CommitSha != sha || run.CheckRunID == nil || *run.CheckRunID != 101 {
+		t.Errorf("run commit = %v, check run = %v, want %s and 101", run.CommitSha, run.CheckRunID, sha)
+	}
+}
+
+// TestDriftChecks_WithoutProgress checks that an upload with a commit gets a completed check run
+// created, and one without a commit none.
+func TestDriftChecks_WithoutProgress(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	ctx := context.Background()
+	repos := repository.NewRepository(testDB, &config.Config{})
+	org, repo := orgAndRepo(t, repos, repoID)
+	publisher := &fakePublisher{}
+	svc := newCheckService(repos, publisher)
+	app := newIngestApp(t)
+	ingest := func(state drift_stream.DriftDetectionResult) uuid.UUID {
+		t.Helper()
+		status, body := postIngest(t, app, seedAnalysisToken, "", state)
+		if status != http.StatusOK {
+			t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+		}
+		runID := uuid.MustParse(runIDFromResponse(t, body))
+		if err := svc.CompleteRun(ctx, org, repo, runID, "http://test.local/run"); err != nil {
+			t.Fatalf("CompleteRun: %v", err)
+		}
+		return runID
+	}
+
+	ingest(singleProjectState(cleanProject("/envs/prod")))
+	if len(publisher.calls) != 0 {
+		t.Fatalf("run without commit: calls = %v, want none", publisher.calls)
+	}
+
+	// Uppercase hex is accepted and stored lowercased.
+	sha := strings.Repeat("CD", 20)
+	state := singleProjectState(cleanProject("/envs/prod"))
+	state.CommitSHA = &sha
+	ingest(state)
+	if want := "create 101 " + strings.ToLower(sha) + " success"; len(publisher.calls) != 1 || publisher.calls[0] != want {
+		t.Errorf("calls = %v, want [%s]", publisher.calls, want)
+	}
+}
+
+// TestDriftChecks_StaleRunTimesOut checks that sweeping a run abandoned after it started a check
+// run queues that check run, and that it is concluded as timed out once GitHub accepts the update.
+func TestDriftChecks_StaleRunTimesOut(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	ctx := context.Background()
+	repos := repository.NewRepository(testDB, &config.Config{})
+	org, repo := orgAndRepo(t, repos, repoID)
+	publisher := &fakePublisher{}
+	svc := newCheckService(repos, publisher)
+	app := newIngestApp(t)
+
+	sha := strings.Repeat("ef", 20)
+	status, body := postProgress(t, app, seedAnalysisToken, "abandoned", drift_stream.DriftProgressRequest{
+		TotalProjects: 1,
+		Running:       []string{"/envs/prod"},
+		CommitSHA:     &sha,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("progress: expected 200, got %d: %s", status, body)
+	}
+	runID := uuid.MustParse(runIDFromResponse(t, body))
+	if err := svc.StartRun(ctx, org, repo, runID, sha, "http://test.local/run"); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	if _, err := withPool(t).Exec(ctx,
+		`UPDATE drift_analysis_run SET updated_at = NOW() - INTERVAL '1 hour' WHERE uuid = $1`, runID); err != nil {
+		t.Fatalf("age run: %v", err)
+	}
+
+	// The sweeper queues the check run with the run it deletes; a GitHub outage keeps it queued.
+	swept, err := repos.DriftAnalysisRepository().DeleteStaleRunningRuns(ctx, sweepStaleMinutes, 100)
+	if err != nil {
+		t.Fatalf("DeleteStaleRunningRuns: %v", err)
+	}
+	if swept != 1 || runExists(t, runID.String()) {
+		t.Fatalf("swept %d run(s), want the abandoned one", swept)
+	}
+	publisher.fail = errors.New("GitHub is down")
+	if claimed, err := svc.ReportTimeouts(ctx, 100); err != nil || claimed != 1 {
+		t.Fatalf("ReportTimeouts = %d, %v; want 1", claimed, err)
+	}
+	var attempts int32
+	if err := withPool(t).QueryRow(ctx, `SELECT attempts FROM check_run_timeout WHERE check_run_id = 101`).Scan(&attempts); err != nil || attempts != 1 {
+		t.Fatalf("queued check run attempts = %d, %v; want 1", attempts, err)
+	}
+	// Not retried before its backoff elapses.
+	if claimed, err := svc.ReportTimeouts(ctx, 100); err != nil || claimed != 0 {
+		t.Fatalf("ReportTimeouts during backoff = %d, %v; want 0", claimed, err)
+	}
+
+	publisher.fail = nil
+	if _, err := withPool(t).Exec(ctx, `UPDATE check_run_timeout SET retry_at = NOW()`); err != nil {
+		t.Fatalf("end backoff: %v", err)
+	}
+	if claimed, err := svc.ReportTimeouts(ctx, 100); err != nil || claimed != 1 {
+		t.Fatalf("ReportTimeouts = %d, %v; want 1", claimed, err)
+	}
+	var queued int
+	if err := withPool(t).QueryRow(ctx, `SELECT count(*) FROM check_run_timeout`).Scan(&queued); err != nil || queued != 0 {
+		t.Fatalf("queued check runs = %d, %v; want none", queued, err)
+	}
+
+	want := []string{"create 101 " + sha + " in_progress", "update 101 timed_out"}
+	if strings.Join(publisher.calls, ", ") != strings.Join(want, ", ") {
+		t.Errorf("calls = %v, want %v", publisher.calls, want)
+	}
+}
@@ -0,0 +1,69 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/usecase/drift_stream"
+)
+
+// TestDriftFingerprint_UnchangedDriftIsFlagged ingests the same drift twice, then a change to it,
+// and checks only the repeat is reported as unchanged, in the ingest response and in the run.
+func TestDriftFingerprint_UnchangedDriftIsFlagged(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	app := newIngestApp(t)
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+
+	ingest := func(plan string) (runID string, unchanged bool) {
+		t.Helper()
+		state := sampleState()
+		state.ProjectResults[0].PlanOutput = plan
+		status, body := postIngest(t, app, seedAnalysisToken, "", state)
+		if status != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", status, body)
+		}
+		var resp drift_stream.DriftAnalysisResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("decode response %s: %v", body, err)
+		}
+		return resp.RunID, resp.DriftUnchanged
+	}
+	projectA := func(runID string) dto.DriftAnalysisProjectDTO {
+		t.Helper()
+		var run dto.DriftAnalysisRunWithProjectsDTO
+		if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+runID, token, &run); status != http.StatusOK {
+			t.Fatalf("GetRunById: expected 200, got %d", status)
+		}
+		for _, p := range run.Projects {
+			if p.Dir == "/projects/a" {
+				return p
+			}
+		}
+		t.Fatalf("project /projects/a missing from %+v", run.Projects)
+		return dto.DriftAnalysisProjectDTO{}
+	}
+
+	if _, unchanged := ingest(resourceChangesPlan); unchanged {
+		t.Error("first run: drift reported as unchanged")
+	}
+	runID, unchanged := ingest(strings.Replace(resourceChangesPlan, "instance_type = ", "instance_type   = ", 1))
+	if !unchanged {
+		t.Error("second run: expected the repeated drift to be reported as unchanged")
+	}
+	if p := projectA(runID); !p.DriftUnchanged || p.DriftFingerprint == nil {
+		t.Errorf("second run: expected an unchanged fingerprinted project, got %+v", p)
+	}
+
+	runID, unchanged = ingest(strings.Replace(resourceChangesPlan, `"t3.small"`, `"t3.large"`, 1))
+	if unchanged {
+		t.Error("third run: changed drift reported as unchanged")
+	}
+	if p := projectA(runID); p.DriftUnchanged {
+		t.Errorf("third run: expected the project's drift to be new, got %+v", p)
+	}
+}
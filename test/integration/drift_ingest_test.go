@@ -4,17 +4,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"sync"
 	"testing"
 	"time"
 
+	"driftive.cloud/api/pkg/blobstore"
 	"driftive.cloud/api/pkg/config"
 	"driftive.cloud/api/pkg/repository"
 	"driftive.cloud/api/pkg/usecase/cleanup"
 	"driftive.cloud/api/pkg/usecase/drift_stream"
+	"driftive.cloud/api/pkg/usecase/outputs"
 	"github.com/gofiber/fiber/v3"
 )
 
@@ -53,26 +57,55 @@ func seedOrgAndRepo(t *testing.T) (repoID int64) {
 	return repoID
 }
 
-// newIngestApp builds a minimal Fiber app exposing the drift ingest and progress endpoints
-// against the shared testDB. Mirrors the public-route registration in main.go.
+// newIngestApp builds a minimal Fiber app exposing the drift ingest, progress and stream
+// endpoints against the shared testDB. Mirrors the public-route registration in main.go.
 func newIngestApp(t *testing.T) *fiber.App {
+	t.Helper()
+	return newIngestAppWithOptions(t, config.IngestConfig{}, nil)
+}
+
+// newIngestAppWithLimits is newIngestApp with ingest size limits; the zero value disables them.
+func newIngestAppWithLimits(t *testing.T, ingest config.IngestConfig) *fiber.App {
+	t.Helper()
+	return newIngestAppWithOptions(t, ingest, nil)
+}
+
+// newIngestAppWithOptions is newIngestApp with ingest size limits and a blob store for outputs.
+func newIngestAppWithOptions(t *testing.T, ingest config.IngestConfig, blobs blobstore.BlobStore) *fiber.App {
+	t.Helper()
+	return newIngestAppWithConfig(t, config.Config{Ingest: ingest}, blobs)
+}
+
+// newIngestAppWithConfig is newIngestApp with an arbitrary config; the frontend URL is filled in.
+func newIngestAppWithConfig(t *testing.T, cfg config.Config, blobs blobstore.BlobStore) *fiber.App {
+	t.Helper()
+	handler := newDriftStateHandler(t, cfg, blobs)
+	app := fiber.New(fiber.Config{StreamRequestBody: true})
+	app.Post("/api/v1/drift_analysis", func(c fiber.Ctx) error { return handler.HandleUpdate(c) })
+	app.Post("/api/v1/drift_analysis/progress", func(c fiber.Ctx) error { return handler.HandleProgress(c) })
+	app.Post("/api/v1/drift_analysis/stream", func(c fiber.Ctx) error { return handler.HandleStream(c) })
+	app.Post("/api/v2/drift_analysis", func(c fiber.Ctx) error { return handler.HandleUpdateV2(c) })
+	app.Get("/api/v2/drift_analysis/schema", func(c fiber.Ctx) error { return handler.GetSchema(c) })
+	return app
+}
+
+// newDriftStateHandler wires a DriftStateHandler against the shared testDB; the frontend URL is
+// filled in.
+func newDriftStateHandler(t *testing.T, cfg config.Config, blobs blobstore.BlobStore) *drift_stream.DriftStateHandler {
 	t.Helper()
 	repos := repository.NewRepository(testDB, &config.Config{})
-	cleanupSvc := cleanup.NewCleanupService(repos.DriftAnalysisRepository(), 400, 15)
-	cfg := &config.Config{
-		Frontend: config.FrontendConfig{FrontendURL: "http://test.local"},
-	}
-	handler := drift_stream.NewDriftStateHandler(
-		cfg,
+	cleanupSvc := cleanup.NewCleanupService(repos.DriftAnalysisRepository(), 400, 15, blobs)
+	cfg.Frontend = config.FrontendConfig{FrontendURL: "http://test.local"}
+	return drift_stream.NewDriftStateHandler(
+		&cfg,
 		repos.GitOrgRepository(),
 		repos.GitRepoRepository(),
 		repos.DriftAnalysisRepository(),
 		cleanupSvc,
+		outputs.NewOutputService(repos.DriftAnalysisRepository(), blobs),
+		nil,
+		nil,
 	)
-	app := fiber.New()
-	app.Post("/api/v1/drift_analysis", func(c fiber.Ctx) error { return handler.HandleUpdate(c) })
-	app.Post("/api/v1/drift_analysis/progress", func(c fiber.Ctx) error { return handler.HandleProgress(c) })
-	return app
 }
 
 func sampleState() drift_stream.DriftDetectionResult {
@@ -207,7 +240,7 @@ func TestDriftIngest_HappyPath(t *testing.T) {
 	}
 
 	rows, err := pool.Query(ctx,
-		`SELECT p.dir, p.type, p.drifted, p.succeeded, p.init_output, p.plan_output, p.skipped_due_to_pr
+		`SELECT p.dir, p.type, p.drifted, p.succeeded, p.skipped_due_to_pr
 		 FROM drift_analysis_project p
 		 JOIN drift_analysis_run r ON r.uuid = p.drift_analysis_run_id
 		 WHERE r.repository_id = $1
@@ -221,12 +254,14 @@ func TestDriftIngest_HappyPath(t *testing.T) {
 		drifted, succeeded, skipd bool
 		initOut, planOut          *string
 	}
+	stored := storedOutputs(t, got.RunID)
 	var gotRows []projRow
 	for rows.Next() {
 		var r projRow
-		if err := rows.Scan(&r.dir, &r.ptype, &r.drifted, &r.succeeded, &r.initOut, &r.planOut, &r.skipd); err != nil {
+		if err := rows.Scan(&r.dir, &r.ptype, &r.drifted, &r.succeeded, &r.skipd); err != nil {
 			t.Fatalf("scan: %v", err)
 		}
+		r.initOut, r.planOut = stored[r.dir][0], stored[r.dir][1]
 		gotRows = append(gotRows, r)
 	}
 	wantRows := []projRow{
@@ -336,6 +371,69 @@ func TestDriftIngest_ParsesResourceCounts(t *testing.T) {
 	}
 }
 
+// TestDriftIngest_MixedToolRepository ingests Pulumi and CloudFormation results next to a
+// Terraform one and checks each is stored with its type and counts from its own parser.
+func TestDriftIngest_MixedToolRepository(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+
+	totalErrored := int32(0)
+	state := drift_stream.DriftDetectionResult{
+		ProjectResults: []drift_stream.DriftProjectResult{
+			{
+				Project:    drift_stream.TypedProject{Dir: "/stacks/cfn", Type: drift_stream.CloudFormation},
+				Drifted:    true,
+				Succeeded:  true,
+				PlanOutput: `{"StackResourceDrifts": [{"StackResourceDriftStatus": "MODIFIED"}, {"StackResourceDriftStatus": "DELETED"}]}`,
+			},
+			{
+				Project:    drift_stream.TypedProject{Dir: "/stacks/pulumi", Type: drift_stream.Pulumi},
+				Drifted:    true,
+				Succeeded:  true,
+				PlanOutput: "Resources:\n    + 1 to create\n    +-2 to replace\n    4 unchanged\n",
+			},
+			{
+				Project:    drift_stream.TypedProject{Dir: "/stacks/tf", Type: drift_stream.Terraform},
+				Drifted:    true,
+				Succeeded:  true,
+				PlanOutput: "Plan: 0 to add, 1 to change, 0 to destroy.",
+			},
+		},
+		TotalDrifted:  3,
+		TotalErrored:  &totalErrored,
+		TotalProjects: 3,
+		TotalChecked:  3,
+	}
+	status, body := postIngest(t, newIngestApp(t), seedAnalysisToken, "", state)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, string(body))
+	}
+
+	rows, err := withPool(t).Query(context.Background(),
+		`SELECT p.type, p.resources_added, p.resources_changed, p.resources_destroyed
+		 FROM drift_analysis_project p
+		 JOIN drift_analysis_run r ON r.uuid = p.drift_analysis_run_id
+		 WHERE r.repository_id = $1
+		 ORDER BY p.dir`, repoID)
+	if err != nil {
+		t.Fatalf("query projects: %v", err)
+	}
+	defer rows.Close()
+	want := []string{"CLOUDFORMATION 0/1/1", "PULUMI 3/0/2", "TERRAFORM 0/1/0"}
+	var got []string
+	for rows.Next() {
+		var projectType string
+		var add, chg, dst int32
+		if err := rows.Scan(&projectType, &add, &chg, &dst); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		got = append(got, fmt.Sprintf("%s %d/%d/%d", projectType, add, chg, dst))
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
 func TestDriftIngest_InvalidToken(t *testing.T) {
 	truncateAll(t)
 	seedOrgAndRepo(t)
@@ -0,0 +1,122 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"driftive.cloud/api/pkg/usecase/drift_stream"
+	"github.com/gofiber/fiber/v3"
+)
+
+func postIngestV2(t *testing.T, app *fiber.App, token string, body any) (int, []byte) {
+	t.Helper()
+	return postJSON(t, app, "/api/v2/drift_analysis", token, "", body)
+}
+
+func validationErrors(t *testing.T, body []byte) map[string]string {
+	t.Helper()
+	var resp struct {
+		Status string                           `json:"status"`
+		Data   drift_stream.ValidationErrorData `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("decode error body %s: %v", body, err)
+	}
+	if resp.Status != "error" || resp.Data.Schema == "" {
+		t.Fatalf("unexpected error body %s", body)
+	}
+	codes := make(map[string]string, len(resp.Data.Errors))
+	for _, e := range resp.Data.Errors {
+		codes[e.Field] = e.Code
+	}
+	return codes
+}
+
+func TestIngestV2_AcceptsValidUpload(t *testing.T) {
+	truncateAll(t)
+	seedOrgAndRepo(t)
+
+	status, body := postIngestV2(t, newIngestApp(t), seedAnalysisToken, sampleState())
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	if got := storedOutputs(t, runIDFromResponse(t, body)); len(got) != 3 {
+		t.Errorf("expected 3 stored projects, got %d", len(got))
+	}
+}
+
+// TestIngestV2_ListsEveryInvalidField checks a payload v1 would have failed with a bare 400 or a
+// 500 from the database is rejected with each problem named, and nothing is stored.
+func TestIngestV2_ListsEveryInvalidField(t *testing.T) {
+	truncateAll(t)
+	seedOrgAndRepo(t)
+
+	state := sampleState()
+	state.ProjectResults[1].Project.Dir = state.ProjectResults[0].Project.Dir
+	state.ProjectResults[2].Project.Dir = strings.Repeat("x", 1501)
+	state.TotalDrifted = 2
+
+	status, body := postIngestV2(t, newIngestApp(t), seedAnalysisToken, state)
+	if status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", status, body)
+	}
+	codes := validationErrors(t, body)
+	want := map[string]string{
+		"project_results[1].project.dir": "duplicate",
+		"project_results[2].project.dir": "too_long",
+		"total_drifted":                  "inconsistent",
+	}
+	for field, code := range want {
+		if codes[field] != code {
+			t.Errorf("%s: got code %q, want %q (all: %v)", field, codes[field], code, codes)
+		}
+	}
+
+	var runs int
+	if err := withPool(t).QueryRow(context.Background(), `SELECT count(*) FROM drift_analysis_run`).Scan(&runs); err != nil {
+		t.Fatalf("count runs: %v", err)
+	}
+	if runs != 0 {
+		t.Errorf("expected no run to be stored, got %d", runs)
+	}
+}
+
+func TestIngestV2_MalformedBody(t *testing.T) {
+	truncateAll(t)
+	seedOrgAndRepo(t)
+
+	body := map[string]any{"project_results": []any{map[string]any{"project": map[string]any{"dir": 42}}}}
+	status, resp := postIngestV2(t, newIngestApp(t), seedAnalysisToken, body)
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", status, resp)
+	}
+	if codes := validationErrors(t, resp); codes["project_results[0].project.dir"] != "invalid_type" {
+		t.Errorf("expected an invalid_type error on the dir, got %v", codes)
+	}
+}
+
+func TestIngestV2_ServesSchema(t *testing.T) {
+	if testDB == nil {
+		t.Skip("integration tests skipped (no testDB)")
+	}
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/v2/drift_analysis/schema", nil)
+	resp, err := newIngestApp(t).Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/schema+json" {
+		t.Fatalf("unexpected response %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(body, &schema); err != nil || schema["$id"] != "/api/v2/drift_analysis/schema" {
+		t.Errorf("unexpected schema %s: %v", body, err)
+	}
+}
@@ -0,0 +1,344 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"driftive.cloud/api/pkg/config"
+	"driftive.cloud/api/pkg/middleware/perms"
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository"
+	"driftive.cloud/api/pkg/repository/queries"
+	"driftive.cloud/api/pkg/usecase/drift_stream"
+	"driftive.cloud/api/pkg/usecase/issues"
+	"driftive.cloud/api/pkg/usecase/outputs"
+	"driftive.cloud/api/pkg/usecase/repos"
+	jwtware "github.com/gofiber/contrib/v3/jwt"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// fakeTracker records the issue calls, numbering opened issues from 1. existing holds the issues
+// already open on GitHub by title.
+type fakeTracker struct {
+	calls    []string
+	bodies   []string
+	opened   int
+	existing map[string]int
+}
+
+func (f *fakeTracker) Find(_ context.Context, title string) (int, error) {
+	return f.existing[title], nil
+}
+
+func (f *fakeTracker) Open(_ context.Context, title, body string) (int, error) {
+	f.opened++
+	f.calls = append(f.calls, fmt.Sprintf("open #%d %s", f.opened, title))
+	f.bodies = append(f.bodies, body)
+	return f.opened, nil
+}
+
+func (f *fakeTracker) Comment(_ context.Context, number int, body string) error {
+	f.calls = append(f.calls, fmt.Sprintf("comment #%d", number))
+	f.bodies = append(f.bodies, body)
+	return nil
+}
+
+func (f *fakeTracker) Close(_ context.Context, number int, body string) error {
+	f.calls = append(f.calls, fmt.Sprintf("close #%d", number))
+	f.bodies = append(f.bodies, body)
+	return nil
+}
+
+func newIssueService(repos repository.Repository, tracker issues.Tracker) *issues.IssueService {
+	driftRepo := repos.DriftAnalysisRepository()
+	return issues.NewIssueService(driftRepo, outputs.NewOutputService(driftRepo, nil),
+		func(context.Context, queries.GitOrganization, queries.GitRepository) (issues.Tracker, error) {
+			return tracker, nil
+		})
+}
+
+func singleProjectState(p drift_stream.DriftProjectResult) drift_stream.DriftDetectionResult {
+	state := drift_stream.DriftDetectionResult{
+		ProjectResults: []drift_stream.DriftProjectResult{p},
+		TotalProjects:  1,
+		TotalChecked:   1,
+		Duration:       100 * time.Millisecond,
+	}
+	if p.Drifted {
+		state.TotalDrifted = 1
+	}
+	return state
+}
+
+// TestDriftIssues_Lifecycle ingests runs of one project drifting, drifting the same, drifting
+// differently, clean and drifting again, and checks the issue is opened, left alone, commented on,
+// closed and opened anew, once per run however many times the run is synced.
+func TestDriftIssues_Lifecycle(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	ctx := context.Background()
+	repos := repository.NewRepository(testDB, &config.Config{})
+	if err := repos.GitRepoRepository().UpdateRepositoryDriftIssues(ctx, repoID, true); err != nil {
+		t.Fatalf("UpdateRepositoryDriftIssues: %v", err)
+	}
+	if _, err := withPool(t).Exec(ctx, `UPDATE git_repository SET is_private = true WHERE id = $1`, repoID); err != nil {
+		t.Fatalf("make repo private: %v", err)
+	}
+
+	tracker := &fakeTracker{}
+	svc := newIssueService(repos, tracker)
+	app := newIngestApp(t)
+	ingestAndSync := func(p drift_stream.DriftProjectResult) string {
+		t.Helper()
+		status, body := postIngest(t, app, seedAnalysisToken, "", singleProjectState(p))
+		if status != http.StatusOK {
+			t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+		}
+		runID := runIDFromResponse(t, body)
+		syncRun(t, svc, repos, repoID, runID)
+		return runID
+	}
+
+	plan := "Refreshing state...\n\nTerraform will perform the following actions:\n\n  # aws_s3_bucket.logs will be updated in-place\n\nPlan: 0 to add, 1 to change, 0 to destroy."
+	first := ingestAndSync(driftedProject("/envs/prod", plan))
+	// A shard or a retry syncing the same run again must not comment twice.
+	syncRun(t, svc, repos, repoID, first)
+	ingestAndSync(driftedProject("/envs/prod", plan))
+	ingestAndSync(driftedProject("/envs/prod", strings.Replace(plan, "aws_s3_bucket.logs", "aws_s3_bucket.audit", 1)))
+	ingestAndSync(cleanProject("/envs/prod"))
+	last := ingestAndSync(driftedProject("/envs/prod", plan))
+	// Only the latest run updates issues.
+	syncRun(t, svc, repos, repoID, first)
+
+	want := []string{"open #1 Drift detected in envs/prod", "comment #1", "close #1", "open #2 Drift detected in envs/prod"}
+	if strings.Join(tracker.calls, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("calls = %v, want %v", tracker.calls, want)
+	}
+	body := tracker.bodies[0]
+	for _, s := range []string{"http://test.local/gh/", "/run/" + first, "0 to add, 1 to change, 0 to destroy", "```\nTerraform will perform the following actions:"} {
+		if !strings.Contains(body, s) {
+			t.Errorf("issue body lacks %q:\n%s", s, body)
+		}
+	}
+	if strings.Contains(body, "Refreshing state") {
+		t.Errorf("issue body should start the excerpt at the actions:\n%s", body)
+	}
+
+	open, err := repos.DriftAnalysisRepository().ListOpenDriftIssues(ctx, repoID)
+	if err != nil {
+		t.Fatalf("ListOpenDriftIssues: %v", err)
+	}
+	if len(open) != 1 || open[0].IssueNumber == nil || *open[0].IssueNumber != 2 || open[0].LastRunID.String() != last {
+		t.Errorf("open issues = %+v, want #2 from run %s", open, last)
+	}
+}
+
+// TestDriftIssues_DisabledOrErrored checks that nothing is filed unless the repository opted in, and
+// that a run whose project failed neither comments on nor closes its issue.
+func TestDriftIssues_DisabledOrErrored(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	ctx := context.Background()
+	repos := repository.NewRepository(testDB, &config.Config{})
+
+	tracker := &fakeTracker{}
+	svc := newIssueService(repos, tracker)
+	app := newIngestApp(t)
+	ingest := func(p drift_stream.DriftProjectResult) string {
+		t.Helper()
+		status, body := postIngest(t, app, seedAnalysisToken, "", singleProjectState(p))
+		if status != http.StatusOK {
+			t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+		}
+		return runIDFromResponse(t, body)
+	}
+
+	syncRun(t, svc, repos, repoID, ingest(driftedProject("/envs/prod", "plan")))
+	if len(tracker.calls) != 0 {
+		t.Fatalf("disabled repository: calls = %v, want none", tracker.calls)
+	}
+
+	if err := repos.GitRepoRepository().UpdateRepositoryDriftIssues(ctx, repoID, true); err != nil {
+		t.Fatalf("UpdateRepositoryDriftIssues: %v", err)
+	}
+	syncRun(t, svc, repos, repoID, ingest(driftedProject("/envs/prod", "plan")))
+	errored := cleanProject("/envs/prod")
+	errored.Succeeded = false
+	state := singleProjectState(errored)
+	one := int32(1)
+	state.TotalErrored = &one
+	status, body := postIngest(t, app, seedAnalysisToken, "", state)
+	if status != http.StatusOK {
+		t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+	}
+	syncRun(t, svc, repos, repoID, runIDFromResponse(t, body))
+
+	if want := "open #1 Drift detected in envs/prod"; len(tracker.calls) != 1 || tracker.calls[0] != want {
+		t.Errorf("calls = %v, want [%s]", tracker.calls, want)
+	}
+}
+
+// TestDriftIssues_UnscannedDir checks that the issue of a dir a run no longer scans is closed, but
+// not by a run the shard finalizer completed without the shard that may have scanned it, nor by a
+// run a dispatch scoped to other dirs.
+func TestDriftIssues_UnscannedDir(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	ctx := context.Background()
+	repos := repository.NewRepository(testDB, &config.Config{})
+	if err := repos.GitRepoRepository().UpdateRepositoryDriftIssues(ctx, repoID, true); err != nil {
+		t.Fatalf("UpdateRepositoryDriftIssues: %v", err)
+	}
+
+	tracker := &fakeTracker{}
+	svc := newIssueService(repos, tracker)
+	app := newIngestApp(t)
+
+	state := singleProjectState(driftedProject("/envs/prod", "plan"))
+	state.ProjectResults = append(state.ProjectResults, cleanProject("/envs/dev"))
+	state.TotalProjects, state.TotalChecked = 2, 2
+	status, body := postIngest(t, app, seedAnalysisToken, "", state)
+	if status != http.StatusOK {
+		t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+	}
+	syncRun(t, svc, repos, repoID, runIDFromResponse(t, body))
+
+	status, body = postIngest(t, app, seedAnalysisToken, "matrix-partial", shardState("0", 2, 1, time.Second, cleanProject("/envs/dev")))
+	if status != http.StatusOK {
+		t.Fatalf("shard 0: expected 200, got %d: %s", status, body)
+	}
+	partialRun := runIDFromResponse(t, body)
+	if _, err := withPool(t).Exec(ctx,
+		`UPDATE drift_analysis_run SET updated_at = NOW() - INTERVAL '2 hours' WHERE uuid = $1::uuid`, partialRun); err != nil {
+		t.Fatalf("age run: %v", err)
+	}
+	if _, err := newDriftStateHandler(t, config.Config{}, nil).FinalizeTimedOutShardedRuns(ctx, 60, 100); err != nil {
+		t.Fatalf("FinalizeTimedOutShardedRuns: %v", err)
+	}
+	syncRun(t, svc, repos, repoID, partialRun)
+
+	seedMember(t, repoID)
+	dispatchID := "9b0f3c52-7d1e-4f4a-9d3e-2a6c1b8e5f01"
+	if _, err := withPool(t).Exec(ctx,
+		`INSERT INTO scan_dispatch (id, repository_id, kind, dirs, requested_by)
+		 VALUES ($1::uuid, $2, 'SCAN', '{/envs/dev}', (SELECT id FROM users LIMIT 1))`, dispatchID, repoID); err != nil {
+		t.Fatalf("seed dispatch: %v", err)
+	}
+	status, body = postIngest(t, app, seedAnalysisToken, dispatchID, singleProjectState(cleanProject("/envs/dev")))
+	if status != http.StatusOK {
+		t.Fatalf("scoped ingest: expected 200, got %d: %s", status, body)
+	}
+	syncRun(t, svc, repos, repoID, runIDFromResponse(t, body))
+
+	status, body = postIngest(t, app, seedAnalysisToken, "", singleProjectState(cleanProject("/envs/dev")))
+	if status != http.StatusOK {
+		t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+	}
+	syncRun(t, svc, repos, repoID, runIDFromResponse(t, body))
+
+	want := []string{"open #1 Drift detected in envs/prod", "close #1"}
+	if strings.Join(tracker.calls, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("calls = %v, want %v", tracker.calls, want)
+	}
+	if body := tracker.bodies[1]; !strings.Contains(body, "no longer scans envs/prod") {
+		t.Errorf("close comment = %q", body)
+	}
+}
+
+// TestDriftIssues_PublicRepoAndExistingIssue checks that the issues of a public repository carry no
+// plan excerpt, and that an issue left open on GitHub is taken over instead of opening another.
+func TestDriftIssues_PublicRepoAndExistingIssue(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	ctx := context.Background()
+	repos := repository.NewRepository(testDB, &config.Config{})
+	if err := repos.GitRepoRepository().UpdateRepositoryDriftIssues(ctx, repoID, true); err != nil {
+		t.Fatalf("UpdateRepositoryDriftIssues: %v", err)
+	}
+
+	tracker := &fakeTracker{existing: map[string]int{"Drift detected in envs/dev": 7}}
+	svc := newIssueService(repos, tracker)
+	app := newIngestApp(t)
+	state := singleProjectState(driftedProject("/envs/prod", "Terraform will perform the following actions:\n\n  # secret"))
+	state.ProjectResults = append(state.ProjectResults, driftedProject("/envs/dev", "plan"))
+	state.TotalProjects, state.TotalChecked, state.TotalDrifted = 2, 2, 2
+	status, body := postIngest(t, app, seedAnalysisToken, "", state)
+	if status != http.StatusOK {
+		t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+	}
+	syncRun(t, svc, repos, repoID, runIDFromResponse(t, body))
+
+	want := []string{"comment #7", "open #1 Drift detected in envs/prod"}
+	if strings.Join(tracker.calls, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("calls = %v, want %v", tracker.calls, want)
+	}
+	if body := tracker.bodies[1]; strings.Contains(body, "secret") || strings.Contains(body, "```") {
+		t.Errorf("public repository issue body holds the plan:\n%s", body)
+	}
+	open, err := repos.DriftAnalysisRepository().ListOpenDriftIssues(ctx, repoID)
+	if err != nil {
+		t.Fatalf("ListOpenDriftIssues: %v", err)
+	}
+	numbers := map[string]int32{}
+	for _, issue := range open {
+		if issue.IssueNumber != nil {
+			numbers[issue.Dir] = *issue.IssueNumber
+		}
+	}
+	if numbers["/envs/dev"] != 7 || numbers["/envs/prod"] != 1 {
+		t.Errorf("open issues = %v, want #7 for /envs/dev and #1 for /envs/prod", numbers)
+	}
+}
+
+// TestDriftIssues_SettingRequiresAdmin checks that only organization admins may turn drift issues
+// on.
+func TestDriftIssues_SettingRequiresAdmin(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	memberToken := seedMember(t, repoID)
+	adminToken := seedAdmin(t, repoID)
+	r := repository.NewRepository(testDB, &config.Config{})
+	handler := repos.NewGitRepositoryHandler(r.GitOrgRepository(), r.GitRepoRepository(), r.UserRepository(), r.DriftAnalysisRepository())
+	app := fiber.New()
+	app.Use(jwtware.New(jwtware.Config{SigningKey: jwtware.SigningKey{Key: []byte(testJWTSecret)}}))
+	app.Use(perms.New(r.GitOrgRepository()))
+	app.Put("/api/v1/repo/:repo_id/drift_issues", func(c fiber.Ctx) error { return handler.UpdateDriftIssues(c) })
+	path := fmt.Sprintf("/api/v1/repo/%d/drift_issues", repoID)
+	enabled := true
+
+	if status, _ := sendJSON(t, app, http.MethodPut, path, memberToken, dto.UpdateDriftIssuesRequest{Enabled: &enabled}); status != http.StatusForbidden {
+		t.Fatalf("by a member: expected 403, got %d", status)
+	}
+	if status, body := sendJSON(t, app, http.MethodPut, path, adminToken, dto.UpdateDriftIssuesRequest{Enabled: &enabled}); status != http.StatusNoContent {
+		t.Fatalf("by an admin: expected 204, got %d: %s", status, body)
+	}
+	repo, err := r.GitRepoRepository().FindGitRepositoryById(context.Background(), repoID)
+	if err != nil {
+		t.Fatalf("FindGitRepositoryById: %v", err)
+	}
+	if !repo.DriftIssuesEnabled {
+		t.Error("drift issues should be enabled")
+	}
+}
+
+func syncRun(t *testing.T, svc *issues.IssueService, repos repository.Repository, repoID int64, runID string) {
+	t.Helper()
+	ctx := context.Background()
+	repo, err := repos.GitRepoRepository().FindGitRepositoryById(ctx, repoID)
+	if err != nil {
+		t.Fatalf("FindGitRepositoryById: %v", err)
+	}
+	org, err := repos.GitOrgRepository().FindGitOrgById(ctx, repo.OrganizationID)
+	if err != nil {
+		t.Fatalf("FindGitOrgById: %v", err)
+	}
+	dashboardURL := "http://test.local/gh/" + org.Name + "/" + repo.Name + "/run/" + runID
+	if err := svc.SyncRunIssues(ctx, org, repo, uuid.MustParse(runID), dashboardURL); err != nil {
+		t.Fatalf("SyncRunIssues: %v", err)
+	}
+}
@@ -65,13 +65,13 @@ func countRuns(t *testing.T) int {
 func projectByDir(t *testing.T, runUUID, dir string) (int64, string) {
 	t.Helper()
 	var id int64
-	var planOutput *string
 	err := withPool(t).QueryRow(context.Background(),
-		`SELECT id, plan_output FROM drift_analysis_project
-		 WHERE drift_analysis_run_id = $1::uuid AND dir = $2`, runUUID, dir).Scan(&id, &planOutput)
+		`SELECT id FROM drift_analysis_project
+		 WHERE drift_analysis_run_id = $1::uuid AND dir = $2`, runUUID, dir).Scan(&id)
 	if err != nil {
 		t.Fatalf("fetch project %s: %v", dir, err)
 	}
+	planOutput := storedOutputs(t, runUUID)[dir][1]
 	if planOutput == nil {
 		return id, ""
 	}
@@ -0,0 +1,167 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"driftive.cloud/api/pkg/usecase/drift_stream"
+)
+
+func shardState(id string, count int32, totalProjects int32, duration time.Duration, results ...drift_stream.DriftProjectResult) drift_stream.DriftDetectionResult {
+	return drift_stream.DriftDetectionResult{
+		ProjectResults: results,
+		TotalProjects:  totalProjects,
+		TotalChecked:   totalProjects,
+		Duration:       duration,
+		Shard:          &drift_stream.DriftShard{ID: id, Count: count},
+	}
+}
+
+func cleanProject(dir string) drift_stream.DriftProjectResult {
+	return drift_stream.DriftProjectResult{
+		Project:   drift_stream.TypedProject{Dir: dir, Type: drift_stream.Terraform},
+		Succeeded: true,
+	}
+}
+
+// TestShards_RunCompletesWhenEveryShardReported merges two matrix jobs into one run: the first
+// shard leaves it RUNNING, and the second completes it with totals recomputed across both.
+func TestShards_RunCompletesWhenEveryShardReported(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	app := newIngestApp(t)
+
+	const idemKey = "matrix-1"
+
+	status, body := postIngest(t, app, seedAnalysisToken, idemKey, shardState("0", 2, 2, 300*time.Millisecond,
+		driftedProject("shard0/a", "Plan: 1 to add, 0 to change, 0 to destroy."),
+		cleanProject("shard0/b"),
+	))
+	if status != http.StatusOK {
+		t.Fatalf("shard 0: expected 200, got %d: %s", status, body)
+	}
+	runID := runIDFromResponse(t, body)
+
+	run, _ := fetchRun(t, repoID)
+	if run.status != "RUNNING" {
+		t.Fatalf("expected the run to wait for shard 1, got %q", run.status)
+	}
+
+	// A retried shard must not count twice.
+	status, body = postIngest(t, app, seedAnalysisToken, idemKey, shardState("0", 2, 2, 300*time.Millisecond,
+		driftedProject("shard0/a", "Plan: 1 to add, 0 to change, 0 to destroy."),
+		cleanProject("shard0/b"),
+	))
+	if status != http.StatusOK {
+		t.Fatalf("shard 0 retry: expected 200, got %d: %s", status, body)
+	}
+	if run, _ = fetchRun(t, repoID); run.status != "RUNNING" {
+		t.Fatalf("a retried shard completed the run; status %q", run.status)
+	}
+
+	status, body = postIngest(t, app, seedAnalysisToken, idemKey, shardState("1", 2, 1, 900*time.Millisecond,
+		drift_stream.DriftProjectResult{
+			Project:   drift_stream.TypedProject{Dir: "shard1/c", Type: drift_stream.Tofu},
+			Succeeded: false,
+		},
+	))
+	if status != http.StatusOK {
+		t.Fatalf("shard 1: expected 200, got %d: %s", status, body)
+	}
+	if got := runIDFromResponse(t, body); got != runID {
+		t.Fatalf("shard 1 landed on run %s, want %s", got, runID)
+	}
+
+	if n := countRuns(t); n != 1 {
+		t.Errorf("expected one run for the whole matrix, got %d", n)
+	}
+	if n := countProjects(t, runID); n != 3 {
+		t.Errorf("expected 3 project rows across shards, got %d", n)
+	}
+	run, running := fetchRun(t, repoID)
+	if run.status != "COMPLETED" {
+		t.Errorf("expected COMPLETED after the last shard, got %q", run.status)
+	}
+	if len(running) != 0 {
+		t.Errorf("expected running_projects cleared, got %v", running)
+	}
+	if run.totalProjects != 3 || run.drifted != 1 || run.errored != 1 || run.skipped != 0 {
+		t.Errorf("totals = %d projects / %d drifted / %d errored / %d skipped, want 3/1/1/0",
+			run.totalProjects, run.drifted, run.errored, run.skipped)
+	}
+	if run.durationMillis != 900 {
+		t.Errorf("expected the slowest shard's duration (900ms), got %d", run.durationMillis)
+	}
+}
+
+func TestShards_RequireIdempotencyKey(t *testing.T) {
+	truncateAll(t)
+	seedOrgAndRepo(t)
+	app := newIngestApp(t)
+
+	status, _ := postIngest(t, app, seedAnalysisToken, "", shardState("0", 2, 1, time.Second, cleanProject("a")))
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400 without an Idempotency-Key, got %d", status)
+	}
+	if n := countRuns(t); n != 0 {
+		t.Errorf("expected no run, got %d", n)
+	}
+}
+
+// TestShards_TimedOutRunIsCompletedWithReportedShards covers a matrix job that never reports: the
+// finalizer completes the run with the shards that did, instead of the stale sweeper deleting it,
+// and a shard reporting after that is turned away.
+func TestShards_TimedOutRunIsCompletedWithReportedShards(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	app := newIngestApp(t)
+	repo := newDriftRepo(t)
+	ctx := context.Background()
+
+	status, body := postIngest(t, app, seedAnalysisToken, "matrix-timeout", shardState("0", 3, 2, time.Second,
+		driftedProject("a", "Plan: 0 to add, 1 to change, 0 to destroy."),
+	))
+	if status != http.StatusOK {
+		t.Fatalf("shard 0: expected 200, got %d: %s", status, body)
+	}
+	runID := runIDFromResponse(t, body)
+
+	if _, err := withPool(t).Exec(ctx,
+		`UPDATE drift_analysis_run SET updated_at = NOW() - INTERVAL '2 hours' WHERE uuid = $1::uuid`, runID); err != nil {
+		t.Fatalf("age run: %v", err)
+	}
+
+	deleted, err := repo.DeleteStaleRunningRuns(ctx, sweepStaleMinutes, 100)
+	if err != nil {
+		t.Fatalf("DeleteStaleRunningRuns: %v", err)
+	}
+	if deleted != 0 || !runExists(t, runID) {
+		t.Fatalf("the stale sweeper deleted a sharded run (deleted=%d)", deleted)
+	}
+
+	completed, err := repo.CompleteTimedOutShardedRuns(ctx, 60, 100)
+	if err != nil {
+		t.Fatalf("CompleteTimedOutShardedRuns: %v", err)
+	}
+	if len(completed) != 1 || completed[0].Uuid.String() != runID {
+		t.Fatalf("expected run %s to be finalized, got %+v", runID, completed)
+	}
+
+	run, _ := fetchRun(t, repoID)
+	if run.status != "COMPLETED" {
+		t.Errorf("expected COMPLETED, got %q", run.status)
+	}
+	if run.totalProjects != 2 || run.drifted != 1 {
+		t.Errorf("totals = %d projects / %d drifted, want 2/1", run.totalProjects, run.drifted)
+	}
+
+	status, body = postIngest(t, app, seedAnalysisToken, "matrix-timeout", shardState("1", 3, 1, time.Second, cleanProject("b")))
+	if status != http.StatusConflict {
+		t.Fatalf("late shard: expected 409, got %d: %s", status, body)
+	}
+	if n := countProjects(t, runID); n != 1 {
+		t.Errorf("the late shard wrote projects into the completed run: %d rows, want 1", n)
+	}
+}
@@ -0,0 +1,241 @@
+package integration
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"driftive.cloud/api/pkg/config"
+	"driftive.cloud/api/pkg/usecase/drift_stream"
+	"github.com/gofiber/fiber/v3"
+	"github.com/klauspost/compress/zstd"
+)
+
+// postRaw sends an already-encoded body, optionally with a Content-Encoding header.
+func postRaw(t *testing.T, app *fiber.App, path, idemKey, encoding string, body []byte) (int, []byte) {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(),
+		http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Token", seedAnalysisToken)
+	if idemKey != "" {
+		req.Header.Set("Idempotency-Key", idemKey)
+	}
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 30 * time.Second})
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, respBody
+}
+
+func gzipBytes(t *testing.T, body []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func zstdBytes(t *testing.T, body []byte) []byte {
+	t.Helper()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("zstd: %v", err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(body, nil)
+}
+
+// ndjson encodes one record per line.
+func ndjson(t *testing.T, records ...drift_stream.DriftStreamRecord) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			t.Fatalf("encode record: %v", err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestIngest_CompressedBodies(t *testing.T) {
+	for _, tc := range []struct {
+		encoding string
+		encode   func(*testing.T, []byte) []byte
+	}{
+		{"gzip", gzipBytes},
+		{"zstd", zstdBytes},
+	} {
+		t.Run(tc.encoding, func(t *testing.T) {
+			truncateAll(t)
+			repoID := seedOrgAndRepo(t)
+			app := newIngestApp(t)
+
+			raw, err := json.Marshal(sampleState())
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			status, body := postRaw(t, app, "/api/v1/drift_analysis", "", tc.encoding, tc.encode(t, raw))
+			if status != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", status, body)
+			}
+			run, _ := fetchRun(t, repoID)
+			if n := countProjects(t, run.uuid); n != 3 {
+				t.Errorf("expected 3 projects, got %d", n)
+			}
+		})
+	}
+}
+
+func TestIngest_UnsupportedEncodingIs415(t *testing.T) {
+	truncateAll(t)
+	seedOrgAndRepo(t)
+	app := newIngestApp(t)
+
+	status, body := postRaw(t, app, "/api/v1/drift_analysis", "", "br", []byte("{}"))
+	if status != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d: %s", status, body)
+	}
+}
+
+// TestIngest_SizeLimits checks both limits answer 413 with a message naming the limit, and that
+// the request limit applies to the decoded size, not the compressed one.
+func TestIngest_SizeLimits(t *testing.T) {
+	truncateAll(t)
+	seedOrgAndRepo(t)
+
+	state := sampleState()
+	state.ProjectResults[0].PlanOutput = strings.Repeat("x", 4096)
+	raw, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	app := newIngestAppWithLimits(t, config.IngestConfig{MaxRequestBytes: 2048})
+	status, body := postRaw(t, app, "/api/v1/drift_analysis", "", "gzip", gzipBytes(t, raw))
+	if status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("request limit: expected 413, got %d: %s", status, body)
+	}
+	if !strings.Contains(string(body), "2048 byte limit") {
+		t.Errorf("request limit: expected the limit in the message, got %s", body)
+	}
+
+	app = newIngestAppWithLimits(t, config.IngestConfig{MaxProjectBytes: 1024})
+	status, body = postIngest(t, app, seedAnalysisToken, "", state)
+	if status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("project limit: expected 413, got %d: %s", status, body)
+	}
+	if !strings.Contains(string(body), "/projects/a") {
+		t.Errorf("project limit: expected the offending dir in the message, got %s", body)
+	}
+	if n := countRuns(t); n != 0 {
+		t.Errorf("expected no run after a rejected upload, got %d", n)
+	}
+}
+
+// TestStream_UpsertsInBatchesAndCompletes streams more projects than one batch holds and checks the
+// run is completed with counters recomputed from the rows.
+func TestStream_UpsertsInBatchesAndCompletes(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	app := newIngestApp(t)
+
+	const projects = 120
+	records := make([]drift_stream.DriftStreamRecord, 0, projects+1)
+	for i := 0; i < projects; i++ {
+		p := cleanProject(fmt.Sprintf("stream/%03d", i))
+		if i%10 == 0 {
+			p = driftedProject(p.Project.Dir, "Plan: 1 to add, 0 to change, 0 to destroy.")
+		}
+		records = append(records, drift_stream.DriftStreamRecord{Project: &p})
+	}
+	records = append(records, drift_stream.DriftStreamRecord{
+		Summary: &drift_stream.DriftStreamSummary{TotalProjects: projects, Duration: 2 * time.Second},
+	})
+
+	status, body := postRaw(t, app, "/api/v1/drift_analysis/stream", "stream-1", "gzip", gzipBytes(t, ndjson(t, records...)))
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	runID := runIDFromResponse(t, body)
+
+	run, _ := fetchRun(t, repoID)
+	if run.status != "COMPLETED" {
+		t.Errorf("expected COMPLETED, got %q", run.status)
+	}
+	if run.totalProjects != projects || run.drifted != projects/10 || run.durationMillis != 2000 {
+		t.Errorf("totals = %d projects / %d drifted / %dms, want %d/%d/2000ms",
+			run.totalProjects, run.drifted, run.durationMillis, projects, projects/10)
+	}
+	if n := countProjects(t, runID); n != projects {
+		t.Errorf("expected %d project rows, got %d", projects, n)
+	}
+
+	// A retry of a completed stream replays without reading the body again.
+	status, body = postRaw(t, app, "/api/v1/drift_analysis/stream", "stream-1", "", nil)
+	if status != http.StatusOK || runIDFromResponse(t, body) != runID {
+		t.Errorf("replay: expected 200 for run %s, got %d: %s", runID, status, body)
+	}
+}
+
+// TestStream_TruncatedStreamIsResumed cuts an upload with an Idempotency-Key before its summary
+// line: the batches already committed stay on the RUNNING run, and the retry completes that same run.
+// Without a key nothing could resume the run, so it is dropped.
+func TestStream_TruncatedStreamIsResumed(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	app := newIngestApp(t)
+
+	a, b := cleanProject("stream/a"), cleanProject("stream/b")
+	status, body := postRaw(t, app, "/api/v1/drift_analysis/stream", "stream-2", "",
+		ndjson(t, drift_stream.DriftStreamRecord{Project: &a}))
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", status, body)
+	}
+	run, _ := fetchRun(t, repoID)
+	if run.status != "RUNNING" || countProjects(t, run.uuid) != 1 {
+		t.Fatalf("expected the cut upload's project kept on a RUNNING run, got %q", run.status)
+	}
+
+	status, body = postRaw(t, app, "/api/v1/drift_analysis/stream", "stream-2", "", ndjson(t,
+		drift_stream.DriftStreamRecord{Project: &a},
+		drift_stream.DriftStreamRecord{Project: &b},
+		drift_stream.DriftStreamRecord{Summary: &drift_stream.DriftStreamSummary{TotalProjects: 2, Duration: time.Second}},
+	))
+	if status != http.StatusOK || runIDFromResponse(t, body) != run.uuid {
+		t.Fatalf("retry: expected 200 for run %s, got %d: %s", run.uuid, status, body)
+	}
+	if run, _ = fetchRun(t, repoID); run.status != "COMPLETED" || run.totalProjects != 2 {
+		t.Errorf("retry: run = %q with %d projects, want COMPLETED with 2", run.status, run.totalProjects)
+	}
+
+	truncateAll(t)
+	seedOrgAndRepo(t)
+	status, body = postRaw(t, app, "/api/v1/drift_analysis/stream", "", "",
+		ndjson(t, drift_stream.DriftStreamRecord{Project: &a}))
+	if status != http.StatusBadRequest {
+		t.Fatalf("without a key: expected 400, got %d: %s", status, body)
+	}
+	if n := countRuns(t); n != 0 {
+		t.Errorf("without a key: expected the run to be dropped, got %d run(s)", n)
+	}
+}
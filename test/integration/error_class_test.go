@@ -0,0 +1,109 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"driftive.cloud/api/pkg/config"
+	"driftive.cloud/api/pkg/errclass"
+	"driftive.cloud/api/pkg/model/dto"
+)
+
+// TestErrorClass_StoredAndBrokenDown ingests two runs in which a project fails, once on a held state
+// lock and once on expired credentials, and checks the class is stored on the failed project only
+// and broken down in the stats and trends.
+func TestErrorClass_StoredAndBrokenDown(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	app := newIngestApp(t)
+
+	ingest := func(planOutput string) string {
+		t.Helper()
+		state := sampleState()
+		state.ProjectResults[1].Succeeded = false
+		state.ProjectResults[1].PlanOutput = planOutput
+		status, body := postIngest(t, app, seedAnalysisToken, "", state)
+		if status != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", status, body)
+		}
+		return runIDFromResponse(t, body)
+	}
+	ingest("│ Error: Error acquiring the state lock\n│\n│ Lock Info:\n│   ID: 7c1f2d5e")
+	runID := ingest("│ Error: No valid credential sources found\n│\n│ Please see https://registry.terraform.io/providers/hashicorp/aws")
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+	var run dto.DriftAnalysisRunWithProjectsDTO
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+runID, token, &run); status != http.StatusOK {
+		t.Fatalf("GetRunById: expected 200, got %d", status)
+	}
+	for _, p := range run.Projects {
+		switch p.Dir {
+		case "/projects/b":
+			if p.ErrorClass == nil || *p.ErrorClass != "CREDENTIALS" {
+				t.Errorf("error_class of %s = %v, want CREDENTIALS", p.Dir, p.ErrorClass)
+			}
+		default:
+			if p.ErrorClass != nil {
+				t.Errorf("expected no error class for %s, got %s", p.Dir, *p.ErrorClass)
+			}
+		}
+	}
+
+	repoPath := "/api/v1/repo/" + strconv.FormatInt(repoID, 10)
+	var stats dto.RepositoryRunStatsDTO
+	if status := getJSON(t, dashboard, repoPath+"/stats", token, &stats); status != http.StatusOK {
+		t.Fatalf("GetRepositoryStats: expected 200, got %d", status)
+	}
+	wantLatest := []dto.ErrorClassCountDTO{{ErrorClass: "CREDENTIALS", ProjectCount: 1}}
+	if len(stats.LatestRunErrorClasses) != 1 || stats.LatestRunErrorClasses[0] != wantLatest[0] {
+		t.Errorf("latest_run_error_classes = %+v, want %+v", stats.LatestRunErrorClasses, wantLatest)
+	}
+
+	var trends dto.RepositoryTrendsDTO
+	if status := getJSON(t, dashboard, repoPath+"/trends", token, &trends); status != http.StatusOK {
+		t.Fatalf("GetRepositoryTrends: expected 200, got %d", status)
+	}
+	if len(trends.ErrorClasses) != 2 {
+		t.Fatalf("error_classes = %+v, want two", trends.ErrorClasses)
+	}
+	// Tied on count, ordered by class.
+	for i, want := range []string{"CREDENTIALS", "STATE_LOCK"} {
+		if c := trends.ErrorClasses[i]; c.ErrorClass != want || c.ProjectCount != 1 || c.RunCount != 1 || c.LastSeenAt.IsZero() {
+			t.Errorf("error_classes[%d] = %+v, want %s seen once", i, c, want)
+		}
+	}
+}
+
+// TestErrorClass_ConfiguredRules checks the rules from the config are tried before the built-in
+// ones.
+func TestErrorClass_ConfiguredRules(t *testing.T) {
+	truncateAll(t)
+	seedOrgAndRepo(t)
+	rules, err := errclass.ParseRules(`[{"class": "QUOTA", "pattern": "(?i)quota exceeded"}]`)
+	if err != nil {
+		t.Fatalf("ParseRules: %v", err)
+	}
+	app := newIngestAppWithConfig(t, config.Config{ErrorClass: config.ErrorClassConfig{Rules: rules}}, nil)
+
+	state := sampleState()
+	state.ProjectResults[1].Succeeded = false
+	// The built-in rules alone would classify this as a rate limit.
+	state.ProjectResults[1].PlanOutput = "│ Error: Quota exceeded for quota metric 'Queries', rate limit reached"
+	status, body := postIngest(t, app, seedAnalysisToken, "", state)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+
+	var class string
+	if err := withPool(t).QueryRow(context.Background(),
+		`SELECT error_class FROM drift_analysis_project WHERE drift_analysis_run_id = $1::uuid AND dir = '/projects/b'`,
+		runIDFromResponse(t, body)).Scan(&class); err != nil {
+		t.Fatalf("fetch project: %v", err)
+	}
+	if class != "QUOTA" {
+		t.Errorf("error_class = %s, want QUOTA", class)
+	}
+}
@@ -167,9 +167,18 @@ func truncateAll(t *testing.T) {
 		t.Skip("integration tests skipped (no testDB)")
 	}
 	tables := []string{
+		"scan_overdue_alert",
+		"scan_dispatch",
+		"drift_issue",
+		"drift_project",
 		"drift_analysis_project",
+		"command_output",
+		"output_blob_deletion",
+		"check_run_timeout",
 		"drift_analysis_run",
+		"git_repository_codeowners",
 		"git_repository",
+		"organization_redaction_rule",
 		"user_git_organization",
 		"git_organization",
 		"sync_status_user",
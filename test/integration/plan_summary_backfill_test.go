@@ -0,0 +1,135 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"driftive.cloud/api/pkg/config"
+	"driftive.cloud/api/pkg/usecase/drift_stream"
+)
+
+// TestPlanSummaryBackfill_ReparsesStoredOutputs stores an import plan, rewinds it to how the
+// original parser left it, and checks the backfill fills in its counts from the stored output.
+func TestPlanSummaryBackfill_ReparsesStoredOutputs(t *testing.T) {
+	truncateAll(t)
+	seedOrgAndRepo(t)
+
+	state := sampleState()
+	state.ProjectResults[0].PlanOutput = "Plan: 1 to import, 0 to add, 2 to change, 0 to destroy.\n\nChanges to Outputs:\n  ~ arn = \"a\" -> \"b\"\n"
+	status, body := postIngest(t, newIngestApp(t), seedAnalysisToken, "", state)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	runID := runIDFromResponse(t, body)
+
+	pool := withPool(t)
+	ctx := context.Background()
+	if _, err := pool.Exec(ctx,
+		`UPDATE drift_analysis_project
+		 SET resources_added = NULL, resources_changed = NULL, resources_destroyed = NULL, resources_imported = NULL,
+		     resources_forgotten = NULL, resources_moved = NULL, outputs_changed = NULL, summary_parser_version = 1`); err != nil {
+		t.Fatalf("rewind summaries: %v", err)
+	}
+
+	handler := newDriftStateHandler(t, config.Config{}, nil)
+	updated, err := handler.BackfillSummaries(ctx, 100)
+	if err != nil || updated != 3 {
+		t.Fatalf("BackfillSummaries = %d, %v; want 3", updated, err)
+	}
+	if updated, err := handler.BackfillSummaries(ctx, 100); err != nil || updated != 0 {
+		t.Errorf("second pass: BackfillSummaries = %d, %v; want 0", updated, err)
+	}
+
+	var changed, imported, outputs *int32
+	var version int16
+	if err := pool.QueryRow(ctx,
+		`SELECT resources_changed, resources_imported, outputs_changed, summary_parser_version
+		 FROM drift_analysis_project WHERE drift_analysis_run_id = $1::uuid AND dir = '/projects/a'`, runID).
+		Scan(&changed, &imported, &outputs, &version); err != nil {
+		t.Fatalf("fetch project: %v", err)
+	}
+	if changed == nil || *changed != 2 || imported == nil || *imported != 1 || outputs == nil || *outputs != 1 {
+		t.Errorf("expected 2 changed, 1 imported and 1 output change, got %v/%v/%v", changed, imported, outputs)
+	}
+	if version != drift_stream.SummaryParserVersion {
+		t.Errorf("summary_parser_version = %d, want %d", version, drift_stream.SummaryParserVersion)
+	}
+}
+
+// TestPlanSummaryBackfill_OnlyRederivesOutdatedFields rewinds a project to the parser version before
+// warnings were extracted and checks the backfill fills them in while leaving the fields of earlier
+// parsers, here a hand-edited error class, as stored.
+func TestPlanSummaryBackfill_OnlyRederivesOutdatedFields(t *testing.T) {
+	truncateAll(t)
+	seedOrgAndRepo(t)
+
+	state := sampleState()
+	state.ProjectResults[0].PlanOutput = "Warning: Argument is deprecated\n\nUse something else.\n\nPlan: 0 to add, 1 to change, 0 to destroy.\n"
+	status, body := postIngest(t, newIngestApp(t), seedAnalysisToken, "", state)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	runID := runIDFromResponse(t, body)
+
+	pool := withPool(t)
+	ctx := context.Background()
+	if _, err := pool.Exec(ctx,
+		`UPDATE drift_analysis_project
+		 SET warnings = NULL, error_class = 'KEPT', summary_parser_version = $1`,
+		drift_stream.SummaryParserVersion-1); err != nil {
+		t.Fatalf("rewind summaries: %v", err)
+	}
+
+	if _, err := newDriftStateHandler(t, config.Config{}, nil).BackfillSummaries(ctx, 100); err != nil {
+		t.Fatalf("BackfillSummaries: %v", err)
+	}
+
+	var warnings []byte
+	var errorClass *string
+	if err := pool.QueryRow(ctx,
+		`SELECT warnings, error_class
+		 FROM drift_analysis_project WHERE drift_analysis_run_id = $1::uuid AND dir = '/projects/a'`, runID).
+		Scan(&warnings, &errorClass); err != nil {
+		t.Fatalf("fetch project: %v", err)
+	}
+	if len(warnings) == 0 {
+		t.Error("expected the backfill to extract the warning")
+	}
+	if errorClass == nil || *errorClass != "KEPT" {
+		t.Errorf("error_class = %v, want the stored KEPT", errorClass)
+	}
+}
+
+// TestPlanSummaryBackfill_SkipsLeasedProjects checks a batch another instance is parsing is left
+// alone until its lease expires.
+func TestPlanSummaryBackfill_SkipsLeasedProjects(t *testing.T) {
+	truncateAll(t)
+	seedOrgAndRepo(t)
+
+	if status, body := postIngest(t, newIngestApp(t), seedAnalysisToken, "", sampleState()); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	pool := withPool(t)
+	ctx := context.Background()
+	if _, err := pool.Exec(ctx,
+		`UPDATE drift_analysis_project SET summary_parser_version = 1, summary_claimed_at = NOW()`); err != nil {
+		t.Fatalf("lease projects: %v", err)
+	}
+
+	handler := newDriftStateHandler(t, config.Config{}, nil)
+	if updated, err := handler.BackfillSummaries(ctx, 100); err != nil || updated != 0 {
+		t.Fatalf("leased: BackfillSummaries = %d, %v; want 0", updated, err)
+	}
+	if _, err := pool.Exec(ctx,
+		`UPDATE drift_analysis_project SET summary_claimed_at = NOW() - INTERVAL '1 hour'`); err != nil {
+		t.Fatalf("expire leases: %v", err)
+	}
+	if updated, err := handler.BackfillSummaries(ctx, 100); err != nil || updated != 3 {
+		t.Fatalf("expired: BackfillSummaries = %d, %v; want 3", updated, err)
+	}
+	var leased int
+	if err := pool.QueryRow(ctx, `SELECT count(*) FROM drift_analysis_project WHERE summary_claimed_at IS NOT NULL`).Scan(&leased); err != nil || leased != 0 {
+		t.Errorf("expected every lease released, got %d (%v)", leased, err)
+	}
+}
@@ -0,0 +1,94 @@
+package integration
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"driftive.cloud/api/pkg/model/dto"
+)
+
+const deprecationWarningOutput = `Terraform will perform the following actions:
+
+Plan: 0 to add, 1 to change, 0 to destroy.
+╷
+│ Warning: Argument is deprecated
+│
+│   with aws_s3_bucket.logs,
+│   on main.tf line 12, in resource "aws_s3_bucket" "logs":
+│   12:   acl = "private"
+│
+│ Use the aws_s3_bucket_acl resource instead
+│
+│ (and 2 more similar warnings elsewhere)
+╵
+`
+
+// TestPlanWarnings_RunDetailAndTrends ingests two runs whose plan output prints the same
+// deprecation warning, and checks the run detail lists and counts it and the warning trends report
+// it as repeated.
+func TestPlanWarnings_RunDetailAndTrends(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+
+	app := newIngestApp(t)
+	var runID string
+	for range 2 {
+		state := sampleState()
+		state.ProjectResults[0].PlanOutput = deprecationWarningOutput
+		status, body := postIngest(t, app, seedAnalysisToken, "", state)
+		if status != http.StatusOK {
+			t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+		}
+		runID = runIDFromResponse(t, body)
+	}
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+
+	var run dto.DriftAnalysisRunWithProjectsDTO
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+runID, token, &run); status != http.StatusOK {
+		t.Fatalf("GetRunById: expected 200, got %d", status)
+	}
+	if run.WarningCount != 3 || run.WarningCounts["DEPRECATED_ATTRIBUTE"] != 3 {
+		t.Errorf("warning_count = %d, warning_counts = %v, want 3 deprecated attributes", run.WarningCount, run.WarningCounts)
+	}
+	for _, p := range run.Projects {
+		if p.Dir != "/projects/a" {
+			if len(p.Warnings) != 0 {
+				t.Errorf("project %s: unexpected warnings %+v", p.Dir, p.Warnings)
+			}
+			continue
+		}
+		want := dto.WarningDTO{
+			Category:    "DEPRECATED_ATTRIBUTE",
+			Summary:     "Argument is deprecated",
+			Detail:      "Use the aws_s3_bucket_acl resource instead",
+			Address:     "aws_s3_bucket.logs",
+			Occurrences: 3,
+		}
+		if len(p.Warnings) != 1 || p.Warnings[0] != want {
+			t.Errorf("warnings = %+v, want %+v", p.Warnings, want)
+		}
+	}
+
+	var trends dto.WarningTrendsDTO
+	path := "/api/v1/repo/" + strconv.FormatInt(repoID, 10) + "/trends/warnings"
+	if status := getJSON(t, dashboard, path, token, &trends); status != http.StatusOK {
+		t.Fatalf("GetRepositoryWarningTrends: expected 200, got %d", status)
+	}
+	if len(trends.WarningsPerRun) != 2 {
+		t.Fatalf("warnings_per_run = %+v, want two runs", trends.WarningsPerRun)
+	}
+	for _, r := range trends.WarningsPerRun {
+		if r.WarningCount != 3 {
+			t.Errorf("run %s: warning_count = %d, want 3", r.RunID, r.WarningCount)
+		}
+	}
+	if len(trends.RepeatedWarnings) != 1 {
+		t.Fatalf("repeated_warnings = %+v, want one", trends.RepeatedWarnings)
+	}
+	if w := trends.RepeatedWarnings[0]; w.Dir != "/projects/a" || w.RunCount != 2 || w.Address != "aws_s3_bucket.logs" {
+		t.Errorf("repeated warning = %+v", w)
+	}
+}
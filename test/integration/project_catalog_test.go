@@ -0,0 +1,172 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/usecase/drift_stream"
+)
+
+// TestProjectCatalog_TracksProjectsAcrossRuns ingests two runs, the second resolving /projects/a's
+// drift, failing /projects/b and adding /projects/d, and checks the catalog keeps one row per
+// project with its latest status and when it last drifted, and that the filters and sorting apply.
+func TestProjectCatalog_TracksProjectsAcrossRuns(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+
+	app := newIngestApp(t)
+	if status, body := postIngest(t, app, seedAnalysisToken, "", sampleState()); status != http.StatusOK {
+		t.Fatalf("first ingest: expected 200, got %d: %s", status, body)
+	}
+	state := sampleState()
+	state.ProjectResults[0].Drifted = false
+	state.ProjectResults[1].Succeeded = false
+	state.ProjectResults = append(state.ProjectResults, drift_stream.DriftProjectResult{
+		Project:   drift_stream.TypedProject{Dir: "/projects/d", Type: drift_stream.Terraform},
+		Succeeded: true,
+	})
+	status, body := postIngest(t, app, seedAnalysisToken, "", state)
+	if status != http.StatusOK {
+		t.Fatalf("second ingest: expected 200, got %d: %s", status, body)
+	}
+	lastRunID := runIDFromResponse(t, body)
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+	path := "/api/v1/repo/" + strconv.FormatInt(repoID, 10) + "/projects"
+
+	var projects []dto.ProjectDTO
+	if status := getJSON(t, dashboard, path, token, &projects); status != http.StatusOK {
+		t.Fatalf("ListRepositoryProjects: expected 200, got %d", status)
+	}
+	wantStatus := map[string]string{
+		"/projects/a": "IN_SYNC",
+		"/projects/b": "ERRORED",
+		"/projects/c": "SKIPPED",
+		"/projects/d": "IN_SYNC",
+	}
+	if len(projects) != len(wantStatus) {
+		t.Fatalf("projects = %+v, want %d", projects, len(wantStatus))
+	}
+	byDir := map[string]dto.ProjectDTO{}
+	for _, p := range projects {
+		byDir[p.Dir] = p
+		if p.Status != wantStatus[p.Dir] {
+			t.Errorf("%s: status = %s, want %s", p.Dir, p.Status, wantStatus[p.Dir])
+		}
+		if p.LastRunID == nil || *p.LastRunID != lastRunID {
+			t.Errorf("%s: last_run_id = %v, want %s", p.Dir, p.LastRunID, lastRunID)
+		}
+	}
+	if a := byDir["/projects/a"]; a.LastDriftedAt == nil || a.LastErroredAt != nil || !a.FirstSeenAt.Before(a.LastSeenAt) {
+		t.Errorf("/projects/a = %+v, want it drifted in the first run and seen in both", a)
+	}
+	if d := byDir["/projects/d"]; !d.FirstSeenAt.Equal(d.LastSeenAt) || d.LastDriftedAt != nil {
+		t.Errorf("/projects/d = %+v, want it first seen in the second run", d)
+	}
+	if b := byDir["/projects/b"]; b.LastErroredAt == nil || !b.LastErroredAt.Equal(b.LastSeenAt) {
+		t.Errorf("/projects/b = %+v, want it errored in the second run", b)
+	}
+
+	if status := getJSON(t, dashboard, path+"?status=errored", token, &projects); status != http.StatusOK {
+		t.Fatalf("ListRepositoryProjects: expected 200, got %d", status)
+	}
+	if len(projects) != 1 || projects[0].Dir != "/projects/b" {
+		t.Errorf("status=errored: got %+v, want /projects/b", projects)
+	}
+	if status := getJSON(t, dashboard, path+"?q=PROJECTS/C&type=terragrunt", token, &projects); status != http.StatusOK {
+		t.Fatalf("ListRepositoryProjects: expected 200, got %d", status)
+	}
+	if len(projects) != 1 || projects[0].Dir != "/projects/c" {
+		t.Errorf("q=PROJECTS/C: got %+v, want /projects/c", projects)
+	}
+	if status := getJSON(t, dashboard, path+"?sort=last_drifted_at", token, &projects); status != http.StatusOK {
+		t.Fatalf("ListRepositoryProjects: expected 200, got %d", status)
+	}
+	if len(projects) != 4 || projects[0].Dir != "/projects/a" {
+		t.Errorf("sort=last_drifted_at: got %+v, want /projects/a first", projects)
+	}
+
+	if status := getJSON(t, dashboard, path+"?sort=name", token, nil); status != http.StatusBadRequest {
+		t.Errorf("unknown sort: expected 400, got %d", status)
+	}
+	if status := getJSON(t, dashboard, path+"?status=broken", token, nil); status != http.StatusBadRequest {
+		t.Errorf("unknown status: expected 400, got %d", status)
+	}
+}
+
+// TestProjectCatalog_SyncedOnCompletionAndPruned checks a run in progress stays out of the catalog
+// until it completes, a failed plan that reported drift doesn't count as drift, and retention
+// prunes the projects none of the remaining runs include.
+func TestProjectCatalog_SyncedOnCompletionAndPruned(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	app := newIngestApp(t)
+	ctx := context.Background()
+	countCatalog := func() int {
+		t.Helper()
+		var n int
+		if err := withPool(t).QueryRow(ctx, `SELECT COUNT(*) FROM drift_project WHERE repository_id = $1`, repoID).Scan(&n); err != nil {
+			t.Fatalf("count catalog: %v", err)
+		}
+		return n
+	}
+
+	const idemKey = "catalog-key"
+	state := sampleState()
+	state.ProjectResults[1].Drifted = true
+	state.ProjectResults[1].Succeeded = false
+	if status, body := postProgress(t, app, seedAnalysisToken, idemKey, drift_stream.DriftProgressRequest{
+		TotalProjects:  3,
+		Running:        []string{"/projects/c"},
+		ProjectResults: state.ProjectResults[:2],
+	}); status != http.StatusOK {
+		t.Fatalf("progress: expected 200, got %d: %s", status, body)
+	}
+	if n := countCatalog(); n != 0 {
+		t.Errorf("catalog holds %d projects of a run in progress, want none", n)
+	}
+	if status, body := postIngest(t, app, seedAnalysisToken, idemKey, state); status != http.StatusOK {
+		t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+	}
+	if n := countCatalog(); n != 3 {
+		t.Errorf("catalog holds %d projects, want 3", n)
+	}
+	var lastDriftedAt *time.Time
+	if err := withPool(t).QueryRow(ctx,
+		`SELECT last_drifted_at FROM drift_project WHERE repository_id = $1 AND dir = '/projects/b'`, repoID).Scan(&lastDriftedAt); err != nil {
+		t.Fatalf("fetch /projects/b: %v", err)
+	}
+	if lastDriftedAt != nil {
+		t.Errorf("/projects/b failed, want no last_drifted_at, got %v", lastDriftedAt)
+	}
+
+	state = sampleState()
+	state.ProjectResults = state.ProjectResults[:1]
+	if status, body := postIngest(t, app, seedAnalysisToken, "", state); status != http.StatusOK {
+		t.Fatalf("second ingest: expected 200, got %d: %s", status, body)
+	}
+	if err := newDriftRepo(t).DeleteOldestRunsExceedingLimit(ctx, repoID, 1); err != nil {
+		t.Fatalf("DeleteOldestRunsExceedingLimit: %v", err)
+	}
+	var dirs []string
+	rows, err := withPool(t).Query(ctx, `SELECT dir FROM drift_project WHERE repository_id = $1`, repoID)
+	if err != nil {
+		t.Fatalf("list catalog: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var dir string
+		if err := rows.Scan(&dir); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		dirs = append(dirs, dir)
+	}
+	if len(dirs) != 1 || dirs[0] != "/projects/a" {
+		t.Errorf("catalog after retention = %v, want only /projects/a", dirs)
+	}
+}
@@ -0,0 +1,151 @@
+package integration
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/usecase/drift_stream"
+)
+
+// TestProjectHistory_RunsAndDriftPeriods ingests four runs in which /projects/a drifts, stays
+// drifted, is fixed and drifts again, and checks the history lists its status per run and the two
+// drift periods, the latest still ongoing.
+func TestProjectHistory_RunsAndDriftPeriods(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+
+	app := newIngestApp(t)
+	var runIDs []string
+	for _, drifted := range []bool{true, true, false, true} {
+		state := sampleState()
+		state.ProjectResults[0].Drifted = drifted
+		status, body := postIngest(t, app, seedAnalysisToken, "", state)
+		if status != http.StatusOK {
+			t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+		}
+		runIDs = append(runIDs, runIDFromResponse(t, body))
+	}
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+	path := "/api/v1/repo/" + strconv.FormatInt(repoID, 10) + "/projects/history?dir=" + url.QueryEscape("/projects/a")
+
+	var history dto.ProjectHistoryDTO
+	if status := getJSON(t, dashboard, path, token, &history); status != http.StatusOK {
+		t.Fatalf("GetProjectHistory: expected 200, got %d", status)
+	}
+	wantStatuses := []string{"DRIFTED", "IN_SYNC", "DRIFTED", "DRIFTED"}
+	if len(history.Runs) != len(wantStatuses) {
+		t.Fatalf("runs = %+v, want %d", history.Runs, len(wantStatuses))
+	}
+	for i, run := range history.Runs {
+		if run.Status != wantStatuses[i] || run.RunID != runIDs[len(runIDs)-1-i] {
+			t.Errorf("runs[%d] = %+v, want %s in run %s", i, run, wantStatuses[i], runIDs[len(runIDs)-1-i])
+		}
+	}
+
+	if len(history.DriftPeriods) != 2 {
+		t.Fatalf("drift_periods = %+v, want two", history.DriftPeriods)
+	}
+	ongoing, resolved := history.DriftPeriods[0], history.DriftPeriods[1]
+	if ongoing.EndedAt != nil || !ongoing.StartedAt.Equal(history.Runs[0].CreatedAt) {
+		t.Errorf("latest period = %+v, want it ongoing since the last run", ongoing)
+	}
+	if resolved.EndedAt == nil || !resolved.StartedAt.Equal(history.Runs[3].CreatedAt) || !resolved.EndedAt.Equal(history.Runs[1].CreatedAt) {
+		t.Errorf("first period = %+v, want it from the first run to the third", resolved)
+	}
+
+	if status := getJSON(t, dashboard, path+"&limit=2", token, &history); status != http.StatusOK {
+		t.Fatalf("GetProjectHistory: expected 200, got %d", status)
+	}
+	if len(history.Runs) != 2 || len(history.DriftPeriods) != 2 {
+		t.Errorf("limit=2: got %d runs and %d periods, want 2 and 2", len(history.Runs), len(history.DriftPeriods))
+	}
+
+	missing := "/api/v1/repo/" + strconv.FormatInt(repoID, 10) + "/projects/history?dir=" + url.QueryEscape("/projects/missing")
+	if status := getJSON(t, dashboard, missing, token, nil); status != http.StatusNotFound {
+		t.Errorf("unknown dir: expected 404, got %d", status)
+	}
+}
+
+// TestProjectHistory_TypeChange moves /projects/a from Terraform, drifted, to Tofu, in sync and then
+// drifted, and checks the Tofu runs neither end the Terraform drift nor merge with it, and that the
+// type query param narrows the history to one of them.
+func TestProjectHistory_TypeChange(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+
+	app := newIngestApp(t)
+	steps := []struct {
+		projectType drift_stream.ProjectType
+		drifted     bool
+	}{
+		{drift_stream.Terraform, true},
+		{drift_stream.Tofu, false},
+		{drift_stream.Tofu, true},
+	}
+	for _, step := range steps {
+		state := sampleState()
+		state.ProjectResults[0].Project.Type = step.projectType
+		state.ProjectResults[0].Drifted = step.drifted
+		if !step.drifted {
+			state.TotalDrifted = 0
+		}
+		if status, body := postIngest(t, app, seedAnalysisToken, "", state); status != http.StatusOK {
+			t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+		}
+	}
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+	path := "/api/v1/repo/" + strconv.FormatInt(repoID, 10) + "/projects/history?dir=" + url.QueryEscape("/projects/a")
+
+	var history dto.ProjectHistoryDTO
+	if status := getJSON(t, dashboard, path, token, &history); status != http.StatusOK {
+		t.Fatalf("GetProjectHistory: expected 200, got %d", status)
+	}
+	if len(history.Runs) != 3 || len(history.DriftPeriods) != 2 {
+		t.Fatalf("got %d runs and periods %+v, want 3 runs and two periods", len(history.Runs), history.DriftPeriods)
+	}
+	// The Terraform drift ended when the project became a Tofu one, while the Tofu drift is ongoing.
+	switchedAt := history.Runs[1].CreatedAt
+	for _, period := range history.DriftPeriods {
+		switch period.Type {
+		case "TERRAFORM":
+			if period.EndedAt == nil || !period.EndedAt.Equal(switchedAt) {
+				t.Errorf("TERRAFORM period = %+v, want it ended at %s", period, switchedAt)
+			}
+		default:
+			if period.EndedAt != nil {
+				t.Errorf("%s period = %+v, want it ongoing", period.Type, period)
+			}
+		}
+	}
+	if status := getJSON(t, dashboard, path+"&type=terraform", token, &history); status != http.StatusOK {
+		t.Fatalf("GetProjectHistory: expected 200, got %d", status)
+	}
+	if len(history.DriftPeriods) != 1 || history.DriftPeriods[0].EndedAt == nil || !history.DriftPeriods[0].EndedAt.Equal(switchedAt) {
+		t.Errorf("type=terraform: drift_periods = %+v, want one ended at %s", history.DriftPeriods, switchedAt)
+	}
+
+	if status := getJSON(t, dashboard, path+"&type=tofu", token, &history); status != http.StatusOK {
+		t.Fatalf("GetProjectHistory: expected 200, got %d", status)
+	}
+	if len(history.Runs) != 2 || history.Runs[0].Type != "TOFU" || history.Runs[1].Type != "TOFU" {
+		t.Errorf("type=tofu: runs = %+v, want the two Tofu runs", history.Runs)
+	}
+	if len(history.DriftPeriods) != 1 || history.DriftPeriods[0].Type != "TOFU" ||
+		!history.DriftPeriods[0].StartedAt.Equal(history.Runs[0].CreatedAt) {
+		t.Errorf("type=tofu: drift_periods = %+v, want one since the last run", history.DriftPeriods)
+	}
+
+	if status := getJSON(t, dashboard, path+"&type=terragrunt", token, nil); status != http.StatusNotFound {
+		t.Errorf("type without runs: expected 404, got %d", status)
+	}
+	if status := getJSON(t, dashboard, path+"&type=ansible", token, nil); status != http.StatusBadRequest {
+		t.Errorf("unknown type: expected 400, got %d", status)
+	}
+}
@@ -0,0 +1,71 @@
+package integration
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"driftive.cloud/api/pkg/model/dto"
+)
+
+// TestProjectOutput_RenderAndDiff ingests two runs whose colored plan output for /projects/a
+// differs by one line, and checks the output endpoint strips or converts the colors and the diff
+// endpoint reports the changed line.
+func TestProjectOutput_RenderAndDiff(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+
+	app := newIngestApp(t)
+	ingest := func(planOutput string) string {
+		t.Helper()
+		state := sampleState()
+		state.ProjectResults[0].PlanOutput = planOutput
+		status, body := postIngest(t, app, seedAnalysisToken, "", state)
+		if status != http.StatusOK {
+			t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+		}
+		return runIDFromResponse(t, body)
+	}
+	baseRunID := ingest("\x1b[33m~\x1b[0m resource \"aws_s3_bucket\" \"logs\" {\n      acl = \"private\"\n  }\n")
+	runID := ingest("\x1b[33m~\x1b[0m resource \"aws_s3_bucket\" \"logs\" {\n      acl = \"public-read\"\n  }\n")
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+	dir := url.QueryEscape("/projects/a")
+
+	var output dto.ProjectOutputDTO
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+runID+"/output?dir="+dir, token, &output); status != http.StatusOK {
+		t.Fatalf("GetRunProjectOutput: expected 200, got %d", status)
+	}
+	if want := "~ resource \"aws_s3_bucket\" \"logs\" {\n      acl = \"public-read\"\n  }\n"; output.Output != want || output.Format != "text" {
+		t.Errorf("text output = %q (%s), want %q", output.Output, output.Format, want)
+	}
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+runID+"/output?format=html&dir="+dir, token, &output); status != http.StatusOK {
+		t.Fatalf("GetRunProjectOutput: expected 200, got %d", status)
+	}
+	if want := "<span class=\"ansi-yellow\">~</span> resource &#34;aws_s3_bucket&#34; &#34;logs&#34; {\n      acl = &#34;public-read&#34;\n  }\n"; output.Output != want {
+		t.Errorf("html output = %q, want %q", output.Output, want)
+	}
+
+	var diff dto.ProjectOutputDiffDTO
+	path := "/api/v1/analysis/run/" + runID + "/diff?dir=" + dir + "&base_run_id=" + baseRunID
+	if status := getJSON(t, dashboard, path, token, &diff); status != http.StatusOK {
+		t.Fatalf("GetRunProjectOutputDiff: expected 200, got %d", status)
+	}
+	wantDiff := "--- " + baseRunID + "\n+++ " + runID + "\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" ~ resource \"aws_s3_bucket\" \"logs\" {\n" +
+		"-      acl = \"private\"\n" +
+		"+      acl = \"public-read\"\n" +
+		"   }\n"
+	if diff.Diff != wantDiff || diff.Added != 1 || diff.Removed != 1 {
+		t.Errorf("diff = %+v, want %q", diff, wantDiff)
+	}
+
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+runID+"/output?dir="+url.QueryEscape("/projects/missing"), token, nil); status != http.StatusNotFound {
+		t.Errorf("unknown dir: expected 404, got %d", status)
+	}
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+runID+"/diff?dir="+dir, token, nil); status != http.StatusBadRequest {
+		t.Errorf("missing base_run_id: expected 400, got %d", status)
+	}
+}
@@ -0,0 +1,85 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"driftive.cloud/api/pkg/model/dto"
+)
+
+// TestProjectTiming_StoredAndRanked ingests two runs reporting per-project timings, the first
+// backdated into the earlier half of the window, and checks the timings are returned with the run
+// and the project that got slower is ranked and reported as a regression.
+func TestProjectTiming_StoredAndRanked(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	app := newIngestApp(t)
+
+	ingest := func(plan time.Duration) string {
+		t.Helper()
+		state := sampleState()
+		initDuration, retries := 2*time.Second, int32(1)
+		started := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+		finished := started.Add(initDuration + plan + 3*time.Second)
+		state.ProjectResults[0].InitDuration = &initDuration
+		state.ProjectResults[0].PlanDuration = &plan
+		state.ProjectResults[0].StartedAt = &started
+		state.ProjectResults[0].FinishedAt = &finished
+		state.ProjectResults[0].Retries = &retries
+		status, body := postIngest(t, app, seedAnalysisToken, "", state)
+		if status != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", status, body)
+		}
+		return runIDFromResponse(t, body)
+	}
+
+	oldRun := ingest(10 * time.Second)
+	if _, err := withPool(t).Exec(context.Background(),
+		`UPDATE drift_analysis_run SET created_at = NOW() - INTERVAL '20 days' WHERE uuid = $1::uuid`, oldRun); err != nil {
+		t.Fatalf("backdate run: %v", err)
+	}
+	runID := ingest(30 * time.Second)
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+	var run dto.DriftAnalysisRunWithProjectsDTO
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+runID, token, &run); status != http.StatusOK {
+		t.Fatalf("GetRunById: expected 200, got %d", status)
+	}
+	for _, p := range run.Projects {
+		switch p.Dir {
+		case "/projects/a":
+			if p.PlanDurationMillis == nil || *p.PlanDurationMillis != 30000 || p.InitDurationMillis == nil || *p.InitDurationMillis != 2000 ||
+				p.StartedAt == nil || p.FinishedAt == nil || p.Retries == nil || *p.Retries != 1 {
+				t.Errorf("unexpected timing for %s: %+v", p.Dir, p)
+			}
+		default:
+			if p.PlanDurationMillis != nil || p.StartedAt != nil || p.Retries != nil {
+				t.Errorf("expected no timing for %s, got %+v", p.Dir, p)
+			}
+		}
+	}
+
+	var trends dto.DurationTrendsDTO
+	if status := getJSON(t, dashboard, "/api/v1/repo/"+strconv.FormatInt(repoID, 10)+"/trends/durations", token, &trends); status != http.StatusOK {
+		t.Fatalf("GetRepositoryDurationTrends: expected 200, got %d", status)
+	}
+	// Wall-clock time wins over init plus plan: 15s and 35s.
+	want := dto.SlowProjectDTO{
+		Dir: "/projects/a", Type: "TERRAFORM", RunCount: 2,
+		AvgDurationMillis: 25000, MaxDurationMillis: 35000,
+		AvgInitDurationMillis: 2000, AvgPlanDurationMillis: 20000, TotalRetries: 2,
+	}
+	if len(trends.SlowestProjects) != 1 || trends.SlowestProjects[0] != want {
+		t.Errorf("slowest_projects = %+v, want [%+v]", trends.SlowestProjects, want)
+	}
+	if len(trends.Regressions) != 1 {
+		t.Fatalf("regressions = %+v, want one", trends.Regressions)
+	}
+	if r := trends.Regressions[0]; r.Dir != "/projects/a" || r.BaselineAvgDurationMillis != 15000 || r.RecentAvgDurationMillis != 35000 {
+		t.Errorf("unexpected regression %+v", r)
+	}
+}
@@ -0,0 +1,92 @@
+package integration
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/usecase/drift_stream"
+)
+
+// TestProjectTree_RollupAndSubtreeTrends ingests the sample state, then a run that also scans
+// envs/prod/vpc, and checks the tree of the latest run counts every status at each dir and the
+// subtree trends only count the runs that scanned the subtree.
+func TestProjectTree_RollupAndSubtreeTrends(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+
+	app := newIngestApp(t)
+	first := sampleState()
+	second := sampleState()
+	second.ProjectResults = append(second.ProjectResults, drift_stream.DriftProjectResult{
+		Project:   drift_stream.TypedProject{Dir: "./envs/prod/vpc", Type: drift_stream.Terraform},
+		Drifted:   true,
+		Succeeded: true,
+	})
+	second.TotalDrifted, second.TotalProjects, second.TotalChecked = 2, 4, 4
+	var runID string
+	for _, state := range []drift_stream.DriftDetectionResult{first, second} {
+		status, body := postIngest(t, app, seedAnalysisToken, "", state)
+		if status != http.StatusOK {
+			t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+		}
+		runID = runIDFromResponse(t, body)
+	}
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+	base := "/api/v1/repo/" + strconv.FormatInt(repoID, 10) + "/projects/tree"
+
+	var tree dto.ProjectTreeDTO
+	if status := getJSON(t, dashboard, base, token, &tree); status != http.StatusOK {
+		t.Fatalf("GetProjectTree: expected 200, got %d", status)
+	}
+	if tree.RunID != runID {
+		t.Errorf("run_id = %s, want the latest run %s", tree.RunID, runID)
+	}
+	if want := (dto.ProjectStatusCountsDTO{Total: 4, InSync: 1, Drifted: 2, Skipped: 1}); tree.Root.Counts != want {
+		t.Errorf("root counts = %+v, want %+v", tree.Root.Counts, want)
+	}
+	if len(tree.Root.Children) != 2 || tree.Root.Children[0].Path != "envs" || tree.Root.Children[1].Path != "projects" {
+		t.Fatalf("root children = %+v, want envs and projects", tree.Root.Children)
+	}
+
+	if status := getJSON(t, dashboard, base+"?prefix=/projects/", token, &tree); status != http.StatusOK {
+		t.Fatalf("GetProjectTree prefix: expected 200, got %d", status)
+	}
+	if tree.Root.Path != "projects" || len(tree.Root.Children) != 3 {
+		t.Fatalf("projects subtree = %+v, want three projects", tree.Root)
+	}
+	if leaf := tree.Root.Children[0]; leaf.Path != "projects/a" || leaf.Project == nil || leaf.Project.Status != "DRIFTED" || leaf.Project.Type != "TERRAFORM" {
+		t.Errorf("projects/a = %+v, want a drifted TERRAFORM project", leaf)
+	}
+	if status := getJSON(t, dashboard, base+"?prefix=envs/staging", token, nil); status != http.StatusNotFound {
+		t.Errorf("unknown prefix: expected 404, got %d", status)
+	}
+
+	var trends dto.SubtreeTrendsDTO
+	if status := getJSON(t, dashboard, base+"/trends?prefix=envs/prod", token, &trends); status != http.StatusOK {
+		t.Fatalf("GetSubtreeTrends: expected 200, got %d", status)
+	}
+	if len(trends.DriftRateOverTime) != 1 {
+		t.Fatalf("envs/prod trends = %+v, want one day", trends.DriftRateOverTime)
+	}
+	if day := trends.DriftRateOverTime[0]; day.TotalRuns != 1 || day.RunsWithDrift != 1 || day.ProjectsScanned != 1 || day.ProjectsDrifted != 1 {
+		t.Errorf("envs/prod day = %+v, want one drifted run of one project", day)
+	}
+
+	if status := getJSON(t, dashboard, base+"/trends?prefix=projects", token, &trends); status != http.StatusOK {
+		t.Fatalf("GetSubtreeTrends: expected 200, got %d", status)
+	}
+	if day := trends.DriftRateOverTime[0]; day.TotalRuns != 2 || day.ProjectsScanned != 6 || day.ProjectsDrifted != 2 || day.DriftRatePercent != 100 {
+		t.Errorf("projects day = %+v, want two drifted runs of three projects", day)
+	}
+	// A prefix matches whole dir segments only.
+	if status := getJSON(t, dashboard, base+"/trends?prefix=proj", token, &trends); status != http.StatusOK || len(trends.DriftRateOverTime) != 0 {
+		t.Errorf("partial segment: got %d with %+v, want 200 and no data", status, trends.DriftRateOverTime)
+	}
+	if status := getJSON(t, dashboard, base+"/trends", token, nil); status != http.StatusBadRequest {
+		t.Errorf("missing prefix: expected 400, got %d", status)
+	}
+}
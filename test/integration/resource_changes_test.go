@@ -0,0 +1,183 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"driftive.cloud/api/pkg/model/dto"
+)
+
+const resourceChangesPlan = `Terraform will perform the following actions:
+
+  # aws_instance.web will be updated in-place
+  ~ resource "aws_instance" "web" {
+        id            = "i-0123456789"
+      ~ instance_type = "t3.micro" -> "t3.small"
+    }
+
+  # data.aws_ami.ubuntu will be read during apply
+
+  # aws_s3_bucket.logs must be replaced
+-/+ resource "aws_s3_bucket" "logs" {
+    }
+
+Plan: 1 to add, 1 to change, 1 to destroy.
+`
+
+// TestResourceChanges_StoredAndRanked ingests a plan naming resources twice, the second time
+// colored as printed to a terminal, and checks the addresses are stored with the project, returned
+// with the run and ranked in the trends, leaving out those of a project that failed.
+func TestResourceChanges_StoredAndRanked(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	app := newIngestApp(t)
+
+	state := sampleState()
+	state.ProjectResults[0].PlanOutput = resourceChangesPlan
+	state.ProjectResults[1].Drifted = true
+	state.ProjectResults[1].Succeeded = false
+	state.ProjectResults[1].PlanOutput = "  # aws_iam_role.ci will be updated in-place\n"
+	var runID string
+	for i := 0; i < 2; i++ {
+		if i == 1 {
+			state.ProjectResults[0].PlanOutput = strings.NewReplacer(
+				"  # ", "  \x1b[1m# ", " will be", "\x1b[0m will be", " must be", "\x1b[0m must be",
+			).Replace(resourceChangesPlan)
+		}
+		status, body := postIngest(t, app, seedAnalysisToken, "", state)
+		if status != http.StatusOK {
+			t.Fatalf("ingest %d: expected 200, got %d: %s", i, status, body)
+		}
+		runID = runIDFromResponse(t, body)
+	}
+
+	var stored string
+	if err := withPool(t).QueryRow(context.Background(),
+		`SELECT resource_changes::TEXT FROM drift_analysis_project
+		 WHERE drift_analysis_run_id = $1::uuid AND dir = '/projects/a'`, runID).Scan(&stored); err != nil {
+		t.Fatalf("fetch project: %v", err)
+	}
+	want := `[{"action": "UPDATE", "address": "aws_instance.web"}, {"action": "READ", "address": "data.aws_ami.ubuntu"}, {"action": "REPLACE", "address": "aws_s3_bucket.logs"}]`
+	if stored != want {
+		t.Errorf("resource_changes = %s, want %s", stored, want)
+	}
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+	var run dto.DriftAnalysisRunWithProjectsDTO
+	if status := getJSON(t, dashboard, "/api/v1/analysis/run/"+runID, token, &run); status != http.StatusOK {
+		t.Fatalf("GetRunById: expected 200, got %d", status)
+	}
+	for _, p := range run.Projects {
+		switch p.Dir {
+		case "/projects/a":
+			if len(p.ResourceChanges) != 3 || p.ResourceChanges[2] != (dto.ResourceChangeDTO{Address: "aws_s3_bucket.logs", Action: "REPLACE"}) {
+				t.Errorf("unexpected resource changes %+v", p.ResourceChanges)
+			}
+		case "/projects/b":
+			if len(p.ResourceChanges) != 1 {
+				t.Errorf("expected the failed project's resource change to be stored, got %+v", p.ResourceChanges)
+			}
+		default:
+			if p.ResourceChanges == nil || len(p.ResourceChanges) != 0 {
+				t.Errorf("expected no resource changes for %s, got %+v", p.Dir, p.ResourceChanges)
+			}
+		}
+	}
+
+	var trends dto.RepositoryTrendsDTO
+	if status := getJSON(t, dashboard, "/api/v1/repo/"+strconv.FormatInt(repoID, 10)+"/trends", token, &trends); status != http.StatusOK {
+		t.Fatalf("GetRepositoryTrends: expected 200, got %d", status)
+	}
+	wantRanked := []dto.FrequentlyDriftedResource{
+		{Dir: "/projects/a", Address: "aws_instance.web", DriftCount: 2},
+		{Dir: "/projects/a", Address: "aws_s3_bucket.logs", DriftCount: 2},
+	}
+	if len(trends.FrequentlyDriftedResources) != len(wantRanked) {
+		t.Fatalf("frequently_drifted_resources = %+v, want %+v", trends.FrequentlyDriftedResources, wantRanked)
+	}
+	for i, r := range wantRanked {
+		if trends.FrequentlyDriftedResources[i] != r {
+			t.Errorf("frequently_drifted_resources[%d] = %+v, want %+v", i, trends.FrequentlyDriftedResources[i], r)
+		}
+	}
+}
+
+// TestResourceTrends_RankTypesAndProviders ingests plans into two repositories of an organization
+// and checks resource types and providers are ranked per repository and across the organization,
+// leaving out the resources of projects that failed or were skipped.
+func TestResourceTrends_RankTypesAndProviders(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	pool := withPool(t)
+	var orgID int64
+	if err := pool.QueryRow(context.Background(),
+		`INSERT INTO git_repository (organization_id, provider_id, name, is_private, analysis_token)
+		 SELECT organization_id, '778', 'other', false, 'other-token' FROM git_repository
+		 RETURNING organization_id`).Scan(&orgID); err != nil {
+		t.Fatalf("seed second repo: %v", err)
+	}
+
+	app := newIngestApp(t)
+	state := sampleState()
+	state.ProjectResults[0].PlanOutput = resourceChangesPlan
+	state.ProjectResults[1].Drifted = true
+	state.ProjectResults[1].Succeeded = false
+	state.ProjectResults[1].PlanOutput = "  # azurerm_resource_group.rg will be updated in-place\n"
+	state.ProjectResults[2].Drifted = true
+	state.ProjectResults[2].PlanOutput = "  # google_storage_bucket.assets will be updated in-place\n"
+	if status, body := postIngest(t, app, seedAnalysisToken, "", state); status != http.StatusOK {
+		t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+	}
+	state.ProjectResults[0].PlanOutput = `  # module.net.aws_security_group.db["primary db"] will be updated in-place
+  # google_compute_instance.vm has changed
+`
+	if status, body := postIngest(t, app, "other-token", "", state); status != http.StatusOK {
+		t.Fatalf("ingest other: expected 200, got %d: %s", status, body)
+	}
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+
+	var repoTrends dto.ResourceTrendsDTO
+	if status := getJSON(t, dashboard, "/api/v1/repo/"+strconv.FormatInt(repoID, 10)+"/trends/resources", token, &repoTrends); status != http.StatusOK {
+		t.Fatalf("GetRepositoryResourceTrends: expected 200, got %d", status)
+	}
+	wantTypes := []dto.ResourceTypeDriftStat{
+		{ResourceType: "aws_instance", Provider: "aws", DriftCount: 1, ResourceCount: 1, RepositoryCount: 1},
+		{ResourceType: "aws_s3_bucket", Provider: "aws", DriftCount: 1, ResourceCount: 1, RepositoryCount: 1},
+	}
+	if !slices.Equal(repoTrends.ResourceTypes, wantTypes) {
+		t.Errorf("repository resource_types = %+v, want %+v", repoTrends.ResourceTypes, wantTypes)
+	}
+	wantProviders := []dto.ProviderDriftStat{{Provider: "aws", DriftCount: 1, ResourceCount: 2, RepositoryCount: 1}}
+	if !slices.Equal(repoTrends.Providers, wantProviders) {
+		t.Errorf("repository providers = %+v, want %+v", repoTrends.Providers, wantProviders)
+	}
+
+	var orgTrends dto.ResourceTrendsDTO
+	if status := getJSON(t, dashboard, "/api/v1/org/"+strconv.FormatInt(orgID, 10)+"/trends/resources?days_back=7", token, &orgTrends); status != http.StatusOK {
+		t.Fatalf("GetOrganizationResourceTrends: expected 200, got %d", status)
+	}
+	wantProviders = []dto.ProviderDriftStat{
+		{Provider: "aws", DriftCount: 2, ResourceCount: 3, RepositoryCount: 2},
+		{Provider: "google", DriftCount: 1, ResourceCount: 1, RepositoryCount: 1},
+	}
+	if !slices.Equal(orgTrends.Providers, wantProviders) {
+		t.Errorf("organization providers = %+v, want %+v", orgTrends.Providers, wantProviders)
+	}
+	if len(orgTrends.ResourceTypes) != 4 || orgTrends.ResourceTypes[0].ResourceType != "aws_instance" {
+		t.Errorf("unexpected organization resource_types %+v", orgTrends.ResourceTypes)
+	}
+	if orgTrends.DaysBack != 7 {
+		t.Errorf("days_back = %d, want 7", orgTrends.DaysBack)
+	}
+
+	if status := getJSON(t, dashboard, "/api/v1/org/"+strconv.FormatInt(orgID+1, 10)+"/trends/resources", token, nil); status != http.StatusUnauthorized {
+		t.Errorf("another organization: expected 401, got %d", status)
+	}
+}
@@ -0,0 +1,160 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"driftive.cloud/api/pkg/config"
+	"driftive.cloud/api/pkg/middleware/perms"
+	"driftive.cloud/api/pkg/model/dto"
+	"driftive.cloud/api/pkg/repository"
+	"driftive.cloud/api/pkg/usecase/repos"
+	"driftive.cloud/api/pkg/usecase/schedule"
+	jwtware "github.com/gofiber/contrib/v3/jwt"
+	"github.com/gofiber/fiber/v3"
+)
+
+// newScheduleApp builds a Fiber app exposing the scan schedule and alert endpoints behind the same JWT and
+// perms middleware as main.go.
+func newScheduleApp(t *testing.T) *fiber.App {
+	t.Helper()
+	r := repository.NewRepository(testDB, &config.Config{})
+	handler := repos.NewGitRepositoryHandler(r.GitOrgRepository(), r.GitRepoRepository(), r.UserRepository(), r.DriftAnalysisRepository())
+	app := fiber.New()
+	app.Use(jwtware.New(jwtware.Config{SigningKey: jwtware.SigningKey{Key: []byte(testJWTSecret)}}))
+	app.Use(perms.New(r.GitOrgRepository()))
+	app.Put("/api/v1/repo/:repo_id/scan_schedule", func(c fiber.Ctx) error { return handler.UpdateScanSchedule(c) })
+	app.Get("/api/v1/repo/:repo_id/scan_alerts", func(c fiber.Ctx) error { return handler.ListScanAlerts(c) })
+	return app
+}
+
+// overdueAlerts returns the open and resolved overdue alerts of a repository.
+func overdueAlerts(t *testing.T, repoID int64) (open int, resolved int) {
+	t.Helper()
+	if err := testDB.Pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FILTER (WHERE resolved_at IS NULL), COUNT(*) FILTER (WHERE resolved_at IS NOT NULL)
+		 FROM scan_overdue_alert WHERE repository_id = $1`, repoID).Scan(&open, &resolved); err != nil {
+		t.Fatalf("count overdue alerts: %v", err)
+	}
+	return open, resolved
+}
+
+// TestScanSchedule_OverdueAlert declares a max interval, lets it pass without a completed run and
+// checks the alert is raised once, shown in the stats and alert list, kept by a run that failed
+// every project and by a rescan of some dirs, and resolved by the next completed scan.
+func TestScanSchedule_OverdueAlert(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	token := seedMember(t, repoID)
+	ctx := context.Background()
+	app := newScheduleApp(t)
+	path := fmt.Sprintf("/api/v1/repo/%d/scan_schedule", repoID)
+
+	invalid := "0 2 * *"
+	if status, _ := sendJSON(t, app, http.MethodPut, path, token, dto.UpdateScanScheduleRequest{Cron: &invalid}); status != http.StatusBadRequest {
+		t.Fatalf("invalid cron: expected 400, got %d", status)
+	}
+	nightly, hour := "0 2 * * *", int32(60)
+	if status, _ := sendJSON(t, app, http.MethodPut, path, token, dto.UpdateScanScheduleRequest{Cron: &nightly, MaxIntervalMinutes: &hour}); status != http.StatusBadRequest {
+		t.Fatalf("cron and interval: expected 400, got %d", status)
+	}
+	if status, body := sendJSON(t, app, http.MethodPut, path, token, dto.UpdateScanScheduleRequest{MaxIntervalMinutes: &hour}); status != http.StatusNoContent {
+		t.Fatalf("schedule: expected 204, got %d: %s", status, body)
+	}
+
+	r := repository.NewRepository(testDB, &config.Config{})
+	monitor := schedule.NewOverdueMonitor(r.GitRepoRepository())
+	if err := monitor.CheckOverdue(ctx, time.Now()); err != nil {
+		t.Fatalf("CheckOverdue: %v", err)
+	}
+	if open, _ := overdueAlerts(t, repoID); open != 0 {
+		t.Fatalf("open alerts right after declaring the schedule = %d, want 0", open)
+	}
+
+	// Pretend the schedule was declared two hours ago, with no run since.
+	if _, err := testDB.Pool.Exec(ctx, "UPDATE git_repository SET scan_schedule_set_at = NOW() - INTERVAL '2 hours' WHERE id = $1", repoID); err != nil {
+		t.Fatalf("backdate schedule: %v", err)
+	}
+	for range 2 {
+		if err := monitor.CheckOverdue(ctx, time.Now()); err != nil {
+			t.Fatalf("CheckOverdue: %v", err)
+		}
+	}
+	if open, _ := overdueAlerts(t, repoID); open != 1 {
+		t.Fatalf("open alerts once overdue = %d, want 1", open)
+	}
+
+	dashboard := newDashboardApp(t, nil)
+	statsPath := fmt.Sprintf("/api/v1/repo/%d/stats", repoID)
+	var stats dto.RepositoryRunStatsDTO
+	if status := getJSON(t, dashboard, statsPath, token, &stats); status != http.StatusOK {
+		t.Fatalf("stats: expected 200, got %d", status)
+	}
+	if !stats.ScanOverdue || stats.ScanDueAt == nil {
+		t.Errorf("stats while overdue = overdue %v due %v, want overdue", stats.ScanOverdue, stats.ScanDueAt)
+	}
+	alertsPath := fmt.Sprintf("/api/v1/repo/%d/scan_alerts", repoID)
+	var alerts []dto.ScanOverdueAlertDTO
+	if status := getJSON(t, app, alertsPath, token, &alerts); status != http.StatusOK {
+		t.Fatalf("scan alerts: expected 200, got %d", status)
+	}
+	if len(alerts) != 1 || alerts[0].ResolvedAt != nil || alerts[0].LastCompletedAt != nil {
+		t.Errorf("scan alerts while overdue = %+v, want one open alert without a completed run", alerts)
+	}
+
+	ingest := newIngestApp(t)
+	errored := cleanProject("/envs/prod")
+	errored.Succeeded = false
+	state := singleProjectState(errored)
+	one := int32(1)
+	state.TotalErrored = &one
+	if status, body := postIngest(t, ingest, seedAnalysisToken, "", state); status != http.StatusOK {
+		t.Fatalf("errored ingest: expected 200, got %d: %s", status, body)
+	}
+	dispatchID := "4c7e2a90-1b3d-4e8f-a6c5-0d9b8f7e6a12"
+	if _, err := testDB.Pool.Exec(ctx,
+		`INSERT INTO scan_dispatch (id, repository_id, kind, dirs, requested_by)
+		 VALUES ($1::uuid, $2, 'SCAN', '{/envs/prod}', (SELECT id FROM users LIMIT 1))`, dispatchID, repoID); err != nil {
+		t.Fatalf("seed dispatch: %v", err)
+	}
+	if status, body := postIngest(t, ingest, seedAnalysisToken, dispatchID, singleProjectState(cleanProject("/envs/prod"))); status != http.StatusOK {
+		t.Fatalf("scoped ingest: expected 200, got %d: %s", status, body)
+	}
+	if err := monitor.CheckOverdue(ctx, time.Now()); err != nil {
+		t.Fatalf("CheckOverdue: %v", err)
+	}
+	if open, _ := overdueAlerts(t, repoID); open != 1 {
+		t.Fatalf("open alerts after a failed run and a rescan of some dirs = %d, want 1", open)
+	}
+	if status := getJSON(t, dashboard, statsPath, token, &stats); status != http.StatusOK {
+		t.Fatalf("stats: expected 200, got %d", status)
+	}
+	if !stats.ScanOverdue {
+		t.Errorf("stats after a failed run and a rescan of some dirs = overdue %v, want overdue", stats.ScanOverdue)
+	}
+
+	if status, body := postIngest(t, ingest, seedAnalysisToken, "", singleProjectState(cleanProject("/envs/prod"))); status != http.StatusOK {
+		t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+	}
+	if err := monitor.CheckOverdue(ctx, time.Now()); err != nil {
+		t.Fatalf("CheckOverdue: %v", err)
+	}
+	if open, resolved := overdueAlerts(t, repoID); open != 0 || resolved != 1 {
+		t.Errorf("alerts after a completed run = %d open, %d resolved, want 0 and 1", open, resolved)
+	}
+	if status := getJSON(t, dashboard, statsPath, token, &stats); status != http.StatusOK {
+		t.Fatalf("stats: expected 200, got %d", status)
+	}
+	if stats.ScanOverdue || stats.ScanDueAt == nil || stats.ScanDueAt.Before(time.Now()) {
+		t.Errorf("stats after a completed run = overdue %v due %v, want due in the future", stats.ScanOverdue, stats.ScanDueAt)
+	}
+	if status := getJSON(t, app, alertsPath, token, &alerts); status != http.StatusOK {
+		t.Fatalf("scan alerts: expected 200, got %d", status)
+	}
+	if len(alerts) != 1 || alerts[0].ResolvedAt == nil {
+		t.Errorf("scan alerts after a completed run = %+v, want one resolved alert", alerts)
+	}
+}
@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	"driftive.cloud/api/pkg/model/dto"
+)
+
+// TestVersionInventory_ListsAndFlagsOutdated ingests init outputs naming different Terraform and
+// provider versions into two repositories of an organization, and checks the inventory counts the
+// versions and the outdated endpoint flags the projects below a minimum.
+func TestVersionInventory_ListsAndFlagsOutdated(t *testing.T) {
+	truncateAll(t)
+	repoID := seedOrgAndRepo(t)
+	var orgID int64
+	if err := withPool(t).QueryRow(context.Background(),
+		`INSERT INTO git_repository (organization_id, provider_id, name, is_private, analysis_token)
+		 SELECT organization_id, '778', 'other', false, 'other-token' FROM git_repository
+		 RETURNING organization_id`).Scan(&orgID); err != nil {
+		t.Fatalf("seed second repo: %v", err)
+	}
+
+	app := newIngestApp(t)
+	ingest := func(token, initOutput string) {
+		t.Helper()
+		state := sampleState()
+		state.ProjectResults[0].InitOutput = initOutput
+		if status, body := postIngest(t, app, token, "", state); status != http.StatusOK {
+			t.Fatalf("ingest: expected 200, got %d: %s", status, body)
+		}
+	}
+	ingest(seedAnalysisToken, "Terraform v1.5.7\n- Installing hashicorp/aws v5.31.0...\n- Installed hashicorp/aws v5.31.0 (signed by HashiCorp)\n")
+	ingest("other-token", "Terraform v1.6.6\n- Using previously-installed hashicorp/aws v4.67.0\n")
+
+	dashboard := newDashboardApp(t, nil)
+	token := seedMember(t, repoID)
+	orgPath := "/api/v1/org/" + strconv.FormatInt(orgID, 10)
+
+	var inventory dto.VersionInventoryDTO
+	if status := getJSON(t, dashboard, orgPath+"/inventory/versions", token, &inventory); status != http.StatusOK {
+		t.Fatalf("GetOrganizationVersionInventory: expected 200, got %d", status)
+	}
+	wantCore := []dto.VersionUsageDTO{
+		{Component: "TERRAFORM", Version: "1.6.6", ProjectCount: 1},
+		{Component: "TERRAFORM", Version: "1.5.7", ProjectCount: 1},
+	}
+	if !slices.Equal(inventory.CoreVersions, wantCore) {
+		t.Errorf("core_versions = %+v, want %+v", inventory.CoreVersions, wantCore)
+	}
+	wantProviders := []dto.VersionUsageDTO{
+		{Component: "hashicorp/aws", Version: "5.31.0", ProjectCount: 1},
+		{Component: "hashicorp/aws", Version: "4.67.0", ProjectCount: 1},
+	}
+	if !slices.Equal(inventory.ProviderVersions, wantProviders) {
+		t.Errorf("provider_versions = %+v, want %+v", inventory.ProviderVersions, wantProviders)
+	}
+	// The other projects' init outputs name no version.
+	if len(inventory.Projects) != 2 {
+		t.Fatalf("projects = %+v, want two", inventory.Projects)
+	}
+	for _, p := range inventory.Projects {
+		if p.Dir != "/projects/a" || len(p.ProviderVersions) != 1 || p.CoreVersion == nil {
+			t.Errorf("unexpected project %+v", p)
+		}
+	}
+
+	var outdated dto.OutdatedProjectsDTO
+	if status := getJSON(t, dashboard, orgPath+"/inventory/outdated?component=terraform&min_version=1.6", token, &outdated); status != http.StatusOK {
+		t.Fatalf("GetOrganizationOutdatedVersions: expected 200, got %d", status)
+	}
+	if len(outdated.Projects) != 1 || outdated.Projects[0].RepositoryID != repoID || outdated.Projects[0].Version != "1.5.7" {
+		t.Errorf("outdated terraform = %+v, want the first repository on 1.5.7", outdated.Projects)
+	}
+	if status := getJSON(t, dashboard, orgPath+"/inventory/outdated?component=hashicorp/aws&min_version=5.0.0", token, &outdated); status != http.StatusOK {
+		t.Fatalf("GetOrganizationOutdatedVersions: expected 200, got %d", status)
+	}
+	if len(outdated.Projects) != 1 || outdated.Projects[0].RepositoryName != "other" || outdated.Projects[0].Version != "4.67.0" {
+		t.Errorf("outdated hashicorp/aws = %+v, want the other repository on 4.67.0", outdated.Projects)
+	}
+
+	if status := getJSON(t, dashboard, orgPath+"/inventory/outdated?component=terraform&min_version=latest", token, nil); status != http.StatusBadRequest {
+		t.Errorf("invalid min_version: expected 400, got %d", status)
+	}
+	if status := getJSON(t, dashboard, "/api/v1/org/"+strconv.FormatInt(orgID+1, 10)+"/inventory/versions", token, nil); status != http.StatusUnauthorized {
+		t.Errorf("another organization: expected 401, got %d", status)
+	}
+}